@@ -9,10 +9,13 @@ import (
 	"normaladmin/backend/internal/middleware"
 	"normaladmin/backend/internal/services"
 	"normaladmin/backend/pkg/auth"
+	"normaladmin/backend/pkg/logger"
+	"normaladmin/backend/pkg/mail"
 	"normaladmin/backend/pkg/rabbitmq"
 	"normaladmin/backend/pkg/sysconfig"
 	"normaladmin/backend/pkg/websocket"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -33,12 +36,28 @@ func SetupRoutes(r *gin.Engine, conf *config.Config, mq *rabbitmq.RabbitMQ) {
 	if _, err := auth.InitCasbin(db, rootDir); err != nil {
 		log.Fatalf("初始化 Casbin 失败: %v", err)
 	}
+	// 订阅其他实例的权限策略变更
+	channel := conf.Casbin.WatcherChannel
+	if channel == "" {
+		channel = "casbin:policy"
+	}
+	if _, err := auth.StartPolicyWatcher(channel, time.Duration(conf.Casbin.ReloadInterval)*time.Second); err != nil {
+		log.Fatalf("启动权限策略监听失败: %v", err)
+	}
 
 	// 初始化系统配置
 	if err := sysconfig.Init(db); err != nil {
 		log.Fatalf("初始化系统配置失败: %v", err)
 	}
 
+	// 按角色菜单重建角色权限策略、按管理员角色重建分组策略，修正历史遗留的不一致
+	if result, err := services.NewPolicySyncService(db).SyncAll(); err != nil {
+		log.Fatalf("同步角色权限策略失败: %v", err)
+	} else {
+		logger.Info("角色权限策略同步完成", logger.Field("roles", result.Roles), logger.Field("policies", result.Policies),
+			logger.Field("admins", result.Admins), logger.Field("groupings", result.Groupings))
+	}
+
 	// 文件访问路由
 	files := r.Group("/uploads")
 	files.Use(middleware.AntiLeech())
@@ -50,7 +69,7 @@ func SetupRoutes(r *gin.Engine, conf *config.Config, mq *rabbitmq.RabbitMQ) {
 	notificationHub := websocket.NewNotificationHub()
 	go notificationHub.Run()
 	// WebSocket路由 - 不经过认证中间件
-	wsHandler := handlers.NewWebSocketHandler(notificationHub, conf.JWT.SecretKey)
+	wsHandler := handlers.NewWebSocketHandler(notificationHub, conf.JWT)
 
 	r.GET("/ws/notifications", wsHandler.WebSocketHandler)
 
@@ -62,29 +81,90 @@ func SetupRoutes(r *gin.Engine, conf *config.Config, mq *rabbitmq.RabbitMQ) {
 		}
 	}()
 
+	// 令牌验证公钥，供其他服务验证管理端令牌
+	r.GET("/.well-known/jwks.json", handlers.GetJWKS)
+
+	// 图形验证码，管理端和会员端登录注册共用
+	captchaService := services.NewCaptchaService()
+	r.GET("/captcha", handlers.GetCaptcha(captchaService))
+
 	// 后台管理需要认证的路由
 	gam := r.Group("/gam")
-	gam.POST("/refresh-token", handlers.RefreshToken(conf.JWT))
-	gam.POST("/login", handlers.Login(conf.JWT))
-
-	gam.Use(middleware.JWTAuth(conf.JWT))
+	sessionService := services.NewSessionService(db, notificationHub, middleware.RefreshTokenTTL(conf.JWT))
+	sessionHandler := handlers.NewSessionHandler(sessionService)
+	notificationService := services.NewNotificationService(db, mq, notificationHub)
+	mailSender, err := mail.NewSender(conf.Mail)
+	if err != nil {
+		log.Fatalf("初始化邮件发送失败: %v", err)
+	}
+	passwordPolicyService := services.NewPasswordPolicyService(db)
+	authServices := &handlers.AuthServices{
+		MFA:            services.NewMFAService(db, conf.JWT.Issuer),
+		LoginGuard:     services.NewLoginGuardService(),
+		Sessions:       sessionService,
+		LoginLogs:      services.NewLoginLogService(db),
+		PasswordPolicy: passwordPolicyService,
+		Impersonation:  services.NewImpersonationService(db, notificationService),
+		Captcha:        captchaService,
+		MemberEmail:    services.NewMemberEmailService(db, mailSender, conf.Mail.LinkBaseURL),
+		Tenants:        services.NewTenantService(db, services.NewPolicySyncService(db), passwordPolicyService),
+	}
+	mfaHandler := handlers.NewMFAHandler(authServices, conf.JWT)
+	oidcHandler := handlers.NewOIDCHandler(services.NewOIDCService(db, conf.OIDC, nil), authServices, conf.JWT)
+	gam.POST("/refresh-token", handlers.RefreshToken(conf.JWT, middleware.UserTypeAdmin, authServices))
+	gam.POST("/login", handlers.Login(conf.JWT, authServices))
+	gam.POST("/login/password", handlers.ChangeExpiredPassword(conf.JWT, authServices))
+	gam.POST("/login/mfa", mfaHandler.VerifyLogin)
+	gam.POST("/login/mfa/setup", mfaHandler.SetupLogin)
+	gam.GET("/oidc/authorize", oidcHandler.Authorize)
+	gam.POST("/oidc/callback", oidcHandler.Callback)
+
+	// 此前注册的路由都不经过接口权限校验，供权限报告区分
+	publicRoutes := r.Routes()
+
+	gam.Use(middleware.JWTAuth(conf.JWT, middleware.UserTypeAdmin))
 	gam.Use(middleware.RequestLoggerMiddleware(db))
+	// 接口权限校验，个人相关接口通过系统配置 casbin_allowlist 免检
+	gam.Use(middleware.CasbinMiddleware())
 	{
 		// 认证相关路由
 		gam.GET("/authmenus", handlers.GetAuthMenus) // 获取用户的菜单和权限信息
-
-		gam.Use(middleware.CasbinMiddleware())
+		gam.POST("/logout", handlers.Logout(conf.JWT, authServices))
+
+		// 登录会话管理，超级管理员可管理任意用户的会话
+		gam.GET("/sessions", sessionHandler.GetSessions)
+		gam.DELETE("/sessions", sessionHandler.RevokeAllSessions)
+		gam.DELETE("/sessions/:session_id", sessionHandler.RevokeSession)
+
+		// 当前管理员的两步验证设置
+		gam.POST("/mfa/enroll", mfaHandler.Enroll)
+		gam.POST("/mfa/confirm", mfaHandler.ConfirmEnroll)
+		gam.POST("/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+		gam.POST("/mfa/disable", mfaHandler.Disable)
+
+		// 当前管理员的 API 密钥，超级管理员可管理任意管理员的密钥
+		apiKeyHandler := handlers.NewAPIKeyHandler(services.NewAPIKeyService(db))
+		gam.GET("/api-keys", apiKeyHandler.GetAPIKeys)
+		gam.GET("/api-keys/scopes", apiKeyHandler.GetAPIKeyScopes)
+		gam.POST("/api-keys", apiKeyHandler.CreateAPIKey)
+		gam.DELETE("/api-keys/:id", apiKeyHandler.RevokeAPIKey)
 
 		// 注册路由
 		v1.RegisterMenuRoutes(gam)
 		v1.RegisterRoleRoutes(gam)
-		v1.RegisterAdminRoutes(gam)
-		v1.RegisterMemberRoutes(gam)
+		v1.RegisterDepartmentRoutes(gam)
+		v1.RegisterAdminRoutes(gam, conf.JWT, authServices)
+		v1.RegisterMemberRoutes(gam, conf.JWT, authServices)
 		v1.RegisterConfigRoutes(gam)
 		v1.RegisterUploadRoutes(gam)
 		v1.RegisterSystemRoutes(gam)
 		v1.RegisterSystemMonitorRoutes(gam)
 		v1.RegisterNotificationRoutes(gam, mq, notificationHub)
+		v1.RegisterSecurityRoutes(gam, authServices.LoginGuard)
+		v1.RegisterPermissionRoutes(gam, r.Routes, publicRoutes)
+		v1.RegisterApiEndpointRoutes(gam)
+		v1.RegisterRoleGrantRoutes(gam, notificationService)
+		v1.RegisterTenantRoutes(gam, authServices.Tenants)
 
 	}
 
@@ -93,13 +173,37 @@ func SetupRoutes(r *gin.Engine, conf *config.Config, mq *rabbitmq.RabbitMQ) {
 
 	memberAuth := apiv1.Group("/member")
 	{
-		memberAuth.POST("/register", handlers.MemberRegister)
-		memberAuth.POST("/login", handlers.MemberLogin(conf.JWT))
-		memberAuth.POST("/refresh-token", handlers.RefreshToken(conf.JWT))
+		memberAuth.POST("/register", handlers.MemberRegister(conf.JWT, authServices))
+		memberAuth.POST("/login", handlers.MemberLogin(conf.JWT, authServices))
+		memberAuth.POST("/login/password", handlers.MemberChangeExpiredPassword(conf.JWT, authServices))
+		memberAuth.POST("/refresh-token", handlers.RefreshToken(conf.JWT, middleware.UserTypeMember, authServices))
+
+		// 邮箱验证和找回密码
+		memberAuth.POST("/email/verify/request", handlers.MemberRequestEmailVerification(conf.JWT, authServices))
+		memberAuth.POST("/email/verify/confirm", handlers.MemberConfirmEmailVerification(conf.JWT, authServices))
+		memberAuth.POST("/password/reset/request", handlers.MemberRequestPasswordReset(conf.JWT, authServices))
+		memberAuth.POST("/password/reset/confirm", handlers.MemberResetPassword(conf.JWT, authServices))
 	}
 
-	apiv1.Use(middleware.JWTAuth(conf.JWT))
+	apiv1.Use(middleware.JWTAuth(conf.JWT, middleware.UserTypeMember))
+	// 模拟登录发起的会员请求记录到系统日志
+	apiv1.Use(middleware.ImpersonationLogger(db))
 	{
+		apiv1.POST("/member/logout", handlers.Logout(conf.JWT, authServices))
 
+		// 修改密码和注销账号不允许模拟登录的令牌执行
+		apiv1.PUT("/member/password", middleware.DenyImpersonation(), handlers.MemberChangePassword(authServices))
+		apiv1.DELETE("/member/account", middleware.DenyImpersonation(), handlers.MemberDeleteAccount(conf.JWT, authServices))
+	}
+
+	// 全部路由注册完成后维护接口目录，供按钮菜单校验接口
+	apiRoutes := make([]services.ApiRoute, 0)
+	for _, route := range r.Routes() {
+		apiRoutes = append(apiRoutes, services.ApiRoute{Method: route.Method, Path: route.Path, Handler: route.Handler})
+	}
+	if result, err := services.NewApiEndpointService(db).SyncRoutes(apiRoutes); err != nil {
+		logger.Error("同步接口目录失败", logger.Field("error", err))
+	} else {
+		logger.Info("接口目录同步完成", logger.Field("total", result.Total), logger.Field("added", result.Added), logger.Field("stale", result.Stale))
 	}
 }
@@ -1,8 +1,10 @@
 package v1
 
 import (
+	"normaladmin/backend/config"
 	"normaladmin/backend/database"
 	"normaladmin/backend/internal/handlers"
+	"normaladmin/backend/internal/middleware"
 	"normaladmin/backend/internal/models"
 	"normaladmin/backend/internal/services"
 
@@ -10,14 +12,14 @@ import (
 )
 
 // RegisterAdminRoutes 注册管理员相关路由
-func RegisterAdminRoutes(r *gin.RouterGroup) {
+func RegisterAdminRoutes(r *gin.RouterGroup, jwtConfig config.JWTConfig, authServices *handlers.AuthServices) {
 	db := database.GetDB()
 	base := services.NewBaseCRUDService[models.Admin](db)
 	cache := services.NewCacheBaseService(base, "admin")
 	log := services.NewLogBaseService(cache, "admin", db)
-	adminService := services.NewAdminService(db, log)
+	adminService := services.NewAdminService(db, log, middleware.RefreshTokenTTL(jwtConfig), authServices.PasswordPolicy, services.NewPolicySyncService(db))
 
-	h := handlers.NewAdminHandler(adminService)
+	h := handlers.NewAdminHandler(adminService, services.NewDataScopeService(db, services.NewDepartmentService(db)), services.NewFieldPermissionService(db), authServices.MFA, authServices.Sessions)
 	admins := r.Group("/admins")
 	{
 		admins.GET("", h.GetAdminList)
@@ -28,5 +30,6 @@ func RegisterAdminRoutes(r *gin.RouterGroup) {
 		admins.PUT("/:id/status", h.UpdateAdminStatus)
 		admins.PUT("/:id/password", h.UpdatePassword)
 		admins.GET("/check-field", h.CheckAdminFieldUnique)
+		admins.DELETE("/:id/mfa", h.ResetAdminMFA)
 	}
 }
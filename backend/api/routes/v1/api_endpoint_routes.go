@@ -0,0 +1,16 @@
+package v1
+
+import (
+	"normaladmin/backend/database"
+	"normaladmin/backend/internal/handlers"
+	"normaladmin/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterApiEndpointRoutes 注册接口目录相关路由
+func RegisterApiEndpointRoutes(r *gin.RouterGroup) {
+	h := handlers.NewApiEndpointHandler(services.NewApiEndpointService(database.GetDB()))
+
+	r.GET("/apis", h.GetApiEndpoints)
+}
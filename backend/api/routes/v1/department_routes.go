@@ -0,0 +1,29 @@
+package v1
+
+import (
+	"normaladmin/backend/database"
+	"normaladmin/backend/internal/handlers"
+	"normaladmin/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterDepartmentRoutes 注册部门相关路由
+func RegisterDepartmentRoutes(r *gin.RouterGroup) {
+	db := database.GetDB()
+	departmentService := services.NewDepartmentService(db)
+	h := handlers.NewDepartmentHandler(departmentService)
+
+	departments := r.Group("/departments")
+	{
+		departments.GET("/tree", h.GetDepartmentTree)
+		departments.GET("", h.GetDepartmentList)
+		departments.GET("/:id", h.GetDepartment)
+		departments.POST("", h.CreateDepartment)
+		departments.PUT("/:id", h.UpdateDepartment)
+		departments.DELETE("/:id", h.DeleteDepartment)
+		departments.PUT("/:id/status", h.UpdateDepartmentStatus)
+		departments.PUT("/:id/move", h.MoveDepartment)
+		departments.POST("/reorder", h.ReorderDepartments)
+	}
+}
@@ -1,8 +1,10 @@
 package v1
 
 import (
+	"normaladmin/backend/config"
 	"normaladmin/backend/database"
 	"normaladmin/backend/internal/handlers"
+	"normaladmin/backend/internal/middleware"
 	"normaladmin/backend/internal/models"
 	"normaladmin/backend/internal/services"
 
@@ -10,13 +12,14 @@ import (
 )
 
 // RegisterMemberRoutes 注册会员相关路由
-func RegisterMemberRoutes(r *gin.RouterGroup) {
+func RegisterMemberRoutes(r *gin.RouterGroup, jwtConfig config.JWTConfig, authServices *handlers.AuthServices) {
 	db := database.GetDB()
 	base := services.NewBaseCRUDService[models.Member](db)
 	cache := services.NewCacheBaseService(base, "member")
 	log := services.NewLogBaseService(cache, "member", db)
-	memberService := services.NewMemberService(db, log)
-	h := handlers.NewMemberHandler(memberService)
+	memberService := services.NewMemberService(db, log, middleware.RefreshTokenTTL(jwtConfig), authServices.PasswordPolicy)
+	h := handlers.NewMemberHandler(memberService, services.NewFieldPermissionService(db), authServices.Sessions)
+	impersonationHandler := handlers.NewImpersonationHandler(authServices.Impersonation, jwtConfig)
 
 	// 会员管理
 	members := r.Group("/members")
@@ -27,6 +30,8 @@ func RegisterMemberRoutes(r *gin.RouterGroup) {
 		members.DELETE("/:id", h.DeleteMember)
 		//members.PUT("/:id/status", h.UpdateMemberStatus)
 		members.GET("/check-field", h.CheckMemberFieldUnique) // 添加这一行
+		members.POST("/:id/impersonate", impersonationHandler.StartImpersonation)
+		members.DELETE("/:id/impersonate", impersonationHandler.EndImpersonation)
 	}
 
 }
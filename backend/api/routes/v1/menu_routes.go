@@ -11,7 +11,7 @@ import (
 // RegisterMenuRoutes 注册菜单相关路由
 func RegisterMenuRoutes(r *gin.RouterGroup) {
 	db := database.GetDB()
-	menuService := services.NewMenuService(db)
+	menuService := services.NewMenuService(db, services.NewPolicySyncService(db), services.NewApiEndpointService(db))
 	h := handlers.NewMenuHandler(menuService)
 
 	menus := r.Group("/menus")
@@ -0,0 +1,26 @@
+package v1
+
+import (
+	"normaladmin/backend/database"
+	"normaladmin/backend/internal/handlers"
+	"normaladmin/backend/internal/middleware"
+	"normaladmin/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterPermissionRoutes 注册权限排查相关路由
+// routes 返回全部已注册的路由，public 为认证之前注册、不经过接口权限校验的路由
+func RegisterPermissionRoutes(r *gin.RouterGroup, routes func() gin.RoutesInfo, public gin.RoutesInfo) {
+	db := database.GetDB()
+	dataScopes := services.NewDataScopeService(db, services.NewDepartmentService(db))
+	explainService := services.NewPermissionExplainService(db, dataScopes, middleware.CasbinAllowlistMatch)
+	h := handlers.NewPermissionHandler(explainService, routes, public)
+
+	permissions := r.Group("/permissions")
+	{
+		permissions.GET("/explain", h.ExplainAdmin)
+		permissions.GET("/explain/role", h.ExplainRole)
+		permissions.GET("/roles/:roleId/report", h.GetRoleReport)
+	}
+}
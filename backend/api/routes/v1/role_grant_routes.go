@@ -0,0 +1,22 @@
+package v1
+
+import (
+	"normaladmin/backend/database"
+	"normaladmin/backend/internal/handlers"
+	"normaladmin/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoleGrantRoutes 注册临时角色授权相关路由
+func RegisterRoleGrantRoutes(r *gin.RouterGroup, notifications services.NotificationService) {
+	db := database.GetDB()
+	h := handlers.NewRoleGrantHandler(services.NewRoleGrantService(db, services.NewPolicySyncService(db), notifications))
+
+	grants := r.Group("/role-grants")
+	{
+		grants.GET("", h.GetRoleGrants)
+		grants.POST("", h.CreateRoleGrant)
+		grants.DELETE("/:id", h.RevokeRoleGrant)
+	}
+}
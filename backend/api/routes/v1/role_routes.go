@@ -15,8 +15,8 @@ func RegisterRoleRoutes(r *gin.RouterGroup) {
 	base := services.NewBaseCRUDService[models.Role](db)
 	cache := services.NewCacheBaseService(base, "role")
 	log := services.NewLogBaseService(cache, "role", db)
-	roleService := services.NewRoleService(db, log)
-	h := handlers.NewRoleHandler(roleService)
+	roleService := services.NewRoleService(db, log, services.NewPolicySyncService(db))
+	h := handlers.NewRoleHandler(roleService, services.NewDataScopeService(db, services.NewDepartmentService(db)))
 	// 角色管理
 	roles := r.Group("/roles")
 	{
@@ -32,5 +32,10 @@ func RegisterRoleRoutes(r *gin.RouterGroup) {
 		// 角色权限管理
 		roles.GET("/permissions/:roleId/menus", h.GetRoleMenus)
 		roles.PUT("/permissions/:roleId/menus", h.UpdateRoleMenus)
+		roles.POST("/permissions/sync", h.SyncPolicies)
+
+		// 角色自定义数据范围
+		roles.GET("/permissions/:roleId/departments", h.GetRoleDepartments)
+		roles.PUT("/permissions/:roleId/departments", h.UpdateRoleDepartments)
 	}
 }
@@ -0,0 +1,19 @@
+package v1
+
+import (
+	"normaladmin/backend/internal/handlers"
+	"normaladmin/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterSecurityRoutes(r *gin.RouterGroup, loginGuard services.LoginGuardService) {
+	h := handlers.NewSecurityHandler(loginGuard)
+
+	security := r.Group("/security")
+	{
+		// 登录锁定管理
+		security.GET("/lockouts", h.GetLockouts)
+		security.DELETE("/lockouts", h.ClearLockout)
+	}
+}
@@ -11,13 +11,14 @@ import (
 func RegisterSystemRoutes(r *gin.RouterGroup) {
 	db := database.GetDB()
 	systemService := services.NewSystemService(db)
-	h := handlers.NewSystemHandler(systemService)
+	h := handlers.NewSystemHandler(systemService, services.NewLoginLogService(db))
 
 	system := r.Group("/system")
 	{
 		// 日志管理
 		system.GET("/logs", h.GetSystemLogs)
 		system.DELETE("/logs", h.DeleteSystemLogs)
+		system.GET("/login-logs", h.GetLoginLogs)
 
 		// 系统监控
 		system.GET("/monitor", h.GetSystemMonitor)
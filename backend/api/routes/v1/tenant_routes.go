@@ -0,0 +1,23 @@
+package v1
+
+import (
+	"normaladmin/backend/internal/handlers"
+	"normaladmin/backend/internal/middleware"
+	"normaladmin/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterTenantRoutes 注册租户管理路由，只有超级租户的管理员可以访问
+func RegisterTenantRoutes(r *gin.RouterGroup, tenantService services.TenantService) {
+	h := handlers.NewTenantHandler(tenantService)
+
+	tenants := r.Group("/tenants", middleware.SuperTenantOnly())
+	{
+		tenants.GET("", h.GetTenants)
+		tenants.GET("/:id", h.GetTenant)
+		tenants.POST("", h.CreateTenant)
+		tenants.PUT("/:id", h.UpdateTenant)
+		tenants.PUT("/:id/status", h.UpdateTenantStatus)
+	}
+}
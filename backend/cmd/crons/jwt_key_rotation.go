@@ -0,0 +1,93 @@
+package crons
+
+import (
+	"log"
+	"normaladmin/backend/pkg/auth"
+	"normaladmin/backend/pkg/cache"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// SetupJWTKeyRotationCron 设置签名密钥轮换定时任务，HS256 模式下返回 nil
+// 每分钟重新加载密钥目录以感知其他实例的轮换；当前签名密钥到期前 auth.KeyPublishLead 发布下一把密钥，
+// 到期时由下一把密钥接替签名，并删除超过 retention 的旧密钥
+func SetupJWTKeyRotationCron(rotateDays int, retention time.Duration) *cron.Cron {
+	keys := auth.Keys()
+	if keys == nil {
+		return nil
+	}
+
+	c := cron.New(cron.WithSeconds())
+	_, err := c.AddFunc("0 * * * * *", func() {
+		if err := keys.Reload(); err != nil {
+			log.Printf("重新加载签名密钥失败: %v", err)
+			return
+		}
+		if rotateDays <= 0 {
+			return
+		}
+
+		rotateAfter := time.Duration(rotateDays) * 24 * time.Hour
+		if _, ok := nextKeyActivation(keys, rotateAfter); !ok {
+			return
+		}
+
+		// 多实例共享密钥目录时只允许一个实例轮换
+		const lockKey = "jwt:key-rotation"
+		if !cache.Lock(lockKey) {
+			return
+		}
+		defer cache.Unlock(lockKey)
+
+		// 获取锁后再确认一次，避免重复轮换
+		if err := keys.Reload(); err != nil {
+			log.Printf("重新加载签名密钥失败: %v", err)
+			return
+		}
+		activateAt, ok := nextKeyActivation(keys, rotateAfter)
+		if !ok {
+			return
+		}
+
+		key, err := keys.RotateAt(activateAt)
+		if err != nil {
+			log.Printf("轮换签名密钥失败: %v", err)
+			return
+		}
+		log.Printf("已发布下一把签名密钥 %s，将于 %s 启用", key.ID, activateAt.Format(time.DateTime))
+
+		// 旧密钥至少保留一个轮换周期加上令牌有效期
+		if err := keys.Prune(rotateAfter + retention); err != nil {
+			log.Printf("清理过期签名密钥失败: %v", err)
+		}
+	})
+
+	if err != nil {
+		log.Fatalf("添加签名密钥轮换定时任务失败: %v", err)
+	}
+
+	c.Start()
+	return c
+}
+
+// nextKeyActivation 需要发布下一把签名密钥时返回其启用时间：当前密钥到期前 auth.KeyPublishLead 发布，到期时启用
+// 已发布尚未启用的密钥时不再发布；当前密钥已超过轮换周期（如刚开启轮换）时同样提前发布，等待 auth.KeyPublishLead 后启用
+func nextKeyActivation(keys *auth.KeySet, rotateAfter time.Duration) (time.Time, bool) {
+	if keys.Next() != nil {
+		return time.Time{}, false
+	}
+	current := keys.Current()
+	if current == nil {
+		return time.Now(), true
+	}
+
+	activateAt := current.CreatedAt.Add(rotateAfter)
+	if time.Until(activateAt) > auth.KeyPublishLead {
+		return time.Time{}, false
+	}
+	if earliest := time.Now().Add(auth.KeyPublishLead); activateAt.Before(earliest) {
+		activateAt = earliest
+	}
+	return activateAt, true
+}
@@ -0,0 +1,38 @@
+package crons
+
+import (
+	"log"
+	"normaladmin/backend/internal/services"
+	"normaladmin/backend/pkg/cache"
+
+	"github.com/robfig/cron/v3"
+)
+
+// SetupRoleGrantExpiryCron 设置临时角色授权定时任务
+// 每分钟同步到达生效时间的授权、收回到期的授权，并提醒即将到期的管理员；多实例时只由获得锁的实例处理
+func SetupRoleGrantExpiryCron(roleGrantService services.RoleGrantService) *cron.Cron {
+	c := cron.New(cron.WithSeconds())
+	_, err := c.AddFunc("30 * * * * *", func() {
+		const lockKey = "role-grant:sweep"
+		if !cache.Lock(lockKey) {
+			return
+		}
+		defer cache.Unlock(lockKey)
+
+		result, err := roleGrantService.Sweep()
+		if err != nil {
+			log.Printf("处理临时角色授权失败: %v", err)
+			return
+		}
+		if result.Activated > 0 || result.Expired > 0 || result.Reminded > 0 {
+			log.Printf("临时角色授权处理完成: 生效 %d, 收回 %d, 提醒 %d", result.Activated, result.Expired, result.Reminded)
+		}
+	})
+
+	if err != nil {
+		log.Fatalf("添加临时角色授权定时任务失败: %v", err)
+	}
+
+	c.Start()
+	return c
+}
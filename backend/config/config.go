@@ -30,6 +30,9 @@ type Config struct {
 	Upload   UploadConfig   `yaml:"upload"`
 	Log      LogConfig      `yaml:"log"`
 	Security SecurityConfig `yaml:"security"`
+	OIDC     OIDCConfig     `yaml:"oidc"`
+	Mail     MailConfig     `yaml:"mail"`
+	Casbin   CasbinConfig   `yaml:"casbin"`
 }
 
 type ServerConfig struct {
@@ -52,9 +55,49 @@ type SecurityConfig struct {
 	EncryptKey string `yaml:"encrypt_key" mapstructure:"encrypt_key"`
 }
 type JWTConfig struct {
-	SecretKey  string `yaml:"secret_key" mapstructure:"secret_key"`
+	SecretKey  string `yaml:"secret_key" mapstructure:"secret_key"` // HS256 模式的签名密钥
 	ExpireTime int    `yaml:"expire_time" mapstructure:"expire_time"`
 	Issuer     string `yaml:"issuer" mapstructure:"issuer"`
+	Algorithm  string `yaml:"algorithm" mapstructure:"algorithm"`     // HS256 / RS256 / EdDSA，默认 HS256
+	KeyDir     string `yaml:"key_dir" mapstructure:"key_dir"`         // 非对称密钥目录，相对路径基于项目根目录
+	RotateDays int    `yaml:"rotate_days" mapstructure:"rotate_days"` // 签名密钥轮换周期（天），0 表示不自动轮换
+}
+
+// OIDCConfig 管理后台单点登录配置
+type OIDCConfig struct {
+	Enabled       bool              `yaml:"enabled" mapstructure:"enabled"`
+	Issuer        string            `yaml:"issuer" mapstructure:"issuer"` // 身份提供方地址，用于发现 /.well-known/openid-configuration
+	ClientID      string            `yaml:"client_id" mapstructure:"client_id"`
+	ClientSecret  string            `yaml:"client_secret" mapstructure:"client_secret"` // 公共客户端可为空，仅使用 PKCE
+	RedirectURL   string            `yaml:"redirect_url" mapstructure:"redirect_url"`   // 前端回调页面地址
+	Scopes        []string          `yaml:"scopes" mapstructure:"scopes"`
+	UsernameClaim string            `yaml:"username_claim" mapstructure:"username_claim"` // 作为管理员用户名的声明，默认 preferred_username
+	GroupsClaim   string            `yaml:"groups_claim" mapstructure:"groups_claim"`     // 用于角色映射的声明，默认 groups
+	RoleMappings  []OIDCRoleMapping `yaml:"role_mappings" mapstructure:"role_mappings"`   // 全部命中的映射都生效，按顺序第一个为主角色
+	DefaultRole   string            `yaml:"default_role" mapstructure:"default_role"`     // 没有命中映射时使用的角色编码，为空则拒绝登录
+	AutoProvision bool              `yaml:"auto_provision" mapstructure:"auto_provision"` // 首次登录时自动创建管理员
+	LinkExisting  bool              `yaml:"link_existing" mapstructure:"link_existing"`   // 首次登录时按用户名关联已有的本地管理员
+	TenantID      uint              `yaml:"tenant_id" mapstructure:"tenant_id"`           // 单点登录的管理员所属租户，0 表示超级租户
+}
+
+// OIDCRoleMapping 身份提供方分组到角色的映射
+type OIDCRoleMapping struct {
+	Group string `yaml:"group" mapstructure:"group"` // 分组名或声明值
+	Role  string `yaml:"role" mapstructure:"role"`   // 角色编码
+}
+
+// MailConfig 邮件发送配置
+type MailConfig struct {
+	Driver      string `yaml:"driver" mapstructure:"driver"` // smtp / file / log，默认 log
+	Host        string `yaml:"host" mapstructure:"host"`
+	Port        int    `yaml:"port" mapstructure:"port"`
+	Username    string `yaml:"username" mapstructure:"username"`
+	Password    string `yaml:"password" mapstructure:"password"`
+	Encryption  string `yaml:"encryption" mapstructure:"encryption"` // starttls / ssl / none，默认 starttls
+	From        string `yaml:"from" mapstructure:"from"`
+	FromName    string `yaml:"from_name" mapstructure:"from_name"`
+	Dir         string `yaml:"dir" mapstructure:"dir"`                     // file 方式的输出目录
+	LinkBaseURL string `yaml:"link_base_url" mapstructure:"link_base_url"` // 会员端前端地址，用于生成验证和重置密码链接
 }
 
 type RedisConfig struct {
@@ -66,6 +109,12 @@ type RedisConfig struct {
 	LockTimeout int    `yaml:"lock_timeout" mapstructure:"lock_timeout"` // 改为 int
 }
 
+// CasbinConfig 权限策略同步配置，多实例部署时通过 Redis 广播策略变更
+type CasbinConfig struct {
+	WatcherChannel string `yaml:"watcher_channel" mapstructure:"watcher_channel"` // 策略变更频道，默认 casbin:policy
+	ReloadInterval int    `yaml:"reload_interval" mapstructure:"reload_interval"` // 定期全量重新加载策略的间隔（秒），0 表示不定期加载
+}
+
 type CORSConfig struct {
 	AllowedOrigins []string `yaml:"allowed_origins" mapstructure:"allowed_origins"`
 	AllowedMethods []string `yaml:"allowed_methods" mapstructure:"allowed_methods"`
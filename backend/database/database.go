@@ -2,6 +2,7 @@ package database
 
 import (
 	"normaladmin/backend/config"
+	"normaladmin/backend/pkg/tenant"
 	"time"
 
 	"gorm.io/driver/mysql"
@@ -19,6 +20,11 @@ func InitDB(cfg config.DatabaseConfig) error {
 		return err
 	}
 
+	// 按请求上下文中的租户隔离数据
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		return err
+	}
+
 	// 获取通用数据库对象 sql.DB
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -3,6 +3,8 @@ package migrations
 import (
 	"normaladmin/backend/database"
 	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/auth"
+	"normaladmin/backend/pkg/tenant"
 
 	"gorm.io/gorm"
 )
@@ -13,6 +15,26 @@ func init() {
 	registerBaseTables()
 	// 2. 初始化超级管理员权限
 	initSuperAdminPermissions()
+	// 3. 管理员两步验证
+	addAdminMFA()
+	addLoginProtectionConfig()
+	addUserSessions()
+	addLoginLogs()
+	addPasswordPolicy()
+	addAdminIdentities()
+	addAPIKeys()
+	addImpersonation()
+	addCaptchaConfig()
+	addMemberEmailVerification()
+	addCasbinAllowlist()
+	addRoleDepartments()
+	addDepartments()
+	addAdminRoles()
+	addApiEndpoints()
+	addFieldPermissions()
+	addRoleGrants()
+	addTenants()
+	addMFATokenMaxFailures()
 }
 
 // registerBaseTables 注册基础表迁移
@@ -52,3 +74,604 @@ func initSuperAdminPermissions() {
 		return nil
 	})
 }
+
+// addAdminMFA 管理员两步验证字段、恢复码表及强制启用配置
+func addAdminMFA() {
+	database.RegisterMigration("003_add_admin_mfa", func(db *gorm.DB) error {
+		if err := db.AutoMigrate(&models.Admin{}, &models.AdminRecoveryCode{}); err != nil {
+			return err
+		}
+
+		return ensureConfigItems(db, securityConfigGroup(), []models.ConfigItem{
+			{
+				ItemKey:     "mfa_required_roles",
+				ItemName:    "强制两步验证角色",
+				ItemValue:   "",
+				ValueType:   "string",
+				Description: "必须启用两步验证才能登录的角色编码，多个用逗号分隔",
+				SortOrder:   1,
+			},
+		})
+	})
+}
+
+// addLoginProtectionConfig 登录防暴力破解阈值配置
+func addLoginProtectionConfig() {
+	database.RegisterMigration("004_add_login_protection_config", func(db *gorm.DB) error {
+		return ensureConfigItems(db, securityConfigGroup(), []models.ConfigItem{
+			{
+				ItemKey:     "login_delay_after",
+				ItemName:    "登录延迟阈值",
+				ItemValue:   "3",
+				ValueType:   "number",
+				Description: "同一用户名连续失败达到该次数后，每次失败的等待时间翻倍（最长60秒）",
+				SortOrder:   2,
+			},
+			{
+				ItemKey:     "login_max_failures",
+				ItemName:    "账号锁定阈值",
+				ItemValue:   "5",
+				ValueType:   "number",
+				Description: "同一用户名在统计窗口内失败达到该次数后临时锁定，0 表示不锁定",
+				SortOrder:   3,
+			},
+			{
+				ItemKey:     "login_ip_max_failures",
+				ItemName:    "IP锁定阈值",
+				ItemValue:   "20",
+				ValueType:   "number",
+				Description: "同一IP在统计窗口内失败达到该次数后临时锁定，0 表示不锁定",
+				SortOrder:   4,
+			},
+			{
+				ItemKey:     "login_lock_minutes",
+				ItemName:    "锁定时长(分钟)",
+				ItemValue:   "15",
+				ValueType:   "number",
+				Description: "达到锁定阈值后禁止登录的时长",
+				SortOrder:   5,
+			},
+			{
+				ItemKey:     "login_failure_window",
+				ItemName:    "失败统计窗口(分钟)",
+				ItemValue:   "15",
+				ValueType:   "number",
+				Description: "从第一次失败开始计算，超过该时长失败次数清零",
+				SortOrder:   6,
+			},
+		})
+	})
+}
+
+// addUserSessions 登录会话镜像表
+func addUserSessions() {
+	database.RegisterMigration("005_add_user_sessions", func(db *gorm.DB) error {
+		return db.AutoMigrate(&models.UserSession{})
+	})
+}
+
+// addLoginLogs 登录日志表
+func addLoginLogs() {
+	database.RegisterMigration("006_add_login_logs", func(db *gorm.DB) error {
+		return db.AutoMigrate(&models.LoginLog{})
+	})
+}
+
+// addPasswordPolicy 密码修改时间字段、历史密码表及密码策略配置
+func addPasswordPolicy() {
+	database.RegisterMigration("007_add_password_policy", func(db *gorm.DB) error {
+		if err := db.AutoMigrate(&models.Admin{}, &models.Member{}, &models.PasswordHistory{}); err != nil {
+			return err
+		}
+
+		return ensureConfigItems(db, securityConfigGroup(), []models.ConfigItem{
+			{
+				ItemKey:     "password_min_length",
+				ItemName:    "密码最小长度",
+				ItemValue:   "8",
+				ValueType:   "number",
+				Description: "新密码的最少字符数",
+				SortOrder:   7,
+			},
+			{
+				ItemKey:     "password_required_classes",
+				ItemName:    "密码字符类别",
+				ItemValue:   "lower,digit",
+				ValueType:   "string",
+				Description: "新密码必须包含的字符类别：upper-大写字母 lower-小写字母 digit-数字 symbol-特殊字符，多个用逗号分隔",
+				SortOrder:   8,
+			},
+			{
+				ItemKey:     "password_banned_list",
+				ItemName:    "禁用密码",
+				ItemValue:   "123456,12345678,password,admin123,qwerty123",
+				ValueType:   "string",
+				Description: "不允许使用的弱密码，不区分大小写，多个用逗号分隔",
+				SortOrder:   9,
+			},
+			{
+				ItemKey:     "password_history_count",
+				ItemName:    "禁止重复使用次数",
+				ItemValue:   "5",
+				ValueType:   "number",
+				Description: "新密码不能与最近N次使用过的密码相同，0 表示只检查当前密码",
+				SortOrder:   10,
+			},
+			{
+				ItemKey:     "password_max_age_days",
+				ItemName:    "密码有效期(天)",
+				ItemValue:   "0",
+				ValueType:   "number",
+				Description: "密码超过该天数后登录时必须修改，0 表示永不过期",
+				SortOrder:   11,
+			},
+		})
+	})
+}
+
+// addAdminIdentities 单点登录外部身份关联表
+func addAdminIdentities() {
+	database.RegisterMigration("008_add_admin_identities", func(db *gorm.DB) error {
+		return db.AutoMigrate(&models.AdminIdentity{})
+	})
+}
+
+// addAPIKeys API 密钥表、系统日志密钥字段及最长有效期配置
+func addAPIKeys() {
+	database.RegisterMigration("009_add_api_keys", func(db *gorm.DB) error {
+		if err := db.AutoMigrate(&models.APIKey{}, &models.SystemLog{}); err != nil {
+			return err
+		}
+
+		return ensureConfigItems(db, securityConfigGroup(), []models.ConfigItem{
+			{
+				ItemKey:     "api_key_max_days",
+				ItemName:    "API密钥最长有效期(天)",
+				ItemValue:   "365",
+				ValueType:   "number",
+				Description: "创建 API 密钥时可设置的最长有效期，0 表示不限制",
+				SortOrder:   12,
+			},
+		})
+	})
+}
+
+// addImpersonation 系统日志模拟登录字段及模拟令牌有效期配置
+func addImpersonation() {
+	database.RegisterMigration("010_add_impersonation", func(db *gorm.DB) error {
+		if err := db.AutoMigrate(&models.SystemLog{}, &models.Notification{}); err != nil {
+			return err
+		}
+
+		return ensureConfigItems(db, securityConfigGroup(), []models.ConfigItem{
+			{
+				ItemKey:     "impersonation_ttl_minutes",
+				ItemName:    "模拟登录有效期(分钟)",
+				ItemValue:   "30",
+				ValueType:   "number",
+				Description: "管理员模拟会员登录时签发的令牌有效期，到期后需重新发起",
+				SortOrder:   13,
+			},
+		})
+	})
+}
+
+// addCaptchaConfig 图形验证码配置
+// 同时修正之前写入的整数配置项类型，配置页面使用 number 渲染数字输入框
+func addCaptchaConfig() {
+	database.RegisterMigration("011_add_captcha_config", func(db *gorm.DB) error {
+		if err := db.Model(&models.ConfigItem{}).Where("value_type = ?", "int").Update("value_type", "number").Error; err != nil {
+			return err
+		}
+
+		return ensureConfigItems(db, securityConfigGroup(), []models.ConfigItem{
+			{
+				ItemKey:     "captcha_mode",
+				ItemName:    "验证码启用方式",
+				ItemValue:   "after_failures",
+				ValueType:   "select",
+				Description: "管理员登录、会员登录和注册时是否需要图形验证码",
+				SortOrder:   14,
+				Options: models.OptionsJSON{
+					{Label: "不启用", Value: "off"},
+					{Label: "始终需要", Value: "always"},
+					{Label: "失败后需要", Value: "after_failures"},
+				},
+			},
+			{
+				ItemKey:          "captcha_after_failures",
+				ItemName:         "验证码失败阈值",
+				ItemValue:        "3",
+				ValueType:        "number",
+				Description:      "同一用户名或IP在统计窗口内失败达到该次数后需要验证码",
+				SortOrder:        15,
+				VisibleCondition: "formData.captcha_mode === 'after_failures'",
+			},
+			{
+				ItemKey:     "captcha_type",
+				ItemName:    "验证码类型",
+				ItemValue:   "text",
+				ValueType:   "select",
+				Description: "字符验证码或算术验证码",
+				SortOrder:   16,
+				Options: models.OptionsJSON{
+					{Label: "字符", Value: "text"},
+					{Label: "算术", Value: "math"},
+				},
+			},
+			{
+				ItemKey:          "captcha_length",
+				ItemName:         "验证码字符数",
+				ItemValue:        "4",
+				ValueType:        "number",
+				Description:      "字符验证码的字符个数",
+				SortOrder:        17,
+				VisibleCondition: "formData.captcha_type === 'text'",
+			},
+			{
+				ItemKey:     "captcha_ttl_seconds",
+				ItemName:    "验证码有效期(秒)",
+				ItemValue:   "120",
+				ValueType:   "number",
+				Description: "验证码生成后的有效时长，校验一次后立即失效",
+				SortOrder:   18,
+			},
+		})
+	})
+}
+
+// addMemberEmailVerification 会员邮箱验证字段及邮箱验证、找回密码配置
+func addMemberEmailVerification() {
+	database.RegisterMigration("012_add_member_email_verification", func(db *gorm.DB) error {
+		if err := db.AutoMigrate(&models.Member{}); err != nil {
+			return err
+		}
+
+		return ensureConfigItems(db, securityConfigGroup(), []models.ConfigItem{
+			{
+				ItemKey:     "member_email_verification_required",
+				ItemName:    "会员强制邮箱验证",
+				ItemValue:   "0",
+				ValueType:   "switch",
+				Description: "开启后未验证邮箱的会员不能登录，已注册的会员需先通过验证邮件完成验证",
+				SortOrder:   19,
+			},
+			{
+				ItemKey:     "member_email_verify_ttl_hours",
+				ItemName:    "邮箱验证链接有效期(小时)",
+				ItemValue:   "24",
+				ValueType:   "number",
+				Description: "邮箱验证邮件中链接的有效时长",
+				SortOrder:   20,
+			},
+			{
+				ItemKey:     "member_password_reset_ttl_minutes",
+				ItemName:    "重置密码链接有效期(分钟)",
+				ItemValue:   "30",
+				ValueType:   "number",
+				Description: "找回密码邮件中链接的有效时长，链接只能使用一次",
+				SortOrder:   21,
+			},
+			{
+				ItemKey:     "member_email_resend_seconds",
+				ItemName:    "邮件发送间隔(秒)",
+				ItemValue:   "60",
+				ValueType:   "number",
+				Description: "同一邮箱重复请求验证邮件或重置密码邮件的最短间隔，0 表示不限制",
+				SortOrder:   22,
+			},
+		})
+	})
+}
+
+// addCasbinAllowlist 接口权限校验的免检名单
+func addCasbinAllowlist() {
+	database.RegisterMigration("013_add_casbin_allowlist", func(db *gorm.DB) error {
+		return ensureConfigItems(db, securityConfigGroup(), []models.ConfigItem{
+			{
+				ItemKey:     "casbin_allowlist",
+				ItemName:    "接口权限免检名单",
+				ItemValue:   "/gam/authmenus\n/gam/logout\n/gam/sessions\n/gam/sessions/:session_id\n/gam/mfa/*\n/gam/api-keys\n/gam/api-keys/*",
+				ValueType:   "textarea",
+				Description: "所有管理员都可访问、不校验接口权限的路径，每行一个，支持 :id 和 * 通配",
+				SortOrder:   23,
+			},
+		})
+	})
+}
+
+// addRoleDepartments 角色自定义数据范围的部门关联表
+func addRoleDepartments() {
+	database.RegisterMigration("014_add_role_departments", func(db *gorm.DB) error {
+		return db.AutoMigrate(&models.RoleDepartment{})
+	})
+}
+
+// addDepartments 部门表及管理员所属部门
+func addDepartments() {
+	database.RegisterMigration("015_add_departments", func(db *gorm.DB) error {
+		return db.AutoMigrate(&models.Department{}, &models.Admin{})
+	})
+}
+
+// addAdminRoles 管理员多角色，按原有的 role_id 初始化管理员角色
+// 分组策略在启动时由全量同步按 admin_roles 生成
+func addAdminRoles() {
+	database.RegisterMigration("016_add_admin_roles", func(db *gorm.DB) error {
+		if err := db.AutoMigrate(&models.AdminRole{}); err != nil {
+			return err
+		}
+		return db.Exec("INSERT INTO admin_roles (admin_id, role_id, created_at) " +
+			"SELECT admins.id, admins.role_id, NOW() FROM admins " +
+			"WHERE admins.role_id <> 0 AND NOT EXISTS (SELECT 1 FROM admin_roles WHERE admin_roles.admin_id = admins.id)").Error
+	})
+}
+
+// addApiEndpoints 接口目录，内容在启动时按已注册的路由维护
+func addApiEndpoints() {
+	database.RegisterMigration("017_add_api_endpoints", func(db *gorm.DB) error {
+		return db.AutoMigrate(&models.ApiEndpoint{})
+	})
+}
+
+// addFieldPermissions 会员和管理员敏感字段的查看、编辑权限，挂在列表菜单下，默认只分配给超级管理员
+// 其他角色需要在角色管理中按需分配，未分配时列表返回掩码、更新时不能修改这些字段
+func addFieldPermissions() {
+	database.RegisterMigration("018_add_field_permissions", func(db *gorm.DB) error {
+		fields := []struct {
+			Parent   string // 所属列表菜单的名称
+			Resource string
+			Field    string
+			Name     string
+			Label    string
+		}{
+			{"MemberList", "member", "mobile", "MemberMobile", "手机号"},
+			{"MemberList", "member", "email", "MemberEmail", "邮箱"},
+			{"MemberList", "member", "birthday", "MemberBirthday", "生日"},
+			{"MemberList", "member", "last_login_ip", "MemberLastLoginIP", "最后登录IP"},
+			{"AdminList", "admin", "email", "AdminEmail", "邮箱"},
+			{"AdminList", "admin", "phone", "AdminPhone", "手机号"},
+		}
+		actions := []struct {
+			Action string
+			Name   string
+			Title  string
+		}{
+			{models.FieldActionView, "View", "查看"},
+			{models.FieldActionEdit, "Edit", "编辑"},
+		}
+
+		var superAdmin models.Role
+		if err := db.Where("code = ?", "SUPER_ADMIN").Limit(1).Find(&superAdmin).Error; err != nil {
+			return err
+		}
+
+		for i, field := range fields {
+			var parent models.Menu
+			if err := db.Where("name = ?", field.Parent).Limit(1).Find(&parent).Error; err != nil {
+				return err
+			}
+			for j, action := range actions {
+				menu := models.Menu{
+					ParentID:   parent.ID,
+					ParentName: parent.Name,
+					Title:      action.Title + field.Label,
+					Name:       field.Name + action.Name,
+					Type:       models.MenuTypeField,
+					Sort:       100 + i*len(actions) + j,
+					Permission: models.FieldPermission(field.Resource, field.Field, action.Action),
+				}
+				if err := db.Where("permission = ?", menu.Permission).FirstOrCreate(&menu).Error; err != nil {
+					return err
+				}
+				if superAdmin.ID == 0 {
+					continue
+				}
+				roleMenu := models.RoleMenu{RoleID: superAdmin.ID, MenuID: menu.ID}
+				if err := db.Where(&roleMenu).FirstOrCreate(&roleMenu).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// addRoleGrants 临时角色授权表及授权时长、到期提醒配置
+func addRoleGrants() {
+	database.RegisterMigration("019_add_role_grants", func(db *gorm.DB) error {
+		if err := db.AutoMigrate(&models.RoleGrant{}); err != nil {
+			return err
+		}
+		return ensureConfigItems(db, securityConfigGroup(), []models.ConfigItem{
+			{
+				ItemKey:     "role_grant_max_hours",
+				ItemName:    "临时授权最长时长",
+				ItemValue:   "72",
+				ValueType:   "number",
+				Description: "临时角色授权从生效到到期的最长小时数，0 表示不限制",
+				SortOrder:   24,
+			},
+			{
+				ItemKey:     "role_grant_remind_minutes",
+				ItemName:    "临时授权到期提醒",
+				ItemValue:   "30",
+				ValueType:   "number",
+				Description: "临时角色授权到期前多少分钟通知被授权的管理员，0 表示不提醒",
+				SortOrder:   25,
+			},
+		})
+	})
+}
+
+// addTenants 多租户：租户表、业务表的 tenant_id 字段和按租户的唯一索引，Casbin 策略增加租户域
+// 已有数据都归属 ID 为 1 的超级租户（平台）；菜单管理、系统配置、系统监控、通知类型和新增的租户管理为平台菜单
+func addTenants() {
+	database.RegisterMigration("020_add_tenants", func(db *gorm.DB) error {
+		if err := db.AutoMigrate(&models.Tenant{}); err != nil {
+			return err
+		}
+		status := 1
+		platform := models.Tenant{ID: tenant.SuperTenantID, Name: "平台", Code: "platform", Status: &status}
+		if err := db.Where("id = ?", platform.ID).FirstOrCreate(&platform).Error; err != nil {
+			return err
+		}
+
+		// 全局唯一索引改为租户内唯一
+		oldIndexes := []struct {
+			Model interface{}
+			Name  string
+		}{
+			{&models.Admin{}, "uni_admins_username"},
+			{&models.Role{}, "uni_roles_name"},
+			{&models.Role{}, "uni_roles_code"},
+			{&models.Member{}, "idx_members_username"},
+			{&models.Member{}, "idx_members_mobile"},
+			{&models.Member{}, "idx_members_email"},
+			{&models.ConfigGroup{}, "config_key"},
+		}
+		for _, index := range oldIndexes {
+			if !db.Migrator().HasIndex(index.Model, index.Name) {
+				continue
+			}
+			if err := db.Migrator().DropIndex(index.Model, index.Name); err != nil {
+				return err
+			}
+		}
+		if err := db.AutoMigrate(
+			&models.Admin{}, &models.Role{}, &models.RoleMenu{}, &models.Member{},
+			&models.ConfigGroup{}, &models.ConfigItem{}, &models.Department{}, &models.RoleGrant{},
+			&models.UploadFile{}, &models.Notification{}, &models.SystemLog{}, &models.LoginLog{},
+			&models.Menu{},
+		); err != nil {
+			return err
+		}
+
+		// 已有策略归入超级租户的域：p 规则 (sub, obj, act) 改为 (sub, dom, obj, act)，g 规则补充域
+		domain := auth.TenantDomain(tenant.SuperTenantID)
+		if err := db.Exec("UPDATE casbin_rule SET v3 = v2, v2 = v1, v1 = ? WHERE ptype = 'p' AND COALESCE(v3, '') = ''", domain).Error; err != nil {
+			return err
+		}
+		if err := db.Exec("UPDATE casbin_rule SET v2 = ? WHERE ptype = 'g' AND COALESCE(v2, '') = ''", domain).Error; err != nil {
+			return err
+		}
+
+		if err := db.Model(&models.Menu{}).
+			Where("permission LIKE ? OR permission LIKE ? OR permission LIKE ? OR permission LIKE ?",
+				"system:menu:%", "system:config:%", "system:monitor:%", "system:notification-type:%").
+			Update("platform", true).Error; err != nil {
+			return err
+		}
+
+		var parent models.Menu
+		if err := db.Where("name = ?", "System").Limit(1).Find(&parent).Error; err != nil {
+			return err
+		}
+		list := models.Menu{
+			ParentID:   parent.ID,
+			ParentName: parent.Name,
+			Title:      "租户管理",
+			Name:       "TenantList",
+			Path:       "/system/tenants",
+			Component:  "system/TenantList",
+			Icon:       "OfficeBuilding",
+			Type:       "menu",
+			Sort:       10,
+			Permission: "system:tenant:list",
+			Platform:   true,
+		}
+		if err := db.Where("permission = ?", list.Permission).FirstOrCreate(&list).Error; err != nil {
+			return err
+		}
+		menus := []models.Menu{list}
+		buttons := []struct {
+			Name       string
+			Title      string
+			Permission string
+			Method     string
+			Path       string
+		}{
+			{"TenantView", "查看租户", "system:tenant:view", "GET", "/gam/tenants"},
+			{"TenantCreate", "开通租户", "system:tenant:create", "POST", "/gam/tenants"},
+			{"TenantEdit", "编辑租户", "system:tenant:update", "PUT", "/gam/tenants/:id"},
+			{"TenantStatus", "启用/禁用租户", "system:tenant:status", "PUT", "/gam/tenants/:id/status"},
+		}
+		for i, button := range buttons {
+			menu := models.Menu{
+				ParentID:   list.ID,
+				Title:      button.Title,
+				Name:       button.Name,
+				Type:       "button",
+				Sort:       i + 1,
+				Permission: button.Permission,
+				ApiMethod:  button.Method,
+				ApiPath:    button.Path,
+				Platform:   true,
+			}
+			if err := db.Where("permission = ?", menu.Permission).FirstOrCreate(&menu).Error; err != nil {
+				return err
+			}
+			menus = append(menus, menu)
+		}
+
+		// 分配给超级管理员，策略在启动时同步
+		var superAdmin models.Role
+		if err := db.Where("code = ?", "SUPER_ADMIN").Limit(1).Find(&superAdmin).Error; err != nil {
+			return err
+		}
+		if superAdmin.ID == 0 {
+			return nil
+		}
+		for _, menu := range menus {
+			roleMenu := models.RoleMenu{RoleID: superAdmin.ID, MenuID: menu.ID}
+			if err := db.Where(&roleMenu).FirstOrCreate(&roleMenu).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// addMFATokenMaxFailures 两步验证待确认令牌的失败次数上限配置
+func addMFATokenMaxFailures() {
+	database.RegisterMigration("021_add_mfa_token_max_failures", func(db *gorm.DB) error {
+		return ensureConfigItems(db, securityConfigGroup(), []models.ConfigItem{
+			{
+				ItemKey:     "mfa_token_max_failures",
+				ItemName:    "两步验证失败次数上限",
+				ItemValue:   "5",
+				ValueType:   "number",
+				Description: "登录时同一个两步验证待确认令牌验证失败达到该次数后作废，需要重新输入密码，0 表示不限制",
+				SortOrder:   26,
+			},
+		})
+	})
+}
+
+// securityConfigGroup 安全设置配置组
+func securityConfigGroup() models.ConfigGroup {
+	return models.ConfigGroup{
+		ConfigKey:   "security",
+		ConfigName:  "安全设置",
+		Description: "登录认证与账号安全相关配置",
+		Icon:        "Lock",
+		SortOrder:   7,
+		Status:      1,
+		IsSystem:    1,
+	}
+}
+
+// ensureConfigItems 确保配置组及配置项存在，已存在的配置项保留原值
+func ensureConfigItems(db *gorm.DB, group models.ConfigGroup, items []models.ConfigItem) error {
+	if err := db.Where("config_key = ?", group.ConfigKey).FirstOrCreate(&group).Error; err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		item.GroupID = int64(group.ID)
+		if err := db.Where("item_key = ?", item.ItemKey).FirstOrCreate(&item).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,60 +1,78 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"normaladmin/backend/internal/middleware"
 	"normaladmin/backend/internal/models"
 	"normaladmin/backend/internal/services"
 	"normaladmin/backend/pkg/utils/response"
 	"strconv"
-	"strings"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type AdminHandler struct {
 	adminService services.AdminService
+	dataScopes   services.DataScopeService
+	fields       services.FieldPermissionService
+	mfaService   services.MFAService
+	sessions     services.SessionService
 }
 
-func NewAdminHandler(adminService services.AdminService) *AdminHandler {
-	return &AdminHandler{adminService: adminService}
+func NewAdminHandler(adminService services.AdminService, dataScopes services.DataScopeService, fields services.FieldPermissionService, mfaService services.MFAService, sessions services.SessionService) *AdminHandler {
+	return &AdminHandler{adminService: adminService, dataScopes: dataScopes, fields: fields, mfaService: mfaService, sessions: sessions}
+}
+
+// AdminRequest 创建或更新管理员的请求；models.Admin 的密码不参与 JSON 绑定，由 Password 单独接收
+type AdminRequest struct {
+	models.Admin
+	Password string `json:"password"` // 明文密码，创建时必填，更新时为空表示不修改
+}
+
+// admins 使用请求上下文的管理员服务，只能访问当前租户的管理员
+func (h *AdminHandler) admins(c *gin.Context) services.AdminService {
+	return h.adminService.WithContext(c.Request.Context())
 }
 
 // GetAdminList godoc
 // @Summary 获取管理员列表
-// @description 获取管理员列表，支持按用户名搜索、分页和排序
+// @description 获取管理员列表，支持过滤、分页和排序；没有查看权限的敏感字段（如 admin.phone:view）返回掩码。过滤参数写作 field=value（默认运算符）或 field[op]=value，如 username[prefix]=adm、status[in]=0,1、created_at[between]=2025-01-01,2025-01-31；可用字段：id、username、real_name、role_id、department_id、status、mfa_enabled、last_login_time、created_at、updated_at
 // @Tags 管理员管理
 // @Accept json
 // @Produce json
-// @Param username query string false "用户名，用于搜索过滤"
+// @Param username query string false "用户名，默认模糊匹配"
 // @Param page query int false "页码，默认值：1" default(1)
 // @Param pageSize query int false "每页数量，默认值：10" default(10)
 // @Param sortField query string false "排序字段，多个字段用逗号分隔" example("id,created_at")
 // @Param sortOrder query string false "排序方式，多个排序方式用逗号分隔(asc/desc)" example("desc,asc")
 // @Success 200 {object} response.ResponseData{data=object{admins=[]models.Admin,total=int}} "成功"
+// @Failure 400 {object} response.ResponseData "过滤或排序参数无效"
 // @Failure 500 {object} response.ResponseData "内部错误"
 // @Router /gam/admins [get]
 func (h *AdminHandler) GetAdminList(c *gin.Context) {
-	query := make(map[string]interface{})
-	if username := c.Query("username"); username != "" {
-		query["username"] = username
+	filter, ok := listFilter(c, models.AdminFilters)
+	if !ok {
+		return
 	}
 	page := c.DefaultQuery("page", "1")
 	pageSize := c.DefaultQuery("pageSize", "10")
-	// 处理排序
-	var opts []models.QueryOption
-
-	sortField := c.DefaultQuery("sortField", "")
-	if sortField != "" {
-		sortFields := strings.Split(sortField, ",")
-		sortOrders := strings.Split(c.DefaultQuery("sortOrder", "desc"), ",")
-		opts = append(opts, models.WithSort(sortFields, sortOrders))
+	// 按数据范围过滤
+	scope, ok := dataScopeOption(c, h.dataScopes)
+	if !ok {
+		return
+	}
+	grants, ok := fieldGrants(c, h.fields)
+	if !ok {
+		return
 	}
-	admins, total, err := h.adminService.List(query, page, pageSize, opts...)
+	admins, total, err := h.admins(c).List(filter, page, pageSize, scope)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to get admin list")
 		return
 	}
+	grants.Mask(services.FieldResourceAdmin, admins)
 
 	response.Success(c, gin.H{
 		"admins": admins,
@@ -64,7 +82,7 @@ func (h *AdminHandler) GetAdminList(c *gin.Context) {
 
 // GetAdmin godoc
 // @Summary 获取单个管理员
-// @Description 根据管理员ID获取详细信息
+// @Description 根据管理员ID获取详细信息，没有查看权限的敏感字段返回掩码
 // @Tags 管理员管理
 // @Accept json
 // @Produce json
@@ -81,39 +99,61 @@ func (h *AdminHandler) GetAdmin(c *gin.Context) {
 		return
 	}
 
-	admin, err := h.adminService.GetByID(uint(id))
+	scope, ok := dataScopeOption(c, h.dataScopes)
+	if !ok {
+		return
+	}
+	grants, ok := fieldGrants(c, h.fields)
+	if !ok {
+		return
+	}
+	admin, err := h.admins(c).GetByID(uint(id), scope)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Error(c, http.StatusNotFound, "Admin not found")
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to get admin")
 		return
 	}
 
+	grants.Mask(services.FieldResourceAdmin, admin)
 	response.Success(c, gin.H{"admin": admin})
 }
 
 // CreateAdmin godoc
 // @Summary 创建管理员
-// @Description 创建新的管理员账号，包括用户名、密码、角色等信息
+// @Description 创建新的管理员账号，包括用户名、密码、角色等信息；role_ids 可分配多个角色，第一个为主角色
 // @Tags 管理员管理
 // @Accept json
 // @Produce json
-// @Param admin body models.Admin true "管理员信息，包括用户名、密码、角色ID等" example({"username":"admin","password":"123456","role_id":1,"email":"admin@example.com"})
+// @Param admin body AdminRequest true "管理员信息，包括用户名、密码、角色ID等" example({"username":"admin","password":"123456","role_ids":[1,2],"email":"admin@example.com"})
 // @Success 200 {object} response.ResponseData{data=object{admin=models.Admin}} "成功"
 // @Failure 400 {object} response.ResponseData "请求参数错误"
+// @Failure 422 {object} response.ResponseData{data=services.PasswordPolicyError} "密码不符合安全策略"
 // @Failure 500 {object} response.ResponseData "内部错误"
 // @Router /gam/admins [post]
 func (h *AdminHandler) CreateAdmin(c *gin.Context) {
-	var admin models.Admin
-	if err := c.ShouldBindJSON(&admin); err != nil {
+	var req AdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(admin.Password), bcrypt.DefaultCost)
-	if err != nil {
-		response.Error(c, http.StatusInternalServerError, "Failed to hash password")
+	if req.Password == "" {
+		response.Error(c, http.StatusBadRequest, "Password is required")
 		return
 	}
-	admin.Password = string(hashedPassword)
-	if err := h.adminService.Create(&admin); err != nil {
+	admin := req.Admin
+	admin.Password = req.Password
+	admin.MFAEnabled = false // 两步验证只能由管理员本人绑定
+	if err := h.admins(c).Create(&admin); err != nil {
+		if respondPasswordPolicyError(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrAdminDepartmentNotFound) || errors.Is(err, services.ErrAdminRoleNotFound) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to create admin")
 		return
 	}
@@ -123,15 +163,17 @@ func (h *AdminHandler) CreateAdmin(c *gin.Context) {
 
 // UpdateAdmin godoc
 // @Summary 更新管理员
-// @Description 更新管理员信息，包括用户名、角色等（密码单独更新）
+// @Description 更新管理员信息，包括用户名、角色等；提交 password 时按密码策略重置密码并吊销其全部令牌，不提交则不修改；提交 role_ids 时替换管理员的全部角色；修改没有编辑权限的敏感字段（如 admin.email:edit）时拒绝，提交原值或掩码视为未修改
 // @Tags 管理员管理
 // @Accept json
 // @Produce json
 // @Param id path int true "管理员ID" minimum(1)
-// @Param admin body models.Admin true "管理员信息" example({"username":"admin","role_id":1,"email":"admin@example.com"})
+// @Param admin body AdminRequest true "管理员信息" example({"username":"admin","role_ids":[1,2],"email":"admin@example.com"})
 // @Success 200 {object} response.ResponseData{data=object{admin=models.Admin}} "成功"
 // @Failure 400 {object} response.ResponseData "无效的请求参数"
+// @Failure 403 {object} response.ResponseData "没有编辑字段的权限"
 // @Failure 404 {object} response.ResponseData "管理员不存在"
+// @Failure 422 {object} response.ResponseData{data=services.PasswordPolicyError} "密码不符合安全策略"
 // @Failure 500 {object} response.ResponseData "内部错误"
 // @Router /gam/admins/{id} [put]
 func (h *AdminHandler) UpdateAdmin(c *gin.Context) {
@@ -141,24 +183,60 @@ func (h *AdminHandler) UpdateAdmin(c *gin.Context) {
 		return
 	}
 
-	var admin models.Admin
-	if err := c.ShouldBindJSON(&admin); err != nil {
+	var req AdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	if admin.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(admin.Password), bcrypt.DefaultCost)
-		if err != nil {
-			response.Error(c, http.StatusInternalServerError, "Failed to hash password")
+	admin := req.Admin
+	admin.Password = req.Password
+	admin.MFAEnabled = false // 两步验证状态不允许通过此接口修改
+	passwordChanged := admin.Password != ""
+	scope, ok := dataScopeOption(c, h.dataScopes)
+	if !ok {
+		return
+	}
+	grants, ok := fieldGrants(c, h.fields)
+	if !ok {
+		return
+	}
+	current, err := h.admins(c).GetByID(uint(id), scope)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Error(c, http.StatusNotFound, "Admin not found")
 			return
 		}
-		admin.Password = string(hashedPassword)
+		response.Error(c, http.StatusInternalServerError, "Failed to update admin")
+		return
+	}
+	if err := grants.CheckWrite(services.FieldResourceAdmin, current, &admin); err != nil {
+		respondFieldNotEditable(c, err)
+		return
 	}
-	if err := h.adminService.Update(uint(id), &admin); err != nil {
+	if err := h.admins(c).Update(uint(id), &admin, scope); err != nil {
+		if respondPasswordPolicyError(c, err) {
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Error(c, http.StatusNotFound, "Admin not found")
+			return
+		}
+		if errors.Is(err, services.ErrAdminDepartmentNotFound) || errors.Is(err, services.ErrAdminRoleNotFound) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to update admin")
 		return
 	}
+	if passwordChanged {
+		// 密码被修改，吊销该管理员的全部令牌
+		if err := h.admins(c).RevokeTokens(uint(id)); err != nil {
+			response.Error(c, http.StatusInternalServerError, "Failed to revoke admin tokens")
+			return
+		}
+	}
 
+	grants.Mask(services.FieldResourceAdmin, &admin)
 	response.Success(c, gin.H{"admin": admin})
 }
 
@@ -181,7 +259,15 @@ func (h *AdminHandler) DeleteAdmin(c *gin.Context) {
 		return
 	}
 
-	if err := h.adminService.Delete(uint(id), false); err != nil {
+	scope, ok := dataScopeOption(c, h.dataScopes)
+	if !ok {
+		return
+	}
+	if err := h.admins(c).Delete(uint(id), false, scope); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Error(c, http.StatusNotFound, "Admin not found")
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to delete admin")
 		return
 	}
@@ -191,7 +277,7 @@ func (h *AdminHandler) DeleteAdmin(c *gin.Context) {
 
 // UpdateAdminStatus godoc
 // @Summary 更新管理员状态
-// @Description 更新管理员的启用/禁用状态（0：禁用，1：启用）
+// @Description 更新管理员的启用/禁用状态（0：禁用，1：启用），禁用后其已签发的令牌全部失效
 // @Tags 管理员管理
 // @Accept json
 // @Produce json
@@ -217,7 +303,15 @@ func (h *AdminHandler) UpdateAdminStatus(c *gin.Context) {
 		return
 	}
 
-	if err := h.adminService.Update(uint(id), map[string]interface{}{"status": req.Status}); err != nil {
+	scope, ok := dataScopeOption(c, h.dataScopes)
+	if !ok {
+		return
+	}
+	if err := h.admins(c).UpdateStatus(uint(id), req.Status, scope); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Error(c, http.StatusNotFound, "Admin not found")
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to update admin status")
 		return
 	}
@@ -227,7 +321,7 @@ func (h *AdminHandler) UpdateAdminStatus(c *gin.Context) {
 
 // UpdatePassword godoc
 // @Summary 更新管理员密码
-// @Description 更新管理员的登录密码，需要提供旧密码
+// @Description 更新管理员的登录密码，需要提供旧密码，修改后其已签发的令牌全部失效
 // @Tags 管理员管理
 // @Accept json
 // @Produce json
@@ -237,6 +331,7 @@ func (h *AdminHandler) UpdateAdminStatus(c *gin.Context) {
 // @Failure 400 {object} response.ResponseData "无效的请求参数"
 // @Failure 401 {object} response.ResponseData "旧密码错误"
 // @Failure 404 {object} response.ResponseData "管理员不存在"
+// @Failure 422 {object} response.ResponseData{data=services.PasswordPolicyError} "密码不符合安全策略"
 // @Failure 500 {object} response.ResponseData "内部错误"
 // @Router /gam/admins/{id}/password [put]
 func (h *AdminHandler) UpdatePassword(c *gin.Context) {
@@ -255,7 +350,14 @@ func (h *AdminHandler) UpdatePassword(c *gin.Context) {
 		return
 	}
 
-	if err := h.adminService.UpdatePassword(uint(id), req.OldPassword, req.NewPassword); err != nil {
+	if err := h.admins(c).UpdatePassword(uint(id), req.OldPassword, req.NewPassword); err != nil {
+		if errors.Is(err, services.ErrOldPasswordIncorrect) {
+			response.Error(c, http.StatusUnauthorized, "Old password is incorrect")
+			return
+		}
+		if respondPasswordPolicyError(c, err) {
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to update password")
 		return
 	}
@@ -263,9 +365,64 @@ func (h *AdminHandler) UpdatePassword(c *gin.Context) {
 	response.Success(c, gin.H{"message": "Password updated successfully"})
 }
 
+// ResetAdminMFA godoc
+// @Summary 重置管理员两步验证
+// @Description 管理员丢失设备和恢复码时，由其他管理员重置其两步验证；只能重置数据范围内的管理员，重置后其会话和已签发的令牌全部失效
+// @Tags 管理员管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "管理员ID" minimum(1)
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 400 {object} response.ResponseData "无效的管理员ID"
+// @Failure 404 {object} response.ResponseData "管理员不存在"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/admins/{id}/mfa [delete]
+func (h *AdminHandler) ResetAdminMFA(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	scope, ok := dataScopeOption(c, h.dataScopes)
+	if !ok {
+		return
+	}
+	if _, err := h.admins(c).GetByID(uint(id), scope); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Error(c, http.StatusNotFound, "Admin not found")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to reset two-factor authentication")
+		return
+	}
+
+	if err := h.mfaService.WithContext(c.Request.Context()).Reset(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Error(c, http.StatusNotFound, "Admin not found")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to reset two-factor authentication")
+		return
+	}
+
+	// 两步验证已失效，注销该管理员的全部会话并吊销已签发的令牌
+	if _, err := h.sessions.RevokeAll(middleware.UserTypeAdmin, uint(id), ""); err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to revoke admin sessions")
+		return
+	}
+	if err := h.admins(c).RevokeTokens(uint(id)); err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to revoke admin tokens")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Two-factor authentication reset successfully"})
+}
+
 // CheckAdminFieldUnique godoc
 // @Summary 检查管理员字段唯一性
-// @Description 检查指定字段的值在管理员表中是否唯一
+// @Description 检查指定字段的值在管理员表中是否唯一，支持 username、email、phone
 // @Tags 管理员管理
 // @Accept json
 // @Produce json
@@ -286,8 +443,12 @@ func (h *AdminHandler) CheckAdminFieldUnique(c *gin.Context) {
 		return
 	}
 
-	isUnique, err := h.adminService.CheckAdminFieldUnique(field, value, uint(excludeID))
+	isUnique, err := h.admins(c).CheckAdminFieldUnique(field, value, uint(excludeID))
 	if err != nil {
+		if errors.Is(err, services.ErrAdminInvalidField) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to check field uniqueness")
 		return
 	}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"normaladmin/backend/internal/services"
+	"normaladmin/backend/pkg/utils/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ApiEndpointHandler struct {
+	apiEndpointService services.ApiEndpointService
+}
+
+func NewApiEndpointHandler(apiEndpointService services.ApiEndpointService) *ApiEndpointHandler {
+	return &ApiEndpointHandler{apiEndpointService: apiEndpointService}
+}
+
+// GetApiEndpoints godoc
+// @Summary 获取接口目录
+// @Description 获取启动时按已注册路由维护的接口目录，用于为按钮菜单选择接口；stale 为 true 表示路由已不存在
+// @Tags 接口目录
+// @Accept json
+// @Produce json
+// @Param keyword query string false "关键字，匹配路径、摘要和处理函数"
+// @Param method query string false "请求方法"
+// @Param group query string false "分组，如 admins"
+// @Param stale query bool false "是否已失效"
+// @Param page query int false "页码，默认值：1" default(1)
+// @Param pageSize query int false "每页数量，默认值：10" default(10)
+// @Success 200 {object} response.ResponseData{data=object{apis=[]models.ApiEndpoint,total=int}} "成功"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/apis [get]
+func (h *ApiEndpointHandler) GetApiEndpoints(c *gin.Context) {
+	query := services.ApiEndpointQuery{
+		Keyword: c.Query("keyword"),
+		Method:  c.Query("method"),
+		Group:   c.Query("group"),
+	}
+	if stale := c.Query("stale"); stale != "" {
+		value := stale == "1" || stale == "true"
+		query.Stale = &value
+	}
+
+	apis, total, err := h.apiEndpointService.List(query, c.DefaultQuery("page", "1"), c.DefaultQuery("pageSize", "10"))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to get api endpoints")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"apis":  apis,
+		"total": total,
+	})
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"normaladmin/backend/internal/middleware"
+	"normaladmin/backend/internal/services"
+	"normaladmin/backend/pkg/utils/jwt"
+	"normaladmin/backend/pkg/utils/response"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	apiKeyService services.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService: apiKeyService}
+}
+
+// keys 使用请求上下文的 API 密钥服务，只能访问当前租户的密钥
+func (h *APIKeyHandler) keys(c *gin.Context) services.APIKeyService {
+	return h.apiKeyService.WithContext(c.Request.Context())
+}
+
+// CreateAPIKeyRequest 创建 API 密钥请求参数
+type CreateAPIKeyRequest struct {
+	Name       string    `json:"name" binding:"required,max=100"`
+	Scopes     []string  `json:"scopes" binding:"required,min=1"`
+	AllowedIPs []string  `json:"allowed_ips"`
+	ExpiresAt  time.Time `json:"expires_at" binding:"required"`
+}
+
+// GetAPIKeys godoc
+// @Summary 获取 API 密钥列表
+// @Description 获取当前管理员的 API 密钥；超级管理员可通过 admin_id 查询其他管理员的密钥，admin_id=0 查询全部
+// @Tags API密钥
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param admin_id query int false "管理员ID"
+// @Success 200 {object} response.ResponseData{data=object{list=[]models.APIKey,total=int}} "成功"
+// @Failure 403 {object} response.ResponseData "无权查看其他管理员的密钥"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/api-keys [get]
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	if !h.requireToken(c) {
+		return
+	}
+
+	adminID := jwt.GetUserID(c)
+	if value := c.Query("admin_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "Invalid admin ID")
+			return
+		}
+		if uint(id) != adminID && !isSuperAdmin(c) {
+			response.Error(c, http.StatusForbidden, "No permission to view api keys of other admins")
+			return
+		}
+		adminID = uint(id)
+	}
+
+	keys, err := h.keys(c).List(adminID)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to fetch api keys")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"list":  keys,
+		"total": len(keys),
+	})
+}
+
+// GetAPIKeyScopes godoc
+// @Summary 获取可授予的权限范围
+// @Description 获取当前管理员可授予 API 密钥的权限范围，即角色已有且配置了接口的按钮权限
+// @Tags API密钥
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.ResponseData{data=[]services.APIKeyScope} "成功"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/api-keys/scopes [get]
+func (h *APIKeyHandler) GetAPIKeyScopes(c *gin.Context) {
+	if !h.requireToken(c) {
+		return
+	}
+
+	scopes, err := h.keys(c).AvailableScopes(jwt.GetUserID(c))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to fetch scopes")
+		return
+	}
+
+	response.Success(c, scopes)
+}
+
+// CreateAPIKey godoc
+// @Summary 创建 API 密钥
+// @Description 为当前管理员创建 API 密钥，调用时使用请求头 Authorization: ApiKey <key>；明文密钥只在创建时返回一次
+// @Tags API密钥
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param req body CreateAPIKeyRequest true "密钥信息" example({"name":"nightly-export","scopes":["system:admin:list"],"allowed_ips":["10.0.0.0/8"],"expires_at":"2026-12-31T00:00:00Z"})
+// @Success 200 {object} response.ResponseData{data=object{api_key=models.APIKey,key=string}} "成功"
+// @Failure 400 {object} response.ResponseData "请求参数错误、权限范围无效或过期时间无效"
+// @Failure 403 {object} response.ResponseData "不能使用 API 密钥创建密钥"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	if !h.requireToken(c) {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	apiKey, key, err := h.keys(c).Create(jwt.GetUserID(c), services.CreateAPIKeyInput{
+		Name:       req.Name,
+		Scopes:     req.Scopes,
+		AllowedIPs: req.AllowedIPs,
+		ExpiresAt:  req.ExpiresAt,
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrAPIKeyInvalidScope) ||
+			errors.Is(err, services.ErrAPIKeyInvalidIP) ||
+			errors.Is(err, services.ErrAPIKeyInvalidExpiry) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to create api key")
+		return
+	}
+
+	// 明文密钥不写入请求日志
+	middleware.SkipResponseLog(c)
+	response.Success(c, gin.H{
+		"api_key": apiKey,
+		"key":     key,
+	})
+}
+
+// RevokeAPIKey godoc
+// @Summary 吊销 API 密钥
+// @Description 吊销 API 密钥并删除其权限策略，只能吊销自己的密钥，超级管理员可吊销任意密钥
+// @Tags API密钥
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "密钥ID"
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 403 {object} response.ResponseData "无权吊销该密钥"
+// @Failure 404 {object} response.ResponseData "密钥不存在"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	if !h.requireToken(c) {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	apiKey, err := h.keys(c).Get(uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			response.Error(c, http.StatusNotFound, "API key not found")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to revoke api key")
+		return
+	}
+	if apiKey.AdminID != jwt.GetUserID(c) && !isSuperAdmin(c) {
+		response.Error(c, http.StatusForbidden, "No permission to revoke this api key")
+		return
+	}
+
+	if err := h.keys(c).Revoke(apiKey.ID); err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to revoke api key")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "API key revoked"})
+}
+
+// requireToken 密钥管理只允许使用登录令牌，防止 API 密钥自我扩散
+func (h *APIKeyHandler) requireToken(c *gin.Context) bool {
+	if _, ok := middleware.GetAPIKeyID(c); ok {
+		response.Error(c, http.StatusForbidden, "API keys cannot manage api keys")
+		return false
+	}
+	return true
+}
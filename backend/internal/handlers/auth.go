@@ -1,21 +1,48 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
+	"math"
 	"net/http"
 	"normaladmin/backend/config"
 	"normaladmin/backend/database"
 	"normaladmin/backend/internal/middleware"
 	"normaladmin/backend/internal/models"
+	"normaladmin/backend/internal/services"
+	"normaladmin/backend/pkg/auth"
+	"normaladmin/backend/pkg/tenant"
+	"normaladmin/backend/pkg/utils"
 	"normaladmin/backend/pkg/utils/response"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// AuthServices 登录认证流程依赖的服务
+type AuthServices struct {
+	MFA            services.MFAService
+	LoginGuard     services.LoginGuardService
+	Sessions       services.SessionService
+	LoginLogs      services.LoginLogService
+	PasswordPolicy services.PasswordPolicyService
+	Impersonation  services.ImpersonationService
+	Captcha        services.CaptchaService
+	MemberEmail    services.MemberEmailService
+	Tenants        services.TenantService
+}
+
 // LoginRequest 登录请求参数
 type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Tenant      string `json:"tenant"` // 租户编码，为空时登录平台（超级租户）
+	Username    string `json:"username" binding:"required"`
+	Password    string `json:"password" binding:"required"`
+	Device      string `json:"device" binding:"max=100"` // 设备名称，可选，为空时根据 User-Agent 识别
+	CaptchaID   string `json:"captcha_id"`               // 需要验证码时必填，通过 /captcha 获取
+	CaptchaCode string `json:"captcha_code"`
 }
 
 // RefreshTokenRequest 刷新令牌请求参数
@@ -25,74 +52,347 @@ type RefreshTokenRequest struct {
 
 // MemberLoginRequest 会员登录请求参数
 type MemberLoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Tenant      string `json:"tenant"` // 租户编码，为空时登录平台（超级租户）
+	Username    string `json:"username" binding:"required"`
+	Password    string `json:"password" binding:"required"`
+	Device      string `json:"device" binding:"max=100"` // 设备名称，可选，为空时根据 User-Agent 识别
+	CaptchaID   string `json:"captcha_id"`               // 需要验证码时必填，通过 /captcha 获取
+	CaptchaCode string `json:"captcha_code"`
 }
 
 // MemberRegisterRequest 会员注册请求参数
 type MemberRegisterRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
-	Email    string `json:"email" binding:"required,email"`
-	Phone    string `json:"phone" binding:"required"`
+	Tenant      string `json:"tenant"` // 租户编码，为空时注册到平台（超级租户）
+	Username    string `json:"username" binding:"required"`
+	Password    string `json:"password" binding:"required"`
+	Email       string `json:"email" binding:"required,email"`
+	Phone       string `json:"phone" binding:"required"`
+	CaptchaID   string `json:"captcha_id"` // 需要验证码时必填，通过 /captcha 获取
+	CaptchaCode string `json:"captcha_code"`
+}
+
+// PasswordChangeRequest 密码过期时修改密码并完成登录
+type PasswordChangeRequest struct {
+	PasswordToken string `json:"password_token" binding:"required"`
+	NewPassword   string `json:"new_password" binding:"required"`
+	Device        string `json:"device" binding:"max=100"`
 }
 
 // Login godoc
 // @Summary 用户登录
-// @Description 管理员登录接口，验证用户名密码并返回访问令牌
+// @Description 管理员登录接口，验证用户名密码并返回访问令牌；需要验证码时（响应中 captcha_required 为 true）先通过 /captcha 获取验证码；密码已过期时返回 password_token，需通过 /gam/login/password 修改密码；需要两步验证时返回 mfa_token，再通过 /gam/login/mfa 完成登录
 // @Tags 认证管理
 // @Accept json
 // @Produce json
 // @Param req body LoginRequest true "登录信息" example({"username":"admin","password":"123456"})
-// @Success 200 {object} response.ResponseData{data=object{token=string,refreshToken=string,user=object}} "成功"
-// @Failure 400 {object} response.ResponseData "请求参数错误"
-// @Failure 401 {object} response.ResponseData "用户名或密码错误"
+// @Success 200 {object} response.ResponseData{data=object{token=string,refreshToken=string,user=object,password_expired=bool,password_token=string,mfa_required=bool,mfa_token=string,mfa_setup_required=bool}} "成功"
+// @Failure 400 {object} response.ResponseData{data=object{captcha_required=bool}} "请求参数错误或验证码错误"
+// @Failure 401 {object} response.ResponseData{data=object{captcha_required=bool}} "用户名或密码错误"
+// @Failure 429 {object} response.ResponseData "登录失败次数过多，暂时锁定"
 // @Failure 500 {object} response.ResponseData "内部错误"
 // @Router /gam/login [post]
-func Login(cfg config.JWTConfig) gin.HandlerFunc {
+func Login(cfg config.JWTConfig, svc *AuthServices) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req LoginRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			fmt.Println(err, req)
 			response.Error(c, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		// 验证用户名密码
+		// 失败次数过多时拒绝尝试
+		account := loginAccount(req.Tenant, req.Username)
+		if err := svc.LoginGuard.Check(middleware.UserTypeAdmin, account, c.ClientIP()); err != nil {
+			svc.recordLogin(c, middleware.UserTypeAdmin, services.LoginActionLogin, 0, account, services.LoginReasonThrottled)
+			respondLoginThrottled(c, err, "Too many failed login attempts, please try again later")
+			return
+		}
+		if !svc.checkLoginCaptcha(c, middleware.UserTypeAdmin, account, req.CaptchaID, req.CaptchaCode, "Invalid captcha") {
+			return
+		}
+		t, ok := svc.resolveLoginTenant(c, req.Tenant, "Tenant is disabled or expired")
+		if !ok {
+			return
+		}
+
+		// 验证用户名密码，租户或用户不存在、密码错误和账号禁用返回相同的错误
 		var user models.Admin
-		if err := database.GetDB().Where("username = ?", req.Username).First(&user).Error; err != nil {
-			response.Error(c, http.StatusUnauthorized, "Invalid username or password")
+		found := t != nil && database.GetDB().WithContext(c.Request.Context()).Where("username = ?", req.Username).First(&user).Error == nil
+		if !checkLoginPassword(found, user.Password, req.Password) {
+			svc.LoginGuard.RecordFailure(middleware.UserTypeAdmin, account, c.ClientIP())
+			svc.recordLogin(c, middleware.UserTypeAdmin, services.LoginActionLogin, user.ID, account, services.LoginReasonInvalidCredentials)
+			svc.respondInvalidCredentials(c, middleware.UserTypeAdmin, account, "Invalid username or password")
+			return
+		}
+		if user.Status == nil || *user.Status != 1 {
+			svc.recordLogin(c, middleware.UserTypeAdmin, services.LoginActionLogin, user.ID, account, services.LoginReasonAccountDisabled)
+			svc.respondInvalidCredentials(c, middleware.UserTypeAdmin, account, "Invalid username or password")
 			return
 		}
+		svc.LoginGuard.RecordSuccess(middleware.UserTypeAdmin, account)
 
-		if !user.CheckPassword(req.Password) {
-			response.Error(c, http.StatusUnauthorized, "Invalid username or password")
+		// 密码已过期，必须先修改密码
+		if svc.PasswordPolicy.IsExpired(user.PasswordChangedAt, user.CreatedAt) {
+			respondPasswordExpired(c, middleware.UserTypeAdmin, user.ID, user.Username, cfg)
 			return
 		}
 
-		// 生成token
-		accessToken, refreshToken, err := middleware.GenerateToken(user.ID, user.RoleID, "admin", user.Username, cfg)
+		completeAdminLogin(c, &user, cfg, svc, req.Device)
+	}
+}
+
+// completeAdminLogin 密码验证通过后继续登录：需要两步验证时签发待确认令牌，否则直接签发访问令牌
+func completeAdminLogin(c *gin.Context, user *models.Admin, cfg config.JWTConfig, svc *AuthServices, device string) {
+	// 需要两步验证时，先签发短期的待确认令牌，登录结果在第二步记录
+	mfaRequired, err := svc.MFA.IsRequired(user)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to check two-factor authentication")
+		return
+	}
+	if mfaRequired {
+		mfaToken, err := middleware.GenerateMFAToken(user.ID, user.Username, cfg)
 		if err != nil {
 			response.Error(c, http.StatusInternalServerError, "Failed to generate token")
 			return
 		}
-
 		response.Success(c, gin.H{
-			"token":        accessToken,
-			"refreshToken": refreshToken,
-			"user": gin.H{
-				"id":       user.ID,
-				"username": user.Username,
-				"email":    user.Email,
-				"role_id":  user.RoleID,
-			},
+			"mfa_required":       true,
+			"mfa_token":          mfaToken,
+			"mfa_setup_required": !user.MFAEnabled,
 		})
+		return
+	}
+
+	respondAdminLogin(c, user, cfg, svc, device, services.LoginActionLogin, nil)
+}
+
+// respondPasswordExpired 密码已过期，签发只能用于修改密码的短期令牌
+func respondPasswordExpired(c *gin.Context, userType string, userID uint, username string, cfg config.JWTConfig) {
+	passwordToken, err := middleware.GeneratePendingToken(userType, userID, username, middleware.TokenTypePasswordChange, middleware.PasswordTokenTTL, cfg)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+	response.Success(c, gin.H{
+		"password_expired": true,
+		"password_token":   passwordToken,
+	})
+}
+
+// respondPasswordPolicyError 新密码不符合密码策略时返回 422 及全部不满足的规则
+func respondPasswordPolicyError(c *gin.Context, err error) bool {
+	var policyErr *services.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+	response.ErrorWithData(c, http.StatusUnprocessableEntity, policyErr.Error(), policyErr)
+	return true
+}
+
+// changeExpiredPassword 使用待修改令牌修改过期密码，令牌只能使用一次
+func changeExpiredPassword(c *gin.Context, userType string, cfg config.JWTConfig, svc *AuthServices) (*PasswordChangeRequest, *middleware.Claims, bool) {
+	var req PasswordChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return nil, nil, false
+	}
+
+	claims, err := middleware.ParsePendingToken(req.PasswordToken, userType, middleware.TokenTypePasswordChange, cfg)
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, "Invalid or expired password token")
+		return nil, nil, false
+	}
+
+	if err := svc.PasswordPolicy.SetPassword(userType, claims.UserID, req.NewPassword); err != nil {
+		if !respondPasswordPolicyError(c, err) {
+			response.Error(c, http.StatusInternalServerError, "Failed to update password")
+		}
+		return nil, nil, false
+	}
+	if err := auth.DenyToken(claims.ID, time.Until(claims.ExpiresAt.Time)); err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to generate token")
+		return nil, nil, false
+	}
+	return &req, claims, true
+}
+
+// ChangeExpiredPassword godoc
+// @Summary 修改过期密码
+// @Description 登录时密码已过期，使用返回的 password_token 设置新密码并继续登录；需要两步验证时返回 mfa_token
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Param req body PasswordChangeRequest true "新密码" example({"password_token":"eyJhbGciOi...","new_password":"NewPassw0rd"})
+// @Success 200 {object} response.ResponseData{data=object{token=string,refreshToken=string,user=object,mfa_required=bool,mfa_token=string,mfa_setup_required=bool}} "成功"
+// @Failure 400 {object} response.ResponseData "请求参数错误"
+// @Failure 401 {object} response.ResponseData "令牌无效"
+// @Failure 422 {object} response.ResponseData{data=services.PasswordPolicyError} "密码不符合安全策略"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/login/password [post]
+func ChangeExpiredPassword(cfg config.JWTConfig, svc *AuthServices) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, claims, ok := changeExpiredPassword(c, middleware.UserTypeAdmin, cfg, svc)
+		if !ok {
+			return
+		}
+
+		var user models.Admin
+		if err := database.GetDB().First(&user, claims.UserID).Error; err != nil || user.Status == nil || *user.Status != 1 {
+			response.Error(c, http.StatusUnauthorized, "Invalid or expired password token")
+			return
+		}
+		completeAdminLogin(c, &user, cfg, svc, req.Device)
+	}
+}
+
+// dummyPasswordHash 用户不存在时参与比对的哈希，使响应耗时与密码错误一致
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := utils.HashPassword("gam-dummy-password")
+	return hash
+})
+
+// checkLoginPassword 校验登录密码，用户不存在时同样执行一次哈希比对
+func checkLoginPassword(found bool, hash, password string) bool {
+	if !found {
+		utils.CheckPassword(password, dummyPasswordHash())
+		return false
+	}
+	return utils.CheckPassword(password, hash)
+}
+
+// loginAccount 登录失败计数和锁定使用的账号，不同租户的同名用户分别计数
+func loginAccount(tenantCode, username string) string {
+	if code := strings.ToLower(strings.TrimSpace(tenantCode)); code != "" {
+		return code + "/" + username
+	}
+	return username
+}
+
+// resolveLoginTenant 确定登录的租户并将本次请求限定在该租户内，编码为空时为超级租户
+// 租户不存在时返回 nil 和 true，由调用方按用户名或密码错误处理，不暴露租户编码是否存在；
+// 租户已禁用或到期时返回 403
+func (svc *AuthServices) resolveLoginTenant(c *gin.Context, code, disabledMessage string) (*models.Tenant, bool) {
+	t, err := svc.Tenants.Resolve(code)
+	switch {
+	case errors.Is(err, services.ErrTenantNotFound):
+		return nil, true
+	case errors.Is(err, services.ErrTenantDisabled):
+		response.Error(c, http.StatusForbidden, disabledMessage)
+		return nil, false
+	case err != nil:
+		response.Error(c, http.StatusInternalServerError, "Failed to resolve tenant")
+		return nil, false
+	}
+	middleware.SetTenant(c, t.ID)
+	return t, true
+}
+
+// respondLoginThrottled 登录被延迟或锁定时返回 429，并通过 Retry-After 告知等待时间
+func respondLoginThrottled(c *gin.Context, err error, message string) {
+	var throttled *services.ErrLoginThrottled
+	if errors.As(err, &throttled) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	}
+	response.Error(c, http.StatusTooManyRequests, message)
+}
+
+// issueLoginTokens 开启新的登录会话并签发令牌，会话ID即令牌家族ID
+func issueLoginTokens(c *gin.Context, user middleware.TokenUser, cfg config.JWTConfig, sessions services.SessionService, device string) (string, string, error) {
+	familyID := auth.NewTokenID()
+	accessToken, refreshToken, err := middleware.GenerateToken(user, familyID, cfg)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := sessions.Create(&models.UserSession{
+		SessionID: familyID,
+		UserType:  user.UserType,
+		UserID:    user.UserID,
+		Username:  user.Username,
+		Device:    device,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}); err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+// recordLogin 记录登录日志，reason 为空表示成功
+func (svc *AuthServices) recordLogin(c *gin.Context, userType, action string, userID uint, username, reason string) {
+	svc.LoginLogs.Record(&models.LoginLog{
+		TenantID:  middleware.GetTenantID(c),
+		UserType:  userType,
+		UserID:    userID,
+		Username:  username,
+		Action:    action,
+		Success:   reason == "",
+		Reason:    reason,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+}
+
+// checkLoginCaptcha 失败次数达到阈值或配置为始终需要时校验验证码，校验失败时提示前端显示验证码
+func (svc *AuthServices) checkLoginCaptcha(c *gin.Context, userType, username, captchaID, captchaCode, message string) bool {
+	if !svc.Captcha.Required(svc.LoginGuard.Failures(userType, username, c.ClientIP())) {
+		return true
+	}
+	if svc.Captcha.Verify(captchaID, captchaCode) {
+		return true
+	}
+	svc.recordLogin(c, userType, services.LoginActionLogin, 0, username, services.LoginReasonInvalidCaptcha)
+	response.ErrorWithData(c, http.StatusBadRequest, message, gin.H{"captcha_required": true})
+	return false
+}
+
+// respondInvalidCredentials 登录失败时返回 401，并告知下次登录是否需要验证码
+func (svc *AuthServices) respondInvalidCredentials(c *gin.Context, userType, username, message string) {
+	required := svc.Captcha.Required(svc.LoginGuard.Failures(userType, username, c.ClientIP()))
+	response.ErrorWithData(c, http.StatusUnauthorized, message, gin.H{"captcha_required": required})
+}
+
+// respondAdminLogin 为管理员签发令牌、记录登录日志并返回登录结果
+func respondAdminLogin(c *gin.Context, user *models.Admin, cfg config.JWTConfig, svc *AuthServices, device, action string, extra gin.H) {
+	// 两步验证、修改过期密码等后续步骤只携带用户ID，以用户所属的租户为准
+	middleware.SetTenant(c, user.TenantID)
+	if !middleware.TenantActive(user.TenantID) {
+		response.Error(c, http.StatusForbidden, "Tenant is disabled or expired")
+		return
+	}
+
+	// 生成token
+	accessToken, refreshToken, err := issueLoginTokens(c, middleware.TokenUser{
+		UserID:   user.ID,
+		UserType: middleware.UserTypeAdmin,
+		Username: user.Username,
+		TenantID: user.TenantID,
+	}, cfg, svc.Sessions, device)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to generate token")
+		return
 	}
+	svc.recordLogin(c, middleware.UserTypeAdmin, action, user.ID, user.Username, "")
+
+	data := gin.H{
+		"token":        accessToken,
+		"refreshToken": refreshToken,
+		"user": gin.H{
+			"id":          user.ID,
+			"username":    user.Username,
+			"email":       user.Email,
+			"role_id":     user.RoleID,
+			"mfa_enabled": user.MFAEnabled,
+		},
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	response.Success(c, data)
 }
 
 // RefreshToken godoc
 // @Summary 刷新访问令牌
-// @Description 使用刷新令牌获取新的访问令牌和刷新令牌
+// @Description 使用刷新令牌获取新的访问令牌和刷新令牌，旧的刷新令牌随即失效
 // @Tags 认证管理
 // @Accept json
 // @Produce json
@@ -102,7 +402,8 @@ func Login(cfg config.JWTConfig) gin.HandlerFunc {
 // @Failure 401 {object} response.ResponseData "无效的刷新令牌"
 // @Failure 500 {object} response.ResponseData "内部错误"
 // @Router /gam/refresh-token [post]
-func RefreshToken(cfg config.JWTConfig) gin.HandlerFunc {
+// @Router /api/member/refresh-token [post]
+func RefreshToken(cfg config.JWTConfig, userType string, svc *AuthServices) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req RefreshTokenRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -111,26 +412,66 @@ func RefreshToken(cfg config.JWTConfig) gin.HandlerFunc {
 		}
 
 		// 验证刷新令牌
-		claims := &middleware.Claims{}
-		token, err := middleware.ParseToken(req.RefreshToken, cfg.SecretKey)
+		token, err := middleware.ParseToken(req.RefreshToken, cfg)
 		if err != nil || !token.Valid {
 			response.Error(c, http.StatusUnauthorized, "Invalid refresh token")
 			return
 		}
 
 		claims, ok := token.Claims.(*middleware.Claims)
-		if !ok {
+		if !ok || claims.TokenType != "refresh" || claims.ID == "" || claims.FamilyID == "" {
 			response.Error(c, http.StatusUnauthorized, "Invalid token claims")
 			return
 		}
 
-		// 生成新的访问令牌
-		accessToken, refreshToken, err := middleware.GenerateToken(claims.UserID, claims.RoleID, claims.UserType, claims.Username, cfg)
+		// 只能刷新本端签发的令牌
+		if !claims.VerifyUserType(userType) {
+			svc.recordLogin(c, userType, services.LoginActionRefresh, 0, claims.Username, services.LoginReasonInvalidToken)
+			response.Error(c, http.StatusUnauthorized, "Invalid refresh token")
+			return
+		}
+
+		// 租户禁用或到期后不再续期
+		middleware.SetTenant(c, claims.Tenant())
+		if !middleware.TenantActive(claims.Tenant()) {
+			svc.recordLogin(c, userType, services.LoginActionRefresh, claims.UserID, claims.Username, services.LoginReasonAccountDisabled)
+			response.Error(c, http.StatusForbidden, "Tenant is disabled or expired")
+			return
+		}
+
+		if middleware.IsClaimsRevoked(claims) {
+			svc.recordLogin(c, userType, services.LoginActionRefresh, claims.UserID, claims.Username, services.LoginReasonTokenRevoked)
+			response.Error(c, http.StatusUnauthorized, "Invalid refresh token")
+			return
+		}
+
+		// 轮换刷新令牌，旧令牌立即失效；重复使用会吊销整个令牌家族
+		if err := auth.RotateRefreshToken(claims.ID, claims.FamilyID, middleware.RefreshTokenTTL(cfg)); err != nil {
+			switch {
+			case errors.Is(err, auth.ErrRefreshTokenReused):
+				svc.recordLogin(c, userType, services.LoginActionRefresh, claims.UserID, claims.Username, services.LoginReasonTokenReused)
+				response.Error(c, http.StatusUnauthorized, "Refresh token reuse detected, please login again")
+			case errors.Is(err, auth.ErrRefreshTokenInvalid), errors.Is(err, auth.ErrTokenFamilyRevoked):
+				svc.recordLogin(c, userType, services.LoginActionRefresh, claims.UserID, claims.Username, services.LoginReasonTokenRevoked)
+				response.Error(c, http.StatusUnauthorized, "Invalid refresh token")
+			default:
+				response.Error(c, http.StatusInternalServerError, "Failed to refresh token")
+			}
+			return
+		}
+
+		// 刷新令牌视为会话活跃
+		middleware.TouchSession(claims, cfg)
+
+		// 在同一令牌家族内签发新的令牌对
+		accessToken, refreshToken, err := middleware.GenerateToken(claims.TokenUser(), claims.FamilyID, cfg)
 		if err != nil {
 			response.Error(c, http.StatusInternalServerError, "Failed to generate token")
 			return
 		}
 
+		svc.recordLogin(c, userType, services.LoginActionRefresh, claims.UserID, claims.Username, "")
+
 		response.Success(c, gin.H{
 			"token":        accessToken,
 			"refreshToken": refreshToken,
@@ -138,19 +479,78 @@ func RefreshToken(cfg config.JWTConfig) gin.HandlerFunc {
 	}
 }
 
+// Logout godoc
+// @Summary 退出登录
+// @Description 注销当前访问令牌，并吊销同一次登录签发的刷新令牌
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 401 {object} response.ResponseData "未授权或Token无效"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/logout [post]
+// @Router /api/member/logout [post]
+func Logout(cfg config.JWTConfig, svc *AuthServices) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := middleware.GetClaims(c)
+		if !ok {
+			response.Error(c, http.StatusUnauthorized, "Invalid token claims")
+			return
+		}
+
+		// 访问令牌在剩余有效期内加入黑名单
+		var remaining time.Duration
+		if claims.ExpiresAt != nil {
+			remaining = time.Until(claims.ExpiresAt.Time)
+		}
+		if err := auth.DenyToken(claims.ID, remaining); err != nil {
+			response.Error(c, http.StatusInternalServerError, "Failed to logout")
+			return
+		}
+
+		// 模拟登录的令牌没有登录会话，退出时结束模拟登录
+		if claims.ImpersonatorID > 0 {
+			err := svc.Impersonation.End(claims.ImpersonatorID, claims.UserID, "logout", auditContext(c))
+			if err != nil && !errors.Is(err, services.ErrImpersonationNotFound) {
+				response.Error(c, http.StatusInternalServerError, "Failed to logout")
+				return
+			}
+			response.Success(c, gin.H{"message": "Logout successfully"})
+			return
+		}
+
+		// 结束登录会话并吊销令牌家族，使对应的刷新令牌失效
+		if claims.FamilyID != "" {
+			err := svc.Sessions.Revoke(claims.FamilyID)
+			if errors.Is(err, services.ErrSessionNotFound) {
+				err = auth.RevokeFamily(claims.FamilyID, middleware.RefreshTokenTTL(cfg))
+			}
+			if err != nil {
+				response.Error(c, http.StatusInternalServerError, "Failed to logout")
+				return
+			}
+		}
+
+		response.Success(c, gin.H{"message": "Logout successfully"})
+	}
+}
+
 // MemberLogin godoc
 // @Summary 会员登录
-// @Description 小程序会员登录接口，验证用户名密码并返回访问令牌
+// @Description 小程序会员登录接口，验证用户名密码并返回访问令牌；开启强制邮箱验证时未验证邮箱的会员返回 403；需要验证码时（响应中 captcha_required 为 true）先通过 /captcha 获取验证码；密码已过期时返回 password_token，需通过 /api/member/login/password 修改密码
 // @Tags 会员认证
 // @Accept json
 // @Produce json
 // @Param req body MemberLoginRequest true "登录信息" example({"username":"member","password":"123456"})
-// @Success 200 {object} response.ResponseData{data=object{token=string,refreshToken=string,member=object}} "成功"
-// @Failure 400 {object} response.ResponseData "请求参数错误"
-// @Failure 401 {object} response.ResponseData "用户名或密码错误"
+// @Success 200 {object} response.ResponseData{data=object{token=string,refreshToken=string,member=object,password_expired=bool,password_token=string}} "成功"
+// @Failure 400 {object} response.ResponseData{data=object{captcha_required=bool}} "请求参数错误或验证码错误"
+// @Failure 401 {object} response.ResponseData{data=object{captcha_required=bool}} "用户名或密码错误"
+// @Failure 403 {object} response.ResponseData{data=object{email_unverified=bool}} "邮箱未验证"
+// @Failure 429 {object} response.ResponseData "登录失败次数过多，暂时锁定"
 // @Failure 500 {object} response.ResponseData "内部错误"
 // @Router /api/member/login [post]
-func MemberLogin(cfg config.JWTConfig) gin.HandlerFunc {
+func MemberLogin(cfg config.JWTConfig, svc *AuthServices) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req MemberLoginRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -158,90 +558,208 @@ func MemberLogin(cfg config.JWTConfig) gin.HandlerFunc {
 			return
 		}
 
-		// 验证用户名密码
+		// 失败次数过多时拒绝尝试
+		account := loginAccount(req.Tenant, req.Username)
+		if err := svc.LoginGuard.Check(middleware.UserTypeMember, account, c.ClientIP()); err != nil {
+			svc.recordLogin(c, middleware.UserTypeMember, services.LoginActionLogin, 0, account, services.LoginReasonThrottled)
+			respondLoginThrottled(c, err, "登录失败次数过多，请稍后再试")
+			return
+		}
+		if !svc.checkLoginCaptcha(c, middleware.UserTypeMember, account, req.CaptchaID, req.CaptchaCode, "验证码错误") {
+			return
+		}
+		t, ok := svc.resolveLoginTenant(c, req.Tenant, "租户已停用或已到期")
+		if !ok {
+			return
+		}
+
+		// 验证用户名密码，不区分租户或用户名错误、密码错误和账号禁用
 		var member models.Member
-		if err := database.GetDB().Where("username = ?", req.Username).First(&member).Error; err != nil {
-			response.Error(c, http.StatusUnauthorized, "用户名错误")
+		found := t != nil && database.GetDB().WithContext(c.Request.Context()).Where("username = ?", req.Username).First(&member).Error == nil
+		if !checkLoginPassword(found, member.Password, req.Password) {
+			svc.LoginGuard.RecordFailure(middleware.UserTypeMember, account, c.ClientIP())
+			svc.recordLogin(c, middleware.UserTypeMember, services.LoginActionLogin, member.ID, account, services.LoginReasonInvalidCredentials)
+			svc.respondInvalidCredentials(c, middleware.UserTypeMember, account, "用户名或密码错误")
+			return
+		}
+		if member.Status == nil || *member.Status != 1 {
+			svc.recordLogin(c, middleware.UserTypeMember, services.LoginActionLogin, member.ID, account, services.LoginReasonAccountDisabled)
+			svc.respondInvalidCredentials(c, middleware.UserTypeMember, account, "用户名或密码错误")
 			return
 		}
+		svc.LoginGuard.RecordSuccess(middleware.UserTypeMember, account)
 
-		if !member.CheckPassword(req.Password) {
-			response.Error(c, http.StatusUnauthorized, "密码错误")
+		// 密码已过期，必须先修改密码
+		if svc.PasswordPolicy.IsExpired(member.PasswordChangedAt, member.CreatedAt) {
+			respondPasswordExpired(c, middleware.UserTypeMember, member.ID, member.Username, cfg)
 			return
 		}
 
-		// 生成token
-		accessToken, refreshToken, err := middleware.GenerateToken(member.ID, member.LevelID, "member", member.Username, cfg)
-		if err != nil {
-			response.Error(c, http.StatusInternalServerError, "生成token失败")
+		respondMemberLogin(c, &member, cfg, svc, req.Device)
+	}
+}
+
+// respondMemberLogin 为会员签发令牌、记录登录日志并返回登录结果
+func respondMemberLogin(c *gin.Context, member *models.Member, cfg config.JWTConfig, svc *AuthServices, device string) {
+	middleware.SetTenant(c, member.TenantID)
+	if !middleware.TenantActive(member.TenantID) {
+		response.Error(c, http.StatusForbidden, "租户已停用或已到期")
+		return
+	}
+
+	// 开启强制邮箱验证时，未验证的会员不能登录
+	if member.EmailVerifiedAt == nil && svc.MemberEmail.VerificationRequired() {
+		svc.recordLogin(c, middleware.UserTypeMember, services.LoginActionLogin, member.ID, member.Username, services.LoginReasonEmailUnverified)
+		response.ErrorWithData(c, http.StatusForbidden, "邮箱未验证，请先完成邮箱验证", gin.H{"email_unverified": true})
+		return
+	}
+
+	// 生成token
+	accessToken, refreshToken, err := issueLoginTokens(c, middleware.TokenUser{
+		UserID:   member.ID,
+		UserType: middleware.UserTypeMember,
+		Username: member.Username,
+		LevelID:  member.LevelID,
+		TenantID: member.TenantID,
+	}, cfg, svc.Sessions, device)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "生成token失败")
+		return
+	}
+	svc.recordLogin(c, middleware.UserTypeMember, services.LoginActionLogin, member.ID, member.Username, "")
+
+	response.Success(c, gin.H{
+		"token":        accessToken,
+		"refreshToken": refreshToken,
+		"member": gin.H{
+			"id":       member.ID,
+			"username": member.Username,
+			"email":    member.Email,
+			"mobile":   member.Mobile,
+			"level_id": member.LevelID,
+		},
+	})
+}
+
+// MemberChangeExpiredPassword godoc
+// @Summary 会员修改过期密码
+// @Description 登录时密码已过期，使用返回的 password_token 设置新密码并完成登录
+// @Tags 会员认证
+// @Accept json
+// @Produce json
+// @Param req body PasswordChangeRequest true "新密码" example({"password_token":"eyJhbGciOi...","new_password":"NewPassw0rd"})
+// @Success 200 {object} response.ResponseData{data=object{token=string,refreshToken=string,member=object}} "成功"
+// @Failure 400 {object} response.ResponseData "请求参数错误"
+// @Failure 401 {object} response.ResponseData "令牌无效"
+// @Failure 422 {object} response.ResponseData{data=services.PasswordPolicyError} "密码不符合安全策略"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /api/member/login/password [post]
+func MemberChangeExpiredPassword(cfg config.JWTConfig, svc *AuthServices) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req, claims, ok := changeExpiredPassword(c, middleware.UserTypeMember, cfg, svc)
+		if !ok {
 			return
 		}
 
-		response.Success(c, gin.H{
-			"token":        accessToken,
-			"refreshToken": refreshToken,
-			"member": gin.H{
-				"id":       member.ID,
-				"username": member.Username,
-				"email":    member.Email,
-				"mobile":   member.Mobile,
-				"level_id": member.LevelID,
-			},
-		})
+		var member models.Member
+		if err := database.GetDB().First(&member, claims.UserID).Error; err != nil || member.Status == nil || *member.Status != 1 {
+			response.Error(c, http.StatusUnauthorized, "Invalid or expired password token")
+			return
+		}
+		respondMemberLogin(c, &member, cfg, svc, req.Device)
 	}
 }
 
 // MemberRegister godoc
 // @Summary 会员注册
-// @Description 小程序会员注册接口，创建新会员账号
+// @Description 小程序会员注册接口，创建新会员账号并发送邮箱验证邮件，密码需满足密码策略；需要验证码时（响应中 captcha_required 为 true）先通过 /captcha 获取验证码
 // @Tags 会员认证
 // @Accept json
 // @Produce json
-// @Param req body MemberRegisterRequest true "注册信息" example({"username":"newmember","password":"123456","email":"member@example.com","phone":"13800138000"})
-// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
-// @Failure 400 {object} response.ResponseData "请求参数错误或用户名已存在"
+// @Param req body MemberRegisterRequest true "注册信息" example({"username":"newmember","password":"Passw0rd","email":"member@example.com","phone":"13800138000"})
+// @Success 200 {object} response.ResponseData{data=object{message=string,email_verification_required=bool}} "成功"
+// @Failure 400 {object} response.ResponseData{data=object{captcha_required=bool}} "请求参数错误、验证码错误或用户名已存在"
+// @Failure 422 {object} response.ResponseData{data=services.PasswordPolicyError} "密码不符合安全策略"
 // @Failure 500 {object} response.ResponseData "内部错误"
 // @Router /api/member/register [post]
-func MemberRegister(c *gin.Context) {
-	var req MemberRegisterRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, err.Error())
-		return
-	}
+func MemberRegister(cfg config.JWTConfig, svc *AuthServices) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MemberRegisterRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
 
-	// 检查用户名是否已存在
-	var count int64
-	if err := database.GetDB().Model(&models.Member{}).Where("username = ?", req.Username).Count(&count).Error; err != nil {
-		response.Error(c, http.StatusInternalServerError, "系统错误")
-		return
-	}
-	if count > 0 {
-		response.Error(c, http.StatusBadRequest, "用户名已存在")
-		return
-	}
+		// 同一IP注册失败次数达到阈值后需要验证码
+		ip := c.ClientIP()
+		if svc.Captcha.Required(svc.Captcha.Failures(services.CaptchaSceneRegister, ip)) && !svc.Captcha.Verify(req.CaptchaID, req.CaptchaCode) {
+			response.ErrorWithData(c, http.StatusBadRequest, "验证码错误", gin.H{"captcha_required": true})
+			return
+		}
 
-	// 创建新会员
-	member := models.Member{
-		Username: req.Username,
-		Email:    req.Email,
-		Mobile:   req.Phone,
-		LevelID:  1, // 默认等级
-	}
-	member.SetPassword(req.Password)
+		// 注册到请求的租户
+		t, ok := svc.resolveLoginTenant(c, req.Tenant, "租户已停用或已到期")
+		if !ok {
+			return
+		}
+		if t == nil {
+			svc.Captcha.RecordFailure(services.CaptchaSceneRegister, ip)
+			response.Error(c, http.StatusBadRequest, "租户不存在")
+			return
+		}
+		db := database.GetDB().WithContext(c.Request.Context())
 
-	if err := database.GetDB().Create(&member).Error; err != nil {
-		response.Error(c, http.StatusInternalServerError, "注册失败")
-		return
-	}
+		// 检查用户名是否已存在
+		var count int64
+		if err := db.Model(&models.Member{}).Where("username = ?", req.Username).Count(&count).Error; err != nil {
+			response.Error(c, http.StatusInternalServerError, "系统错误")
+			return
+		}
+		if count > 0 {
+			svc.Captcha.RecordFailure(services.CaptchaSceneRegister, ip)
+			response.Error(c, http.StatusBadRequest, "用户名已存在")
+			return
+		}
 
-	response.Success(c, gin.H{
-		"message": "注册成功",
-	})
+		// 校验密码策略
+		if err := svc.PasswordPolicy.Validate(middleware.UserTypeMember, 0, req.Username, req.Password, ""); err != nil {
+			svc.Captcha.RecordFailure(services.CaptchaSceneRegister, ip)
+			respondPasswordPolicyError(c, err)
+			return
+		}
+
+		// 创建新会员
+		member := models.Member{
+			Username: req.Username,
+			Password: req.Password, // 由 BeforeCreate 钩子加密
+			Email:    req.Email,
+			Mobile:   req.Phone,
+			LevelID:  1, // 默认等级
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&member).Error; err != nil {
+				return err
+			}
+			return svc.PasswordPolicy.Record(tx, middleware.UserTypeMember, member.ID, member.Password)
+		})
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, "注册失败")
+			return
+		}
+
+		sendVerificationEmail(&member, cfg, svc)
+
+		response.Success(c, gin.H{
+			"message":                     "注册成功，请查收邮箱验证邮件",
+			"email_verification_required": svc.MemberEmail.VerificationRequired(),
+		})
+	}
 }
 
 // GetAuthMenus godoc
 // @Summary 获取用户菜单权限
-// @Description 获取当前登录用户的菜单列表和按钮权限
+// @Description 获取当前登录用户全部已启用角色（包括生效中的临时授权）的菜单列表和按钮权限、字段权限（并集），禁用的角色不提供任何菜单和权限
 // @Tags 认证管理
 // @Accept json
 // @Produce json
@@ -251,33 +769,42 @@ func MemberRegister(c *gin.Context) {
 // @Failure 500 {object} response.ResponseData "内部错误"
 // @Router /gam/authmenus [get]
 func GetAuthMenus(c *gin.Context) {
-	roleID, exists := c.Get("role_id")
+	userID, exists := c.Get("user_id")
 	if !exists {
-		response.Error(c, http.StatusUnauthorized, "Role ID not found")
+		response.Error(c, http.StatusUnauthorized, "User ID not found")
 		return
 	}
 
+	// 管理员已启用角色的菜单ID，包括生效中的临时授权
+	db := database.GetDB().WithContext(c.Request.Context())
+	menuIDs := db.Table("role_menus").
+		Select("role_menus.menu_id").
+		Joins("INNER JOIN (?) AS admin_roles ON admin_roles.role_id = role_menus.role_id", models.AdminRoleAssignments(db)).
+		Joins("INNER JOIN roles ON roles.id = role_menus.role_id AND roles.status = 1 AND roles.deleted_at IS NULL").
+		Where("admin_roles.admin_id = ?", userID)
+
+	// 平台菜单只对超级租户开放
+	menuDB := db
+	if !tenant.IsSuper(c.Request.Context()) {
+		menuDB = db.Where("menus.platform = ?", false).Session(&gorm.Session{})
+	}
+
 	// 首先获取所有菜单（type = 'menu'）
 	var menus []models.Menu
-	if err := database.GetDB().
-		Select("menus.*").
-		Table("menus").
-		Joins("INNER JOIN role_menus ON menus.id = role_menus.menu_id").
-		Where("role_menus.role_id = ? AND menus.status = 1 AND menus.type = 'menu'", roleID).
+	if err := menuDB.
+		Where("menus.id IN (?) AND menus.status = 1 AND menus.type = 'menu'", menuIDs).
 		Order("menus.sort ASC").
 		Find(&menus).Error; err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to fetch menus")
 		return
 	}
 
-	// 获取所有按钮权限（type = 'button'）
+	// 获取所有按钮权限和字段权限（type = 'button' / 'field'）
 	var buttons []string
-	if err := database.GetDB().
-		Select("menus.permission").
-		Table("menus").
-		Joins("INNER JOIN role_menus ON menus.id = role_menus.menu_id").
-		Where("role_menus.role_id = ? AND menus.status = 1 AND menus.type = 'button'", roleID).
-		Find(&buttons).Error; err != nil {
+	if err := menuDB.Model(&models.Menu{}).
+		Distinct("menus.permission").
+		Where("menus.id IN (?) AND menus.status = 1 AND menus.type IN ('button', ?)", menuIDs, models.MenuTypeField).
+		Pluck("menus.permission", &buttons).Error; err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to fetch button permissions")
 		return
 	}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+	"normaladmin/backend/internal/services"
+	"normaladmin/backend/pkg/logger"
+	"normaladmin/backend/pkg/utils/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCaptcha godoc
+// @Summary 获取图形验证码
+// @Description 生成字符或算术图形验证码，image 为 PNG 图片的 data URI；登录或注册时提交 captcha_id 和 captcha_code，验证码只能使用一次
+// @Tags 认证管理
+// @Produce json
+// @Success 200 {object} response.ResponseData{data=services.CaptchaChallenge} "成功"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /captcha [get]
+func GetCaptcha(captchaService services.CaptchaService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		challenge, err := captchaService.Generate()
+		if err != nil {
+			logger.Error("生成验证码失败", logger.Field("error", err))
+			response.Error(c, http.StatusInternalServerError, "Failed to generate captcha")
+			return
+		}
+
+		c.Header("Cache-Control", "no-store")
+		response.Success(c, challenge)
+	}
+}
@@ -21,6 +21,11 @@ func NewConfigHandler(configService services.ConfigService) *ConfigHandler {
 	}
 }
 
+// configs 使用请求上下文的配置服务
+func (h *ConfigHandler) configs(c *gin.Context) services.ConfigService {
+	return h.configService.WithContext(c.Request.Context())
+}
+
 // GetConfigGroups godoc
 // @Summary 获取配置组列表
 // @Description 获取所有系统配置组信息，包括组名称、标识符等
@@ -33,7 +38,7 @@ func NewConfigHandler(configService services.ConfigService) *ConfigHandler {
 // @Failure 500 {object} response.ResponseData "内部错误"
 // @Router /gam/configs/groups [get]
 func (h *ConfigHandler) GetConfigGroups(c *gin.Context) {
-	groups, err := h.configService.GetConfigGroups()
+	groups, err := h.configs(c).GetConfigGroups()
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "获取配置组失败")
 		return
@@ -62,7 +67,7 @@ func (h *ConfigHandler) GetConfigItems(c *gin.Context) {
 		return
 	}
 
-	items, err := h.configService.GetConfigItems(int64(groupID))
+	items, err := h.configs(c).GetConfigItems(int64(groupID))
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "获取配置项失败")
 		return
@@ -95,7 +100,7 @@ func (h *ConfigHandler) UpdateConfigValue(c *gin.Context) {
 		return
 	}
 
-	err := h.configService.UpdateConfigValue(req.GroupID, req.ItemKey, req.Value)
+	err := h.configs(c).UpdateConfigValue(req.GroupID, req.ItemKey, req.Value)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "更新配置失败")
 		return
@@ -127,7 +132,7 @@ func (h *ConfigHandler) BatchUpdateConfigs(c *gin.Context) {
 		return
 	}
 	fmt.Println(req)
-	err := h.configService.BatchUpdateConfigs(req.GroupID, req.Configs)
+	err := h.configs(c).BatchUpdateConfigs(req.GroupID, req.Configs)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "批量更新配置失败")
 		return
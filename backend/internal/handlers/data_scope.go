@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/internal/services"
+	"normaladmin/backend/pkg/logger"
+	"normaladmin/backend/pkg/utils/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dataScopeOption 当前管理员的数据范围查询选项，失败时已写入响应并返回 false
+func dataScopeOption(c *gin.Context, svc services.DataScopeService) (models.QueryOption, bool) {
+	userID, _ := c.Get("user_id")
+	adminID, _ := userID.(uint)
+
+	scope, err := svc.Resolve(adminID)
+	if err != nil {
+		logger.Error("计算数据范围失败", logger.Field("admin_id", adminID), logger.Field("error", err))
+		response.Error(c, http.StatusForbidden, "Failed to resolve data scope")
+		return nil, false
+	}
+	return models.WithDataScope(scope), true
+}
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/internal/services"
+	"normaladmin/backend/pkg/utils/response"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DepartmentHandler struct {
+	departmentService services.DepartmentService
+}
+
+func NewDepartmentHandler(departmentService services.DepartmentService) *DepartmentHandler {
+	return &DepartmentHandler{departmentService: departmentService}
+}
+
+// departments 使用请求上下文的部门服务，只能访问当前租户的部门
+func (h *DepartmentHandler) departments(c *gin.Context) services.DepartmentService {
+	return h.departmentService.WithContext(c.Request.Context())
+}
+
+// GetDepartmentTree godoc
+// @Summary 获取部门树
+// @Description 获取完整的部门层级结构树
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} response.ResponseData{data=object{departmentTree=[]models.Department}} "成功"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/departments/tree [get]
+func (h *DepartmentHandler) GetDepartmentTree(c *gin.Context) {
+	departments, err := h.departments(c).GetDepartmentTree()
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to get department tree")
+		return
+	}
+	response.Success(c, gin.H{"departmentTree": departments})
+}
+
+// GetDepartmentList godoc
+// @Summary 获取部门列表
+// @Description 获取部门列表，支持按名称搜索和状态过滤
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Param name query string false "部门名称，用于搜索过滤"
+// @Param status query int false "状态(1:启用 0:禁用)"
+// @Success 200 {object} response.ResponseData{data=object{departments=[]models.Department}} "成功"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/departments [get]
+func (h *DepartmentHandler) GetDepartmentList(c *gin.Context) {
+	query := make(map[string]interface{})
+	if name := c.Query("name"); name != "" {
+		query["name"] = name
+	}
+	if status, err := strconv.Atoi(c.Query("status")); err == nil {
+		query["status"] = status
+	}
+
+	departments, err := h.departments(c).GetDepartments(query)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to get departments")
+		return
+	}
+
+	response.Success(c, gin.H{"departments": departments})
+}
+
+// GetDepartment godoc
+// @Summary 获取单个部门
+// @Description 根据部门ID获取部门详细信息
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Param id path int true "部门ID" minimum(1)
+// @Success 200 {object} response.ResponseData{data=object{department=models.Department}} "成功"
+// @Failure 400 {object} response.ResponseData "无效的部门ID"
+// @Failure 404 {object} response.ResponseData "部门不存在"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/departments/{id} [get]
+func (h *DepartmentHandler) GetDepartment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	department, err := h.departments(c).GetDepartment(uint(id))
+	if err != nil {
+		respondDepartmentError(c, err, "Failed to get department")
+		return
+	}
+
+	response.Success(c, gin.H{"department": department})
+}
+
+// CreateDepartment godoc
+// @Summary 创建部门
+// @Description 创建新的部门，parent_id 为0表示顶级部门
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Param department body models.Department true "部门信息" example({"parent_id":0,"name":"研发部","leader_id":1,"sort":1,"status":1})
+// @Success 200 {object} response.ResponseData{data=object{department=models.Department}} "成功"
+// @Failure 400 {object} response.ResponseData "无效的请求参数或上级部门不存在"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/departments [post]
+func (h *DepartmentHandler) CreateDepartment(c *gin.Context) {
+	var department models.Department
+	if err := c.ShouldBindJSON(&department); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.departments(c).CreateDepartment(&department); err != nil {
+		respondDepartmentError(c, err, "Failed to create department")
+		return
+	}
+
+	response.Success(c, gin.H{"department": department})
+}
+
+// UpdateDepartment godoc
+// @Summary 更新部门
+// @Description 更新部门信息，修改上级部门时连同下级部门一起移动，不能选择自身或下级部门作为上级部门
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Param id path int true "部门ID" minimum(1)
+// @Param department body models.Department true "部门信息" example({"parent_id":0,"name":"研发中心","leader_id":1,"sort":1,"status":1})
+// @Success 200 {object} response.ResponseData{data=object{department=models.Department}} "成功"
+// @Failure 400 {object} response.ResponseData "无效的请求参数或上级部门无效"
+// @Failure 404 {object} response.ResponseData "部门不存在"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/departments/{id} [put]
+func (h *DepartmentHandler) UpdateDepartment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	var department models.Department
+	if err := c.ShouldBindJSON(&department); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.departments(c).UpdateDepartment(uint(id), &department); err != nil {
+		respondDepartmentError(c, err, "Failed to update department")
+		return
+	}
+
+	response.Success(c, gin.H{"department": department})
+}
+
+// DeleteDepartment godoc
+// @Summary 删除部门
+// @Description 删除指定部门，存在下级部门或部门下还有管理员时不允许删除
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Param id path int true "部门ID" minimum(1)
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 400 {object} response.ResponseData "无效的部门ID"
+// @Failure 404 {object} response.ResponseData "部门不存在"
+// @Failure 409 {object} response.ResponseData "存在下级部门或管理员，无法删除"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/departments/{id} [delete]
+func (h *DepartmentHandler) DeleteDepartment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	if err := h.departments(c).DeleteDepartment(uint(id)); err != nil {
+		respondDepartmentError(c, err, "Failed to delete department")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Department deleted successfully"})
+}
+
+// UpdateDepartmentStatus godoc
+// @Summary 更新部门状态
+// @Description 更新部门的启用/禁用状态（0：禁用，1：启用）
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Param id path int true "部门ID" minimum(1)
+// @Param status body object true "状态信息" example({"status":1})
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 400 {object} response.ResponseData "无效的请求参数"
+// @Failure 404 {object} response.ResponseData "部门不存在"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/departments/{id}/status [put]
+func (h *DepartmentHandler) UpdateDepartmentStatus(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	var req struct {
+		Status int `json:"status"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.departments(c).UpdateStatus(uint(id), req.Status); err != nil {
+		respondDepartmentError(c, err, "Failed to update department status")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Department status updated successfully"})
+}
+
+// MoveDepartment godoc
+// @Summary 移动部门
+// @Description 将部门连同下级部门移动到新的上级部门下并设置排序，parent_id 为0表示移动为顶级部门
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Param id path int true "部门ID" minimum(1)
+// @Param move body object true "目标位置" example({"parent_id":2,"sort":1})
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 400 {object} response.ResponseData "无效的请求参数或上级部门无效"
+// @Failure 404 {object} response.ResponseData "部门不存在"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/departments/{id}/move [put]
+func (h *DepartmentHandler) MoveDepartment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	var req struct {
+		ParentID uint `json:"parent_id"`
+		Sort     int  `json:"sort"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.departments(c).MoveDepartment(uint(id), req.ParentID, req.Sort); err != nil {
+		respondDepartmentError(c, err, "Failed to move department")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Department moved successfully"})
+}
+
+// ReorderDepartments godoc
+// @Summary 部门排序
+// @Description 按给定顺序重排同一上级部门下的部门，排序值依次为1、2、3…
+// @Tags 部门管理
+// @Accept json
+// @Produce json
+// @Param reorder body object true "上级部门和排序后的部门ID" example({"parent_id":0,"ids":[3,1,2]})
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 400 {object} response.ResponseData "无效的请求参数"
+// @Failure 404 {object} response.ResponseData "部门不存在或不属于该上级部门"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/departments/reorder [post]
+func (h *DepartmentHandler) ReorderDepartments(c *gin.Context) {
+	var req struct {
+		ParentID uint   `json:"parent_id"`
+		IDs      []uint `json:"ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.departments(c).ReorderDepartments(req.ParentID, req.IDs); err != nil {
+		respondDepartmentError(c, err, "Failed to reorder departments")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Departments reordered successfully"})
+}
+
+// respondDepartmentError 部门服务错误对应的响应
+func respondDepartmentError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrDepartmentNotFound):
+		response.Error(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrDepartmentParentNotFound),
+		errors.Is(err, services.ErrDepartmentCycle),
+		errors.Is(err, services.ErrDepartmentLeaderNotFound):
+		response.Error(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrDepartmentHasChildren),
+		errors.Is(err, services.ErrDepartmentHasAdmins):
+		response.Error(c, http.StatusConflict, err.Error())
+	default:
+		response.Error(c, http.StatusInternalServerError, message)
+	}
+}
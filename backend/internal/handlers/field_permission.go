@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"normaladmin/backend/internal/services"
+	"normaladmin/backend/pkg/logger"
+	"normaladmin/backend/pkg/utils/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fieldGrants 当前管理员的字段权限，失败时已写入响应并返回 false
+func fieldGrants(c *gin.Context, svc services.FieldPermissionService) (*services.FieldGrants, bool) {
+	userID, _ := c.Get("user_id")
+	adminID, _ := userID.(uint)
+
+	grants, err := svc.Grants(adminID)
+	if err != nil {
+		logger.Error("获取字段权限失败", logger.Field("admin_id", adminID), logger.Field("error", err))
+		response.Error(c, http.StatusInternalServerError, "Failed to resolve field permissions")
+		return nil, false
+	}
+	return grants, true
+}
+
+// respondFieldNotEditable 修改了没有编辑权限的字段时返回 403，已写入响应时返回 true
+func respondFieldNotEditable(c *gin.Context, err error) bool {
+	if !errors.Is(err, services.ErrFieldNotEditable) {
+		return false
+	}
+	response.Error(c, http.StatusForbidden, err.Error())
+	return true
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"normaladmin/backend/config"
+	"normaladmin/backend/database"
+	"normaladmin/backend/internal/middleware"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/internal/services"
+	"normaladmin/backend/pkg/logger"
+	"normaladmin/backend/pkg/utils/jwt"
+	"normaladmin/backend/pkg/utils/response"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ImpersonationHandler struct {
+	impersonation services.ImpersonationService
+	cfg           config.JWTConfig
+}
+
+func NewImpersonationHandler(impersonation services.ImpersonationService, cfg config.JWTConfig) *ImpersonationHandler {
+	return &ImpersonationHandler{impersonation: impersonation, cfg: cfg}
+}
+
+// StartImpersonation godoc
+// @Summary 模拟会员登录
+// @Description 以会员身份签发短期访问令牌，用于排查问题或协助操作；令牌不可刷新，不能修改密码或注销账号，请求会记录到系统日志并通知会员
+// @Tags 会员管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "会员ID"
+// @Success 200 {object} response.ResponseData{data=object{token=string,expires_at=string,member=object}} "成功"
+// @Failure 400 {object} response.ResponseData "会员已禁用"
+// @Failure 403 {object} response.ResponseData "不能使用 API 密钥模拟登录"
+// @Failure 404 {object} response.ResponseData "会员不存在"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/members/{id}/impersonate [post]
+func (h *ImpersonationHandler) StartImpersonation(c *gin.Context) {
+	if _, ok := middleware.GetAPIKeyID(c); ok {
+		response.Error(c, http.StatusForbidden, "API keys cannot impersonate members")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	var member models.Member
+	if err := database.GetDB().WithContext(c.Request.Context()).First(&member, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Error(c, http.StatusNotFound, "Member not found")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to impersonate member")
+		return
+	}
+	if member.Status == nil || *member.Status != 1 {
+		response.Error(c, http.StatusBadRequest, "Member is disabled")
+		return
+	}
+
+	ttl := h.impersonation.TTL()
+	token, claims, err := middleware.GenerateImpersonationToken(middleware.TokenUser{
+		UserID:   member.ID,
+		UserType: middleware.UserTypeMember,
+		Username: member.Username,
+		LevelID:  member.LevelID,
+		TenantID: member.TenantID,
+	}, jwt.GetUserID(c), ttl, h.cfg)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to impersonate member")
+		return
+	}
+
+	now := time.Now()
+	session := &services.ImpersonationSession{
+		FamilyID:       claims.FamilyID,
+		AdminID:        jwt.GetUserID(c),
+		AdminUsername:  jwt.GetUsername(c),
+		MemberID:       member.ID,
+		MemberUsername: member.Username,
+		StartedAt:      now,
+		ExpiresAt:      claims.ExpiresAt.Time,
+	}
+	if err := h.impersonation.WithContext(c.Request.Context()).Start(session, auditContext(c)); err != nil {
+		logger.Error("开始模拟登录失败", logger.Field("error", err))
+		response.Error(c, http.StatusInternalServerError, "Failed to impersonate member")
+		return
+	}
+
+	// 模拟令牌不写入请求日志
+	middleware.SkipResponseLog(c)
+	response.Success(c, gin.H{
+		"token":      token,
+		"expires_at": session.ExpiresAt,
+		"member": gin.H{
+			"id":       member.ID,
+			"username": member.Username,
+			"level_id": member.LevelID,
+		},
+	})
+}
+
+// EndImpersonation godoc
+// @Summary 结束模拟会员登录
+// @Description 结束当前管理员对该会员的模拟登录并吊销模拟令牌
+// @Tags 会员管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "会员ID"
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 404 {object} response.ResponseData "没有正在进行的模拟登录"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/members/{id}/impersonate [delete]
+func (h *ImpersonationHandler) EndImpersonation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	if err := h.impersonation.WithContext(c.Request.Context()).End(jwt.GetUserID(c), uint(id), "ended", auditContext(c)); err != nil {
+		if errors.Is(err, services.ErrImpersonationNotFound) {
+			response.Error(c, http.StatusNotFound, "No active impersonation for this member")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to end impersonation")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Impersonation ended"})
+}
+
+// auditContext 审计日志的请求信息
+func auditContext(c *gin.Context) services.AuditContext {
+	return services.AuditContext{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Method:    c.Request.Method,
+		URL:       c.Request.URL.Path,
+	}
+}
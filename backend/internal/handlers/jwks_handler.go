@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"net/http"
+	"normaladmin/backend/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetJWKS godoc
+// @Summary 获取令牌验证公钥
+// @Description 以 JWKS 格式返回全部有效的令牌验证公钥（RS256/EdDSA），其他服务可据此按 kid 验证令牌；HS256 模式下返回空集合
+// @Tags 认证管理
+// @Produce json
+// @Success 200 {object} auth.JWKS "成功"
+// @Router /.well-known/jwks.json [get]
+func GetJWKS(c *gin.Context) {
+	// JWKS 按标准格式直接返回，不使用统一响应包装
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, auth.Keys().JWKS())
+}
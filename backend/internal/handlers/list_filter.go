@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/utils/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listFilter 按资源的过滤规格解析列表查询参数，参数无效时已写入 400 响应并返回 false
+func listFilter(c *gin.Context, spec models.FilterSpec) (models.Filter, bool) {
+	filter, err := spec.Parse(c.Request.URL.Query())
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return models.Filter{}, false
+	}
+	return filter, true
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"normaladmin/backend/config"
+	"normaladmin/backend/database"
+	"normaladmin/backend/internal/middleware"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/auth"
+	"normaladmin/backend/pkg/cache"
+	"normaladmin/backend/pkg/utils/jwt"
+	"normaladmin/backend/pkg/utils/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MemberChangePasswordRequest 会员修改密码请求参数
+type MemberChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// MemberDeleteAccountRequest 会员注销账号请求参数
+type MemberDeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// MemberChangePassword godoc
+// @Summary 会员修改密码
+// @Description 验证原密码后设置新密码，新密码需满足密码策略；修改后注销其他登录会话。模拟登录时不允许调用
+// @Tags 会员认证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param req body MemberChangePasswordRequest true "密码信息" example({"old_password":"Passw0rd","new_password":"NewPassw0rd"})
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 400 {object} response.ResponseData "请求参数错误或原密码错误"
+// @Failure 403 {object} response.ResponseData "模拟登录时不允许执行该操作"
+// @Failure 422 {object} response.ResponseData{data=services.PasswordPolicyError} "密码不符合安全策略"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /api/member/password [put]
+func MemberChangePassword(svc *AuthServices) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MemberChangePasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		member, ok := currentMember(c)
+		if !ok {
+			return
+		}
+		if !member.CheckPassword(req.OldPassword) {
+			response.Error(c, http.StatusBadRequest, "原密码错误")
+			return
+		}
+
+		if err := svc.PasswordPolicy.SetPassword(middleware.UserTypeMember, member.ID, req.NewPassword); err != nil {
+			if respondPasswordPolicyError(c, err) {
+				return
+			}
+			response.Error(c, http.StatusInternalServerError, "修改密码失败")
+			return
+		}
+		_ = cache.Delete(fmt.Sprintf("member:%d", member.ID))
+
+		// 保留当前会话，注销其他设备上的登录
+		var except string
+		if claims, exists := middleware.GetClaims(c); exists {
+			except = claims.FamilyID
+		}
+		if _, err := svc.Sessions.RevokeAll(middleware.UserTypeMember, member.ID, except); err != nil {
+			response.Error(c, http.StatusInternalServerError, "注销其他会话失败")
+			return
+		}
+
+		response.Success(c, gin.H{"message": "密码已修改"})
+	}
+}
+
+// MemberDeleteAccount godoc
+// @Summary 会员注销账号
+// @Description 验证密码后注销当前会员账号，并注销全部登录会话。模拟登录时不允许调用
+// @Tags 会员认证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param req body MemberDeleteAccountRequest true "密码" example({"password":"Passw0rd"})
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 400 {object} response.ResponseData "请求参数错误或密码错误"
+// @Failure 403 {object} response.ResponseData "模拟登录时不允许执行该操作"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /api/member/account [delete]
+func MemberDeleteAccount(cfg config.JWTConfig, svc *AuthServices) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MemberDeleteAccountRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		member, ok := currentMember(c)
+		if !ok {
+			return
+		}
+		if !member.CheckPassword(req.Password) {
+			response.Error(c, http.StatusBadRequest, "密码错误")
+			return
+		}
+
+		if err := database.GetDB().Delete(member).Error; err != nil {
+			response.Error(c, http.StatusInternalServerError, "注销账号失败")
+			return
+		}
+		_ = cache.Delete(fmt.Sprintf("member:%d", member.ID))
+
+		// 注销全部会话，并使已签发的令牌立即失效
+		if _, err := svc.Sessions.RevokeAll(middleware.UserTypeMember, member.ID, ""); err != nil {
+			response.Error(c, http.StatusInternalServerError, "注销会话失败")
+			return
+		}
+		if err := auth.RevokeUserTokens(middleware.UserTypeMember, member.ID, middleware.RefreshTokenTTL(cfg)); err != nil {
+			response.Error(c, http.StatusInternalServerError, "注销会话失败")
+			return
+		}
+
+		response.Success(c, gin.H{"message": "账号已注销"})
+	}
+}
+
+// currentMember 获取当前登录的会员
+func currentMember(c *gin.Context) (*models.Member, bool) {
+	var member models.Member
+	if err := database.GetDB().First(&member, jwt.GetUserID(c)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Error(c, http.StatusUnauthorized, "会员不存在")
+			return nil, false
+		}
+		response.Error(c, http.StatusInternalServerError, "系统错误")
+		return nil, false
+	}
+	return &member, true
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"normaladmin/backend/config"
+	"normaladmin/backend/database"
+	"normaladmin/backend/internal/middleware"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/internal/services"
+	"normaladmin/backend/pkg/auth"
+	"normaladmin/backend/pkg/logger"
+	"normaladmin/backend/pkg/tenant"
+	"normaladmin/backend/pkg/utils/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MemberEmailRequest 发送邮件请求参数
+type MemberEmailRequest struct {
+	Tenant string `json:"tenant"` // 租户编码，为空时为平台（超级租户）
+	Email  string `json:"email" binding:"required,email"`
+}
+
+// MemberEmailTokenRequest 邮箱验证请求参数
+type MemberEmailTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// MemberResetPasswordRequest 重置密码请求参数
+type MemberResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// MemberRequestEmailVerification godoc
+// @Summary 发送邮箱验证邮件
+// @Description 向未验证的会员邮箱发送验证链接；为避免泄露邮箱是否注册，无论邮箱是否存在都返回成功，同一邮箱有发送间隔限制
+// @Tags 会员认证
+// @Accept json
+// @Produce json
+// @Param req body MemberEmailRequest true "邮箱" example({"email":"member@example.com"})
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 400 {object} response.ResponseData "请求参数错误"
+// @Router /api/member/email/verify/request [post]
+func MemberRequestEmailVerification(cfg config.JWTConfig, svc *AuthServices) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MemberEmailRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var member models.Member
+		err := findMemberByEmail(c, svc, req, &member)
+		if err == nil && member.EmailVerifiedAt == nil && svc.MemberEmail.Allow(services.MemberEmailVerify, member.Email) {
+			sendVerificationEmail(&member, cfg, svc)
+		}
+
+		response.Success(c, gin.H{"message": "如果该邮箱已注册且未验证，您将收到验证邮件"})
+	}
+}
+
+// MemberConfirmEmailVerification godoc
+// @Summary 验证邮箱
+// @Description 使用邮件中的令牌完成邮箱验证，令牌只能使用一次，邮箱变更后失效
+// @Tags 会员认证
+// @Accept json
+// @Produce json
+// @Param req body MemberEmailTokenRequest true "验证令牌"
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 400 {object} response.ResponseData "请求参数错误或令牌无效"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /api/member/email/verify/confirm [post]
+func MemberConfirmEmailVerification(cfg config.JWTConfig, svc *AuthServices) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MemberEmailTokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		claims, err := middleware.ParsePendingToken(req.Token, middleware.UserTypeMember, middleware.TokenTypeEmailVerify, cfg)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "验证链接无效或已过期")
+			return
+		}
+		if err := svc.MemberEmail.MarkVerified(claims.UserID, claims.Subject); err != nil {
+			if errors.Is(err, services.ErrEmailChanged) {
+				response.Error(c, http.StatusBadRequest, "验证链接无效或已过期")
+				return
+			}
+			response.Error(c, http.StatusInternalServerError, "邮箱验证失败")
+			return
+		}
+		_ = auth.DenyToken(claims.ID, time.Until(claims.ExpiresAt.Time))
+
+		response.Success(c, gin.H{"message": "邮箱验证成功"})
+	}
+}
+
+// MemberRequestPasswordReset godoc
+// @Summary 发送找回密码邮件
+// @Description 向会员邮箱发送重置密码链接；为避免泄露邮箱是否注册，无论邮箱是否存在都返回成功，同一邮箱有发送间隔限制
+// @Tags 会员认证
+// @Accept json
+// @Produce json
+// @Param req body MemberEmailRequest true "邮箱" example({"email":"member@example.com"})
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 400 {object} response.ResponseData "请求参数错误"
+// @Router /api/member/password/reset/request [post]
+func MemberRequestPasswordReset(cfg config.JWTConfig, svc *AuthServices) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MemberEmailRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var member models.Member
+		err := findMemberByEmail(c, svc, req, &member)
+		if err == nil && member.Status != nil && *member.Status == 1 && svc.MemberEmail.Allow(services.MemberEmailPasswordReset, member.Email) {
+			token, err := middleware.GenerateEmailToken(middleware.UserTypeMember, member.ID, member.Username, member.Email,
+				middleware.TokenTypePasswordReset, svc.MemberEmail.ResetTTL(), cfg)
+			if err != nil {
+				logger.Error("签发重置密码令牌失败", logger.Field("member_id", member.ID), logger.Field("error", err))
+			} else {
+				// 异步发送，响应耗时与邮箱不存在时一致
+				go func(member models.Member) {
+					if err := svc.MemberEmail.SendPasswordReset(context.Background(), &member, token); err != nil {
+						logger.Error("发送重置密码邮件失败", logger.Field("member_id", member.ID), logger.Field("error", err))
+					}
+				}(member)
+			}
+		}
+
+		response.Success(c, gin.H{"message": "如果该邮箱已注册，您将收到重置密码邮件"})
+	}
+}
+
+// MemberResetPassword godoc
+// @Summary 重置密码
+// @Description 使用邮件中的令牌设置新密码，新密码需满足密码策略；重置后注销全部登录会话，令牌只能使用一次
+// @Tags 会员认证
+// @Accept json
+// @Produce json
+// @Param req body MemberResetPasswordRequest true "令牌和新密码"
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 400 {object} response.ResponseData "请求参数错误或令牌无效"
+// @Failure 422 {object} response.ResponseData{data=services.PasswordPolicyError} "密码不符合安全策略"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /api/member/password/reset/confirm [post]
+func MemberResetPassword(cfg config.JWTConfig, svc *AuthServices) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MemberResetPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		claims, err := middleware.ParsePendingToken(req.Token, middleware.UserTypeMember, middleware.TokenTypePasswordReset, cfg)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "重置链接无效或已过期")
+			return
+		}
+
+		// 邮箱已变更、账号已禁用或令牌签发后密码已修改时，令牌失效
+		var member models.Member
+		if err := database.GetDB().First(&member, claims.UserID).Error; err != nil ||
+			member.Email != claims.Subject ||
+			member.Status == nil || *member.Status != 1 ||
+			(member.PasswordChangedAt != nil && claims.IssuedTime().Before(member.PasswordChangedAt.Truncate(time.Millisecond))) {
+			response.Error(c, http.StatusBadRequest, "重置链接无效或已过期")
+			return
+		}
+
+		if err := svc.PasswordPolicy.SetPassword(middleware.UserTypeMember, member.ID, req.NewPassword); err != nil {
+			if !respondPasswordPolicyError(c, err) {
+				response.Error(c, http.StatusInternalServerError, "重置密码失败")
+			}
+			return
+		}
+		_ = auth.DenyToken(claims.ID, time.Until(claims.ExpiresAt.Time))
+
+		// 通过邮箱重置密码也证明了邮箱归属
+		if member.EmailVerifiedAt == nil {
+			_ = svc.MemberEmail.MarkVerified(member.ID, member.Email)
+		}
+		// 注销全部会话并解除登录锁定
+		if _, err := svc.Sessions.RevokeAll(middleware.UserTypeMember, member.ID, ""); err != nil {
+			logger.Error("重置密码后注销会话失败", logger.Field("member_id", member.ID), logger.Field("error", err))
+		}
+		_ = auth.RevokeUserTokens(middleware.UserTypeMember, member.ID, middleware.RefreshTokenTTL(cfg))
+		svc.LoginGuard.RecordSuccess(middleware.UserTypeMember, loginAccount(tenantCode(member.TenantID), member.Username))
+
+		response.Success(c, gin.H{"message": "密码已重置，请使用新密码登录"})
+	}
+}
+
+// findMemberByEmail 在请求的租户中按邮箱查找会员，租户不存在或已停用时同样返回错误，不暴露租户是否存在
+func findMemberByEmail(c *gin.Context, svc *AuthServices, req MemberEmailRequest, member *models.Member) error {
+	t, err := svc.Tenants.Resolve(req.Tenant)
+	if err != nil {
+		return err
+	}
+	middleware.SetTenant(c, t.ID)
+	return database.GetDB().WithContext(c.Request.Context()).Where("email = ?", req.Email).First(member).Error
+}
+
+// tenantCode 租户的登录编码，超级租户登录时不填写编码
+func tenantCode(tenantID uint) string {
+	if tenantID == tenant.SuperTenantID {
+		return ""
+	}
+	var t models.Tenant
+	database.GetDB().Select("id", "code").First(&t, tenantID)
+	return t.Code
+}
+
+// sendVerificationEmail 签发邮箱验证令牌并异步发送验证邮件
+func sendVerificationEmail(member *models.Member, cfg config.JWTConfig, svc *AuthServices) {
+	token, err := middleware.GenerateEmailToken(middleware.UserTypeMember, member.ID, member.Username, member.Email,
+		middleware.TokenTypeEmailVerify, svc.MemberEmail.VerifyTTL(), cfg)
+	if err != nil {
+		logger.Error("签发邮箱验证令牌失败", logger.Field("member_id", member.ID), logger.Field("error", err))
+		return
+	}
+	go func(member models.Member) {
+		if err := svc.MemberEmail.SendVerification(context.Background(), &member, token); err != nil {
+			logger.Error("发送邮箱验证邮件失败", logger.Field("member_id", member.ID), logger.Field("error", err))
+		}
+	}(*member)
+}
@@ -1,73 +1,88 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"normaladmin/backend/internal/middleware"
 	"normaladmin/backend/internal/models"
 	"normaladmin/backend/internal/services"
 	"strconv"
-	"strings"
 
 	"normaladmin/backend/pkg/utils/response"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type MemberHandler struct {
 	memberService services.MemberService
+	fields        services.FieldPermissionService
+	sessions      services.SessionService
 }
 
-func NewMemberHandler(memberService services.MemberService) *MemberHandler {
-	return &MemberHandler{memberService: memberService}
+func NewMemberHandler(memberService services.MemberService, fields services.FieldPermissionService, sessions services.SessionService) *MemberHandler {
+	return &MemberHandler{memberService: memberService, fields: fields, sessions: sessions}
+}
+
+// MemberRequest 创建或更新会员的请求；models.Member 的密码不参与 JSON 绑定，由 Password 单独接收
+type MemberRequest struct {
+	models.Member
+	Password string `json:"password"` // 明文密码，创建时必填，更新时为空表示不修改
+}
+
+// members 使用请求上下文的会员服务，只能访问当前租户的会员
+func (h *MemberHandler) members(c *gin.Context) services.MemberService {
+	return h.memberService.WithContext(c.Request.Context())
 }
 
 // GetMemberList godoc
 // @Summary 获取会员列表
-// @Description 获取会员列表,支持分页和筛选
+// @Description 获取会员列表,支持分页和筛选；没有查看权限的敏感字段（如 member.mobile:view）返回掩码。过滤参数写作 field=value（默认运算符）或 field[op]=value，如 level_id[in]=1,2、points[gte]=100、email_verified_at[null]=true；可用字段：id、username、nickname、mobile、gender、level_id、points、status、email_verified_at、last_login_time、created_at、updated_at
 // @Tags 会员管理
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Param username query string false "用户名"
-// @Param mobile query string false "手机号"
+// @Param username query string false "用户名，默认模糊匹配"
+// @Param mobile query string false "手机号，默认模糊匹配；需要 member.mobile:view 权限"
 // @Param page query int false "页码" default(1)
 // @Param pageSize query int false "每页数量" default(10)
 // @Param sortField query string false "排序字段，多个字段用逗号分隔" example("id,created_at")
 // @Param sortOrder query string false "排序方式(asc/desc)，多个排序方式用逗号分隔" example("desc,asc")
 // @Success 200 {object} response.ResponseData{data=object{members=[]models.Member,total=int64}} "成功"
+// @Failure 400 {object} response.ResponseData "过滤或排序参数无效"
 // @Failure 401 {object} response.ResponseData "未授权"
+// @Failure 403 {object} response.ResponseData "没有手机号的查看权限时按手机号过滤"
 // @Failure 500 {object} response.ResponseData "内部错误"
 // @Router /gam/members [get]
 func (h *MemberHandler) GetMemberList(c *gin.Context) {
-	query := make(map[string]interface{})
-	if username := c.Query("username"); username != "" {
-		query["username"] = username
-	}
-	if mobile := c.Query("mobile"); mobile != "" {
-		query["mobile"] = mobile
+	filter, ok := listFilter(c, models.MemberFilters)
+	if !ok {
+		return
 	}
 	page := c.DefaultQuery("page", "1")
 	pageSize := c.DefaultQuery("pageSize", "10")
 
-	// 处理排序
-	var opts []models.QueryOption
-
-	sortField := c.DefaultQuery("sortField", "")
-	if sortField != "" {
-		sortFields := strings.Split(sortField, ",")
-		sortOrders := strings.Split(c.DefaultQuery("sortOrder", "desc"), ",")
-		opts = append(opts, models.WithSort(sortFields, sortOrders))
-	}
-
 	// 添加其他查询选项
+	var opts []models.QueryOption
 	// if c.Query("with_profile") == "true" {
 	// 	opts = append(opts, services.WithPreload("Profile"))
 	// }
 
-	members, total, err := h.memberService.List(query, page, pageSize, opts...)
+	grants, ok := fieldGrants(c, h.fields)
+	if !ok {
+		return
+	}
+	// 没有查看权限时不能按手机号过滤，否则可以通过结果数量逐位探测被掩码的手机号
+	if filter.Uses("mobile") && !grants.Can(services.FieldResourceMember, "mobile", models.FieldActionView) {
+		response.Error(c, http.StatusForbidden, "Permission denied")
+		return
+	}
+	members, total, err := h.members(c).List(filter, page, pageSize, opts...)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to get member list")
 		return
 	}
+	grants.Mask(services.FieldResourceMember, members)
 
 	response.Success(c, gin.H{"members": members, "total": total})
 }
@@ -79,20 +94,30 @@ func (h *MemberHandler) GetMemberList(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Param member body models.Member true "会员信息" example({"username":"test","mobile":"13800138000","password":"123456","status":1})
+// @Param member body MemberRequest true "会员信息" example({"username":"test","mobile":"13800138000","password":"123456","status":1})
 // @Success 200 {object} response.ResponseData{data=object{member=models.Member}} "成功"
 // @Failure 400 {object} response.ResponseData "无效的请求参数"
 // @Failure 401 {object} response.ResponseData "未授权"
+// @Failure 422 {object} response.ResponseData{data=services.PasswordPolicyError} "密码不符合安全策略"
 // @Failure 500 {object} response.ResponseData "内部错误"
 // @Router /gam/members [post]
 func (h *MemberHandler) CreateMember(c *gin.Context) {
-	var member models.Member
-	if err := c.ShouldBindJSON(&member); err != nil {
+	var req MemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if req.Password == "" {
+		response.Error(c, http.StatusBadRequest, "Password is required")
+		return
+	}
+	member := req.Member
+	member.Password = req.Password
 
-	if err := h.memberService.Create(&member); err != nil {
+	if err := h.members(c).Create(&member); err != nil {
+		if respondPasswordPolicyError(c, err) {
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to create member")
 		return
 	}
@@ -102,17 +127,19 @@ func (h *MemberHandler) CreateMember(c *gin.Context) {
 
 // UpdateMember godoc
 // @Summary 更新会员
-// @Description 更新会员信息
+// @Description 更新会员信息，提交 password 时按密码策略重置密码并注销该会员的全部会话，不提交则不修改；修改没有编辑权限的敏感字段（如 member.mobile:edit）时拒绝，提交原值或掩码视为未修改
 // @Tags 会员管理
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Param id path int true "会员ID" minimum(1)
-// @Param member body models.Member true "会员信息" example({"username":"test","mobile":"13800138000","status":1})
+// @Param member body MemberRequest true "会员信息" example({"username":"test","mobile":"13800138000","status":1})
 // @Success 200 {object} response.ResponseData{data=object{member=models.Member}} "成功"
 // @Failure 400 {object} response.ResponseData "无效的请求参数"
 // @Failure 401 {object} response.ResponseData "未授权"
+// @Failure 403 {object} response.ResponseData "没有编辑字段的权限"
 // @Failure 404 {object} response.ResponseData "会员不存在"
+// @Failure 422 {object} response.ResponseData{data=services.PasswordPolicyError} "密码不符合安全策略"
 // @Failure 500 {object} response.ResponseData "内部错误"
 // @Router /gam/members/{id} [put]
 func (h *MemberHandler) UpdateMember(c *gin.Context) {
@@ -122,17 +149,53 @@ func (h *MemberHandler) UpdateMember(c *gin.Context) {
 		return
 	}
 
-	var member models.Member
-	if err := c.ShouldBindJSON(&member); err != nil {
+	var req MemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		response.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	member := req.Member
+	member.Password = req.Password
+	passwordChanged := member.Password != ""
 
-	if err := h.memberService.Update(uint(id), &member); err != nil {
+	grants, ok := fieldGrants(c, h.fields)
+	if !ok {
+		return
+	}
+	current, err := h.members(c).GetByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Error(c, http.StatusNotFound, "Member not found")
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to update member")
 		return
 	}
+	if err := grants.CheckWrite(services.FieldResourceMember, current, &member); err != nil {
+		respondFieldNotEditable(c, err)
+		return
+	}
 
+	if err := h.members(c).Update(uint(id), &member); err != nil {
+		if respondPasswordPolicyError(c, err) {
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to update member")
+		return
+	}
+	if passwordChanged {
+		// 密码被修改，注销该会员的全部会话并吊销已签发的令牌
+		if _, err := h.sessions.RevokeAll(middleware.UserTypeMember, uint(id), ""); err != nil {
+			response.Error(c, http.StatusInternalServerError, "Failed to revoke member sessions")
+			return
+		}
+		if err := h.members(c).RevokeTokens(uint(id)); err != nil {
+			response.Error(c, http.StatusInternalServerError, "Failed to revoke member tokens")
+			return
+		}
+	}
+
+	grants.Mask(services.FieldResourceMember, &member)
 	response.Success(c, gin.H{"member": member})
 }
 
@@ -157,7 +220,7 @@ func (h *MemberHandler) DeleteMember(c *gin.Context) {
 		return
 	}
 
-	if err := h.memberService.Delete(uint(id), false); err != nil {
+	if err := h.members(c).Delete(uint(id), false); err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to delete member")
 		return
 	}
@@ -167,7 +230,7 @@ func (h *MemberHandler) DeleteMember(c *gin.Context) {
 
 // CheckMemberFieldUnique godoc
 // @Summary 检查会员字段唯一性
-// @Description 检查会员的某个字段值是否唯一（如用户名、手机号等）
+// @Description 检查会员的某个字段值是否唯一（username、mobile、email）；检查手机号、邮箱需要对应字段的查看权限（如 member.mobile:view），避免通过唯一性探测敏感信息
 // @Tags 会员管理
 // @Accept json
 // @Produce json
@@ -178,6 +241,7 @@ func (h *MemberHandler) DeleteMember(c *gin.Context) {
 // @Success 200 {object} response.ResponseData{data=object{unique=bool}} "成功"
 // @Failure 400 {object} response.ResponseData "无效的请求参数"
 // @Failure 401 {object} response.ResponseData "未授权"
+// @Failure 403 {object} response.ResponseData "没有查看字段的权限"
 // @Failure 500 {object} response.ResponseData "内部错误"
 // @Router /gam/members/check-field [get]
 func (h *MemberHandler) CheckMemberFieldUnique(c *gin.Context) {
@@ -190,7 +254,19 @@ func (h *MemberHandler) CheckMemberFieldUnique(c *gin.Context) {
 		return
 	}
 
-	isUnique, err := h.memberService.CheckMemberFieldUnique(field, value, uint(excludeID))
+	// 手机号、邮箱是否已被使用同样属于敏感信息
+	if field == "mobile" || field == "email" {
+		grants, ok := fieldGrants(c, h.fields)
+		if !ok {
+			return
+		}
+		if !grants.Can(services.FieldResourceMember, field, models.FieldActionView) {
+			response.Error(c, http.StatusForbidden, "Permission denied")
+			return
+		}
+	}
+
+	isUnique, err := h.members(c).CheckMemberFieldUnique(field, value, uint(excludeID))
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to check field uniqueness")
 		return
@@ -229,7 +305,7 @@ func (h *MemberHandler) UpdateMemberStatus(c *gin.Context) {
 		return
 	}
 
-	if err := h.memberService.Update(uint(id), map[string]interface{}{"status": req.Status}); err != nil {
+	if err := h.members(c).Update(uint(id), map[string]interface{}{"status": req.Status}); err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to update member status")
 		return
 	}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"normaladmin/backend/database"
@@ -22,6 +23,11 @@ func NewMenuHandler(menuService services.MenuService) *MenuHandler {
 	return &MenuHandler{menuService: menuService}
 }
 
+// menus 使用请求上下文的菜单服务，其他租户看不到平台菜单
+func (h *MenuHandler) menus(c *gin.Context) services.MenuService {
+	return h.menuService.WithContext(c.Request.Context())
+}
+
 // GetMenuTree godoc
 // @Summary 获取菜单树
 // @Description 获取完整的菜单层级结构树
@@ -34,7 +40,7 @@ func NewMenuHandler(menuService services.MenuService) *MenuHandler {
 // @Failure 500 {object} response.ResponseData "内部错误"
 // @Router /gam/menus/tree [get]
 func (h *MenuHandler) GetMenuTree(c *gin.Context) {
-	menus, err := h.menuService.GetMenuTree()
+	menus, err := h.menus(c).GetMenuTree()
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to get menu tree")
 		return
@@ -65,7 +71,7 @@ func (h *MenuHandler) GetMenuList(c *gin.Context) {
 		query["name"] = name
 	}
 
-	menus, err := h.menuService.GetMenus(query)
+	menus, err := h.menus(c).GetMenus(query)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to get menus")
 		return
@@ -83,7 +89,7 @@ func (h *MenuHandler) GetMenuList(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Param menu body models.Menu true "菜单信息" example({"name":"系统管理","path":"/system","component":"Layout","sort":1,"parent_id":0,"meta":{"title":"系统管理","icon":"setting"}})
 // @Success 200 {object} response.ResponseData{data=object{menu=models.Menu}} "成功"
-// @Failure 400 {object} response.ResponseData "无效的请求参数"
+// @Failure 400 {object} response.ResponseData "无效的请求参数或接口不在接口目录中"
 // @Failure 401 {object} response.ResponseData "未授权或Token无效"
 // @Failure 500 {object} response.ResponseData "内部错误"
 // @Router /gam/menus [post]
@@ -94,7 +100,10 @@ func (h *MenuHandler) CreateMenu(c *gin.Context) {
 		return
 	}
 
-	if err := h.menuService.CreateMenu(&menu); err != nil {
+	if err := h.menus(c).CreateMenu(&menu); err != nil {
+		if respondMenuAPIError(c, err) {
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to create menu")
 		return
 	}
@@ -112,7 +121,7 @@ func (h *MenuHandler) CreateMenu(c *gin.Context) {
 // @Param id path int true "菜单ID" minimum(1)
 // @Param menu body models.Menu true "菜单信息" example({"name":"系统设置","path":"/settings","component":"Layout","sort":1,"meta":{"title":"系统设置","icon":"setting"}})
 // @Success 200 {object} response.ResponseData{data=object{menu=models.Menu}} "成功"
-// @Failure 400 {object} response.ResponseData "无效的请求参数"
+// @Failure 400 {object} response.ResponseData "无效的请求参数或接口不在接口目录中"
 // @Failure 401 {object} response.ResponseData "未授权或Token无效"
 // @Failure 404 {object} response.ResponseData "菜单不存在"
 // @Failure 500 {object} response.ResponseData "内部错误"
@@ -130,7 +139,10 @@ func (h *MenuHandler) UpdateMenu(c *gin.Context) {
 		return
 	}
 
-	if err := h.menuService.UpdateMenu(uint(id), &menu); err != nil {
+	if err := h.menus(c).UpdateMenu(uint(id), &menu); err != nil {
+		if respondMenuAPIError(c, err) {
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to update menu")
 		return
 	}
@@ -160,7 +172,7 @@ func (h *MenuHandler) DeleteMenu(c *gin.Context) {
 		return
 	}
 
-	if err := h.menuService.DeleteMenu(uint(id)); err != nil {
+	if err := h.menus(c).DeleteMenu(uint(id)); err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to delete menu")
 		return
 	}
@@ -198,7 +210,7 @@ func (h *MenuHandler) UpdateMenuStatus(c *gin.Context) {
 		return
 	}
 
-	if err := h.menuService.Update(uint(id), map[string]interface{}{"status": req.Status}); err != nil {
+	if err := h.menus(c).Update(uint(id), map[string]interface{}{"status": req.Status}); err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to update menu status")
 		return
 	}
@@ -236,7 +248,7 @@ func (h *MenuHandler) UpdateMenuSort(c *gin.Context) {
 		return
 	}
 
-	if err := h.menuService.Update(uint(id), map[string]interface{}{"sort": req.Sort}); err != nil {
+	if err := h.menus(c).Update(uint(id), map[string]interface{}{"sort": req.Sort}); err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to update menu sort")
 		return
 	}
@@ -271,7 +283,7 @@ func (h *MenuHandler) UpdateMenuHidden(c *gin.Context) {
 		return
 	}
 
-	if err := h.menuService.Update(uint(id), map[string]interface{}{"is_hidden": req.IsHidden}); err != nil {
+	if err := h.menus(c).Update(uint(id), map[string]interface{}{"is_hidden": req.IsHidden}); err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to update menu hidden status")
 		return
 	}
@@ -315,3 +327,12 @@ func GetUserMenus(c *gin.Context) {
 
 	response.Success(c, gin.H{"menus": menus})
 }
+
+// respondMenuAPIError 菜单接口校验失败时返回 400，返回是否已处理
+func respondMenuAPIError(c *gin.Context, err error) bool {
+	if errors.Is(err, services.ErrMenuAPIIncomplete) || errors.Is(err, services.ErrMenuAPINotFound) {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return true
+	}
+	return false
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"normaladmin/backend/config"
+	"normaladmin/backend/database"
+	"normaladmin/backend/internal/middleware"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/internal/services"
+	"normaladmin/backend/pkg/auth"
+	"normaladmin/backend/pkg/cache"
+	"normaladmin/backend/pkg/sysconfig"
+	"normaladmin/backend/pkg/utils/jwt"
+	"normaladmin/backend/pkg/utils/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mfaTokenFailPrefix 待确认令牌的验证失败次数，按令牌ID统计
+const mfaTokenFailPrefix = "mfa:fail:"
+
+type MFAHandler struct {
+	mfaService services.MFAService
+	svc        *AuthServices
+	cfg        config.JWTConfig
+}
+
+func NewMFAHandler(svc *AuthServices, cfg config.JWTConfig) *MFAHandler {
+	return &MFAHandler{mfaService: svc.MFA, svc: svc, cfg: cfg}
+}
+
+// mfa 使用请求上下文的两步验证服务，只能访问当前租户的管理员
+func (h *MFAHandler) mfa(c *gin.Context) services.MFAService {
+	return h.mfaService.WithContext(c.Request.Context())
+}
+
+// MFALoginRequest 两步验证登录请求参数
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFATokenRequest 两步验证待确认令牌
+type MFATokenRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+// MFACodeRequest 两步验证码
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// VerifyLogin godoc
+// @Summary 两步验证登录
+// @Description 使用登录第一步返回的 mfa_token 和验证码（或恢复码）完成登录；首次强制启用时会同时返回恢复码；同一个 mfa_token 验证失败达到系统配置 mfa_token_max_failures（默认 5）次后作废，需要重新输入密码；失败次数与密码登录共同计入账号的登录失败次数，达到上限时锁定
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Param req body MFALoginRequest true "两步验证信息" example({"mfa_token":"eyJhbGciOi...","code":"123456"})
+// @Success 200 {object} response.ResponseData{data=object{token=string,refreshToken=string,user=object,recovery_codes=[]string}} "成功"
+// @Failure 400 {object} response.ResponseData "请求参数错误"
+// @Failure 401 {object} response.ResponseData "令牌无效或验证码错误"
+// @Failure 429 {object} response.ResponseData "失败次数过多，账号已被临时锁定"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/login/mfa [post]
+func (h *MFAHandler) VerifyLogin(c *gin.Context) {
+	var req MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	claims, err := middleware.ParseMFAToken(req.MFAToken, h.cfg)
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, "Invalid or expired mfa token")
+		return
+	}
+
+	var user models.Admin
+	if err := database.GetDB().First(&user, claims.UserID).Error; err != nil {
+		response.Error(c, http.StatusUnauthorized, "Invalid or expired mfa token")
+		return
+	}
+	middleware.SetTenant(c, user.TenantID)
+
+	// 与密码登录共用失败计数，账号被锁定时拒绝尝试
+	account := loginAccount(tenantCode(user.TenantID), user.Username)
+	if err := h.svc.LoginGuard.Check(middleware.UserTypeAdmin, account, c.ClientIP()); err != nil {
+		h.svc.recordLogin(c, middleware.UserTypeAdmin, services.LoginActionMFA, user.ID, user.Username, services.LoginReasonThrottled)
+		respondLoginThrottled(c, err, "Too many failed login attempts, please try again later")
+		return
+	}
+
+	var extra gin.H
+	if user.MFAEnabled {
+		err = h.mfa(c).Verify(user.ID, req.Code)
+	} else {
+		// 角色强制要求但尚未启用，本次验证同时完成绑定
+		var codes []string
+		codes, err = h.mfa(c).ConfirmEnrollment(user.ID, req.Code)
+		if err == nil {
+			user.MFAEnabled = true
+			extra = gin.H{"recovery_codes": codes}
+		}
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrMFAInvalidCode) {
+			h.svc.LoginGuard.RecordFailure(middleware.UserTypeAdmin, account, c.ClientIP())
+			recordMFATokenFailure(claims)
+			h.svc.recordLogin(c, middleware.UserTypeAdmin, services.LoginActionMFA, user.ID, user.Username, services.LoginReasonInvalidMFACode)
+		}
+		respondMFAError(c, err)
+		return
+	}
+	h.svc.LoginGuard.RecordSuccess(middleware.UserTypeAdmin, account)
+
+	// 待确认令牌只能使用一次
+	if err := auth.DenyToken(claims.ID, time.Until(claims.ExpiresAt.Time)); err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+
+	respondAdminLogin(c, &user, h.cfg, h.svc, "", services.LoginActionMFA, extra)
+}
+
+// SetupLogin godoc
+// @Summary 登录时绑定两步验证
+// @Description 角色被强制要求两步验证但尚未绑定时，使用 mfa_token 获取密钥和二维码 URI
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Param req body MFATokenRequest true "待确认令牌"
+// @Success 200 {object} response.ResponseData{data=object{secret=string,uri=string}} "成功"
+// @Failure 400 {object} response.ResponseData "请求参数错误或已启用"
+// @Failure 401 {object} response.ResponseData "令牌无效"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/login/mfa/setup [post]
+func (h *MFAHandler) SetupLogin(c *gin.Context) {
+	var req MFATokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	claims, err := middleware.ParseMFAToken(req.MFAToken, h.cfg)
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, "Invalid or expired mfa token")
+		return
+	}
+
+	secret, uri, err := h.mfa(c).BeginEnrollment(claims.UserID)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"secret": secret, "uri": uri})
+}
+
+// Enroll godoc
+// @Summary 开始绑定两步验证
+// @Description 为当前管理员生成新的 TOTP 密钥和二维码 URI，需要调用确认接口后才会生效
+// @Tags 两步验证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.ResponseData{data=object{secret=string,uri=string}} "成功"
+// @Failure 400 {object} response.ResponseData "已启用两步验证"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/mfa/enroll [post]
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	secret, uri, err := h.mfa(c).BeginEnrollment(jwt.GetUserID(c))
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	// 密钥不写入请求日志
+	middleware.SkipResponseLog(c)
+	response.Success(c, gin.H{"secret": secret, "uri": uri})
+}
+
+// ConfirmEnroll godoc
+// @Summary 确认绑定两步验证
+// @Description 校验认证器 App 上的第一个验证码并启用两步验证，返回恢复码（只显示一次）
+// @Tags 两步验证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param req body MFACodeRequest true "验证码" example({"code":"123456"})
+// @Success 200 {object} response.ResponseData{data=object{recovery_codes=[]string}} "成功"
+// @Failure 400 {object} response.ResponseData "验证码错误"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/mfa/confirm [post]
+func (h *MFAHandler) ConfirmEnroll(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	codes, err := h.mfa(c).ConfirmEnrollment(jwt.GetUserID(c), req.Code)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	// 恢复码不写入请求日志
+	middleware.SkipResponseLog(c)
+	response.Success(c, gin.H{"recovery_codes": codes})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary 重新生成恢复码
+// @Description 校验验证码后重新生成恢复码，旧恢复码全部作废
+// @Tags 两步验证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param req body MFACodeRequest true "验证码" example({"code":"123456"})
+// @Success 200 {object} response.ResponseData{data=object{recovery_codes=[]string}} "成功"
+// @Failure 400 {object} response.ResponseData "验证码错误"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	codes, err := h.mfa(c).RegenerateRecoveryCodes(jwt.GetUserID(c), req.Code)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	// 恢复码不写入请求日志
+	middleware.SkipResponseLog(c)
+	response.Success(c, gin.H{"recovery_codes": codes})
+}
+
+// Disable godoc
+// @Summary 关闭两步验证
+// @Description 校验验证码（或恢复码）后关闭当前管理员的两步验证
+// @Tags 两步验证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param req body MFACodeRequest true "验证码" example({"code":"123456"})
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 400 {object} response.ResponseData "验证码错误"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/mfa/disable [post]
+func (h *MFAHandler) Disable(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.mfa(c).Disable(jwt.GetUserID(c), req.Code); err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// recordMFATokenFailure 累加待确认令牌的验证失败次数，达到上限（系统配置 mfa_token_max_failures）后令牌作废，需要重新输入密码
+func recordMFATokenFailure(claims *middleware.Claims) {
+	maxFailures := sysconfig.GetInt("mfa_token_max_failures", 5)
+	if maxFailures <= 0 {
+		return
+	}
+	ttl := time.Until(claims.ExpiresAt.Time)
+	key := mfaTokenFailPrefix + claims.ID
+	if _, err := cache.SetNX(key, 0, ttl); err != nil {
+		return
+	}
+	if failures, err := cache.Incr(key); err == nil && failures >= int64(maxFailures) {
+		_ = auth.DenyToken(claims.ID, ttl)
+	}
+}
+
+// respondMFAError 将两步验证错误转换为响应
+func respondMFAError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrMFAInvalidCode):
+		response.Error(c, http.StatusUnauthorized, "Invalid verification code")
+	case errors.Is(err, services.ErrMFANotEnrolled), errors.Is(err, services.ErrMFAAlreadyEnrolled):
+		response.Error(c, http.StatusBadRequest, err.Error())
+	default:
+		response.Error(c, http.StatusInternalServerError, "Two-factor authentication failed")
+	}
+}
@@ -26,6 +26,11 @@ func NewNotificationHandler(notificationService services.NotificationService) *N
 	}
 }
 
+// notifications 使用请求上下文的通知服务，通知和接收人限定在当前租户内
+func (h *NotificationHandler) notifications(c *gin.Context) services.NotificationService {
+	return h.notificationService.WithContext(c.Request.Context())
+}
+
 // CreateNotificationType 创建通知类型
 // @Summary 创建通知类型
 // @Description 创建新的通知类型
@@ -42,7 +47,7 @@ func (h *NotificationHandler) CreateNotificationType(c *gin.Context) {
 		return
 	}
 
-	if err := h.notificationService.CreateNotificationType(&notificationType); err != nil {
+	if err := h.notifications(c).CreateNotificationType(&notificationType); err != nil {
 		response.Error(c, http.StatusInternalServerError, "创建通知类型失败: "+err.Error())
 		return
 	}
@@ -73,7 +78,7 @@ func (h *NotificationHandler) UpdateNotificationType(c *gin.Context) {
 		return
 	}
 
-	if err := h.notificationService.UpdateNotificationType(uint(id), &notificationType); err != nil {
+	if err := h.notifications(c).UpdateNotificationType(uint(id), &notificationType); err != nil {
 		response.Error(c, http.StatusInternalServerError, "更新通知类型失败: "+err.Error())
 		return
 	}
@@ -97,7 +102,7 @@ func (h *NotificationHandler) DeleteNotificationType(c *gin.Context) {
 		return
 	}
 
-	if err := h.notificationService.DeleteNotificationType(uint(id)); err != nil {
+	if err := h.notifications(c).DeleteNotificationType(uint(id)); err != nil {
 		response.Error(c, http.StatusInternalServerError, "删除通知类型失败: "+err.Error())
 		return
 	}
@@ -121,7 +126,7 @@ func (h *NotificationHandler) GetNotificationType(c *gin.Context) {
 		return
 	}
 
-	notificationType, err := h.notificationService.GetNotificationType(uint(id))
+	notificationType, err := h.notifications(c).GetNotificationType(uint(id))
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "获取通知类型失败: "+err.Error())
 		return
@@ -139,7 +144,7 @@ func (h *NotificationHandler) GetNotificationType(c *gin.Context) {
 // @Success 200 {object} response.Response{data=[]models.NotificationType}
 // @Router /api/notifications/types [get]
 func (h *NotificationHandler) GetAllNotificationTypes(c *gin.Context) {
-	types, err := h.notificationService.GetAllNotificationTypes()
+	types, err := h.notifications(c).GetAllNotificationTypes()
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "获取通知类型列表失败: "+err.Error())
 		return
@@ -183,7 +188,7 @@ func (h *NotificationHandler) CreateNotification(c *gin.Context) {
 	// 设置默认状态为草稿
 	notification.Status = 0
 
-	if err := h.notificationService.CreateNotification(&notification); err != nil {
+	if err := h.notifications(c).CreateNotification(&notification); err != nil {
 		response.Error(c, http.StatusInternalServerError, "创建通知失败: "+err.Error())
 		return
 	}
@@ -214,7 +219,7 @@ func (h *NotificationHandler) UpdateNotification(c *gin.Context) {
 		return
 	}
 
-	if err := h.notificationService.UpdateNotification(uint(id), &notification); err != nil {
+	if err := h.notifications(c).UpdateNotification(uint(id), &notification); err != nil {
 		response.Error(c, http.StatusInternalServerError, "更新通知失败: "+err.Error())
 		return
 	}
@@ -238,7 +243,7 @@ func (h *NotificationHandler) DeleteNotification(c *gin.Context) {
 		return
 	}
 
-	if err := h.notificationService.DeleteNotification(uint(id)); err != nil {
+	if err := h.notifications(c).DeleteNotification(uint(id)); err != nil {
 		response.Error(c, http.StatusInternalServerError, "删除通知失败: "+err.Error())
 		return
 	}
@@ -262,7 +267,7 @@ func (h *NotificationHandler) GetNotification(c *gin.Context) {
 		return
 	}
 
-	notification, err := h.notificationService.GetNotification(uint(id))
+	notification, err := h.notifications(c).GetNotification(uint(id))
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "获取通知详情失败: "+err.Error())
 		return
@@ -294,7 +299,7 @@ func (h *NotificationHandler) GetNotifications(c *gin.Context) {
 		return
 	}
 
-	notifications, total, err := h.notificationService.GetNotifications(&query)
+	notifications, total, err := h.notifications(c).GetNotifications(&query)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "获取通知列表失败: "+err.Error())
 		return
@@ -332,7 +337,7 @@ func (h *NotificationHandler) PublishNotification(c *gin.Context) {
 
 	username := jwt.GetUsername(c)
 
-	if err := h.notificationService.PublishNotification(uint(id), userID, username, userType); err != nil {
+	if err := h.notifications(c).PublishNotification(uint(id), userID, username, userType); err != nil {
 		response.Error(c, http.StatusInternalServerError, "发布通知失败: "+err.Error())
 		return
 	}
@@ -356,7 +361,7 @@ func (h *NotificationHandler) RecallNotification(c *gin.Context) {
 		return
 	}
 
-	if err := h.notificationService.RecallNotification(uint(id)); err != nil {
+	if err := h.notifications(c).RecallNotification(uint(id)); err != nil {
 		response.Error(c, http.StatusInternalServerError, "撤回通知失败: "+err.Error())
 		return
 	}
@@ -380,7 +385,7 @@ func (h *NotificationHandler) GetNotificationStats(c *gin.Context) {
 		return
 	}
 
-	stats, err := h.notificationService.GetNotificationStats(uint(id))
+	stats, err := h.notifications(c).GetNotificationStats(uint(id))
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "获取通知统计信息失败: "+err.Error())
 	}
@@ -422,7 +427,7 @@ func (h *NotificationHandler) GetUserNotifications(c *gin.Context) {
 		return
 	}
 
-	notifications, total, err := h.notificationService.GetUserNotifications(userID, userType, &query)
+	notifications, total, err := h.notifications(c).GetUserNotifications(userID, userType, &query)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "获取通知列表失败")
 		return
@@ -461,7 +466,7 @@ func (h *NotificationHandler) MarkNotificationAsRead(c *gin.Context) {
 		return // GetUserType 已经处理了错误响应
 	}
 
-	if err := h.notificationService.MarkNotificationAsRead(uint(notificationID), userID, userType); err != nil {
+	if err := h.notifications(c).MarkNotificationAsRead(uint(notificationID), userID, userType); err != nil {
 		response.Error(c, http.StatusInternalServerError, "标记已读失败")
 		return
 	}
@@ -489,7 +494,7 @@ func (h *NotificationHandler) MarkAllNotificationsAsRead(c *gin.Context) {
 		return // GetUserType 已经处理了错误响应
 	}
 
-	if err := h.notificationService.MarkAllNotificationsAsRead(userID, userType); err != nil {
+	if err := h.notifications(c).MarkAllNotificationsAsRead(userID, userType); err != nil {
 		response.Error(c, http.StatusInternalServerError, "标记全部已读失败")
 		return
 	}
@@ -524,7 +529,7 @@ func (h *NotificationHandler) DeleteUserNotification(c *gin.Context) {
 		return // GetUserType 已经处理了错误响应
 	}
 
-	if err := h.notificationService.DeleteUserNotification(uint(notificationID), userID, userType); err != nil {
+	if err := h.notifications(c).DeleteUserNotification(uint(notificationID), userID, userType); err != nil {
 		response.Error(c, http.StatusInternalServerError, "删除通知失败")
 		return
 	}
@@ -551,7 +556,7 @@ func (h *NotificationHandler) GetUnreadNotificationCount(c *gin.Context) {
 	if userType == "" {
 		return // GetUserType 已经处理了错误响应
 	}
-	count, err := h.notificationService.GetUnreadNotificationCount(userID, userType)
+	count, err := h.notifications(c).GetUnreadNotificationCount(userID, userType)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "获取未读通知数量失败")
 		return
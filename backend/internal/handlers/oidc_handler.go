@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"normaladmin/backend/config"
+	"normaladmin/backend/internal/middleware"
+	"normaladmin/backend/internal/services"
+	"normaladmin/backend/pkg/auth"
+	"normaladmin/backend/pkg/logger"
+	"normaladmin/backend/pkg/utils/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCHandler struct {
+	oidcService services.OIDCService
+	svc         *AuthServices
+	cfg         config.JWTConfig
+}
+
+func NewOIDCHandler(oidcService services.OIDCService, svc *AuthServices, cfg config.JWTConfig) *OIDCHandler {
+	return &OIDCHandler{oidcService: oidcService, svc: svc, cfg: cfg}
+}
+
+// OIDCCallbackRequest 单点登录回调参数
+type OIDCCallbackRequest struct {
+	Code   string `json:"code" binding:"required"`
+	State  string `json:"state" binding:"required"`
+	Device string `json:"device" binding:"max=100"`
+}
+
+// Authorize godoc
+// @Summary 发起单点登录
+// @Description 生成身份提供方授权地址（授权码 + PKCE），前端跳转到 auth_url，回调页面收到 code 和 state 后调用 /gam/oidc/callback
+// @Tags 认证管理
+// @Produce json
+// @Success 200 {object} response.ResponseData{data=object{auth_url=string,state=string}} "成功"
+// @Failure 404 {object} response.ResponseData "未启用单点登录"
+// @Failure 502 {object} response.ResponseData "身份提供方不可用"
+// @Router /gam/oidc/authorize [get]
+func (h *OIDCHandler) Authorize(c *gin.Context) {
+	authURL, state, err := h.oidcService.AuthorizeURL(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, services.ErrOIDCDisabled) {
+			response.Error(c, http.StatusNotFound, "Single sign-on is not enabled")
+			return
+		}
+		logger.Error("发起单点登录失败", logger.Field("error", err))
+		response.Error(c, http.StatusBadGateway, "Identity provider is unavailable")
+		return
+	}
+
+	response.Success(c, gin.H{"auth_url": authURL, "state": state})
+}
+
+// Callback godoc
+// @Summary 完成单点登录
+// @Description 使用身份提供方回调的 code 和 state 换取并校验 ID 令牌，按分组映射角色，首次登录时关联或创建管理员，然后签发访问令牌
+// @Tags 认证管理
+// @Accept json
+// @Produce json
+// @Param req body OIDCCallbackRequest true "回调参数"
+// @Success 200 {object} response.ResponseData{data=object{token=string,refreshToken=string,user=object}} "成功"
+// @Failure 400 {object} response.ResponseData "请求参数错误或 state 无效"
+// @Failure 401 {object} response.ResponseData "ID 令牌校验失败"
+// @Failure 403 {object} response.ResponseData "没有映射的角色、账号未开通或已禁用"
+// @Failure 404 {object} response.ResponseData "未启用单点登录"
+// @Failure 502 {object} response.ResponseData "身份提供方不可用"
+// @Router /gam/oidc/callback [post]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	var req OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	admin, err := h.oidcService.Authenticate(c.Request.Context(), req.Code, req.State)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrOIDCDisabled):
+			response.Error(c, http.StatusNotFound, "Single sign-on is not enabled")
+		case errors.Is(err, services.ErrOIDCInvalidState):
+			response.Error(c, http.StatusBadRequest, "Invalid or expired login state")
+		case errors.Is(err, services.ErrOIDCNoRole),
+			errors.Is(err, services.ErrOIDCNotProvisioned),
+			errors.Is(err, services.ErrOIDCUsernameTaken),
+			errors.Is(err, services.ErrOIDCAccountDisabled):
+			h.svc.recordLogin(c, middleware.UserTypeAdmin, services.LoginActionSSO, 0, "", services.LoginReasonSSORejected)
+			response.Error(c, http.StatusForbidden, "Your account is not allowed to sign in to the admin console")
+		case errors.Is(err, auth.ErrOIDCInvalidIDToken), errors.Is(err, auth.ErrOIDCNonceMismatch), errors.Is(err, auth.ErrUnknownKeyID):
+			h.svc.recordLogin(c, middleware.UserTypeAdmin, services.LoginActionSSO, 0, "", services.LoginReasonSSOFailed)
+			response.Error(c, http.StatusUnauthorized, "Invalid identity token")
+		default:
+			logger.Error("单点登录失败", logger.Field("error", err))
+			h.svc.recordLogin(c, middleware.UserTypeAdmin, services.LoginActionSSO, 0, "", services.LoginReasonSSOFailed)
+			response.Error(c, http.StatusBadGateway, "Identity provider is unavailable")
+		}
+		return
+	}
+
+	// 身份提供方负责多因素认证，这里直接签发令牌
+	respondAdminLogin(c, admin, h.cfg, h.svc, req.Device, services.LoginActionSSO, nil)
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"normaladmin/backend/internal/services"
+	"normaladmin/backend/pkg/utils/response"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PermissionHandler struct {
+	explainService services.PermissionExplainService
+	routes         func() gin.RoutesInfo
+	public         map[string]bool
+}
+
+// NewPermissionHandler routes 返回全部已注册的路由，public 为不经过接口权限校验的路由
+func NewPermissionHandler(explainService services.PermissionExplainService, routes func() gin.RoutesInfo, public gin.RoutesInfo) *PermissionHandler {
+	h := &PermissionHandler{explainService: explainService, routes: routes, public: make(map[string]bool, len(public))}
+	for _, route := range public {
+		h.public[route.Method+" "+route.Path] = true
+	}
+	return h
+}
+
+// explainer 使用请求上下文的权限排查服务，只能排查当前租户的管理员和角色
+func (h *PermissionHandler) explainer(c *gin.Context) services.PermissionExplainService {
+	return h.explainService.WithContext(c.Request.Context())
+}
+
+// ExplainAdmin godoc
+// @Summary 排查管理员接口权限
+// @Description 模拟管理员访问接口，按权限校验的顺序评估免检名单、超级管理员、角色状态、菜单状态和权限策略，返回判定结果、命中或缺少的策略以及数据范围
+// @Tags 权限排查
+// @Accept json
+// @Produce json
+// @Param user query string true "管理员ID或用户名"
+// @Param method query string false "请求方法，默认 GET"
+// @Param path query string true "请求路径，如 /gam/admins/1"
+// @Success 200 {object} response.ResponseData{data=object{explanation=services.PermissionExplanation}} "成功"
+// @Failure 400 {object} response.ResponseData "无效的请求参数"
+// @Failure 404 {object} response.ResponseData "管理员不存在"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/permissions/explain [get]
+func (h *PermissionHandler) ExplainAdmin(c *gin.Context) {
+	user := strings.TrimSpace(c.Query("user"))
+	if user == "" {
+		response.Error(c, http.StatusBadRequest, "user is required")
+		return
+	}
+	method, path, ok := explainRequest(c)
+	if !ok {
+		return
+	}
+
+	explanation, err := h.explainer(c).ExplainAdmin(user, method, path)
+	if err != nil {
+		if errors.Is(err, services.ErrPermissionAdminNotFound) {
+			response.Error(c, http.StatusNotFound, "Admin not found")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to explain permission")
+		return
+	}
+
+	response.Success(c, gin.H{"explanation": explanation})
+}
+
+// ExplainRole godoc
+// @Summary 排查角色接口权限
+// @Description 模拟角色访问接口，评估免检名单、角色状态、菜单状态和权限策略，返回判定结果、命中或缺少的策略以及角色的数据范围
+// @Tags 权限排查
+// @Accept json
+// @Produce json
+// @Param role_id query int true "角色ID" minimum(1)
+// @Param method query string false "请求方法，默认 GET"
+// @Param path query string true "请求路径，如 /gam/admins/1"
+// @Success 200 {object} response.ResponseData{data=object{explanation=services.PermissionExplanation}} "成功"
+// @Failure 400 {object} response.ResponseData "无效的请求参数"
+// @Failure 404 {object} response.ResponseData "角色不存在"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/permissions/explain/role [get]
+func (h *PermissionHandler) ExplainRole(c *gin.Context) {
+	roleID, err := strconv.ParseUint(c.Query("role_id"), 10, 32)
+	if err != nil || roleID == 0 {
+		response.Error(c, http.StatusBadRequest, "Invalid role ID")
+		return
+	}
+	method, path, ok := explainRequest(c)
+	if !ok {
+		return
+	}
+
+	explanation, err := h.explainer(c).ExplainRole(uint(roleID), method, path)
+	if err != nil {
+		if errors.Is(err, services.ErrRoleNotFound) {
+			response.Error(c, http.StatusNotFound, "Role not found")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to explain permission")
+		return
+	}
+
+	response.Success(c, gin.H{"explanation": explanation})
+}
+
+// GetRoleReport godoc
+// @Summary 角色接口权限报告
+// @Description 列出全部已注册的路由，并标注角色是否可以访问（public：不经过权限校验，allowlist：免检名单，allowed：允许，denied：拒绝）
+// @Tags 权限排查
+// @Accept json
+// @Produce json
+// @Param roleId path int true "角色ID" minimum(1)
+// @Success 200 {object} response.ResponseData{data=object{report=services.RoleRouteReport}} "成功"
+// @Failure 400 {object} response.ResponseData "无效的角色ID"
+// @Failure 404 {object} response.ResponseData "角色不存在"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/permissions/roles/{roleId}/report [get]
+func (h *PermissionHandler) GetRoleReport(c *gin.Context) {
+	roleID, err := strconv.ParseUint(c.Param("roleId"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid role ID")
+		return
+	}
+
+	registered := h.routes()
+	routes := make([]services.RouteAccess, 0, len(registered))
+	for _, route := range registered {
+		access := ""
+		// 只有 /gam 下认证之后注册的路由经过接口权限校验
+		if !strings.HasPrefix(route.Path, "/gam/") || h.public[route.Method+" "+route.Path] {
+			access = services.RouteAccessPublic
+		}
+		routes = append(routes, services.RouteAccess{Method: route.Method, Path: route.Path, Access: access})
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	report, err := h.explainer(c).RoleReport(uint(roleID), routes)
+	if err != nil {
+		if errors.Is(err, services.ErrRoleNotFound) {
+			response.Error(c, http.StatusNotFound, "Role not found")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to build role report")
+		return
+	}
+
+	response.Success(c, gin.H{"report": report})
+}
+
+// explainRequest 要排查的请求方法和路径，失败时已写入响应并返回 false
+func explainRequest(c *gin.Context) (string, string, bool) {
+	method := strings.ToUpper(strings.TrimSpace(c.DefaultQuery("method", http.MethodGet)))
+	path := strings.TrimSpace(c.Query("path"))
+	if !strings.HasPrefix(path, "/") {
+		response.Error(c, http.StatusBadRequest, "path must start with /")
+		return "", "", false
+	}
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	return method, path, true
+}
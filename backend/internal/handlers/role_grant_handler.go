@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"normaladmin/backend/internal/services"
+	"normaladmin/backend/pkg/utils/response"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleGrantHandler struct {
+	roleGrantService services.RoleGrantService
+}
+
+func NewRoleGrantHandler(roleGrantService services.RoleGrantService) *RoleGrantHandler {
+	return &RoleGrantHandler{roleGrantService: roleGrantService}
+}
+
+// grants 使用请求上下文的临时授权服务
+func (h *RoleGrantHandler) grants(c *gin.Context) services.RoleGrantService {
+	return h.roleGrantService.WithContext(c.Request.Context())
+}
+
+// GetRoleGrants godoc
+// @Summary 获取临时角色授权记录
+// @Description 分页查询全部临时角色授权，包括已到期和已撤销的记录，用于审计（status：0待生效 1生效中 2已到期 3已撤销）
+// @Tags 临时角色授权
+// @Accept json
+// @Produce json
+// @Param admin_id query int false "被授权的管理员ID"
+// @Param role_id query int false "角色ID"
+// @Param status query int false "状态"
+// @Param page query int false "页码，默认值：1" default(1)
+// @Param pageSize query int false "每页数量，默认值：10" default(10)
+// @Success 200 {object} response.ResponseData{data=object{grants=[]models.RoleGrant,total=int}} "成功"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/role-grants [get]
+func (h *RoleGrantHandler) GetRoleGrants(c *gin.Context) {
+	var query services.RoleGrantQuery
+	if adminID, err := strconv.ParseUint(c.Query("admin_id"), 10, 32); err == nil {
+		query.AdminID = uint(adminID)
+	}
+	if roleID, err := strconv.ParseUint(c.Query("role_id"), 10, 32); err == nil {
+		query.RoleID = uint(roleID)
+	}
+	if status, err := strconv.Atoi(c.Query("status")); err == nil {
+		query.Status = &status
+	}
+
+	grants, total, err := h.grants(c).List(query, c.DefaultQuery("page", "1"), c.DefaultQuery("pageSize", "10"))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to get role grants")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"grants": grants,
+		"total":  total,
+	})
+}
+
+// CreateRoleGrant godoc
+// @Summary 临时授予角色
+// @Description 在指定时段内临时授予管理员角色，starts_at 为空时立即生效；到期后自动收回，到期前和收回时通知该管理员
+// @Tags 临时角色授权
+// @Accept json
+// @Produce json
+// @Param grant body services.RoleGrantRequest true "授权信息" example({"admin_id":2,"role_id":1,"ends_at":"2025-01-01T18:00:00+08:00","reason":"线上故障排查"})
+// @Success 200 {object} response.ResponseData{data=object{grant=models.RoleGrant}} "成功"
+// @Failure 400 {object} response.ResponseData "无效的请求参数、授权时段或角色"
+// @Failure 404 {object} response.ResponseData "管理员不存在"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/role-grants [post]
+func (h *RoleGrantHandler) CreateRoleGrant(c *gin.Context) {
+	var req services.RoleGrantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	grantorID, _ := userID.(uint)
+	grantor := c.GetString("username")
+	grant, err := h.grants(c).Grant(req, grantorID, grantor)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRoleGrantAdminNotFound):
+			response.Error(c, http.StatusNotFound, "Admin not found")
+		case errors.Is(err, services.ErrRoleGrantInvalidPeriod), errors.Is(err, services.ErrRoleGrantSelf),
+			errors.Is(err, services.ErrAdminRoleNotFound):
+			response.Error(c, http.StatusBadRequest, err.Error())
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to grant role")
+		}
+		return
+	}
+
+	response.Success(c, gin.H{"grant": grant})
+}
+
+// RevokeRoleGrant godoc
+// @Summary 撤销临时角色授权
+// @Description 提前收回临时授予的角色并通知该管理员
+// @Tags 临时角色授权
+// @Accept json
+// @Produce json
+// @Param id path int true "授权ID" minimum(1)
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 400 {object} response.ResponseData "无效的授权ID"
+// @Failure 404 {object} response.ResponseData "授权不存在"
+// @Failure 409 {object} response.ResponseData "授权已到期或已撤销"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/role-grants/{id} [delete]
+func (h *RoleGrantHandler) RevokeRoleGrant(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	if err := h.grants(c).Revoke(uint(id), c.GetString("username")); err != nil {
+		switch {
+		case errors.Is(err, services.ErrRoleGrantNotFound):
+			response.Error(c, http.StatusNotFound, "Role grant not found")
+		case errors.Is(err, services.ErrRoleGrantClosed):
+			response.Error(c, http.StatusConflict, err.Error())
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to revoke role grant")
+		}
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Role grant revoked successfully"})
+}
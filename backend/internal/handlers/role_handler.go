@@ -1,64 +1,63 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"normaladmin/backend/internal/models"
 	"normaladmin/backend/internal/services"
 	"normaladmin/backend/pkg/utils/response"
 	"strconv"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 type RoleHandler struct {
 	roleService services.RoleService
+	dataScopes  services.DataScopeService
 }
 
-func NewRoleHandler(roleService services.RoleService) *RoleHandler {
+func NewRoleHandler(roleService services.RoleService, dataScopes services.DataScopeService) *RoleHandler {
 	return &RoleHandler{
 		roleService: roleService,
+		dataScopes:  dataScopes,
 	}
 }
 
+// roles 使用请求上下文的角色服务，只能访问当前租户的角色
+func (h *RoleHandler) roles(c *gin.Context) services.RoleService {
+	return h.roleService.WithContext(c.Request.Context())
+}
+
 // GetRoleList godoc
 // @Summary 获取角色列表
-// @Description 获取角色列表，支持按名称搜索、分页和排序
+// @Description 获取角色列表，支持过滤、分页和排序。过滤参数写作 field=value（默认运算符）或 field[op]=value，如 code=ADMIN、status[ne]=1；可用字段：id、name、code、status、data_scope、is_preset、sort、created_at、updated_at
 // @Tags 角色管理
 // @Accept json
 // @Produce json
-// @Param name query string false "角色名称，用于搜索过滤"
+// @Param name query string false "角色名称，默认模糊匹配"
 // @Param page query int false "页码，默认值：1" default(1)
 // @Param pageSize query int false "每页数量，默认值：10" default(10)
 // @Param sortField query string false "排序字段，多个字段用逗号分隔" example("id,created_at")
 // @Param sortOrder query string false "排序方式，多个排序方式用逗号分隔(asc/desc)" example("desc,asc")
 // @Success 200 {object} response.ResponseData{data=object{roles=[]models.Role,total=int}} "成功"
+// @Failure 400 {object} response.ResponseData "过滤或排序参数无效"
 // @Failure 500 {object} response.ResponseData "内部错误"
 // @Router /gam/roles [get]
 func (h *RoleHandler) GetRoleList(c *gin.Context) {
-	query := make(map[string]interface{})
-	if name := c.Query("name"); name != "" {
-		query["name"] = name
+	filter, ok := listFilter(c, models.RoleFilters)
+	if !ok {
+		return
 	}
 	page := c.DefaultQuery("page", "1")
 	pageSize := c.DefaultQuery("pageSize", "10")
 
-	// 处理排序
-	var opts []models.QueryOption
-
-	sortField := c.DefaultQuery("sortField", "")
-	if sortField != "" {
-		sortFields := strings.Split(sortField, ",")
-		sortOrders := strings.Split(c.DefaultQuery("sortOrder", "desc"), ",")
-		opts = append(opts, models.WithSort(sortFields, sortOrders))
-	}
-
 	// 添加其他查询选项
+	var opts []models.QueryOption
 	// if c.Query("with_profile") == "true" {
 	// 	opts = append(opts, services.WithPreload("Profile"))
 	// }
 
-	roles, total, err := h.roleService.List(query, page, pageSize, opts...)
+	roles, total, err := h.roles(c).List(filter, page, pageSize, opts...)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to get role list")
 		return
@@ -86,7 +85,7 @@ func (h *RoleHandler) GetRole(c *gin.Context) {
 		return
 	}
 
-	role, err := h.roleService.GetByID(uint(id))
+	role, err := h.roles(c).GetByID(uint(id))
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to get role")
 		return
@@ -113,7 +112,7 @@ func (h *RoleHandler) CreateRole(c *gin.Context) {
 		return
 	}
 
-	if err := h.roleService.Create(&role); err != nil {
+	if err := h.roles(c).Create(&role); err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to create role")
 		return
 	}
@@ -147,7 +146,7 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 		return
 	}
 
-	if err := h.roleService.Update(uint(id), &role); err != nil {
+	if err := h.roles(c).Update(uint(id), &role); err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to update role")
 		return
 	}
@@ -174,7 +173,7 @@ func (h *RoleHandler) DeleteRole(c *gin.Context) {
 		return
 	}
 
-	if err := h.roleService.Delete(uint(id), false); err != nil {
+	if err := h.roles(c).Delete(uint(id), false); err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to delete role")
 		return
 	}
@@ -210,7 +209,7 @@ func (h *RoleHandler) UpdateRoleStatus(c *gin.Context) {
 		return
 	}
 
-	if err := h.roleService.Update(uint(id), map[string]interface{}{"status": req.Status}); err != nil {
+	if err := h.roles(c).Update(uint(id), map[string]interface{}{"status": req.Status}); err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to update role status")
 		return
 	}
@@ -246,7 +245,7 @@ func (h *RoleHandler) UpdateRoleSort(c *gin.Context) {
 		return
 	}
 
-	if err := h.roleService.Update(uint(id), map[string]interface{}{"sort": req.Sort}); err != nil {
+	if err := h.roles(c).Update(uint(id), map[string]interface{}{"sort": req.Sort}); err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to update role sort")
 		return
 	}
@@ -277,7 +276,7 @@ func (h *RoleHandler) CheckRoleFieldUnique(c *gin.Context) {
 		return
 	}
 
-	isUnique, err := h.roleService.CheckRoleFieldUnique(field, value, uint(excludeID))
+	isUnique, err := h.roles(c).CheckRoleFieldUnique(field, value, uint(excludeID))
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "Failed to check field uniqueness")
 		return
@@ -305,8 +304,12 @@ func (h *RoleHandler) GetRoleMenus(c *gin.Context) {
 		return
 	}
 
-	menuTree, checkedMenus, err := h.roleService.GetRoleMenus(uint(roleID))
+	menuTree, checkedMenus, err := h.roles(c).GetRoleMenus(uint(roleID))
 	if err != nil {
+		if errors.Is(err, services.ErrRoleNotFound) {
+			response.Error(c, http.StatusNotFound, "Role not found")
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to get role menus")
 		return
 	}
@@ -342,10 +345,104 @@ func (h *RoleHandler) UpdateRoleMenus(c *gin.Context) {
 		return
 	}
 
-	if err := h.roleService.UpdateRoleMenus(uint(roleID), req.MenuIDs); err != nil {
+	if err := h.roles(c).UpdateRoleMenus(uint(roleID), req.MenuIDs); err != nil {
+		if errors.Is(err, services.ErrRoleNotFound) {
+			response.Error(c, http.StatusNotFound, "Role not found")
+			return
+		}
 		response.Error(c, http.StatusInternalServerError, "Failed to update role menus")
 		return
 	}
 
 	response.Success(c, gin.H{"message": "Role menus updated successfully"})
 }
+
+// SyncPolicies godoc
+// @Summary 同步全部角色权限策略
+// @Description 按角色已分配的按钮菜单重建全部角色的接口权限策略，按管理员角色重建分组策略，并清理已删除角色和管理员遗留的策略，不影响 API 密钥的策略
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} response.ResponseData{data=object{result=services.PolicySyncResult}} "成功"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/roles/permissions/sync [post]
+func (h *RoleHandler) SyncPolicies(c *gin.Context) {
+	result, err := h.roles(c).SyncPolicies()
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to sync role policies")
+		return
+	}
+
+	response.Success(c, gin.H{"result": result})
+}
+
+// GetRoleDepartments godoc
+// @Summary 获取角色自定义数据范围
+// @Description 获取角色在自定义数据权限（data_scope=2）下可访问的部门ID列表
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Param roleId path int true "角色ID" minimum(1)
+// @Success 200 {object} response.ResponseData{data=object{departmentIds=[]uint}} "成功"
+// @Failure 400 {object} response.ResponseData "无效的角色ID"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/roles/permissions/{roleId}/departments [get]
+func (h *RoleHandler) GetRoleDepartments(c *gin.Context) {
+	roleID, err := strconv.ParseUint(c.Param("roleId"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid role ID format")
+		return
+	}
+
+	departmentIDs, err := h.dataScopes.WithContext(c.Request.Context()).GetRoleDepartments(uint(roleID))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to get role departments")
+		return
+	}
+
+	response.Success(c, gin.H{"departmentIds": departmentIDs})
+}
+
+// UpdateRoleDepartments godoc
+// @Summary 更新角色自定义数据范围
+// @Description 设置角色在自定义数据权限（data_scope=2）下可访问的部门，传空列表表示不能访问任何部门的数据
+// @Tags 角色管理
+// @Accept json
+// @Produce json
+// @Param roleId path int true "角色ID" minimum(1)
+// @Param departmentIds body object true "部门ID列表" example({"departmentIds": [1,2,3]})
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 400 {object} response.ResponseData "无效的请求参数或部门不存在"
+// @Failure 404 {object} response.ResponseData "角色不存在"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/roles/permissions/{roleId}/departments [put]
+func (h *RoleHandler) UpdateRoleDepartments(c *gin.Context) {
+	roleID, err := strconv.ParseUint(c.Param("roleId"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid role ID format")
+		return
+	}
+
+	var req struct {
+		DepartmentIDs []uint `json:"departmentIds" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.dataScopes.WithContext(c.Request.Context()).UpdateRoleDepartments(uint(roleID), req.DepartmentIDs); err != nil {
+		if errors.Is(err, services.ErrRoleNotFound) {
+			response.Error(c, http.StatusNotFound, "Role not found")
+			return
+		}
+		if errors.Is(err, services.ErrDepartmentNotFound) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to update role departments")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Role departments updated successfully"})
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"normaladmin/backend/internal/services"
+	"normaladmin/backend/pkg/utils/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SecurityHandler struct {
+	loginGuard services.LoginGuardService
+}
+
+func NewSecurityHandler(loginGuard services.LoginGuardService) *SecurityHandler {
+	return &SecurityHandler{loginGuard: loginGuard}
+}
+
+// ClearLockoutRequest 解除登录锁定请求参数
+type ClearLockoutRequest struct {
+	Type     string `json:"type" binding:"required,oneof=user ip"`
+	UserType string `json:"user_type" binding:"omitempty,oneof=admin member"`
+	Subject  string `json:"subject" binding:"required"`
+}
+
+// GetLockouts godoc
+// @Summary 获取登录锁定列表
+// @Description 获取因登录失败次数过多而被临时锁定的用户名和IP
+// @Tags 安全管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} response.ResponseData{data=object{list=[]services.Lockout,total=int}} "成功"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/security/lockouts [get]
+func (h *SecurityHandler) GetLockouts(c *gin.Context) {
+	lockouts, err := h.loginGuard.ListLockouts()
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取锁定列表失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"list":  lockouts,
+		"total": len(lockouts),
+	})
+}
+
+// ClearLockout godoc
+// @Summary 解除登录锁定
+// @Description 解除用户名或IP的登录锁定并清空失败计数；按用户名解除时需指定用户类型
+// @Tags 安全管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param req body ClearLockoutRequest true "锁定信息" example({"type":"user","user_type":"admin","subject":"admin"})
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 400 {object} response.ResponseData "请求参数错误"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/security/lockouts [delete]
+func (h *SecurityHandler) ClearLockout(c *gin.Context) {
+	var req ClearLockoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Type == services.LockoutTypeUser && req.UserType == "" {
+		response.Error(c, http.StatusBadRequest, "user_type is required")
+		return
+	}
+
+	if err := h.loginGuard.ClearLockout(req.Type, req.UserType, req.Subject); err != nil {
+		response.Error(c, http.StatusInternalServerError, "解除锁定失败")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "解除锁定成功"})
+}
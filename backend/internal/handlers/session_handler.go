@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"normaladmin/backend/database"
+	"normaladmin/backend/internal/middleware"
+	"normaladmin/backend/internal/services"
+	"normaladmin/backend/pkg/utils/jwt"
+	"normaladmin/backend/pkg/utils/response"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler struct {
+	sessionService services.SessionService
+}
+
+func NewSessionHandler(sessionService services.SessionService) *SessionHandler {
+	return &SessionHandler{sessionService: sessionService}
+}
+
+// GetSessions godoc
+// @Summary 获取登录会话列表
+// @Description 获取当前管理员的有效登录会话；超级管理员可通过 user_type 和 user_id 查询任意用户的会话
+// @Tags 会话管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param user_type query string false "用户类型" Enums(admin, member)
+// @Param user_id query int false "用户ID"
+// @Success 200 {object} response.ResponseData{data=object{list=[]models.UserSession,total=int}} "成功"
+// @Failure 400 {object} response.ResponseData "请求参数错误"
+// @Failure 403 {object} response.ResponseData "无权查看其他用户的会话"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/sessions [get]
+func (h *SessionHandler) GetSessions(c *gin.Context) {
+	userType, userID, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.sessionService.List(userType, userID)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取会话列表失败")
+		return
+	}
+
+	if claims, exists := middleware.GetClaims(c); exists {
+		for i := range sessions {
+			sessions[i].Current = sessions[i].SessionID == claims.FamilyID
+		}
+	}
+
+	response.Success(c, gin.H{
+		"list":  sessions,
+		"total": len(sessions),
+	})
+}
+
+// RevokeSession godoc
+// @Summary 注销登录会话
+// @Description 注销指定会话，该会话的令牌立即失效并断开其 WebSocket 连接；只能注销自己的会话，超级管理员可注销任意会话
+// @Tags 会话管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param session_id path string true "会话ID"
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 403 {object} response.ResponseData "无权注销该会话"
+// @Failure 404 {object} response.ResponseData "会话不存在"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/sessions/{session_id} [delete]
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	session, err := h.sessionService.Get(c.Param("session_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			response.Error(c, http.StatusNotFound, "会话不存在")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "注销会话失败")
+		return
+	}
+
+	isOwner := session.UserType == jwt.GetUserType(c) && session.UserID == jwt.GetUserID(c)
+	if !isOwner && !isSuperAdmin(c) {
+		response.Error(c, http.StatusForbidden, "无权注销该会话")
+		return
+	}
+
+	if err := h.sessionService.Revoke(session.SessionID); err != nil {
+		response.Error(c, http.StatusInternalServerError, "注销会话失败")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "会话已注销"})
+}
+
+// RevokeAllSessions godoc
+// @Summary 注销全部登录会话
+// @Description 注销当前管理员的全部会话，keep_current=1 时保留当前会话；超级管理员可通过 user_type 和 user_id 注销任意用户的全部会话
+// @Tags 会话管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param user_type query string false "用户类型" Enums(admin, member)
+// @Param user_id query int false "用户ID"
+// @Param keep_current query int false "是否保留当前会话" Enums(0, 1)
+// @Success 200 {object} response.ResponseData{data=object{message=string,revoked=int}} "成功"
+// @Failure 400 {object} response.ResponseData "请求参数错误"
+// @Failure 403 {object} response.ResponseData "无权注销其他用户的会话"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/sessions [delete]
+func (h *SessionHandler) RevokeAllSessions(c *gin.Context) {
+	userType, userID, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	var except string
+	if c.Query("keep_current") == "1" {
+		if claims, exists := middleware.GetClaims(c); exists {
+			except = claims.FamilyID
+		}
+	}
+
+	revoked, err := h.sessionService.RevokeAll(userType, userID, except)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "注销会话失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"message": "会话已注销",
+		"revoked": revoked,
+	})
+}
+
+// targetUser 解析要操作的用户，未指定时为当前用户；操作其他用户需要超级管理员
+func (h *SessionHandler) targetUser(c *gin.Context) (string, uint, bool) {
+	userType := jwt.GetUserType(c)
+	userID := jwt.GetUserID(c)
+
+	if c.Query("user_id") == "" {
+		return userType, userID, true
+	}
+
+	id, err := strconv.ParseUint(c.Query("user_id"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid user ID")
+		return "", 0, false
+	}
+	targetType := c.DefaultQuery("user_type", middleware.UserTypeAdmin)
+	if targetType != middleware.UserTypeAdmin && targetType != middleware.UserTypeMember {
+		response.Error(c, http.StatusBadRequest, "Invalid user type")
+		return "", 0, false
+	}
+
+	if (targetType != userType || uint(id) != userID) && !isSuperAdmin(c) {
+		response.Error(c, http.StatusForbidden, "无权操作其他用户的会话")
+		return "", 0, false
+	}
+	return targetType, uint(id), true
+}
+
+// isSuperAdmin 当前请求是否来自超级管理员
+func isSuperAdmin(c *gin.Context) bool {
+	if jwt.GetUserType(c) != middleware.UserTypeAdmin {
+		return false
+	}
+	userID, _ := c.Get("user_id")
+	id, ok := userID.(uint)
+	return ok && services.IsSuperAdmin(database.GetDB(), id)
+}
@@ -2,26 +2,40 @@ package handlers
 
 import (
 	"net/http"
+	"normaladmin/backend/internal/models"
 	"normaladmin/backend/internal/services"
 	"normaladmin/backend/pkg/utils/response"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type SystemHandler struct {
-	systemService services.SystemService
+	systemService   services.SystemService
+	loginLogService services.LoginLogService
 }
 
-func NewSystemHandler(systemService services.SystemService) *SystemHandler {
+func NewSystemHandler(systemService services.SystemService, loginLogService services.LoginLogService) *SystemHandler {
 	return &SystemHandler{
-		systemService: systemService,
+		systemService:   systemService,
+		loginLogService: loginLogService,
 	}
 }
 
+// loginLogs 使用请求上下文的登录日志服务
+func (h *SystemHandler) loginLogs(c *gin.Context) services.LoginLogService {
+	return h.loginLogService.WithContext(c.Request.Context())
+}
+
+// logs 使用请求上下文的系统服务，只能访问当前租户的操作日志
+func (h *SystemHandler) logs(c *gin.Context) services.SystemService {
+	return h.systemService.WithContext(c.Request.Context())
+}
+
 // GetSystemLogs godoc
 // @Summary 获取系统日志列表
-// @Description 分页获取系统操作日志
+// @Description 分页获取系统操作日志。过滤参数写作 field=value（默认运算符）或 field[op]=value，如 created_at[between]=2025-01-01,2025-01-31、status[gte]=400、url[prefix]=/gam/admins；可用字段：id、user_id、username、api_key_id、impersonator_id、module、action、method、url、ip、status、duration、created_at
 // @Tags 系统管理
 // @Accept json
 // @Produce json
@@ -29,20 +43,21 @@ func NewSystemHandler(systemService services.SystemService) *SystemHandler {
 // @Param page_size query string false "每页数量"
 // @Param username query string false "用户名"
 // @Param module query string false "模块"
-// @Param start_time query string false "开始时间"
-// @Param end_time query string false "结束时间"
+// @Param api_key_id query int false "API 密钥ID，查询通过该密钥发起的请求"
+// @Param impersonator_id query int false "管理员ID，查询该管理员模拟会员登录期间发起的请求"
+// @Param created_at[between] query string false "时间范围，开始和结束用逗号分隔" example("2025-01-01,2025-01-31")
+// @Param sortField query string false "排序字段" example("created_at")
+// @Param sortOrder query string false "排序方式(asc/desc)" example("desc")
 // @Success 200 {object} response.ResponseData{data=[]models.SystemLog} "成功"
+// @Failure 400 {object} response.ResponseData "过滤或排序参数无效"
 // @Router /gam/system/logs [get]
 func (h *SystemHandler) GetSystemLogs(c *gin.Context) {
-	query := make(map[string]interface{})
-	if username := c.Query("username"); username != "" {
-		query["username"] = username
-	}
-	if module := c.Query("module"); module != "" {
-		query["module"] = module
+	filter, ok := listFilter(c, models.SystemLogFilters)
+	if !ok {
+		return
 	}
 
-	logs, total, err := h.systemService.GetLogList(query, c.Query("page"), c.Query("page_size"))
+	logs, total, err := h.logs(c).GetLogList(filter, c.Query("page"), c.Query("page_size"))
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "获取日志失败")
 		return
@@ -54,6 +69,84 @@ func (h *SystemHandler) GetSystemLogs(c *gin.Context) {
 	})
 }
 
+// GetLoginLogs godoc
+// @Summary 获取登录日志列表
+// @Description 分页获取管理员和会员的登录、两步验证及令牌刷新记录，包含失败原因
+// @Tags 系统管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param page query string false "页码"
+// @Param page_size query string false "每页数量"
+// @Param user_type query string false "用户类型" Enums(admin, member)
+// @Param user_id query int false "用户ID"
+// @Param username query string false "用户名（模糊匹配）"
+// @Param action query string false "动作" Enums(login, mfa, refresh)
+// @Param success query int false "是否成功" Enums(0, 1)
+// @Param reason query string false "失败原因"
+// @Param ip query string false "IP"
+// @Param start_time query string false "开始时间（2006-01-02 15:04:05）"
+// @Param end_time query string false "结束时间（2006-01-02 15:04:05）"
+// @Success 200 {object} response.ResponseData{data=object{list=[]models.LoginLog,total=int}} "成功"
+// @Failure 400 {object} response.ResponseData "请求参数错误"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/system/login-logs [get]
+func (h *SystemHandler) GetLoginLogs(c *gin.Context) {
+	query := services.LoginLogQuery{
+		UserType: c.Query("user_type"),
+		Username: c.Query("username"),
+		Action:   c.Query("action"),
+		Reason:   c.Query("reason"),
+		IP:       c.Query("ip"),
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		id, err := strconv.ParseUint(userID, 10, 32)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "用户ID格式错误")
+			return
+		}
+		query.UserID = uint(id)
+	}
+	if success := c.Query("success"); success != "" {
+		value := success == "1" || success == "true"
+		query.Success = &value
+	}
+	for param, target := range map[string]**time.Time{"start_time": &query.StartTime, "end_time": &query.EndTime} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := parseQueryTime(value)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "时间格式错误")
+			return
+		}
+		*target = &t
+	}
+
+	logs, total, err := h.loginLogs(c).List(query, c.Query("page"), c.Query("page_size"))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "获取登录日志失败")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"list":  logs,
+		"total": total,
+	})
+}
+
+// parseQueryTime 解析查询参数中的时间，支持 RFC3339、日期时间和日期格式
+func parseQueryTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
 // GetSystemMonitor godoc
 // @Summary 获取系统监控数据
 // @Description 获取系统资源使用情况
@@ -63,7 +156,7 @@ func (h *SystemHandler) GetSystemLogs(c *gin.Context) {
 // @Success 200 {object} response.ResponseData{data=models.SystemMonitor} "成功"
 // @Router /gam/system/monitor [get]
 func (h *SystemHandler) GetSystemMonitor(c *gin.Context) {
-	data, err := h.systemService.CollectSystemInfo()
+	data, err := h.logs(c).CollectSystemInfo()
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "获取系统监控数据失败")
 		return
@@ -93,7 +186,7 @@ func (h *SystemHandler) DeleteSystemLogs(c *gin.Context) {
 		return
 	}
 
-	if err := h.systemService.DeleteLogs(beforeTime); err != nil {
+	if err := h.logs(c).DeleteLogs(beforeTime); err != nil {
 		response.Error(c, http.StatusInternalServerError, "删除日志失败")
 		return
 	}
@@ -117,7 +210,7 @@ func (h *SystemHandler) GetMonitorHistory(c *gin.Context) {
 		return
 	}
 
-	data, err := h.systemService.GetMonitorData(duration)
+	data, err := h.logs(c).GetMonitorData(duration)
 	if err != nil {
 		response.Error(c, http.StatusInternalServerError, "获取监控历史数据失败")
 		return
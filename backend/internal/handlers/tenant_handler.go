@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"normaladmin/backend/internal/services"
+	"normaladmin/backend/pkg/utils/response"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TenantHandler struct {
+	tenantService services.TenantService
+}
+
+func NewTenantHandler(tenantService services.TenantService) *TenantHandler {
+	return &TenantHandler{tenantService: tenantService}
+}
+
+// GetTenants godoc
+// @Summary 获取租户列表
+// @Description 分页查询租户，支持按名称或编码搜索；只有超级租户的管理员可以访问
+// @Tags 租户管理
+// @Accept json
+// @Produce json
+// @Param query query string false "名称或编码"
+// @Param page query int false "页码，默认值：1" default(1)
+// @Param pageSize query int false "每页数量，默认值：10" default(10)
+// @Success 200 {object} response.ResponseData{data=object{tenants=[]models.Tenant,total=int}} "成功"
+// @Failure 403 {object} response.ResponseData "不是超级租户"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/tenants [get]
+func (h *TenantHandler) GetTenants(c *gin.Context) {
+	tenants, total, err := h.tenantService.List(c.Query("query"), c.DefaultQuery("page", "1"), c.DefaultQuery("pageSize", "10"))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Failed to get tenants")
+		return
+	}
+
+	response.Success(c, gin.H{
+		"tenants": tenants,
+		"total":   total,
+	})
+}
+
+// GetTenant godoc
+// @Summary 获取租户详情
+// @Description 根据ID获取租户信息
+// @Tags 租户管理
+// @Accept json
+// @Produce json
+// @Param id path int true "租户ID" minimum(1)
+// @Success 200 {object} response.ResponseData{data=object{tenant=models.Tenant}} "成功"
+// @Failure 400 {object} response.ResponseData "无效的租户ID"
+// @Failure 404 {object} response.ResponseData "租户不存在"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/tenants/{id} [get]
+func (h *TenantHandler) GetTenant(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	tenant, err := h.tenantService.Get(uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrTenantNotFound) {
+			response.Error(c, http.StatusNotFound, "Tenant not found")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to get tenant")
+		return
+	}
+
+	response.Success(c, gin.H{"tenant": tenant})
+}
+
+// CreateTenant godoc
+// @Summary 开通租户
+// @Description 创建租户、拥有全部非平台菜单的租户管理员角色和租户的第一个管理员；租户的管理员和会员登录时填写租户编码
+// @Tags 租户管理
+// @Accept json
+// @Produce json
+// @Param tenant body services.TenantProvisionRequest true "租户信息" example({"name":"示例公司","code":"example","admin_username":"admin","admin_password":"Passw0rd!"})
+// @Success 200 {object} response.ResponseData{data=services.TenantProvisionResult} "成功"
+// @Failure 400 {object} response.ResponseData "请求参数错误或编码无效"
+// @Failure 409 {object} response.ResponseData "租户编码已存在"
+// @Failure 422 {object} response.ResponseData{data=services.PasswordPolicyError} "密码不符合安全策略"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/tenants [post]
+func (h *TenantHandler) CreateTenant(c *gin.Context) {
+	var req services.TenantProvisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.tenantService.Provision(req)
+	if err != nil {
+		if respondPasswordPolicyError(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, services.ErrTenantInvalidCode):
+			response.Error(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrTenantCodeExists):
+			response.Error(c, http.StatusConflict, err.Error())
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to create tenant")
+		}
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// UpdateTenant godoc
+// @Summary 更新租户
+// @Description 更新租户名称、联系人、到期时间等信息，租户编码不能修改
+// @Tags 租户管理
+// @Accept json
+// @Produce json
+// @Param id path int true "租户ID" minimum(1)
+// @Param tenant body services.TenantRequest true "租户信息" example({"name":"示例公司","expires_at":"2026-01-01T00:00:00+08:00"})
+// @Success 200 {object} response.ResponseData{data=object{tenant=models.Tenant}} "成功"
+// @Failure 400 {object} response.ResponseData "请求参数错误"
+// @Failure 404 {object} response.ResponseData "租户不存在"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/tenants/{id} [put]
+func (h *TenantHandler) UpdateTenant(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	var req services.TenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tenant, err := h.tenantService.Update(uint(id), req)
+	if err != nil {
+		if errors.Is(err, services.ErrTenantNotFound) {
+			response.Error(c, http.StatusNotFound, "Tenant not found")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Failed to update tenant")
+		return
+	}
+
+	response.Success(c, gin.H{"tenant": tenant})
+}
+
+// UpdateTenantStatus godoc
+// @Summary 更新租户状态
+// @Description 启用或禁用租户（0：禁用，1：启用），禁用后租户的管理员和会员不能登录，已签发的令牌随即失效；超级租户不能禁用
+// @Tags 租户管理
+// @Accept json
+// @Produce json
+// @Param id path int true "租户ID" minimum(1)
+// @Param status body object true "状态信息" example({"status":0})
+// @Success 200 {object} response.ResponseData{data=object{message=string}} "成功"
+// @Failure 400 {object} response.ResponseData "无效的请求参数"
+// @Failure 404 {object} response.ResponseData "租户不存在"
+// @Failure 500 {object} response.ResponseData "内部错误"
+// @Router /gam/tenants/{id}/status [put]
+func (h *TenantHandler) UpdateTenantStatus(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	var req struct {
+		Status *int `json:"status" binding:"required,oneof=0 1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.tenantService.UpdateStatus(uint(id), *req.Status); err != nil {
+		switch {
+		case errors.Is(err, services.ErrTenantNotFound):
+			response.Error(c, http.StatusNotFound, "Tenant not found")
+		case errors.Is(err, services.ErrTenantSuper):
+			response.Error(c, http.StatusBadRequest, err.Error())
+		default:
+			response.Error(c, http.StatusInternalServerError, "Failed to update tenant status")
+		}
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Tenant status updated successfully"})
+}
@@ -18,6 +18,11 @@ func NewUploadHandler(uploadService services.UploadService) *UploadHandler {
 	}
 }
 
+// uploads 使用请求上下文的上传服务，文件记录属于当前租户
+func (h *UploadHandler) uploads(c *gin.Context) services.UploadService {
+	return h.uploadService.WithContext(c.Request.Context())
+}
+
 // UploadFile godoc
 // @Summary 通用文件上传
 // @Description 上传单个文件，支持多种文件类型
@@ -36,7 +41,7 @@ func (h *UploadHandler) UploadFile(c *gin.Context) {
 		return
 	}
 
-	uploadFile, err := h.uploadService.UploadFile(file, "file")
+	uploadFile, err := h.uploads(c).UploadFile(file, "file")
 	if err != nil {
 		response.Error(c, http.StatusBadRequest, err.Error())
 		return
@@ -67,7 +72,7 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 		return
 	}
 
-	uploadFile, err := h.uploadService.UploadFile(file, "image")
+	uploadFile, err := h.uploads(c).UploadFile(file, "image")
 	if err != nil {
 		response.Error(c, http.StatusBadRequest, err.Error())
 		return
@@ -91,7 +96,7 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 // @Failure 500 {object} response.ResponseData "内部错误"
 // @Router /gam/upload/config [get]
 func (h *UploadHandler) GetUploadConfig(c *gin.Context) {
-	config, err := h.uploadService.GetUploadConfig()
+	config, err := h.uploads(c).GetUploadConfig()
 	if err != nil {
 		response.Error(c, http.StatusBadRequest, err.Error())
 		return
@@ -121,7 +126,7 @@ func (h *UploadHandler) DeleteFile(c *gin.Context) {
 		return
 	}
 
-	if err := h.uploadService.DeleteFile(req.FilePath); err != nil {
+	if err := h.uploads(c).DeleteFile(req.FilePath); err != nil {
 		response.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
@@ -153,7 +158,7 @@ func (h *UploadHandler) BatchUpload(c *gin.Context) {
 		return
 	}
 
-	results, err := h.uploadService.BatchUploadFiles(files, "file")
+	results, err := h.uploads(c).BatchUploadFiles(files, "file")
 	if err != nil {
 		response.Error(c, http.StatusBadRequest, err.Error())
 		return
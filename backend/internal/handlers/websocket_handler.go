@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"normaladmin/backend/config"
 	"normaladmin/backend/internal/middleware"
 	"normaladmin/backend/pkg/utils/response"
 	"normaladmin/backend/pkg/websocket"
@@ -13,14 +14,14 @@ import (
 // WebSocketHandler WebSocket处理器
 type WebSocketHandler struct {
 	notificationHub *websocket.NotificationHub
-	secretKey       string
+	jwtConfig       config.JWTConfig
 }
 
 // NewWebSocketHandler 创建WebSocket处理器
-func NewWebSocketHandler(notificationHub *websocket.NotificationHub, secretKey string) *WebSocketHandler {
+func NewWebSocketHandler(notificationHub *websocket.NotificationHub, jwtConfig config.JWTConfig) *WebSocketHandler {
 	return &WebSocketHandler{
 		notificationHub: notificationHub,
-		secretKey:       secretKey,
+		jwtConfig:       jwtConfig,
 	}
 }
 
@@ -33,12 +34,24 @@ func (h *WebSocketHandler) WebSocketHandler(c *gin.Context) {
 	}
 
 	// 验证token并获取用户信息
-	token, err := middleware.ParseToken(urltoken, h.secretKey)
+	token, err := middleware.ParseToken(urltoken, h.jwtConfig)
 	if err != nil {
 		response.Error(c, http.StatusUnauthorized, "无效的token")
 		return
 	}
 
+	claims, ok := token.Claims.(*middleware.Claims)
+	if !ok || !token.Valid || claims.TokenType != "access" {
+		response.Error(c, http.StatusUnauthorized, "Invalid token claims")
+		return
+	}
+
+	// 已注销的令牌不允许建立连接
+	if middleware.IsClaimsRevoked(claims) {
+		response.Error(c, http.StatusUnauthorized, "token已失效")
+		return
+	}
+
 	upgrader := gorillaws.Upgrader{
 		CheckOrigin: func(r *http.Request) bool {
 			return true // 可以根据需要设置更严格的来源检查
@@ -49,22 +62,18 @@ func (h *WebSocketHandler) WebSocketHandler(c *gin.Context) {
 	if err != nil {
 		return
 	}
-	if claims, ok := token.Claims.(*middleware.Claims); ok && token.Valid {
-		client := websocket.NewClient(
-			conn,
-			claims.UserID,
-			claims.UserType,
-			claims.Username,
-			h.notificationHub,
-		)
 
-		h.notificationHub.Register <- client
+	client := websocket.NewClient(
+		conn,
+		claims.UserID,
+		claims.UserType,
+		claims.Username,
+		claims.FamilyID,
+		h.notificationHub,
+	)
 
-		go client.ReadPump()
-		go client.WritePump()
-	} else {
-		response.Error(c, http.StatusUnauthorized, "Invalid token claims")
-		return
-	}
+	h.notificationHub.Register <- client
 
+	go client.ReadPump()
+	go client.WritePump()
 }
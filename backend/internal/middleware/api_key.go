@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"normaladmin/backend/database"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/internal/services"
+	"normaladmin/backend/pkg/auth"
+	"normaladmin/backend/pkg/logger"
+	"normaladmin/backend/pkg/utils/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// APIKeyScheme API 密钥的认证方式：Authorization: ApiKey <key>
+	APIKeyScheme = "ApiKey"
+	// APIKeyIDKey 通过 API 密钥认证时，密钥ID在 gin.Context 中的 key
+	APIKeyIDKey = "api_key_id"
+
+	// apiKeyTouchInterval 最后使用时间的更新间隔，避免每个请求都写数据库
+	apiKeyTouchInterval = time.Minute
+)
+
+// authenticateAPIKey 校验 API 密钥：未吊销、未过期、来源IP在白名单内、所属管理员及其租户启用，
+// 且请求同时在密钥的权限范围和所属管理员当前的权限内，管理员被降权或菜单接口变更后密钥随之失去对应权限
+// 通过后以所属管理员的身份继续处理请求
+func authenticateAPIKey(c *gin.Context, key string) {
+	db := database.GetDB()
+
+	var apiKey models.APIKey
+	if err := db.Where("key_hash = ?", auth.HashAPIKey(key)).First(&apiKey).Error; err != nil || !apiKey.IsActive() {
+		response.Error(c, http.StatusUnauthorized, "Invalid or expired api key")
+		c.Abort()
+		return
+	}
+
+	if !ipAllowed(apiKey.AllowedIPs, c.ClientIP()) {
+		response.Error(c, http.StatusForbidden, "IP address is not allowed for this api key")
+		c.Abort()
+		return
+	}
+
+	var admin models.Admin
+	if err := db.First(&admin, apiKey.AdminID).Error; err != nil || admin.Status == nil || *admin.Status != 1 {
+		response.Error(c, http.StatusUnauthorized, "Invalid or expired api key")
+		c.Abort()
+		return
+	}
+
+	if !TenantActive(admin.TenantID) {
+		response.Error(c, http.StatusForbidden, "Tenant is disabled or expired")
+		c.Abort()
+		return
+	}
+
+	// 权限范围检查
+	enforcer := auth.GetEnforcer()
+	if enforcer == nil {
+		response.Error(c, http.StatusInternalServerError, "Permission check error")
+		c.Abort()
+		return
+	}
+	domain := auth.TenantDomain(admin.TenantID)
+	ok, err := enforcer.Enforce(auth.APIKeySubject(apiKey.ID), domain, c.Request.URL.Path, c.Request.Method)
+	if err == nil && ok && !services.IsSuperAdmin(db, admin.ID) {
+		ok, err = enforcer.Enforce(auth.AdminSubject(admin.ID), domain, c.Request.URL.Path, c.Request.Method)
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, "Permission check error")
+		c.Abort()
+		return
+	}
+	if !ok {
+		response.Error(c, http.StatusForbidden, "Request is outside the scopes of this api key")
+		c.Abort()
+		return
+	}
+
+	touchAPIKey(&apiKey, c.ClientIP())
+
+	c.Set("user_id", admin.ID)
+	c.Set(SubjectKey, auth.AdminSubject(admin.ID))
+	c.Set("user_type", UserTypeAdmin)
+	c.Set("username", admin.Username)
+	c.Set(APIKeyIDKey, apiKey.ID)
+	SetTenant(c, admin.TenantID)
+	c.Next()
+}
+
+// touchAPIKey 按间隔更新最后使用时间和IP
+func touchAPIKey(apiKey *models.APIKey, ip string) {
+	now := time.Now()
+	if apiKey.LastUsedAt != nil && now.Sub(*apiKey.LastUsedAt) < apiKeyTouchInterval && apiKey.LastUsedIP == ip {
+		return
+	}
+	if err := database.GetDB().Model(&models.APIKey{}).Where("id = ?", apiKey.ID).
+		UpdateColumns(map[string]interface{}{"last_used_at": now, "last_used_ip": ip}).Error; err != nil {
+		logger.Error("更新API密钥使用时间失败", logger.Field("error", err), logger.Field("api_key_id", apiKey.ID))
+	}
+}
+
+// ipAllowed 白名单为空时不限制来源IP
+func ipAllowed(allowed []string, clientIP string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	for _, entry := range allowed {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if allowedIP := net.ParseIP(entry); allowedIP != nil && allowedIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAPIKeyID 当前请求使用的 API 密钥ID，使用令牌认证时返回 false
+func GetAPIKeyID(c *gin.Context) (uint, bool) {
+	value, exists := c.Get(APIKeyIDKey)
+	if !exists {
+		return 0, false
+	}
+	id, ok := value.(uint)
+	return id, ok
+}
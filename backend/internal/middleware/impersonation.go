@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"normaladmin/backend/pkg/utils/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ImpersonatorIDKey 模拟登录时，管理员ID在 gin.Context 中的 key
+const ImpersonatorIDKey = "impersonator_id"
+
+// GetImpersonatorID 当前请求是否由管理员模拟登录发起
+func GetImpersonatorID(c *gin.Context) (uint, bool) {
+	value, exists := c.Get(ImpersonatorIDKey)
+	if !exists {
+		return 0, false
+	}
+	id, ok := value.(uint)
+	return id, ok && id > 0
+}
+
+// DenyImpersonation 禁止模拟登录的令牌执行敏感操作，如修改密码、注销账号
+func DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := GetImpersonatorID(c); ok {
+			response.Error(c, http.StatusForbidden, "模拟登录时不允许执行该操作")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// ImpersonationLogger 只记录模拟登录发起的请求，用于会员端接口的审计
+func ImpersonationLogger(db *gorm.DB) gin.HandlerFunc {
+	logRequest := RequestLoggerMiddleware(db)
+	return func(c *gin.Context) {
+		if _, ok := GetImpersonatorID(c); !ok {
+			c.Next()
+			return
+		}
+		logRequest(c)
+	}
+}
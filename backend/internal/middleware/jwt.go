@@ -4,6 +4,10 @@ import (
 	"errors"
 	"net/http"
 	"normaladmin/backend/config"
+	"normaladmin/backend/database"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/auth"
+	"normaladmin/backend/pkg/tenant"
 	"normaladmin/backend/pkg/utils/response"
 	"strings"
 	"time"
@@ -12,65 +16,309 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// ClaimsKey 令牌声明在 gin.Context 中的 key
+const ClaimsKey = "claims"
+
+// SubjectKey 当前用户在 Casbin 策略中的主体在 gin.Context 中的 key
+const SubjectKey = "subject"
+
+// 用户类型
+const (
+	UserTypeAdmin  = "admin"
+	UserTypeMember = "member"
+)
+
+// 令牌受众，/gam 只接受管理员令牌，/api 只接受会员令牌
+const (
+	AudienceAdmin  = "gam"
+	AudienceMember = "api"
+)
+
+// Claims 令牌声明，访问令牌和刷新令牌的 sub 为用户主体（如 admin:1），
+// 管理员的角色在请求时按主体的分组策略确定，修改角色后无需重新签发令牌
 type Claims struct {
 	UserID    uint   `json:"user_id"`
-	RoleID    uint   `json:"role_id"`
+	LevelID   uint   `json:"level_id,omitempty"` // 会员等级ID
 	UserType  string `json:"user_type"`
 	Username  string `json:"username"`
 	TokenType string `json:"token_type"` // access 或 refresh
+	FamilyID  string `json:"fid"`        // 令牌家族ID，同一次登录轮换出的令牌共享
+	// ImpersonatorID 模拟登录的管理员ID，仅出现在管理员模拟会员时签发的令牌中
+	ImpersonatorID uint `json:"impersonator_id,omitempty"`
+	// TenantID 用户所属租户，开启多租户前签发的令牌没有该声明，视为超级租户
+	TenantID uint `json:"tid,omitempty"`
+	// IssuedAtMilli 毫秒精度的签发时间，用于与用户令牌吊销时间比较；标准声明 iat 只精确到秒
+	IssuedAtMilli int64 `json:"iat_ms,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID uint, RoleID uint, userType string, username string, config config.JWTConfig) (string, string, error) {
+// TokenUser 令牌签发对象
+type TokenUser struct {
+	UserID   uint
+	UserType string
+	Username string
+	LevelID  uint // 仅会员
+	TenantID uint // 所属租户，取自用户记录
+}
+
+// Subject 用户在 Casbin 策略中的主体
+func (u TokenUser) Subject() string {
+	return auth.UserSubject(u.UserType, u.UserID)
+}
+
+// TokenUser 从令牌声明还原签发对象，用于刷新令牌
+func (c *Claims) TokenUser() TokenUser {
+	return TokenUser{
+		UserID:   c.UserID,
+		UserType: c.UserType,
+		Username: c.Username,
+		LevelID:  c.LevelID,
+		TenantID: c.Tenant(),
+	}
+}
+
+// Tenant 令牌所属租户，没有租户声明的旧令牌属于超级租户
+func (c *Claims) Tenant() uint {
+	if c.TenantID == 0 {
+		return tenant.SuperTenantID
+	}
+	return c.TenantID
+}
+
+// AudienceFor 获取用户类型对应的令牌受众
+func AudienceFor(userType string) string {
+	if userType == UserTypeMember {
+		return AudienceMember
+	}
+	return AudienceAdmin
+}
+
+// AccessTokenTTL 访问令牌有效期
+func AccessTokenTTL(config config.JWTConfig) time.Duration {
+	return time.Duration(config.ExpireTime) * time.Hour
+}
+
+// RefreshTokenTTL 刷新令牌有效期
+func RefreshTokenTTL(config config.JWTConfig) time.Duration {
+	return 7 * AccessTokenTTL(config)
+}
+
+// GenerateToken 签发访问令牌和刷新令牌，familyID 为空时开启新的令牌家族
+func GenerateToken(user TokenUser, familyID string, config config.JWTConfig) (string, string, error) {
+	if familyID == "" {
+		familyID = auth.NewTokenID()
+	}
+	now := time.Now()
+	audience := jwt.ClaimStrings{AudienceFor(user.UserType)}
+
+	newClaims := func(tokenType string, ttl time.Duration) Claims {
+		return Claims{
+			UserID:        user.UserID,
+			LevelID:       user.LevelID,
+			UserType:      user.UserType,
+			Username:      user.Username,
+			TokenType:     tokenType,
+			FamilyID:      familyID,
+			TenantID:      user.TenantID,
+			IssuedAtMilli: now.UnixMilli(),
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        auth.NewTokenID(),
+				Issuer:    config.Issuer,
+				Subject:   user.Subject(),
+				Audience:  audience,
+				ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+				IssuedAt:  jwt.NewNumericDate(now),
+			},
+		}
+	}
+
 	// 访问令牌 - 短期(如24小时)
-	accessClaims := Claims{
-		UserID:    userID,
-		RoleID:    RoleID,
-		UserType:  userType,
-		Username:  username,
-		TokenType: "access",
+	accessClaims := newClaims("access", AccessTokenTTL(config))
+	accessTokenString, err := signToken(accessClaims, config)
+	if err != nil {
+		return "", "", err
+	}
+
+	// 刷新令牌 - 长期(如7天)
+	refreshClaims := newClaims("refresh", RefreshTokenTTL(config))
+	refreshTokenString, err := signToken(refreshClaims, config)
+	if err != nil {
+		return "", "", err
+	}
+
+	// 登记刷新令牌，用于轮换和吊销
+	if err := auth.SaveRefreshToken(refreshClaims.ID, familyID, RefreshTokenTTL(config)); err != nil {
+		return "", "", err
+	}
+
+	return accessTokenString, refreshTokenString, nil
+}
+
+// GenerateImpersonationToken 签发管理员模拟会员的访问令牌
+// 只签发短期访问令牌，不签发刷新令牌；令牌家族用于结束模拟登录时吊销
+func GenerateImpersonationToken(user TokenUser, impersonatorID uint, ttl time.Duration, config config.JWTConfig) (string, *Claims, error) {
+	now := time.Now()
+	claims := &Claims{
+		UserID:         user.UserID,
+		LevelID:        user.LevelID,
+		UserType:       user.UserType,
+		Username:       user.Username,
+		TokenType:      "access",
+		FamilyID:       auth.NewTokenID(),
+		ImpersonatorID: impersonatorID,
+		TenantID:       user.TenantID,
+		IssuedAtMilli:  now.UnixMilli(),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(config.ExpireTime) * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        auth.NewTokenID(),
+			Issuer:    config.Issuer,
+			Subject:   user.Subject(),
+			Audience:  jwt.ClaimStrings{AudienceFor(user.UserType)},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessTokenString, err := accessToken.SignedString([]byte(config.SecretKey))
+	token, err := signToken(*claims, config)
 	if err != nil {
-		return "", "", err
+		return "", nil, err
 	}
+	return token, claims, nil
+}
 
-	// 刷新令牌 - 长期(如7天)
-	refreshClaims := Claims{
-		UserID:    userID,
-		RoleID:    RoleID,
-		UserType:  userType,
-		Username:  username,
-		TokenType: "refresh",
+// 待确认令牌类型，只能用于完成登录流程中的对应步骤
+const (
+	TokenTypeMFAPending     = "mfa_pending"     // 等待两步验证
+	TokenTypePasswordChange = "password_change" // 密码已过期，等待修改密码
+	TokenTypeEmailVerify    = "email_verify"    // 邮箱验证链接
+	TokenTypePasswordReset  = "password_reset"  // 找回密码链接
+)
+
+// MFATokenTTL 两步验证待确认令牌有效期
+const MFATokenTTL = 5 * time.Minute
+
+// PasswordTokenTTL 密码过期待修改令牌有效期
+const PasswordTokenTTL = 10 * time.Minute
+
+// GeneratePendingToken 签发登录流程中的短期待确认令牌
+func GeneratePendingToken(userType string, userID uint, username, tokenType string, ttl time.Duration, config config.JWTConfig) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		UserID:        userID,
+		UserType:      userType,
+		Username:      username,
+		TokenType:     tokenType,
+		IssuedAtMilli: now.UnixMilli(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        auth.NewTokenID(),
+			Issuer:    config.Issuer,
+			Audience:  jwt.ClaimStrings{AudienceFor(userType)},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	return signToken(claims, config)
+}
+
+// GenerateEmailToken 签发邮件链接中的令牌，sub 为收件邮箱，邮箱变更后令牌失效
+func GenerateEmailToken(userType string, userID uint, username, email, tokenType string, ttl time.Duration, config config.JWTConfig) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		UserID:        userID,
+		UserType:      userType,
+		Username:      username,
+		TokenType:     tokenType,
+		IssuedAtMilli: now.UnixMilli(),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * time.Duration(config.ExpireTime) * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        auth.NewTokenID(),
+			Issuer:    config.Issuer,
+			Subject:   email,
+			Audience:  jwt.ClaimStrings{AudienceFor(userType)},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString([]byte(config.SecretKey))
+	return signToken(claims, config)
+}
+
+// ParsePendingToken 解析待确认令牌，校验令牌类型、用户类型以及是否已被使用
+func ParsePendingToken(tokenString, userType, tokenType string, config config.JWTConfig) (*Claims, error) {
+	token, err := ParseToken(tokenString, config)
 	if err != nil {
-		return "", "", err
+		return nil, err
+	}
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid || claims.TokenType != tokenType || !claims.VerifyUserType(userType) {
+		return nil, errors.New("invalid pending token")
+	}
+	if IsClaimsRevoked(claims) {
+		return nil, errors.New("pending token has been used")
 	}
+	return claims, nil
+}
 
-	return accessTokenString, refreshTokenString, nil
+// GenerateMFAToken 签发两步验证待确认令牌，只能用于完成登录的第二步
+func GenerateMFAToken(userID uint, username string, config config.JWTConfig) (string, error) {
+	return GeneratePendingToken(UserTypeAdmin, userID, username, TokenTypeMFAPending, MFATokenTTL, config)
+}
+
+// ParseMFAToken 解析两步验证待确认令牌
+func ParseMFAToken(tokenString string, config config.JWTConfig) (*Claims, error) {
+	return ParsePendingToken(tokenString, UserTypeAdmin, TokenTypeMFAPending, config)
+}
+
+// UserSubject 令牌所属用户在 Casbin 策略中的主体，兼容没有 sub 的旧令牌
+func (c *Claims) UserSubject() string {
+	if c.Subject != "" {
+		return c.Subject
+	}
+	return c.TokenUser().Subject()
 }
 
-func ParseToken(tokenString string, secretKey string) (*jwt.Token, error) {
+// VerifyUserType 校验令牌的用户类型与受众是否与路由分组一致
+func (c *Claims) VerifyUserType(userType string) bool {
+	return c.UserType == userType && c.VerifyAudience(AudienceFor(userType), true)
+}
+
+// signToken 签名令牌：非对称模式使用当前签名密钥并写入 kid，否则使用 HS256
+func signToken(claims Claims, config config.JWTConfig) (string, error) {
+	if keys := auth.Keys(); keys != nil {
+		key := keys.Current()
+		if key == nil {
+			return "", auth.ErrNoSigningKey
+		}
+		token := jwt.NewWithClaims(key.Method, claims)
+		token.Header["kid"] = key.ID
+		return token.SignedString(key.Private)
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.SecretKey))
+}
+
+// ParseToken 解析并验证令牌签名
+// 非对称模式下按 kid 查找验证密钥，且只接受与密钥匹配的算法；HS256 模式只接受 HMAC 签名
+func ParseToken(tokenString string, config config.JWTConfig) (*jwt.Token, error) {
 	return jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		keys := auth.Keys()
+		if keys == nil {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, errors.New("invalid signing method")
+			}
+			return []byte(config.SecretKey), nil
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keys.Lookup(kid)
+		if !ok {
+			return nil, auth.ErrUnknownKeyID
+		}
+		if token.Method.Alg() != key.Method.Alg() {
 			return nil, errors.New("invalid signing method")
 		}
-		return []byte(secretKey), nil
+		return key.Public, nil
 	})
 }
 
-func JWTAuth(cfg config.JWTConfig) gin.HandlerFunc {
+// JWTAuth 认证中间件，userType 限定该路由分组接受的用户类型
+// 管理端还接受 Authorization: ApiKey <key>，以密钥所属管理员的身份访问密钥权限范围内的接口
+func JWTAuth(cfg config.JWTConfig, userType string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -79,8 +327,12 @@ func JWTAuth(cfg config.JWTConfig) gin.HandlerFunc {
 			return
 		}
 
-		// 检查 Bearer 前缀
+		// 检查 Bearer 前缀，管理端同时接受 API 密钥
 		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) == 2 && parts[0] == APIKeyScheme && userType == UserTypeAdmin {
+			authenticateAPIKey(c, parts[1])
+			return
+		}
 		if !(len(parts) == 2 && parts[0] == "Bearer") {
 			response.Error(c, http.StatusUnauthorized, "Invalid authorization format")
 			c.Abort()
@@ -88,24 +340,94 @@ func JWTAuth(cfg config.JWTConfig) gin.HandlerFunc {
 		}
 
 		// 解析 token
-		token, err := ParseToken(parts[1], cfg.SecretKey)
+		token, err := ParseToken(parts[1], cfg)
 		if err != nil {
 			response.Error(c, http.StatusUnauthorized, "Invalid or expired token")
 			c.Abort()
 			return
 		}
 
-		if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-			// 将用户ID存储在上下文中
-			c.Set("user_id", claims.UserID)
-			c.Set("role_id", claims.RoleID)
-			c.Set("user_type", claims.UserType)
-			c.Set("username", claims.Username)
-			c.Next()
-		} else {
+		claims, ok := token.Claims.(*Claims)
+		if !ok || !token.Valid || claims.TokenType != "access" {
 			response.Error(c, http.StatusUnauthorized, "Invalid token claims")
 			c.Abort()
 			return
 		}
+
+		// 管理端与会员端令牌互不通用
+		if !claims.VerifyUserType(userType) {
+			response.Error(c, http.StatusForbidden, "Token is not allowed for this API")
+			c.Abort()
+			return
+		}
+
+		// 检查令牌是否已注销
+		if IsClaimsRevoked(claims) {
+			response.Error(c, http.StatusUnauthorized, "Token has been revoked")
+			c.Abort()
+			return
+		}
+
+		// 所属租户被禁用或到期后，已签发的令牌随即失效
+		if !TenantActive(claims.Tenant()) {
+			response.Error(c, http.StatusForbidden, "Tenant is disabled or expired")
+			c.Abort()
+			return
+		}
+
+		// 记录会话最后活跃时间
+		TouchSession(claims, cfg)
+
+		// 将用户ID存储在上下文中
+		c.Set("user_id", claims.UserID)
+		if claims.UserType == UserTypeMember {
+			c.Set("level_id", claims.LevelID)
+		}
+		c.Set(SubjectKey, claims.UserSubject())
+		c.Set("user_type", claims.UserType)
+		c.Set("username", claims.Username)
+		if claims.ImpersonatorID > 0 {
+			c.Set(ImpersonatorIDKey, claims.ImpersonatorID)
+		}
+		c.Set(ClaimsKey, claims)
+		SetTenant(c, claims.Tenant())
+		c.Next()
+	}
+}
+
+// IssuedTime 令牌的签发时间，优先使用毫秒精度的 iat_ms；没有该声明的令牌按 iat 取整到秒，比较时视为更早签发
+func (c *Claims) IssuedTime() time.Time {
+	if c.IssuedAtMilli > 0 {
+		return time.UnixMilli(c.IssuedAtMilli)
+	}
+	if c.IssuedAt != nil {
+		return c.IssuedAt.Time
+	}
+	return time.Time{}
+}
+
+// IsClaimsRevoked 检查令牌是否在黑名单中，或其令牌家族、所属用户的令牌已被吊销
+func IsClaimsRevoked(claims *Claims) bool {
+	return auth.IsTokenRevoked(claims.ID, claims.FamilyID, claims.UserType, claims.UserID, claims.IssuedTime())
+}
+
+// TouchSession 更新令牌所属会话的最后活跃时间，并按节流频率同步到数据库镜像
+func TouchSession(claims *Claims, cfg config.JWTConfig) {
+	session, updated := auth.TouchSession(claims.UserType, claims.UserID, claims.FamilyID, RefreshTokenTTL(cfg))
+	if !updated {
+		return
+	}
+	database.GetDB().Model(&models.UserSession{}).
+		Where("session_id = ?", session.ID).
+		Updates(map[string]interface{}{"last_seen_at": session.LastSeenAt, "expires_at": session.ExpiresAt})
+}
+
+// GetClaims 从上下文获取当前请求的令牌声明
+func GetClaims(c *gin.Context) (*Claims, bool) {
+	value, exists := c.Get(ClaimsKey)
+	if !exists {
+		return nil, false
 	}
+	claims, ok := value.(*Claims)
+	return claims, ok
 }
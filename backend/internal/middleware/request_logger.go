@@ -26,6 +26,14 @@ func (r ResponseBodyWriter) Write(b []byte) (int, error) {
 	return r.ResponseWriter.Write(b)
 }
 
+// skipResponseLogKey 标记本次请求的响应不写入日志
+const skipResponseLogKey = "skip_response_log"
+
+// SkipResponseLog 响应中包含只展示一次的密钥或令牌时调用，日志中不记录响应内容
+func SkipResponseLog(c *gin.Context) {
+	c.Set(skipResponseLogKey, true)
+}
+
 // RequestLoggerMiddleware 请求日志中间件
 func RequestLoggerMiddleware(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -77,9 +85,12 @@ func RequestLoggerMiddleware(db *gorm.DB) gin.HandlerFunc {
 			}
 		}
 
-		// 如果需要用户名，可以根据 userID 查询数据库
+		// 会员请求（模拟登录）直接使用令牌中的用户名，管理员请求根据 userID 查询数据库
+		isMember := c.GetString("user_type") == UserTypeMember
+		username := c.GetString("username")
+		impersonatorID, _ := GetImpersonatorID(c)
 
-		if userID > 0 {
+		if userID > 0 && !isMember {
 			// 异步查询用户名，避免阻塞请求
 			go func(uid uint) {
 				var admin models.Admin
@@ -108,35 +119,47 @@ func RequestLoggerMiddleware(db *gorm.DB) gin.HandlerFunc {
 		status := c.Writer.Status()
 		responseBody := responseWriter.body.String()
 
+		// 响应中包含密钥、令牌等敏感信息时不记录
+		if c.GetBool(skipResponseLogKey) {
+			responseBody = "[已省略]"
+		}
 		// 截断过长的响应内容
 		if len(responseBody) > 1000 {
 			responseBody = responseBody[:1000] + "... [截断]"
 		}
 
+		// 通过 API 密钥调用时记录密钥ID
+		apiKeyID, _ := GetAPIKeyID(c)
+
 		// 确定模块和操作
 		module := getModuleFromPath(path)
 		action := getActionFromMethod(method, path)
 
 		// 创建日志记录
 		log := models.SystemLog{
-			UserID:    userID,
-			Module:    module,
-			Action:    action,
-			Method:    method,
-			URL:       path,
-			IP:        ip,
-			UserAgent: userAgent,
-			Params:    params,
-			Result:    responseBody,
-			Status:    status,
-			Duration:  duration,
-			CreatedAt: endTime,
+			TenantID:       GetTenantID(c),
+			UserID:         userID,
+			APIKeyID:       apiKeyID,
+			ImpersonatorID: impersonatorID,
+			Module:         module,
+			Action:         action,
+			Method:         method,
+			URL:            path,
+			IP:             ip,
+			UserAgent:      userAgent,
+			Params:         params,
+			Result:         responseBody,
+			Status:         status,
+			Duration:       duration,
+			CreatedAt:      endTime,
 		}
 
 		// 异步保存日志到数据库
 		go func(log models.SystemLog) {
 			// 如果有用户ID，尝试查询用户名
-			if log.UserID > 0 {
+			if isMember {
+				log.Username = username
+			} else if log.UserID > 0 {
 				var admin models.Admin
 				if err := db.Select("username").First(&admin, log.UserID).Error; err == nil {
 					log.Username = admin.Username
@@ -163,6 +186,7 @@ func RequestLoggerMiddleware(db *gorm.DB) gin.HandlerFunc {
 			logger.Error(logMsg,
 				logger.Field("ip", ip),
 				logger.Field("user_id", userID),
+				logger.Field("impersonator_id", impersonatorID),
 				logger.Field("params", params),
 				logger.Field("error", responseBody),
 			)
@@ -170,6 +194,7 @@ func RequestLoggerMiddleware(db *gorm.DB) gin.HandlerFunc {
 			logger.Info(logMsg,
 				logger.Field("ip", ip),
 				logger.Field("user_id", userID),
+				logger.Field("impersonator_id", impersonatorID),
 			)
 		}
 	}
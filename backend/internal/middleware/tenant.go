@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"normaladmin/backend/database"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/tenant"
+	"normaladmin/backend/pkg/utils/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantIDKey 当前用户所属租户在 gin.Context 中的 key
+const TenantIDKey = "tenant_id"
+
+// SetTenant 记录当前请求的租户，并写入请求上下文，
+// 使用 c.Request.Context() 的查询只能访问该租户的数据
+func SetTenant(c *gin.Context, tenantID uint) {
+	if tenantID == 0 {
+		tenantID = tenant.SuperTenantID
+	}
+	c.Set(TenantIDKey, tenantID)
+	c.Request = c.Request.WithContext(tenant.WithTenant(c.Request.Context(), tenantID))
+}
+
+// GetTenantID 当前请求的租户，未认证的请求返回 0
+func GetTenantID(c *gin.Context) uint {
+	value, exists := c.Get(TenantIDKey)
+	if !exists {
+		return 0
+	}
+	id, _ := value.(uint)
+	return id
+}
+
+// TenantActive 租户是否启用且未到期，超级租户始终可用
+func TenantActive(tenantID uint) bool {
+	if tenantID == 0 || tenantID == tenant.SuperTenantID {
+		return true
+	}
+	var t models.Tenant
+	if err := database.GetDB().Select("id", "status", "expires_at").First(&t, tenantID).Error; err != nil {
+		return false
+	}
+	return t.Active(time.Now())
+}
+
+// SuperTenantOnly 只允许超级租户的用户访问，用于租户管理等平台级接口
+func SuperTenantOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if GetTenantID(c) != tenant.SuperTenantID {
+			response.Error(c, http.StatusForbidden, "Permission denied")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -8,20 +8,26 @@ import (
 )
 
 type Admin struct {
-	ID            uint           `json:"id" gorm:"primarykey"`
-	Username      string         `json:"username" gorm:"unique;not null"`
-	Password      string         `json:"-" gorm:"not null"` // json:"-" 表示不返回密码
-	Email         string         `json:"email" gorm:"size:100"`
-	Phone         string         `json:"phone" gorm:"size:20"`
-	RealName      string         `json:"real_name" gorm:"size:50"`
-	Avatar        string         `json:"avatar" gorm:"size:255"`
-	RoleID        uint           `json:"role_id"`
-	Role          Role           `json:"role" gorm:"foreignKey:RoleID"`
-	Status        *int           `json:"status" gorm:"default:1"` // 1-启用 0-禁用
-	LastLoginTime *time.Time     `json:"last_login_time"`         // 改为指针类型，允许为 null
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggerignore:"true"`
+	ID                uint           `json:"id" gorm:"primarykey"`
+	TenantID          uint           `json:"-" gorm:"not null;default:1;uniqueIndex:idx_admins_tenant_username,priority:1"` // 所属租户
+	Username          string         `json:"username" gorm:"size:191;not null;uniqueIndex:idx_admins_tenant_username,priority:2"`
+	Password          string         `json:"-" gorm:"not null"` // json:"-" 表示不返回密码
+	Email             string         `json:"email" gorm:"size:100" mask:"email"`
+	Phone             string         `json:"phone" gorm:"size:20" mask:"mobile"`
+	RealName          string         `json:"real_name" gorm:"size:50"`
+	Avatar            string         `json:"avatar" gorm:"size:255"`
+	RoleID            uint           `json:"role_id"` // 主角色，为 RoleIDs 中的第一个角色
+	Role              Role           `json:"role" gorm:"foreignKey:RoleID"`
+	RoleIDs           []uint         `json:"role_ids" gorm:"-"`                                   // 全部角色，保存在 admin_roles 中
+	DepartmentID      uint           `json:"department_id" gorm:"default:0;index"`                // 所属部门，0 表示未分配
+	Status            *int           `json:"status" gorm:"default:1"`                             // 1-启用 0-禁用
+	LastLoginTime     *time.Time     `json:"last_login_time"`                                     // 改为指针类型，允许为 null
+	PasswordChangedAt *time.Time     `json:"password_changed_at"`                                 // 最后修改密码时间，用于密码过期策略
+	MFAEnabled        bool           `json:"mfa_enabled" gorm:"column:mfa_enabled;default:false"` // 是否已启用两步验证
+	MFASecret         string         `json:"-" gorm:"column:mfa_secret;size:255"`                 // TOTP密钥（加密存储）
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggerignore:"true"`
 }
 
 // TableName 指定表名
@@ -29,6 +35,33 @@ func (Admin) TableName() string {
 	return "admins"
 }
 
+// AdminFilters 管理员列表可用的过滤和排序字段；邮箱、手机号受字段权限保护，不能用于过滤
+var AdminFilters = NewFilterSpec(
+	FilterField{Name: "id", Type: FilterUint, Ops: NumberOps, Sortable: true},
+	FilterField{Name: "username", Type: FilterString, Ops: StringOps, Sortable: true},
+	FilterField{Name: "real_name", Type: FilterString, Ops: StringOps, Sortable: true},
+	FilterField{Name: "role_id", Type: FilterUint, Ops: []FilterOp{OpEq, OpNe, OpIn}},
+	FilterField{Name: "department_id", Type: FilterUint, Ops: []FilterOp{OpEq, OpNe, OpIn}, Sortable: true},
+	FilterField{Name: "status", Type: FilterInt, Ops: []FilterOp{OpEq, OpNe, OpIn}, Sortable: true},
+	FilterField{Name: "mfa_enabled", Type: FilterBool, Ops: []FilterOp{OpEq}},
+	FilterField{Name: "last_login_time", Type: FilterTime, Ops: append(TimeOps, OpIsNull), Sortable: true},
+	FilterField{Name: "created_at", Type: FilterTime, Ops: TimeOps, Sortable: true},
+	FilterField{Name: "updated_at", Type: FilterTime, Ops: TimeOps, Sortable: true},
+)
+
+// AdminRole 管理员角色关联模型，管理员拥有全部已启用角色的菜单和权限
+type AdminRole struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	AdminID   uint      `json:"admin_id" gorm:"column:admin_id;not null;uniqueIndex:idx_admin_role;comment:管理员ID"`
+	RoleID    uint      `json:"role_id" gorm:"column:role_id;not null;uniqueIndex:idx_admin_role;index;comment:角色ID"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (AdminRole) TableName() string {
+	return "admin_roles"
+}
+
 // CheckPassword 验证密码是否正确
 func (a *Admin) CheckPassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(a.Password), []byte(password))
@@ -60,3 +93,8 @@ func (a *Admin) BeforeUpdate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// DataScopeColumns 管理员按数据范围过滤时，以记录本身作为所属人，按所属部门过滤
+func (Admin) DataScopeColumns() (ownerColumn, departmentColumn string) {
+	return "id", "department_id"
+}
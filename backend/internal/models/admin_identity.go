@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// AdminIdentity 管理员的外部身份，用于单点登录时关联本地账号
+type AdminIdentity struct {
+	ID          uint       `json:"id" gorm:"primarykey"`
+	AdminID     uint       `json:"admin_id" gorm:"not null;index;comment:管理员ID"`
+	Issuer      string     `json:"issuer" gorm:"size:255;not null;uniqueIndex:idx_admin_identities_subject;comment:身份提供方"`
+	Subject     string     `json:"subject" gorm:"size:255;not null;uniqueIndex:idx_admin_identities_subject;comment:身份提供方用户标识"`
+	Email       string     `json:"email" gorm:"size:100;comment:邮箱"`
+	LastLoginAt *time.Time `json:"last_login_at" gorm:"comment:最后登录时间"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (AdminIdentity) TableName() string {
+	return "admin_identities"
+}
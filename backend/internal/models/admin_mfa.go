@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// AdminRecoveryCode 两步验证恢复码，仅保存哈希值
+type AdminRecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	AdminID   uint       `json:"admin_id" gorm:"not null;index;comment:管理员ID"`
+	CodeHash  string     `json:"-" gorm:"size:100;not null;comment:恢复码哈希"`
+	UsedAt    *time.Time `json:"used_at" gorm:"comment:使用时间"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (AdminRecoveryCode) TableName() string {
+	return "admin_recovery_codes"
+}
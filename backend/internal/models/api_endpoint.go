@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// ApiEndpoint 接口目录，启动时按已注册的路由自动维护，按钮菜单的 ApiMethod/ApiPath 需与之匹配
+type ApiEndpoint struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	Method     string     `json:"method" gorm:"size:10;not null;uniqueIndex:idx_api_endpoint"`
+	Path       string     `json:"path" gorm:"size:255;not null;uniqueIndex:idx_api_endpoint"`
+	Group      string     `json:"group" gorm:"column:route_group;size:50;index;comment:分组，如 admins"`
+	Handler    string     `json:"handler" gorm:"size:255;comment:处理函数"`
+	Summary    string     `json:"summary" gorm:"size:255;comment:Swagger 摘要"`
+	Stale      bool       `json:"stale" gorm:"default:false;index;comment:路由已不存在"`
+	LastSeenAt *time.Time `json:"last_seen_at" gorm:"comment:最后一次在路由中出现的时间"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (ApiEndpoint) TableName() string {
+	return "api_endpoints"
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// APIKey 管理员创建的 API 密钥，供脚本和内部工具调用管理接口
+// 只保存密钥哈希，权限范围映射为以 apikey:<id> 为主体的 Casbin 策略
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	AdminID    uint       `json:"admin_id" gorm:"not null;index;comment:所属管理员ID"`
+	Name       string     `json:"name" gorm:"size:100;not null;comment:密钥名称"`
+	Prefix     string     `json:"prefix" gorm:"size:20;not null;comment:密钥开头，用于辨认"`
+	KeyHash    string     `json:"-" gorm:"size:64;not null;uniqueIndex;comment:密钥哈希"`
+	Scopes     []string   `json:"scopes" gorm:"serializer:json;type:text;comment:权限范围（按钮权限标识）"`
+	AllowedIPs []string   `json:"allowed_ips" gorm:"column:allowed_ips;serializer:json;type:text;comment:IP白名单，支持CIDR，为空不限制"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null;comment:过期时间"`
+	LastUsedAt *time.Time `json:"last_used_at" gorm:"comment:最后使用时间"`
+	LastUsedIP string     `json:"last_used_ip" gorm:"size:50;comment:最后使用IP"`
+	RevokedAt  *time.Time `json:"revoked_at" gorm:"comment:吊销时间"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// IsActive 未吊销且未过期
+func (k *APIKey) IsActive() bool {
+	return k.RevokedAt == nil && time.Now().Before(k.ExpiresAt)
+}
@@ -10,8 +10,9 @@ import (
 // ConfigGroup 配置组
 type ConfigGroup struct {
 	gorm.Model     `swaggerignore:"true"`
-	ID             uint   `json:"id" gorm:"primaryKey;autoIncrement"` // 将 ID 字段的 JSON 标签设置为 "id"
-	ConfigKey      string `gorm:"column:config_key;type:varchar(50);uniqueIndex;not null" json:"config_key"`
+	ID             uint   `json:"id" gorm:"primaryKey;autoIncrement"`                                                               // 将 ID 字段的 JSON 标签设置为 "id"
+	TenantID       uint   `gorm:"column:tenant_id;not null;default:1;uniqueIndex:idx_config_groups_tenant_key,priority:1" json:"-"` // 所属租户，系统配置（sysconfig）读取超级租户的配置
+	ConfigKey      string `gorm:"column:config_key;type:varchar(50);not null;uniqueIndex:idx_config_groups_tenant_key,priority:2" json:"config_key"`
 	ConfigName     string `gorm:"column:config_name;type:varchar(100);not null" json:"config_name"`
 	Description    string `gorm:"column:description;type:text" json:"description"`
 	Icon           string `gorm:"column:icon;type:varchar(50)" json:"icon"`
@@ -27,7 +28,8 @@ type ConfigGroup struct {
 // ConfigItem 配置项
 type ConfigItem struct {
 	gorm.Model       `swaggerignore:"true"`
-	ID               uint        `json:"id" gorm:"primaryKey;autoIncrement"` // 将 ID 字段的 JSON 标签设置为 "id"
+	ID               uint        `json:"id" gorm:"primaryKey;autoIncrement"`                 // 将 ID 字段的 JSON 标签设置为 "id"
+	TenantID         uint        `gorm:"column:tenant_id;not null;default:1;index" json:"-"` // 所属租户，与配置组一致
 	GroupID          int64       `gorm:"column:group_id;not null" json:"group_id"`
 	ItemKey          string      `gorm:"column:item_key;type:varchar(50);not null" json:"item_key"`
 	ItemName         string      `gorm:"column:item_name;type:varchar(100);not null" json:"item_name"`
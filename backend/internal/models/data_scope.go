@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 角色数据范围，对应 Role.DataScope
+const (
+	DataScopeAll          = 1 // 全部数据
+	DataScopeCustom       = 2 // 自定义部门
+	DataScopeDept         = 3 // 本部门
+	DataScopeDeptAndBelow = 4 // 本部门及以下
+	DataScopeSelf         = 5 // 仅本人
+)
+
+// dataScopeSetting 数据范围在 gorm 会话中的键
+const dataScopeSetting = "normaladmin:data_scope"
+
+// RoleDepartment 角色自定义数据范围的部门
+type RoleDepartment struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	RoleID       uint      `json:"role_id" gorm:"column:role_id;not null;index;comment:角色ID"`
+	DepartmentID uint      `json:"department_id" gorm:"column:department_id;not null;comment:部门ID"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
+}
+
+// TableName 指定表名
+func (RoleDepartment) TableName() string {
+	return "role_departments"
+}
+
+// DataScoped 需要按数据范围过滤的资源，返回所属人和所属部门的列名，没有的返回空字符串
+type DataScoped interface {
+	DataScopeColumns() (ownerColumn, departmentColumn string)
+}
+
+// DataScope 当前管理员的数据范围，拥有多个角色时取各角色范围的并集
+// All 为 true 时不过滤；否则只能访问所属部门在 DepartmentIDs 中或所属人为 UserID 的数据
+type DataScope struct {
+	All           bool   `json:"all"`
+	Scopes        []int  `json:"scopes"` // 参与计算的角色数据范围
+	UserID        uint   `json:"user_id"`
+	DepartmentIDs []uint `json:"department_ids"`
+}
+
+// WithDataScope 创建数据范围查询选项，只对实现了 DataScoped 的资源生效，由 ApplyDataScope 应用
+func WithDataScope(scope *DataScope) QueryOption {
+	return func(db *gorm.DB) *gorm.DB {
+		if scope == nil {
+			return db
+		}
+		return db.Set(dataScopeSetting, scope)
+	}
+}
+
+// ApplyDataScope 按会话中的数据范围为 model 对应的资源添加过滤条件
+// 资源未实现 DataScoped 或会话中没有数据范围（系统内部调用）时不过滤
+func ApplyDataScope(db *gorm.DB, model interface{}) *gorm.DB {
+	scoped, ok := model.(DataScoped)
+	if !ok {
+		return db
+	}
+	value, ok := db.Get(dataScopeSetting)
+	if !ok {
+		return db
+	}
+	scope, ok := value.(*DataScope)
+	if !ok || scope.All {
+		return db
+	}
+
+	ownerColumn, departmentColumn := scoped.DataScopeColumns()
+	var conditions []clause.Expression
+	if departmentColumn != "" && len(scope.DepartmentIDs) > 0 {
+		values := make([]interface{}, len(scope.DepartmentIDs))
+		for i, id := range scope.DepartmentIDs {
+			values[i] = id
+		}
+		conditions = append(conditions, clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: departmentColumn}, Values: values})
+	}
+	if ownerColumn != "" && scope.UserID > 0 {
+		conditions = append(conditions, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: ownerColumn}, Value: scope.UserID})
+	}
+	if len(conditions) == 0 {
+		// 资源无法按当前范围归属，不返回任何数据
+		return db.Where("1 = 0")
+	}
+	return db.Where(clause.Or(conditions...))
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// scopedRecord 同时按所属人和所属部门过滤的资源
+type scopedRecord struct {
+	ID           uint
+	CreatedBy    uint
+	DepartmentID uint
+}
+
+func (scopedRecord) DataScopeColumns() (ownerColumn, departmentColumn string) {
+	return "created_by", "department_id"
+}
+
+// deptRecord 只能按所属部门过滤的资源
+type deptRecord struct {
+	ID           uint
+	DepartmentID uint
+}
+
+func (deptRecord) DataScopeColumns() (ownerColumn, departmentColumn string) {
+	return "", "department_id"
+}
+
+// plainRecord 不按数据范围过滤的资源
+type plainRecord struct {
+	ID           uint
+	DepartmentID uint
+}
+
+func newDataScopeTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&scopedRecord{}, &deptRecord{}, &plainRecord{}); err != nil {
+		t.Fatal(err)
+	}
+	// 部门 1、2、3 各一条，所属人分别为 10、20、30
+	for i := uint(1); i <= 3; i++ {
+		if err := db.Create(&scopedRecord{ID: i, CreatedBy: i * 10, DepartmentID: i}).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Create(&deptRecord{ID: i, DepartmentID: i}).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Create(&plainRecord{ID: i, DepartmentID: i}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// scopedIDs 按数据范围查询 model 对应的表，返回可见记录的ID
+func scopedIDs(t *testing.T, db *gorm.DB, scope *DataScope, model interface{}) []uint {
+	t.Helper()
+	query := WithDataScope(scope)(db.Model(model))
+	var ids []uint
+	if err := ApplyDataScope(query, model).Order("id").Pluck("id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestApplyDataScope(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope *DataScope
+		model interface{}
+		want  []uint
+	}{
+		{name: "no scope", scope: nil, model: &scopedRecord{}, want: []uint{1, 2, 3}},
+		{name: "all", scope: &DataScope{All: true, UserID: 10}, model: &scopedRecord{}, want: []uint{1, 2, 3}},
+		{name: "departments", scope: &DataScope{DepartmentIDs: []uint{2, 3}}, model: &scopedRecord{}, want: []uint{2, 3}},
+		{name: "self", scope: &DataScope{UserID: 10}, model: &scopedRecord{}, want: []uint{1}},
+		{name: "departments or self", scope: &DataScope{UserID: 10, DepartmentIDs: []uint{3}}, model: &scopedRecord{}, want: []uint{1, 3}},
+		{name: "empty scope sees nothing", scope: &DataScope{}, model: &scopedRecord{}, want: []uint{}},
+		{name: "owner ignored without owner column", scope: &DataScope{UserID: 10, DepartmentIDs: []uint{2}}, model: &deptRecord{}, want: []uint{2}},
+		{name: "self only without owner column sees nothing", scope: &DataScope{UserID: 10}, model: &deptRecord{}, want: []uint{}},
+		{name: "resource not scoped", scope: &DataScope{UserID: 10}, model: &plainRecord{}, want: []uint{1, 2, 3}},
+	}
+
+	db := newDataScopeTestDB(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scopedIDs(t, db, tt.scope, tt.model); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("visible ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 数据范围条件与已有条件用 AND 组合，不能因 OR 放宽原有查询
+func TestApplyDataScopeKeepsExistingConditions(t *testing.T) {
+	db := newDataScopeTestDB(t)
+	query := WithDataScope(&DataScope{UserID: 10, DepartmentIDs: []uint{2}})(db.Model(&scopedRecord{}).Where("id <> ?", 2))
+	var ids []uint
+	if err := ApplyDataScope(query, &scopedRecord{}).Order("id").Pluck("id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []uint{1}) {
+		t.Fatalf("visible ids = %v, want [1]", ids)
+	}
+}
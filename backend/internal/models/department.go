@@ -0,0 +1,40 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Department 部门模型
+// Path 为物化路径，由根到自身的部门ID组成，如 /1/5/12/，用于快速查询全部下级部门
+type Department struct {
+	ID        uint           `json:"id" gorm:"primarykey"`
+	TenantID  uint           `json:"-" gorm:"not null;default:1;index"` // 所属租户
+	ParentID  uint           `json:"parent_id" gorm:"column:parent_id;default:0;index"`
+	Name      string         `json:"name" gorm:"size:50;not null"`
+	Path      string         `json:"path" gorm:"size:255;index;comment:物化路径"`
+	LeaderID  uint           `json:"leader_id" gorm:"default:0;comment:负责人(管理员ID)"`
+	Phone     string         `json:"phone" gorm:"size:20"`
+	Email     string         `json:"email" gorm:"size:100"`
+	Sort      int            `json:"sort" gorm:"default:0"`
+	Status    *int           `json:"status" gorm:"default:1"` // 1-启用 0-禁用
+	Children  []Department   `json:"children" gorm:"-"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index" swaggerignore:"true"`
+}
+
+// TableName 指定表名
+func (Department) TableName() string {
+	return "departments"
+}
+
+// DepartmentPath 部门的物化路径，parentPath 为上级部门的路径，顶级部门为空
+func DepartmentPath(parentPath string, id uint) string {
+	if parentPath == "" {
+		parentPath = "/"
+	}
+	return parentPath + strconv.FormatUint(uint64(id), 10) + "/"
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// MenuTypeField 字段权限菜单，与按钮权限一样分配给角色，权限编码见 FieldPermission
+const MenuTypeField = "field"
+
+// 字段权限的操作
+const (
+	FieldActionView = "view"
+	FieldActionEdit = "edit"
+)
+
+// FieldPermission 字段权限编码，如 member.mobile:view，field 为字段的 JSON 名称
+func FieldPermission(resource, field, action string) string {
+	return resource + "." + field + ":" + action
+}
+
+// SensitiveField 模型上通过 mask 标签声明了字段权限的字段，如 `mask:"mobile"`
+type SensitiveField struct {
+	Name  string // JSON 字段名
+	Mask  string // 掩码方式，见 utils.Mask
+	Index int    // 在结构体中的字段序号
+}
+
+var sensitiveFields sync.Map // reflect.Type -> []SensitiveField
+
+// SensitiveFields 结构体类型上声明了字段权限的字段
+func SensitiveFields(t reflect.Type) []SensitiveField {
+	if cached, ok := sensitiveFields.Load(t); ok {
+		return cached.([]SensitiveField)
+	}
+
+	var fields []SensitiveField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		mask, ok := field.Tag.Lookup("mask")
+		if !ok {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			name = field.Name
+		}
+		fields = append(fields, SensitiveField{Name: name, Mask: mask, Index: i})
+	}
+	sensitiveFields.Store(t, fields)
+	return fields
+}
@@ -0,0 +1,367 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidFilter 列表查询的过滤或排序参数无效：字段不在白名单中、运算符不允许或值无法转换
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// FilterOp 过滤运算符，查询参数写作 field[op]=value
+type FilterOp string
+
+const (
+	OpEq      FilterOp = "eq"      // 等于
+	OpNe      FilterOp = "ne"      // 不等于
+	OpLike    FilterOp = "like"    // 包含
+	OpPrefix  FilterOp = "prefix"  // 以值开头
+	OpIn      FilterOp = "in"      // 在列表中，多个值用逗号分隔
+	OpGt      FilterOp = "gt"      // 大于
+	OpGte     FilterOp = "gte"     // 大于等于
+	OpLt      FilterOp = "lt"      // 小于
+	OpLte     FilterOp = "lte"     // 小于等于
+	OpBetween FilterOp = "between" // 闭区间，两个值用逗号分隔
+	OpIsNull  FilterOp = "null"    // true 为空，false 不为空
+)
+
+// 常用的运算符组合，第一个为不写运算符时的默认运算符
+var (
+	StringOps  = []FilterOp{OpLike, OpEq, OpNe, OpPrefix, OpIn}
+	KeywordOps = []FilterOp{OpEq, OpNe, OpIn, OpLike, OpPrefix}
+	NumberOps  = []FilterOp{OpEq, OpNe, OpIn, OpGt, OpGte, OpLt, OpLte, OpBetween}
+	TimeOps    = []FilterOp{OpBetween, OpGte, OpLte, OpGt, OpLt}
+)
+
+// FilterType 过滤值的类型，查询参数按类型转换后再作为 SQL 参数
+type FilterType int
+
+const (
+	FilterString FilterType = iota
+	FilterInt
+	FilterUint
+	FilterBool
+	FilterTime // 支持 RFC3339、2006-01-02 15:04:05 和 2006-01-02，只有日期的上限取当天结束
+)
+
+// maxFilterValues in 运算符最多的值个数
+const maxFilterValues = 100
+
+// 查询参数中的排序参数
+const (
+	SortFieldParam = "sortField"
+	SortOrderParam = "sortOrder"
+)
+
+// FilterField 可过滤、排序的字段
+type FilterField struct {
+	Name     string     // 查询参数中的字段名
+	Column   string     // 数据库列名，为空时与 Name 相同
+	Type     FilterType // 值的类型
+	Ops      []FilterOp // 允许的运算符，第一个为默认运算符；为空时不能过滤，只能排序
+	Sortable bool       // 是否允许排序
+}
+
+func (f FilterField) column() string {
+	if f.Column != "" {
+		return f.Column
+	}
+	return f.Name
+}
+
+func (f FilterField) allows(op FilterOp) bool {
+	for _, allowed := range f.Ops {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterSpec 资源列表的过滤规格，只有声明的字段和运算符可以出现在查询中
+type FilterSpec struct {
+	fields map[string]FilterField
+}
+
+// NewFilterSpec 声明资源可过滤、排序的字段
+func NewFilterSpec(fields ...FilterField) FilterSpec {
+	spec := FilterSpec{fields: make(map[string]FilterField, len(fields))}
+	for _, field := range fields {
+		spec.fields[field.Name] = field
+	}
+	return spec
+}
+
+// FilterCondition 一个过滤条件，Values 为已按字段类型转换的值
+type FilterCondition struct {
+	Field  string
+	Column string
+	Op     FilterOp
+	Values []interface{}
+}
+
+// FilterSort 一个排序字段
+type FilterSort struct {
+	Column string
+	Desc   bool
+}
+
+// Filter 解析后的过滤和排序条件，列名都来自过滤规格，值通过参数绑定，不会拼接进 SQL
+type Filter struct {
+	Conditions []FilterCondition
+	Sorts      []FilterSort
+}
+
+// Parse 解析查询参数：field=value 使用字段的默认运算符，field[op]=value 指定运算符，空值忽略；
+// 不带运算符且不在规格中的参数视为分页等其他参数，带运算符的未知字段、不允许的运算符和无法转换的值返回 ErrInvalidFilter。
+// 排序使用 sortField、sortOrder，多个字段用逗号分隔，只能按声明为可排序的字段排序
+func (s FilterSpec) Parse(values url.Values) (Filter, error) {
+	// 按参数名顺序生成条件，相同的查询生成相同的 SQL
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var filter Filter
+	for _, key := range keys {
+		list := values[key]
+		name, op, explicit, err := parseFilterKey(key)
+		if err != nil {
+			return Filter{}, err
+		}
+		if name == SortFieldParam || name == SortOrderParam {
+			continue
+		}
+		field, ok := s.fields[name]
+		if !ok || len(field.Ops) == 0 {
+			if explicit {
+				return Filter{}, fmt.Errorf("%w: unknown field %q", ErrInvalidFilter, name)
+			}
+			continue
+		}
+		if !explicit {
+			op = field.Ops[0]
+		} else if !field.allows(op) {
+			return Filter{}, fmt.Errorf("%w: operator %q is not allowed on %q", ErrInvalidFilter, op, name)
+		}
+		for _, raw := range list {
+			if strings.TrimSpace(raw) == "" {
+				continue
+			}
+			condition, err := newFilterCondition(field, op, raw)
+			if err != nil {
+				return Filter{}, err
+			}
+			filter.Conditions = append(filter.Conditions, condition)
+		}
+	}
+
+	sorts, err := s.parseSorts(values.Get(SortFieldParam), values.Get(SortOrderParam))
+	if err != nil {
+		return Filter{}, err
+	}
+	filter.Sorts = sorts
+	return filter, nil
+}
+
+// parseSorts 解析排序字段和方向，与此前的排序参数一致：未传 sortOrder 时第一个字段降序，缺少方向的字段升序
+func (s FilterSpec) parseSorts(fields, orders string) ([]FilterSort, error) {
+	if strings.TrimSpace(fields) == "" {
+		return nil, nil
+	}
+	if strings.TrimSpace(orders) == "" {
+		orders = "desc"
+	}
+	orderList := strings.Split(orders, ",")
+	var sorts []FilterSort
+	for i, name := range strings.Split(fields, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		field, ok := s.fields[name]
+		if !ok || !field.Sortable {
+			return nil, fmt.Errorf("%w: cannot sort by %q", ErrInvalidFilter, name)
+		}
+		desc := false
+		if i < len(orderList) {
+			switch strings.ToLower(strings.TrimSpace(orderList[i])) {
+			case "desc":
+				desc = true
+			case "asc", "":
+			default:
+				return nil, fmt.Errorf("%w: invalid sort order %q", ErrInvalidFilter, orderList[i])
+			}
+		}
+		sorts = append(sorts, FilterSort{Column: field.column(), Desc: desc})
+	}
+	return sorts, nil
+}
+
+// parseFilterKey 拆分 field[op] 形式的参数名
+func parseFilterKey(key string) (name string, op FilterOp, explicit bool, err error) {
+	open := strings.IndexByte(key, '[')
+	if open < 0 {
+		return key, "", false, nil
+	}
+	if !strings.HasSuffix(key, "]") || open == 0 {
+		return "", "", false, fmt.Errorf("%w: malformed parameter %q", ErrInvalidFilter, key)
+	}
+	return key[:open], FilterOp(key[open+1 : len(key)-1]), true, nil
+}
+
+// newFilterCondition 按运算符拆分参数值并转换类型
+func newFilterCondition(field FilterField, op FilterOp, raw string) (FilterCondition, error) {
+	condition := FilterCondition{Field: field.Name, Column: field.column(), Op: op}
+	switch op {
+	case OpIsNull:
+		isNull, err := strconv.ParseBool(raw)
+		if err != nil {
+			return condition, fmt.Errorf("%w: %s[%s] expects true or false", ErrInvalidFilter, field.Name, op)
+		}
+		condition.Values = []interface{}{isNull}
+		return condition, nil
+	case OpLike, OpPrefix:
+		if field.Type != FilterString {
+			return condition, fmt.Errorf("%w: operator %q requires a string field", ErrInvalidFilter, op)
+		}
+		condition.Values = []interface{}{raw}
+		return condition, nil
+	}
+
+	parts := []string{raw}
+	switch op {
+	case OpIn:
+		parts = strings.Split(raw, ",")
+		if len(parts) > maxFilterValues {
+			return condition, fmt.Errorf("%w: %s[in] accepts at most %d values", ErrInvalidFilter, field.Name, maxFilterValues)
+		}
+	case OpBetween:
+		parts = strings.Split(raw, ",")
+		if len(parts) != 2 {
+			return condition, fmt.Errorf("%w: %s[between] expects two values", ErrInvalidFilter, field.Name)
+		}
+	}
+	for i, part := range parts {
+		upper := op == OpLte || (op == OpBetween && i == 1)
+		value, err := coerceFilterValue(field.Type, strings.TrimSpace(part), upper)
+		if err != nil {
+			return condition, fmt.Errorf("%w: invalid value %q for %s", ErrInvalidFilter, part, field.Name)
+		}
+		condition.Values = append(condition.Values, value)
+	}
+	return condition, nil
+}
+
+// filterTimeLayouts 时间过滤值支持的格式
+var filterTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+// coerceFilterValue 将参数值转换为字段类型，upper 表示区间上限（只有日期时取当天结束）
+func coerceFilterValue(typ FilterType, raw string, upper bool) (interface{}, error) {
+	switch typ {
+	case FilterInt:
+		return strconv.ParseInt(raw, 10, 64)
+	case FilterUint:
+		return strconv.ParseUint(raw, 10, 64)
+	case FilterBool:
+		return strconv.ParseBool(raw)
+	case FilterTime:
+		for _, layout := range filterTimeLayouts {
+			t, err := time.ParseInLocation(layout, raw, time.Local)
+			if err != nil {
+				continue
+			}
+			if upper && layout == "2006-01-02" {
+				t = t.Add(24*time.Hour - time.Millisecond)
+			}
+			return t, nil
+		}
+		return nil, fmt.Errorf("unsupported time format")
+	default:
+		return raw, nil
+	}
+}
+
+// likeEscaper 转义 LIKE 的通配符，参数中的 % 和 _ 按字面匹配
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// expression 条件对应的 SQL 表达式，列名限定为主表
+func (c FilterCondition) expression() clause.Expression {
+	column := clause.Column{Table: clause.CurrentTable, Name: c.Column}
+	switch c.Op {
+	case OpNe:
+		return clause.Neq{Column: column, Value: c.Values[0]}
+	case OpLike:
+		return clause.Like{Column: column, Value: "%" + likeEscaper.Replace(c.Values[0].(string)) + "%"}
+	case OpPrefix:
+		return clause.Like{Column: column, Value: likeEscaper.Replace(c.Values[0].(string)) + "%"}
+	case OpIn:
+		return clause.IN{Column: column, Values: c.Values}
+	case OpGt:
+		return clause.Gt{Column: column, Value: c.Values[0]}
+	case OpGte:
+		return clause.Gte{Column: column, Value: c.Values[0]}
+	case OpLt:
+		return clause.Lt{Column: column, Value: c.Values[0]}
+	case OpLte:
+		return clause.Lte{Column: column, Value: c.Values[0]}
+	case OpBetween:
+		return clause.And(
+			clause.Gte{Column: column, Value: c.Values[0]},
+			clause.Lte{Column: column, Value: c.Values[1]},
+		)
+	case OpIsNull:
+		if c.Values[0].(bool) {
+			return clause.Eq{Column: column, Value: nil}
+		}
+		return clause.Neq{Column: column, Value: nil}
+	default:
+		return clause.Eq{Column: column, Value: c.Values[0]}
+	}
+}
+
+// Apply 将过滤条件和排序应用到查询
+func (f Filter) Apply(db *gorm.DB) *gorm.DB {
+	for _, condition := range f.Conditions {
+		db = db.Where(condition.expression())
+	}
+	for _, order := range f.Sorts {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: order.Column}, Desc: order.Desc})
+	}
+	return db
+}
+
+// Uses 是否按指定字段过滤
+func (f Filter) Uses(field string) bool {
+	for _, condition := range f.Conditions {
+		if condition.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// String 过滤条件的可读形式，用于操作日志
+func (f Filter) String() string {
+	parts := make([]string, 0, len(f.Conditions)+len(f.Sorts))
+	for _, condition := range f.Conditions {
+		parts = append(parts, fmt.Sprintf("%s %s %v", condition.Field, condition.Op, condition.Values))
+	}
+	for _, order := range f.Sorts {
+		direction := "asc"
+		if order.Desc {
+			direction = "desc"
+		}
+		parts = append(parts, "sort "+order.Column+" "+direction)
+	}
+	return strings.Join(parts, "; ")
+}
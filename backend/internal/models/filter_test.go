@@ -0,0 +1,183 @@
+package models
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testFilterSpec 覆盖各种字段类型的过滤规格
+var testFilterSpec = NewFilterSpec(
+	FilterField{Name: "username", Type: FilterString, Ops: StringOps, Sortable: true},
+	FilterField{Name: "status", Type: FilterInt, Ops: NumberOps, Sortable: true},
+	FilterField{Name: "department_id", Type: FilterUint, Ops: NumberOps},
+	FilterField{Name: "mfa_enabled", Type: FilterBool, Ops: []FilterOp{OpEq}},
+	FilterField{Name: "created_at", Type: FilterTime, Ops: TimeOps, Sortable: true},
+	FilterField{Name: "deleted_at", Type: FilterTime, Ops: []FilterOp{OpIsNull}},
+	FilterField{Name: "nickname", Column: "real_name", Type: FilterString, Ops: StringOps},
+	FilterField{Name: "id", Sortable: true},
+)
+
+func TestFilterSpecParse(t *testing.T) {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.Local)
+	}
+	tests := []struct {
+		name  string
+		query string
+		want  []FilterCondition
+	}{
+		{
+			name:  "default operator",
+			query: "username=alice&status=1",
+			want: []FilterCondition{
+				{Field: "status", Column: "status", Op: OpEq, Values: []interface{}{int64(1)}},
+				{Field: "username", Column: "username", Op: OpLike, Values: []interface{}{"alice"}},
+			},
+		},
+		{
+			name:  "explicit operator and column",
+			query: "nickname[prefix]=Al&department_id[in]=1, 2,3",
+			want: []FilterCondition{
+				{Field: "department_id", Column: "department_id", Op: OpIn, Values: []interface{}{uint64(1), uint64(2), uint64(3)}},
+				{Field: "nickname", Column: "real_name", Op: OpPrefix, Values: []interface{}{"Al"}},
+			},
+		},
+		{
+			name:  "bool and null",
+			query: "mfa_enabled=true&deleted_at[null]=false",
+			want: []FilterCondition{
+				{Field: "deleted_at", Column: "deleted_at", Op: OpIsNull, Values: []interface{}{false}},
+				{Field: "mfa_enabled", Column: "mfa_enabled", Op: OpEq, Values: []interface{}{true}},
+			},
+		},
+		{
+			name:  "date range upper bound is end of day",
+			query: "created_at=2024-05-01,2024-05-31",
+			want: []FilterCondition{
+				{Field: "created_at", Column: "created_at", Op: OpBetween, Values: []interface{}{
+					day(2024, 5, 1), day(2024, 6, 1).Add(-time.Millisecond),
+				}},
+			},
+		},
+		{
+			name:  "empty values, pagination and unknown plain parameters are ignored",
+			query: "username=&page=2&pageSize=20&foo=bar",
+		},
+		{
+			name:  "field without operators cannot be filtered",
+			query: "id=1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filter, err := testFilterSpec.Parse(values)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(filter.Conditions, tt.want) {
+				t.Fatalf("Parse() conditions = %#v, want %#v", filter.Conditions, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterSpecParseRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "unknown field with operator", query: "password[eq]=secret"},
+		{name: "sort-only field with operator", query: "id[eq]=1"},
+		{name: "operator not allowed", query: "username[gt]=a"},
+		{name: "unknown operator", query: "status[regex]=1"},
+		{name: "malformed parameter", query: "status[eq=1"},
+		{name: "like on number", query: "status[like]=1"},
+		{name: "not a number", query: "status=abc"},
+		{name: "negative unsigned", query: "department_id=-1"},
+		{name: "not a bool", query: "mfa_enabled=yes"},
+		{name: "bad time", query: "created_at[gte]=yesterday"},
+		{name: "between needs two values", query: "status[between]=1"},
+		{name: "sort by unknown field", query: "sortField=password"},
+		{name: "sort by non-sortable field", query: "sortField=department_id"},
+		{name: "invalid sort order", query: "sortField=id&sortOrder=sideways"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := testFilterSpec.Parse(values); !errors.Is(err, ErrInvalidFilter) {
+				t.Fatalf("Parse() error = %v, want ErrInvalidFilter", err)
+			}
+		})
+	}
+}
+
+func TestFilterSpecParseInLimit(t *testing.T) {
+	ids := "0"
+	for i := 1; i <= maxFilterValues; i++ {
+		ids += ",1"
+	}
+	if _, err := testFilterSpec.Parse(url.Values{"status[in]": {ids}}); !errors.Is(err, ErrInvalidFilter) {
+		t.Fatalf("Parse() error = %v, want ErrInvalidFilter", err)
+	}
+}
+
+func TestFilterSpecParseSorts(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []FilterSort
+	}{
+		{name: "no sort", query: "", want: nil},
+		{name: "default descending", query: "sortField=created_at", want: []FilterSort{{Column: "created_at", Desc: true}}},
+		{name: "explicit order", query: "sortField=status&sortOrder=ASC", want: []FilterSort{{Column: "status"}}},
+		{
+			name:  "missing order of later fields is ascending",
+			query: "sortField=status,id&sortOrder=desc",
+			want:  []FilterSort{{Column: "status", Desc: true}, {Column: "id"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filter, err := testFilterSpec.Parse(values)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(filter.Sorts, tt.want) {
+				t.Fatalf("Parse() sorts = %#v, want %#v", filter.Sorts, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterUses(t *testing.T) {
+	filter, err := testFilterSpec.Parse(url.Values{"nickname": {"Al"}, "sortField": {"status"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !filter.Uses("nickname") {
+		t.Fatal("Uses(nickname) = false")
+	}
+	// 只按查询参数中的字段名判断，列名和排序字段不算
+	for _, field := range []string{"real_name", "status", "username"} {
+		if filter.Uses(field) {
+			t.Fatalf("Uses(%s) = true", field)
+		}
+	}
+}
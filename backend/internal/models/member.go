@@ -9,25 +9,45 @@ import (
 
 // Member 会员模型
 type Member struct {
-	ID            uint           `gorm:"primarykey" json:"id"`
-	Username      string         `gorm:"uniqueIndex;size:50;not null" json:"username"`
-	Password      string         `gorm:"size:100;not null" json:"-"`
-	Nickname      string         `gorm:"size:50" json:"nickname"`
-	Avatar        string         `gorm:"size:255" json:"avatar"`
-	Mobile        string         `gorm:"uniqueIndex;size:20" json:"mobile"`
-	Email         string         `gorm:"uniqueIndex;size:100" json:"email"`
-	Gender        *int           `gorm:"default:0" json:"gender"` // 0-未知 1-男 2-女
-	Birthday      *time.Time     `json:"birthday"`
-	LevelID       uint           `gorm:"default:1" json:"level_id"`
-	Points        int            `gorm:"default:0" json:"points"`
-	Status        *int           `gorm:"default:1" json:"status"` // 0-禁用 1-启用 2-黑名单
-	LastLoginTime *time.Time     `json:"last_login_time"`
-	LastLoginIP   string         `gorm:"size:50" json:"last_login_ip"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-" swaggerignore:"true"`
+	ID                uint           `gorm:"primarykey" json:"id"`
+	TenantID          uint           `gorm:"not null;default:1;uniqueIndex:idx_members_tenant_username,priority:1;uniqueIndex:idx_members_tenant_mobile,priority:1;uniqueIndex:idx_members_tenant_email,priority:1" json:"-"` // 所属租户，用户名、手机号和邮箱在租户内唯一
+	Username          string         `gorm:"uniqueIndex:idx_members_tenant_username,priority:2;size:50;not null" json:"username"`
+	Password          string         `gorm:"size:100;not null" json:"-"`
+	Nickname          string         `gorm:"size:50" json:"nickname"`
+	Avatar            string         `gorm:"size:255" json:"avatar"`
+	Mobile            string         `gorm:"uniqueIndex:idx_members_tenant_mobile,priority:2;size:20" json:"mobile" mask:"mobile"`
+	Email             string         `gorm:"uniqueIndex:idx_members_tenant_email,priority:2;size:100" json:"email" mask:"email"`
+	EmailVerifiedAt   *time.Time     `json:"email_verified_at"`       // 邮箱验证时间，为空表示未验证
+	Gender            *int           `gorm:"default:0" json:"gender"` // 0-未知 1-男 2-女
+	Birthday          *time.Time     `json:"birthday" mask:"hidden"`
+	LevelID           uint           `gorm:"default:1" json:"level_id"`
+	Points            int            `gorm:"default:0" json:"points"`
+	Status            *int           `gorm:"default:1" json:"status"` // 0-禁用 1-启用 2-黑名单
+	LastLoginTime     *time.Time     `json:"last_login_time"`
+	LastLoginIP       string         `gorm:"size:50" json:"last_login_ip" mask:"ip"`
+	PasswordChangedAt *time.Time     `json:"password_changed_at"` // 最后修改密码时间，用于密码过期策略
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-" swaggerignore:"true"`
 }
 
+// MemberFilters 会员列表可用的过滤和排序字段，手机号保留此前的模糊搜索；
+// 手机号受字段权限保护，只有拥有查看权限时才能用于过滤，由处理器校验
+var MemberFilters = NewFilterSpec(
+	FilterField{Name: "id", Type: FilterUint, Ops: NumberOps, Sortable: true},
+	FilterField{Name: "username", Type: FilterString, Ops: StringOps, Sortable: true},
+	FilterField{Name: "nickname", Type: FilterString, Ops: StringOps, Sortable: true},
+	FilterField{Name: "mobile", Type: FilterString, Ops: []FilterOp{OpLike, OpEq, OpPrefix}},
+	FilterField{Name: "gender", Type: FilterInt, Ops: []FilterOp{OpEq, OpIn}},
+	FilterField{Name: "level_id", Type: FilterUint, Ops: []FilterOp{OpEq, OpNe, OpIn}, Sortable: true},
+	FilterField{Name: "points", Type: FilterInt, Ops: NumberOps, Sortable: true},
+	FilterField{Name: "status", Type: FilterInt, Ops: []FilterOp{OpEq, OpNe, OpIn}, Sortable: true},
+	FilterField{Name: "email_verified_at", Type: FilterTime, Ops: append(TimeOps, OpIsNull), Sortable: true},
+	FilterField{Name: "last_login_time", Type: FilterTime, Ops: append(TimeOps, OpIsNull), Sortable: true},
+	FilterField{Name: "created_at", Type: FilterTime, Ops: TimeOps, Sortable: true},
+	FilterField{Name: "updated_at", Type: FilterTime, Ops: TimeOps, Sortable: true},
+)
+
 // MemberLevel 会员等级
 type MemberLevel struct {
 	ID          uint      `gorm:"primarykey" json:"id"`
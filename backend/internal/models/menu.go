@@ -18,11 +18,12 @@ type Menu struct {
 	Sort       int    `json:"sort" gorm:"column:sort;default:0"`
 	ParentName string `json:"parent_name" gorm:"column:parent_name;default:''"`
 	IsHidden   bool   `json:"is_hidden" gorm:"column:is_hidden;default:false"`
-	Type       string `json:"type" gorm:"column:type;default:menu"` // menu or button
+	Type       string `json:"type" gorm:"column:type;default:menu"` // menu, button or field
 	Permission string `json:"permission"`
 	ApiMethod  string `json:"api_method"` // 新增字段
 	ApiPath    string `json:"api_path"`   // 新增字段
 	Status     *int   `json:"status" gorm:"default:1"`
+	Platform   bool   `json:"platform" gorm:"column:platform;default:false"` // 平台菜单，只分配给超级租户的角色，如菜单管理、租户管理
 	Children   []Menu `json:"children" gorm:"-"`
 	gorm.Model `swaggerignore:"true"`
 }
@@ -35,6 +36,7 @@ func (Menu) TableName() string {
 // RoleMenu 角色菜单关联模型
 type RoleMenu struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
+	TenantID  uint      `json:"-" gorm:"column:tenant_id;not null;default:1;index;comment:所属租户"`
 	RoleID    uint      `json:"role_id" gorm:"column:role_id;not null;comment:角色ID"`
 	MenuID    uint      `json:"menu_id" gorm:"column:menu_id;not null;comment:菜单ID"`
 	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP"`
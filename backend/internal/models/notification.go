@@ -17,6 +17,7 @@ type NotificationType struct {
 // Notification 通知
 type Notification struct {
 	ID             uint             `json:"id" gorm:"primarykey"`
+	TenantID       uint             `json:"-" gorm:"not null;default:1;index;comment:所属租户"`
 	Title          string           `json:"title" gorm:"size:100;not null;comment:通知标题"`
 	Content        string           `json:"content" gorm:"type:text;comment:通知内容"`
 	TypeID         uint             `json:"type_id" gorm:"not null;comment:通知类型ID"`
@@ -27,7 +28,7 @@ type Notification struct {
 	SenderName     string           `json:"sender_name" gorm:"size:50;comment:发送者名称"`
 	PublishTime    *time.Time       `json:"publish_time" gorm:"comment:发布时间"`
 	ExpirationTime string           `json:"expiration_time" gorm:"comment:过期时间"`
-	ReceiverType   string           `json:"receiver_type" gorm:"size:20;not null;default:'all';comment:接收者类型(all/members/admins)"`
+	ReceiverType   string           `json:"receiver_type" gorm:"size:20;not null;default:'all';comment:接收者类型(all/members/admins/user)"`
 	ReadCount      int              `json:"read_count" gorm:"default:0;comment:已读数量"`
 	CreatedAt      time.Time        `json:"created_at"`
 	UpdatedAt      time.Time        `json:"updated_at"`
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// PasswordHistory 历史密码，用于禁止重复使用最近的密码
+type PasswordHistory struct {
+	ID           uint      `json:"id" gorm:"primarykey"`
+	UserType     string    `json:"user_type" gorm:"size:20;not null;index:idx_password_histories_user;comment:用户类型 admin/member"`
+	UserID       uint      `json:"user_id" gorm:"not null;index:idx_password_histories_user;comment:用户ID"`
+	PasswordHash string    `json:"-" gorm:"size:100;not null;comment:密码哈希"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (PasswordHistory) TableName() string {
+	return "password_histories"
+}
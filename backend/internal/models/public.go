@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"strconv"
 
 	"gorm.io/gorm"
@@ -31,35 +32,18 @@ func ParsePagination(page, pageSize string) (offset, limit int) {
 	return offset, limitNum
 }
 
-// WithPreload 创建预加载查询选项
-func WithPreload(relations ...string) QueryOption {
+// WithContext 使用请求上下文执行查询，上下文中的租户会限定查询、更新和删除的范围
+func WithContext(ctx context.Context) QueryOption {
 	return func(db *gorm.DB) *gorm.DB {
-		for _, relation := range relations {
-			db = db.Preload(relation)
-		}
-		return db
+		return db.WithContext(ctx)
 	}
 }
 
-// WithSort 创建排序查询选项
-func WithSort(sortFields []string, sortOrders []string) QueryOption {
+// WithPreload 创建预加载查询选项
+func WithPreload(relations ...string) QueryOption {
 	return func(db *gorm.DB) *gorm.DB {
-		// 如果排序字段为空或只包含空字符串，则不进行排序
-		if len(sortFields) == 0 || (len(sortFields) == 1 && sortFields[0] == "") {
-			return db
-		}
-
-		for i, field := range sortFields {
-			// 跳过空的排序字段
-			if field == "" {
-				continue
-			}
-
-			order := "ASC"
-			if i < len(sortOrders) && sortOrders[i] == "desc" {
-				order = "DESC"
-			}
-			db = db.Order(field + " " + order)
+		for _, relation := range relations {
+			db = db.Preload(relation)
 		}
 		return db
 	}
@@ -4,9 +4,10 @@ import "gorm.io/gorm"
 
 type Role struct {
 	gorm.Model  `swaggerignore:"true"`
-	ID          uint   `json:"id" gorm:"primaryKey;autoIncrement"` // 将 ID 字段的 JSON 标签设置为 "id"
-	Name        string `json:"name" gorm:"type:varchar(50);unique;not null"`
-	Code        string `json:"code" gorm:"type:varchar(50);unique;not null"`
+	ID          uint   `json:"id" gorm:"primaryKey;autoIncrement"`                                                                                    // 将 ID 字段的 JSON 标签设置为 "id"
+	TenantID    uint   `json:"-" gorm:"not null;default:1;uniqueIndex:idx_roles_tenant_name,priority:1;uniqueIndex:idx_roles_tenant_code,priority:1"` // 所属租户，名称和编码在租户内唯一
+	Name        string `json:"name" gorm:"type:varchar(50);not null;uniqueIndex:idx_roles_tenant_name,priority:2"`
+	Code        string `json:"code" gorm:"type:varchar(50);not null;uniqueIndex:idx_roles_tenant_code,priority:2"`
 	Description string `json:"description" gorm:"type:varchar(200)"`
 	IsPreset    bool   `json:"is_preset" gorm:"column:is_preset;default:false"`                                                                                // 是否为系统预设角色
 	Status      *int   `json:"status" gorm:"type:tinyint;default:1;comment:'状态 1:启用 2:禁用'"`                                                                    // 状态
@@ -14,3 +15,16 @@ type Role struct {
 	Remark      string `json:"remark" gorm:"type:varchar(500);comment:'备注信息'"`                                                                                 // 备注
 	DataScope   int    `json:"data_scope" gorm:"column:data_scope;type:tinyint;default:1;comment:'数据范围（1：全部数据权限 2：自定义数据权限 3：本部门数据权限 4：本部门及以下数据权限 5：仅本人数据权限）'"` // 数据权限范围
 }
+
+// RoleFilters 角色列表可用的过滤和排序字段
+var RoleFilters = NewFilterSpec(
+	FilterField{Name: "id", Type: FilterUint, Ops: NumberOps, Sortable: true},
+	FilterField{Name: "name", Type: FilterString, Ops: StringOps, Sortable: true},
+	FilterField{Name: "code", Type: FilterString, Ops: KeywordOps, Sortable: true},
+	FilterField{Name: "status", Type: FilterInt, Ops: []FilterOp{OpEq, OpNe, OpIn}, Sortable: true},
+	FilterField{Name: "data_scope", Type: FilterInt, Ops: []FilterOp{OpEq, OpNe, OpIn}},
+	FilterField{Name: "is_preset", Type: FilterBool, Ops: []FilterOp{OpEq}},
+	FilterField{Name: "sort", Type: FilterInt, Ops: NumberOps, Sortable: true},
+	FilterField{Name: "created_at", Type: FilterTime, Ops: TimeOps, Sortable: true},
+	FilterField{Name: "updated_at", Type: FilterTime, Ops: TimeOps, Sortable: true},
+)
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 临时角色授权状态
+const (
+	RoleGrantPending = 0 // 待生效
+	RoleGrantActive  = 1 // 生效中
+	RoleGrantExpired = 2 // 已到期收回
+	RoleGrantRevoked = 3 // 已提前撤销
+)
+
+// RoleGrant 临时角色授权，在 StartsAt 到 EndsAt 之间管理员额外拥有该角色，到期后由定时任务收回
+// 记录不删除，作为授权审计
+type RoleGrant struct {
+	ID               uint       `json:"id" gorm:"primarykey"`
+	TenantID         uint       `json:"-" gorm:"not null;default:1;index;comment:所属租户"`
+	AdminID          uint       `json:"admin_id" gorm:"not null;index;comment:被授权的管理员ID"`
+	RoleID           uint       `json:"role_id" gorm:"not null;index;comment:临时授予的角色ID"`
+	StartsAt         time.Time  `json:"starts_at" gorm:"not null;comment:生效时间"`
+	EndsAt           time.Time  `json:"ends_at" gorm:"not null;index;comment:到期时间"`
+	Reason           string     `json:"reason" gorm:"size:255;not null;comment:授权原因"`
+	GrantorID        uint       `json:"grantor_id" gorm:"comment:授权人ID"`
+	GrantorName      string     `json:"grantor_name" gorm:"size:50;comment:授权人用户名"`
+	Status           int        `json:"status" gorm:"default:0;index;comment:状态(0待生效 1生效中 2已到期 3已撤销)"`
+	ExpiryNotifiedAt *time.Time `json:"expiry_notified_at" gorm:"comment:到期提醒时间"`
+	RevokedAt        *time.Time `json:"revoked_at" gorm:"comment:收回时间"`
+	RevokedBy        string     `json:"revoked_by" gorm:"size:50;comment:撤销人用户名，到期自动收回时为空"`
+	Admin            *Admin     `json:"admin,omitempty" gorm:"foreignKey:AdminID"`
+	Role             *Role      `json:"role,omitempty" gorm:"foreignKey:RoleID"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (RoleGrant) TableName() string {
+	return "role_grants"
+}
+
+// AdminRoleAssignments 管理员当前拥有的全部角色：admin_roles 中的角色加上生效中的临时授权
+// 作为子查询替代 admin_roles 表关联，如 Joins("INNER JOIN (?) AS admin_roles ON ...", AdminRoleAssignments(db))；
+// 列为 admin_id、role_id 以及排序用的 temporary、sort，临时授权排在长期角色之后，已长期拥有的角色不重复
+func AdminRoleAssignments(db *gorm.DB) *gorm.DB {
+	now := time.Now()
+	return db.Session(&gorm.Session{NewDB: true}).Raw(
+		"SELECT admin_id, role_id, 0 AS temporary, id AS sort FROM admin_roles "+
+			"UNION ALL "+
+			"SELECT role_grants.admin_id, role_grants.role_id, 1 AS temporary, MIN(role_grants.id) AS sort FROM role_grants "+
+			"WHERE role_grants.status IN ? AND role_grants.starts_at <= ? AND role_grants.ends_at > ? "+
+			"AND NOT EXISTS (SELECT 1 FROM admin_roles AS ar WHERE ar.admin_id = role_grants.admin_id AND ar.role_id = role_grants.role_id) "+
+			"GROUP BY role_grants.admin_id, role_grants.role_id",
+		[]int{RoleGrantPending, RoleGrantActive}, now, now)
+}
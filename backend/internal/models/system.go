@@ -6,22 +6,42 @@ import (
 
 // SystemLog 系统日志
 type SystemLog struct {
-	ID        uint      `json:"id" gorm:"primarykey"`
-	UserID    uint      `json:"user_id"`                    // 操作用户ID
-	Username  string    `json:"username"`                   // 操作用户名
-	Module    string    `json:"module"`                     // 操作模块
-	Action    string    `json:"action"`                     // 操作动作
-	Method    string    `json:"method"`                     // 请求方法
-	URL       string    `json:"url"`                        // 请求URL
-	IP        string    `json:"ip"`                         // 请求IP
-	UserAgent string    `json:"user_agent" gorm:"size:500"` // 用户代理
-	Params    string    `json:"params" gorm:"type:text"`    // 请求参数
-	Result    string    `json:"result" gorm:"type:text"`    // 操作结果
-	Status    int       `json:"status"`                     // 状态码
-	Duration  int64     `json:"duration"`                   // 执行时长(ms)
-	CreatedAt time.Time `json:"created_at"`
+	ID             uint      `json:"id" gorm:"primarykey"`
+	TenantID       uint      `json:"-" gorm:"not null;default:1;index"` // 所属租户
+	UserID         uint      `json:"user_id"`                           // 操作用户ID
+	Username       string    `json:"username"`                          // 操作用户名
+	APIKeyID       uint      `json:"api_key_id" gorm:"index"`           // 通过 API 密钥调用时的密钥ID
+	ImpersonatorID uint      `json:"impersonator_id" gorm:"index"`      // 模拟登录时发起请求的管理员ID
+	Module         string    `json:"module"`                            // 操作模块
+	Action         string    `json:"action"`                            // 操作动作
+	Method         string    `json:"method"`                            // 请求方法
+	URL            string    `json:"url"`                               // 请求URL
+	IP             string    `json:"ip"`                                // 请求IP
+	UserAgent      string    `json:"user_agent" gorm:"size:500"`        // 用户代理
+	Params         string    `json:"params" gorm:"type:text"`           // 请求参数
+	Result         string    `json:"result" gorm:"type:text"`           // 操作结果
+	Status         int       `json:"status"`                            // 状态码
+	Duration       int64     `json:"duration"`                          // 执行时长(ms)
+	CreatedAt      time.Time `json:"created_at"`
 }
 
+// SystemLogFilters 系统日志列表可用的过滤和排序字段，用户名、模块等按精确值过滤
+var SystemLogFilters = NewFilterSpec(
+	FilterField{Name: "id", Type: FilterUint, Ops: NumberOps, Sortable: true},
+	FilterField{Name: "user_id", Type: FilterUint, Ops: []FilterOp{OpEq, OpIn}},
+	FilterField{Name: "username", Type: FilterString, Ops: KeywordOps},
+	FilterField{Name: "api_key_id", Type: FilterUint, Ops: []FilterOp{OpEq, OpIn}},
+	FilterField{Name: "impersonator_id", Type: FilterUint, Ops: []FilterOp{OpEq, OpIn}},
+	FilterField{Name: "module", Type: FilterString, Ops: KeywordOps},
+	FilterField{Name: "action", Type: FilterString, Ops: KeywordOps},
+	FilterField{Name: "method", Type: FilterString, Ops: []FilterOp{OpEq, OpIn}},
+	FilterField{Name: "url", Type: FilterString, Ops: []FilterOp{OpPrefix, OpEq, OpLike}},
+	FilterField{Name: "ip", Type: FilterString, Ops: []FilterOp{OpEq, OpPrefix}},
+	FilterField{Name: "status", Type: FilterInt, Ops: NumberOps, Sortable: true},
+	FilterField{Name: "duration", Type: FilterInt, Ops: NumberOps, Sortable: true},
+	FilterField{Name: "created_at", Type: FilterTime, Ops: TimeOps, Sortable: true},
+)
+
 // SystemMonitor 系统监控
 type SystemMonitor struct {
 	ID           uint      `json:"id" gorm:"primarykey"`
@@ -40,3 +60,18 @@ type SystemMonitorQuery struct {
 	EndTime   string `form:"end_time"`   // 结束时间
 	Limit     int    `form:"limit"`      // 限制条数
 }
+
+// LoginLog 登录日志，记录管理员和会员的登录、两步验证及令牌刷新结果
+type LoginLog struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	TenantID  uint      `json:"-" gorm:"not null;default:1;index"` // 所属租户
+	UserType  string    `json:"user_type" gorm:"size:20;index"`    // 用户类型 admin/member
+	UserID    uint      `json:"user_id" gorm:"index"`              // 用户ID，用户不存在时为0
+	Username  string    `json:"username" gorm:"size:50;index"`     // 登录用户名
+	Action    string    `json:"action" gorm:"size:20"`             // login/mfa/refresh
+	Success   bool      `json:"success"`                           // 是否成功
+	Reason    string    `json:"reason" gorm:"size:50"`             // 失败原因
+	IP        string    `json:"ip" gorm:"size:64;index"`           // 请求IP
+	UserAgent string    `json:"user_agent" gorm:"size:500"`        // 用户代理
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
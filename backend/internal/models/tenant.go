@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tenant 租户，每个租户是一个独立的后台：管理员、角色、会员、配置、通知、上传文件和日志按租户隔离，
+// 菜单为全部租户共用的目录。ID 为 1 的超级租户即平台本身，只有超级租户的管理员可以开通和管理租户
+type Tenant struct {
+	ID        uint           `json:"id" gorm:"primarykey"`
+	Name      string         `json:"name" gorm:"size:100;not null;comment:租户名称"`
+	Code      string         `json:"code" gorm:"size:50;not null;uniqueIndex;comment:租户编码，登录时使用"`
+	Contact   string         `json:"contact" gorm:"size:50;comment:联系人"`
+	Phone     string         `json:"phone" gorm:"size:20;comment:联系电话"`
+	Status    *int           `json:"status" gorm:"default:1;comment:状态(1启用 0禁用)"`
+	ExpiresAt *time.Time     `json:"expires_at" gorm:"comment:到期时间，为空表示长期有效"`
+	Remark    string         `json:"remark" gorm:"size:500"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index" swaggerignore:"true"`
+}
+
+// TableName 指定表名
+func (Tenant) TableName() string {
+	return "tenants"
+}
+
+// Active 租户是否已启用且未到期，禁用或到期的租户不能登录，已签发的令牌也随即失效
+func (t *Tenant) Active(now time.Time) bool {
+	if t.Status == nil || *t.Status != 1 {
+		return false
+	}
+	return t.ExpiresAt == nil || now.Before(*t.ExpiresAt)
+}
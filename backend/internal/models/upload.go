@@ -5,6 +5,7 @@ import "gorm.io/gorm"
 // UploadFile 上传文件记录
 type UploadFile struct {
 	gorm.Model `swaggerignore:"true"`
+	TenantID   uint   `gorm:"not null;default:1;index" json:"-"`           // 所属租户
 	FileName   string `gorm:"type:varchar(255);not null" json:"file_name"` // 文件名
 	FilePath   string `gorm:"type:varchar(255);not null" json:"file_path"` // 文件路径
 	FileType   string `gorm:"type:varchar(50);not null" json:"file_type"`  // 文件类型
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// UserSession 登录会话，每次登录创建一条，以刷新令牌家族ID标识
+// Redis 中保存在线会话，数据库为镜像记录，用于审计和 Redis 数据丢失后的查询
+type UserSession struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	SessionID  string     `json:"session_id" gorm:"size:64;not null;uniqueIndex;comment:会话ID（令牌家族ID）"`
+	UserType   string     `json:"user_type" gorm:"size:20;not null;index:idx_user_sessions_user;comment:用户类型 admin/member"`
+	UserID     uint       `json:"user_id" gorm:"not null;index:idx_user_sessions_user;comment:用户ID"`
+	Username   string     `json:"username" gorm:"size:50;comment:用户名"`
+	Device     string     `json:"device" gorm:"size:100;comment:设备"`
+	IP         string     `json:"ip" gorm:"size:64;comment:登录IP"`
+	UserAgent  string     `json:"user_agent" gorm:"size:500;comment:User-Agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at" gorm:"comment:最后活跃时间"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"comment:过期时间"`
+	RevokedAt  *time.Time `json:"revoked_at" gorm:"comment:注销时间"`
+	Current    bool       `json:"current" gorm:"-"` // 是否为当前请求所属会话
+}
+
+// TableName 指定表名
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
@@ -1,35 +1,170 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/auth"
+	"normaladmin/backend/pkg/cache"
+	"slices"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrOldPasswordIncorrect 修改密码时旧密码错误
+	ErrOldPasswordIncorrect = errors.New("old password is incorrect")
+	// ErrAdminDepartmentNotFound 所属部门不存在
+	ErrAdminDepartmentNotFound = errors.New("admin department not found")
+	// ErrAdminInvalidField 不支持检查唯一性的字段
+	ErrAdminInvalidField = errors.New("invalid field")
+)
+
 type AdminService interface {
 	BaseCRUD[models.Admin]
 
 	CheckAdminFieldUnique(field, value string, excludeID uint) (bool, error)
 	UpdatePassword(id uint, oldPassword, newPassword string) error
+	UpdateStatus(id uint, status int, opts ...models.QueryOption) error
+	RevokeTokens(id uint) error
+	// WithContext 返回使用请求上下文的服务，查询和写入限定在上下文中的租户内
+	WithContext(ctx context.Context) AdminService
 }
 
 type adminService struct {
 	BaseCRUD[models.Admin]
-	db *gorm.DB
+	db             *gorm.DB
+	tokenTTL       time.Duration // 刷新令牌有效期，用于吊销管理员令牌
+	passwordPolicy PasswordPolicyService
+	policies       PolicySyncService
 }
 
-func NewAdminService(db *gorm.DB, base BaseCRUD[models.Admin]) AdminService {
+func NewAdminService(db *gorm.DB, base BaseCRUD[models.Admin], tokenTTL time.Duration, passwordPolicy PasswordPolicyService, policies PolicySyncService) AdminService {
 
 	return &adminService{
-		BaseCRUD: base, // 使用装饰后的服务
-		db:       db,   // 保存db实例
+		BaseCRUD:       base,     // 使用装饰后的服务
+		db:             db,       // 保存db实例
+		tokenTTL:       tokenTTL, // 令牌吊销标记的保留时间
+		passwordPolicy: passwordPolicy,
+		policies:       policies,
+	}
+}
+
+func (s *adminService) WithContext(ctx context.Context) AdminService {
+	clone := *s
+	clone.BaseCRUD = withContextCRUD(s.BaseCRUD, ctx)
+	clone.db = s.db.WithContext(ctx)
+	clone.policies = s.policies.WithContext(ctx)
+	return &clone
+}
+
+// GetByID 获取管理员，并填充全部角色
+func (s *adminService) GetByID(id uint, opts ...models.QueryOption) (*models.Admin, error) {
+	admin, err := s.BaseCRUD.GetByID(id, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.fillRoleIDs([]*models.Admin{admin}); err != nil {
+		return nil, err
+	}
+	return admin, nil
+}
+
+// List 获取管理员列表，并填充全部角色
+func (s *adminService) List(filter models.Filter, page, pageSize string, opts ...models.QueryOption) ([]models.Admin, int64, error) {
+	admins, total, err := s.BaseCRUD.List(filter, page, pageSize, opts...)
+	if err != nil {
+		return nil, 0, err
+	}
+	list := make([]*models.Admin, len(admins))
+	for i := range admins {
+		list[i] = &admins[i]
+	}
+	if err := s.fillRoleIDs(list); err != nil {
+		return nil, 0, err
+	}
+	return admins, total, nil
+}
+
+// Create 校验密码策略后创建管理员，记录初始密码并分配角色
+func (s *adminService) Create(admin *models.Admin, opts ...models.QueryOption) error {
+	if err := s.validateDepartment(admin.DepartmentID); err != nil {
+		return err
+	}
+	roleIDs, err := s.validateRoles(admin)
+	if err != nil {
+		return err
+	}
+	if err := s.passwordPolicy.Validate("admin", 0, admin.Username, admin.Password, ""); err != nil {
+		return err
+	}
+
+	// 密码由 BeforeCreate 钩子加密，创建后记录加密后的密码
+	if err := s.BaseCRUD.Create(admin, opts...); err != nil {
+		return err
+	}
+	if err := s.passwordPolicy.Record(s.db, "admin", admin.ID, admin.Password); err != nil {
+		return err
 	}
+	return s.assignRoles(admin.ID, roleIDs)
 }
 
+// Update 更新管理员，包含新密码时先确认数据范围并按密码策略校验，资料更新成功后再修改密码，
+// 资料更新失败（如邮箱、手机号冲突）时密码保持不变；包含 role_ids（或旧版的 role_id）时替换管理员的全部角色
+func (s *adminService) Update(id uint, data interface{}, opts ...models.QueryOption) error {
+	var roleIDs []uint
+	if admin, ok := data.(*models.Admin); ok {
+		if err := s.validateDepartment(admin.DepartmentID); err != nil {
+			return err
+		}
+		var err error
+		if roleIDs, err = s.validateRoles(admin); err != nil {
+			return err
+		}
+	}
+	var password string
+	if admin, ok := data.(*models.Admin); ok && admin.Password != "" {
+		if _, err := s.BaseCRUD.GetByID(id, opts...); err != nil {
+			return err
+		}
+		if err := s.passwordPolicy.ValidateFor("admin", id, admin.Password); err != nil {
+			return err
+		}
+		password, admin.Password = admin.Password, ""
+	}
+	if err := s.BaseCRUD.Update(id, data, opts...); err != nil {
+		return err
+	}
+	if password != "" {
+		if err := s.passwordPolicy.SetPassword("admin", id, password); err != nil {
+			return err
+		}
+	}
+	if roleIDs == nil {
+		return nil
+	}
+	return s.assignRoles(id, roleIDs)
+}
+
+// CheckAdminFieldUnique 只支持 username、email、phone，其他字段返回 ErrAdminInvalidField
 func (s *adminService) CheckAdminFieldUnique(field, value string, excludeID uint) (bool, error) {
 	var count int64
-	db := s.db.Model(&models.Admin{}).Where(field+" = ?", value)
+	db := s.db.Model(&models.Admin{})
+
+	switch field {
+	case "username":
+		db = db.Where("username = ?", value)
+	case "email":
+		db = db.Where("email = ?", value)
+	case "phone":
+		db = db.Where("phone = ?", value)
+	default:
+		return false, ErrAdminInvalidField
+	}
+
 	if excludeID > 0 {
 		db = db.Where("id != ?", excludeID)
 	}
@@ -47,14 +182,166 @@ func (s *adminService) UpdatePassword(id uint, oldPassword, newPassword string)
 
 	// 验证旧密码
 	if err := bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(oldPassword)); err != nil {
+		return ErrOldPasswordIncorrect
+	}
+
+	// 按密码策略校验并保存新密码
+	if err := s.passwordPolicy.SetPassword("admin", id, newPassword); err != nil {
 		return err
 	}
 
-	// 加密新密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
-	if err != nil {
+	// 修改密码后，已签发的令牌全部失效
+	return s.RevokeTokens(id)
+}
+
+// UpdateStatus 更新管理员状态，禁用时吊销其全部令牌
+func (s *adminService) UpdateStatus(id uint, status int, opts ...models.QueryOption) error {
+	if err := s.BaseCRUD.Update(id, map[string]interface{}{"status": status}, opts...); err != nil {
+		return err
+	}
+	if status == 0 {
+		return s.RevokeTokens(id)
+	}
+	return nil
+}
+
+// Delete 删除管理员，清理其分组策略并吊销其全部令牌
+func (s *adminService) Delete(id uint, hardDelete bool, opts ...models.QueryOption) error {
+	if err := s.BaseCRUD.Delete(id, hardDelete, opts...); err != nil {
+		return err
+	}
+	if err := s.removeGroupings(hardDelete, id); err != nil {
+		return err
+	}
+	return s.RevokeTokens(id)
+}
+
+// BatchDelete 批量删除管理员，清理其分组策略并吊销其全部令牌
+func (s *adminService) BatchDelete(ids []uint, hardDelete bool, opts ...models.QueryOption) error {
+	if err := s.BaseCRUD.BatchDelete(ids, hardDelete, opts...); err != nil {
+		return err
+	}
+	// 数据范围外的管理员不会被删除，只处理实际删除的管理员
+	var remaining []uint
+	if err := s.db.Model(&models.Admin{}).Where("id IN ?", ids).Pluck("id", &remaining).Error; err != nil {
+		return err
+	}
+	deleted := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !slices.Contains(remaining, id) {
+			deleted = append(deleted, id)
+		}
+	}
+	if len(deleted) == 0 {
+		return nil
+	}
+	if err := s.removeGroupings(hardDelete, deleted...); err != nil {
+		return err
+	}
+	for _, id := range deleted {
+		if err := s.RevokeTokens(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeGroupings 重建已删除管理员的分组策略（结果为空），彻底删除时同时删除角色关联
+func (s *adminService) removeGroupings(hardDelete bool, ids ...uint) error {
+	return s.policies.Transaction(func(tx *gorm.DB) error {
+		if hardDelete {
+			if err := tx.Where("admin_id IN ?", ids).Delete(&models.AdminRole{}).Error; err != nil {
+				return err
+			}
+		}
+		return s.policies.SyncAdmins(tx, ids...)
+	})
+}
+
+// RevokeTokens 吊销管理员已签发的全部令牌
+func (s *adminService) RevokeTokens(id uint) error {
+	return auth.RevokeUserTokens("admin", id, s.tokenTTL)
+}
+
+// validateRoles 确定要分配的角色并校验角色存在，第一个角色作为主角色写回 RoleID
+// role_ids 优先，只提交 role_id 时视为只分配该角色；都没有提交时返回 nil，表示不修改角色
+func (s *adminService) validateRoles(admin *models.Admin) ([]uint, error) {
+	roleIDs := admin.RoleIDs
+	if roleIDs == nil && admin.RoleID != 0 {
+		roleIDs = []uint{admin.RoleID}
+	}
+	if roleIDs == nil {
+		return nil, nil
+	}
+
+	unique := make([]uint, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		if roleID != 0 && !slices.Contains(unique, roleID) {
+			unique = append(unique, roleID)
+		}
+	}
+	if len(unique) > 0 {
+		var count int64
+		if err := s.db.Model(&models.Role{}).Where("id IN ?", unique).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if int(count) != len(unique) {
+			return nil, ErrAdminRoleNotFound
+		}
+		admin.RoleID = unique[0]
+	}
+	admin.RoleIDs = unique
+	return unique, nil
+}
+
+// assignRoles 替换管理员的角色并同步分组策略，主角色直接写入数据库，需清除管理员缓存
+func (s *adminService) assignRoles(adminID uint, roleIDs []uint) error {
+	if err := s.policies.Transaction(func(tx *gorm.DB) error {
+		return s.policies.AssignRoles(tx, adminID, roleIDs)
+	}); err != nil {
 		return err
 	}
+	_ = cache.Delete(fmt.Sprintf("admin:%d", adminID))
+	return nil
+}
 
-	return s.db.Model(&admin).Update("password", string(hashedPassword)).Error
+// fillRoleIDs 填充管理员的全部角色，按分配顺序排列
+func (s *adminService) fillRoleIDs(admins []*models.Admin) error {
+	if len(admins) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(admins))
+	for i, admin := range admins {
+		ids[i] = admin.ID
+	}
+	var adminRoles []models.AdminRole
+	if err := s.db.Where("admin_id IN ?", ids).Order("id").Find(&adminRoles).Error; err != nil {
+		return err
+	}
+	roleIDs := make(map[uint][]uint, len(admins))
+	for _, adminRole := range adminRoles {
+		roleIDs[adminRole.AdminID] = append(roleIDs[adminRole.AdminID], adminRole.RoleID)
+	}
+	for _, admin := range admins {
+		admin.RoleIDs = roleIDs[admin.ID]
+		if admin.RoleIDs == nil {
+			admin.RoleIDs = []uint{}
+		}
+	}
+	return nil
+}
+
+// validateDepartment 校验所属部门存在，0 表示不分配部门
+func (s *adminService) validateDepartment(departmentID uint) error {
+	if departmentID == 0 {
+		return nil
+	}
+	var count int64
+	if err := s.db.Model(&models.Department{}).Where("id = ?", departmentID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrAdminDepartmentNotFound
+	}
+	return nil
 }
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"normaladmin/backend/internal/models"
+	"strings"
+	"time"
+
+	"github.com/casbin/casbin/v2/util"
+	"github.com/swaggo/swag"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrMenuAPIIncomplete 按钮菜单的接口路径和请求方法需同时填写
+	ErrMenuAPIIncomplete = errors.New("api_path and api_method must be set together")
+	// ErrMenuAPINotFound 按钮菜单的接口不在接口目录中
+	ErrMenuAPINotFound = errors.New("api endpoint not found in catalogue")
+)
+
+// ApiRoute 已注册的路由
+type ApiRoute struct {
+	Method  string
+	Path    string
+	Handler string // 处理函数的完整名称
+}
+
+// ApiEndpointSyncResult 接口目录同步结果
+type ApiEndpointSyncResult struct {
+	Total int `json:"total"` // 当前注册的路由数
+	Added int `json:"added"` // 新增的接口数
+	Stale int `json:"stale"` // 已不存在的接口数
+}
+
+// ApiEndpointQuery 接口目录查询条件
+type ApiEndpointQuery struct {
+	Keyword string // 匹配路径、摘要和处理函数
+	Method  string
+	Group   string
+	Stale   *bool
+}
+
+// ApiEndpointService 接口目录服务
+// 启动时按已注册的路由维护接口目录，不再注册的接口标记为已失效而不删除，便于发现引用了失效接口的菜单
+type ApiEndpointService interface {
+	// SyncRoutes 按已注册的路由新增或更新接口，并标记已不存在的接口
+	SyncRoutes(routes []ApiRoute) (*ApiEndpointSyncResult, error)
+	// List 分页查询接口目录，按路径和请求方法排序
+	List(query ApiEndpointQuery, page, pageSize string) ([]models.ApiEndpoint, int64, error)
+	// Match 判断接口路径和请求方法是否匹配目录中未失效的接口，路径支持 :id 和 * 通配
+	Match(method, path string) (bool, error)
+}
+
+type apiEndpointService struct {
+	db *gorm.DB
+}
+
+func NewApiEndpointService(db *gorm.DB) ApiEndpointService {
+	return &apiEndpointService{db: db}
+}
+
+func (s *apiEndpointService) SyncRoutes(routes []ApiRoute) (*ApiEndpointSyncResult, error) {
+	summaries := swaggerSummaries()
+	result := &ApiEndpointSyncResult{Total: len(routes)}
+	now := time.Now()
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var endpoints []models.ApiEndpoint
+		if err := tx.Find(&endpoints).Error; err != nil {
+			return err
+		}
+		existing := make(map[string]*models.ApiEndpoint, len(endpoints))
+		for i := range endpoints {
+			existing[endpoints[i].Method+" "+endpoints[i].Path] = &endpoints[i]
+		}
+
+		seen := make(map[string]bool, len(routes))
+		for _, route := range routes {
+			key := route.Method + " " + route.Path
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			endpoint := models.ApiEndpoint{
+				Method:     route.Method,
+				Path:       route.Path,
+				Group:      routeGroup(route.Path),
+				Handler:    handlerName(route.Handler),
+				Summary:    summaries[key],
+				LastSeenAt: &now,
+			}
+			if current, ok := existing[key]; ok {
+				if err := tx.Model(current).Select("route_group", "handler", "summary", "stale", "last_seen_at").
+					Updates(&endpoint).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Create(&endpoint).Error; err != nil {
+				return err
+			}
+			result.Added++
+		}
+
+		// 不再注册的接口标记为已失效
+		for key, endpoint := range existing {
+			if seen[key] {
+				continue
+			}
+			result.Stale++
+			if endpoint.Stale {
+				continue
+			}
+			if err := tx.Model(endpoint).Update("stale", true).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *apiEndpointService) List(query ApiEndpointQuery, page, pageSize string) ([]models.ApiEndpoint, int64, error) {
+	var endpoints []models.ApiEndpoint
+	var total int64
+	db := s.db.Model(&models.ApiEndpoint{})
+
+	if query.Keyword != "" {
+		keyword := "%" + query.Keyword + "%"
+		db = db.Where("path LIKE ? OR summary LIKE ? OR handler LIKE ?", keyword, keyword, keyword)
+	}
+	if query.Method != "" {
+		db = db.Where("method = ?", strings.ToUpper(query.Method))
+	}
+	if query.Group != "" {
+		db = db.Where("route_group = ?", query.Group)
+	}
+	if query.Stale != nil {
+		db = db.Where("stale = ?", *query.Stale)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Order("path, method").Scopes(models.Paginate(page, pageSize)).Find(&endpoints).Error; err != nil {
+		return nil, 0, err
+	}
+	return endpoints, total, nil
+}
+
+func (s *apiEndpointService) Match(method, path string) (bool, error) {
+	var paths []string
+	if err := s.db.Model(&models.ApiEndpoint{}).
+		Where("method = ? AND stale = ?", strings.ToUpper(method), false).
+		Pluck("path", &paths).Error; err != nil {
+		return false, err
+	}
+	for _, endpoint := range paths {
+		if endpoint == path || util.KeyMatch2(endpoint, path) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// swaggerSummaries 从 Swagger 文档读取接口摘要，键为 "GET /gam/admins/:id"；没有文档时返回空
+func swaggerSummaries() map[string]string {
+	summaries := make(map[string]string)
+	doc, err := swag.ReadDoc()
+	if err != nil {
+		return summaries
+	}
+
+	var spec struct {
+		Paths map[string]map[string]struct {
+			Summary string `json:"summary"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal([]byte(doc), &spec); err != nil {
+		return summaries
+	}
+	for path, operations := range spec.Paths {
+		// Swagger 的 {id} 对应路由的 :id
+		path = strings.NewReplacer("{", ":", "}", "").Replace(path)
+		for method, operation := range operations {
+			summaries[strings.ToUpper(method)+" "+path] = operation.Summary
+		}
+	}
+	return summaries
+}
+
+// routeGroup 接口分组，/gam 和 /api 下取第二段路径，其他取第一段，如 /gam/admins/:id 为 admins
+func routeGroup(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) > 1 && (segments[0] == "gam" || segments[0] == "api") {
+		return segments[1]
+	}
+	return segments[0]
+}
+
+// handlerName 去掉包路径和方法值后缀，如 handlers.(*AdminHandler).GetAdminList
+func handlerName(name string) string {
+	name = name[strings.LastIndex(name, "/")+1:]
+	return strings.TrimSuffix(name, "-fm")
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/auth"
+	"normaladmin/backend/pkg/sysconfig"
+	"normaladmin/backend/pkg/tenant"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAPIKeyNotFound API 密钥不存在
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrAPIKeyInvalidScope 权限范围不存在或超出所属管理员的权限
+	ErrAPIKeyInvalidScope = errors.New("invalid api key scope")
+	// ErrAPIKeyInvalidIP IP 白名单格式错误
+	ErrAPIKeyInvalidIP = errors.New("invalid api key ip allowlist entry")
+	// ErrAPIKeyInvalidExpiry 过期时间早于当前时间或超过最长有效期
+	ErrAPIKeyInvalidExpiry = errors.New("invalid api key expiry")
+)
+
+// APIKeyScope 可授予 API 密钥的权限范围，对应一个带接口信息的按钮权限
+type APIKeyScope struct {
+	Permission string `json:"permission"`
+	Title      string `json:"title"`
+	ApiMethod  string `json:"api_method"`
+	ApiPath    string `json:"api_path"`
+}
+
+// CreateAPIKeyInput 创建 API 密钥参数
+type CreateAPIKeyInput struct {
+	Name       string
+	Scopes     []string
+	AllowedIPs []string
+	ExpiresAt  time.Time
+}
+
+// APIKeyService API 密钥管理服务
+// 权限范围只能从所属管理员已有的按钮权限中选择，创建时与密钥记录在同一事务中写入以 apikey:<id> 为主体的 Casbin 策略；
+// 认证时还会校验所属管理员当前的权限，密钥的策略只用于收窄权限
+type APIKeyService interface {
+	// Create 创建 API 密钥，返回记录和只展示一次的明文密钥
+	Create(adminID uint, input CreateAPIKeyInput) (*models.APIKey, string, error)
+	// List 获取管理员的 API 密钥，adminID 为0时返回全部
+	List(adminID uint) ([]models.APIKey, error)
+	// Get 按ID获取 API 密钥
+	Get(id uint) (*models.APIKey, error)
+	// Revoke 吊销 API 密钥并删除其 Casbin 策略
+	Revoke(id uint) error
+	// AvailableScopes 管理员可授予 API 密钥的权限范围
+	AvailableScopes(adminID uint) ([]APIKeyScope, error)
+	// WithContext 返回使用请求上下文的服务，只能访问上下文中租户的管理员及其密钥
+	WithContext(ctx context.Context) APIKeyService
+}
+
+type apiKeyService struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyService(db *gorm.DB) APIKeyService {
+	return &apiKeyService{db: db}
+}
+
+func (s *apiKeyService) WithContext(ctx context.Context) APIKeyService {
+	return &apiKeyService{db: s.db.WithContext(ctx)}
+}
+
+// keys API 密钥查询，密钥没有租户字段，上下文带租户时按所属管理员限定在该租户内
+func (s *apiKeyService) keys() *gorm.DB {
+	db := s.db.Model(&models.APIKey{})
+	if tenantID, ok := tenant.FromContext(s.db.Statement.Context); ok {
+		db = db.Where("admin_id IN (?)", s.db.Model(&models.Admin{}).Select("id").Where("tenant_id = ?", tenantID))
+	}
+	return db
+}
+
+func (s *apiKeyService) Create(adminID uint, input CreateAPIKeyInput) (*models.APIKey, string, error) {
+	if !input.ExpiresAt.After(time.Now()) {
+		return nil, "", fmt.Errorf("%w: must be in the future", ErrAPIKeyInvalidExpiry)
+	}
+	if maxDays := sysconfig.GetInt("api_key_max_days", 365); maxDays > 0 && input.ExpiresAt.After(time.Now().AddDate(0, 0, maxDays)) {
+		return nil, "", fmt.Errorf("%w: must be within %d days", ErrAPIKeyInvalidExpiry, maxDays)
+	}
+
+	allowedIPs, err := normalizeAllowedIPs(input.AllowedIPs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 权限范围必须是所属管理员已有的按钮权限
+	available, err := s.AvailableScopes(adminID)
+	if err != nil {
+		return nil, "", err
+	}
+	byPermission := make(map[string][]APIKeyScope)
+	for _, scope := range available {
+		byPermission[scope.Permission] = append(byPermission[scope.Permission], scope)
+	}
+	var scopes []string
+	var policies []auth.Policy
+	seen := make(map[string]bool)
+	for _, scope := range input.Scopes {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || seen[scope] {
+			continue
+		}
+		matched, ok := byPermission[scope]
+		if !ok {
+			return nil, "", fmt.Errorf("%w: %s", ErrAPIKeyInvalidScope, scope)
+		}
+		seen[scope] = true
+		scopes = append(scopes, scope)
+		for _, policy := range matched {
+			policies = append(policies, auth.Policy{Path: policy.ApiPath, Method: strings.ToUpper(policy.ApiMethod)})
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("%w: at least one scope is required", ErrAPIKeyInvalidScope)
+	}
+
+	// 密钥的策略写入所属管理员租户的域
+	var admin models.Admin
+	if err := s.db.Select("id", "tenant_id").First(&admin, adminID).Error; err != nil {
+		return nil, "", err
+	}
+	domain := auth.TenantDomain(admin.TenantID)
+
+	key, prefix, hash := auth.GenerateAPIKey()
+	apiKey := &models.APIKey{
+		AdminID:    adminID,
+		Name:       strings.TrimSpace(input.Name),
+		Prefix:     prefix,
+		KeyHash:    hash,
+		Scopes:     scopes,
+		AllowedIPs: allowedIPs,
+		ExpiresAt:  input.ExpiresAt,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(apiKey).Error; err != nil {
+			return err
+		}
+		_, err := auth.ReplaceSubjectPolicies(tx, auth.APIKeySubject(apiKey.ID), domain, policies)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if err := auth.ReloadPolicy(); err != nil {
+		return nil, "", err
+	}
+	return apiKey, key, nil
+}
+
+func (s *apiKeyService) List(adminID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	db := s.keys().Order("id DESC")
+	if adminID > 0 {
+		db = db.Where("admin_id = ?", adminID)
+	}
+	if err := db.Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (s *apiKeyService) Get(id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := s.keys().First(&key, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (s *apiKeyService) Revoke(id uint) error {
+	key, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if key.RevokedAt == nil {
+			if err := tx.Model(key).Update("revoked_at", time.Now()).Error; err != nil {
+				return err
+			}
+		}
+		_, err := auth.ReplaceSubjectPolicies(tx, auth.APIKeySubject(id), "", nil)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return auth.ReloadPolicy()
+}
+
+func (s *apiKeyService) AvailableScopes(adminID uint) ([]APIKeyScope, error) {
+	var admin models.Admin
+	if err := s.db.First(&admin, adminID).Error; err != nil {
+		return nil, err
+	}
+
+	db := s.db.Model(&models.Menu{}).
+		Select("menus.permission, menus.title, menus.api_method, menus.api_path").
+		Where("menus.type = 'button' AND menus.status = 1 AND menus.permission <> '' AND menus.api_path <> '' AND menus.api_method <> ''")
+	// 超级管理员可授予全部权限，其他管理员只能授予已启用角色已有的权限
+	if !IsSuperAdmin(s.db, admin.ID) {
+		db = db.Where("menus.id IN (?)", s.db.Model(&models.RoleMenu{}).
+			Select("role_menus.menu_id").
+			Joins("INNER JOIN (?) AS admin_roles ON admin_roles.role_id = role_menus.role_id", models.AdminRoleAssignments(s.db)).
+			Joins("INNER JOIN roles ON roles.id = role_menus.role_id AND roles.status = 1 AND roles.deleted_at IS NULL").
+			Where("admin_roles.admin_id = ?", admin.ID))
+	}
+
+	var scopes []APIKeyScope
+	if err := db.Order("menus.permission ASC").Scan(&scopes).Error; err != nil {
+		return nil, err
+	}
+	return scopes, nil
+}
+
+// normalizeAllowedIPs 校验 IP 白名单，支持单个 IP 和 CIDR
+func normalizeAllowedIPs(entries []string) ([]string, error) {
+	allowed := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrAPIKeyInvalidIP, entry)
+			}
+			allowed = append(allowed, network.String())
+			continue
+		}
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return nil, fmt.Errorf("%w: %s", ErrAPIKeyInvalidIP, entry)
+		}
+		allowed = append(allowed, ip.String())
+	}
+	return allowed, nil
+}
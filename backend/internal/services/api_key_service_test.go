@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/tenant"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 租户隔离测试使用的两个租户，各有一个管理员
+const (
+	testTenantA uint = 2
+	testTenantB uint = 3
+	testAdminA  uint = 1
+	testAdminB  uint = 2
+)
+
+// newTenantTestDB 内存数据库，租户 A、B 各一个管理员，每个管理员各有一个 API 密钥和恢复码
+func newTenantTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Role{}, &models.Admin{}, &models.APIKey{}, &models.AdminRecoveryCode{}); err != nil {
+		t.Fatal(err)
+	}
+
+	status := 1
+	for _, admin := range []models.Admin{
+		{ID: testAdminA, TenantID: testTenantA, Username: "alice", Password: "secret", Status: &status, MFAEnabled: true, MFASecret: "a"},
+		{ID: testAdminB, TenantID: testTenantB, Username: "bob", Password: "secret", Status: &status, MFAEnabled: true, MFASecret: "b"},
+	} {
+		admin := admin
+		if err := db.Create(&admin).Error; err != nil {
+			t.Fatal(err)
+		}
+		key := models.APIKey{ID: admin.ID, AdminID: admin.ID, Name: admin.Username, Prefix: "nak_", KeyHash: admin.Username, ExpiresAt: time.Now().Add(time.Hour)}
+		if err := db.Create(&key).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Create(&models.AdminRecoveryCode{AdminID: admin.ID, CodeHash: admin.Username}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func tenantContext(tenantID uint) context.Context {
+	return tenant.WithTenant(context.Background(), tenantID)
+}
+
+func TestAPIKeyServiceTenantIsolation(t *testing.T) {
+	base := NewAPIKeyService(newTenantTestDB(t))
+	tests := []struct {
+		name    string
+		service APIKeyService
+		adminID uint
+		want    []uint
+	}{
+		{name: "no tenant lists all", service: base, want: []uint{2, 1}},
+		{name: "tenant a", service: base.WithContext(tenantContext(testTenantA)), want: []uint{1}},
+		{name: "tenant b", service: base.WithContext(tenantContext(testTenantB)), want: []uint{2}},
+		{name: "admin of another tenant", service: base.WithContext(tenantContext(testTenantA)), adminID: testAdminB, want: []uint{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := tt.service.List(tt.adminID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]uint, 0, len(keys))
+			for _, key := range keys {
+				ids = append(ids, key.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Fatalf("List() ids = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	a := base.WithContext(tenantContext(testTenantA))
+	if _, err := a.Get(testAdminB); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Fatalf("Get() key of another tenant error = %v, want ErrAPIKeyNotFound", err)
+	}
+	if _, err := a.Get(testAdminA); err != nil {
+		t.Fatalf("Get() own key error = %v", err)
+	}
+	// 吊销前先按租户查询密钥，其他租户的密钥不会被吊销
+	if err := a.Revoke(testAdminB); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Fatalf("Revoke() key of another tenant error = %v, want ErrAPIKeyNotFound", err)
+	}
+	key, err := base.Get(testAdminB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.RevokedAt != nil {
+		t.Fatal("key of another tenant was revoked")
+	}
+}
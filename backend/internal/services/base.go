@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"normaladmin/backend/internal/models"
 
 	"gorm.io/gorm"
@@ -9,11 +10,11 @@ import (
 // CRUDService 基础CRUD服务接口
 type BaseCRUD[T any] interface {
 	GetByID(id uint, opts ...models.QueryOption) (*T, error)
-	List(query map[string]interface{}, page, pageSize string, opts ...models.QueryOption) ([]T, int64, error)
-	Create(entity *T) error
-	Update(id uint, data interface{}) error
-	Delete(id uint, hardDelete bool) error
-	BatchDelete(ids []uint, hardDelete bool) error
+	List(filter models.Filter, page, pageSize string, opts ...models.QueryOption) ([]T, int64, error)
+	Create(entity *T, opts ...models.QueryOption) error
+	Update(id uint, data interface{}, opts ...models.QueryOption) error
+	Delete(id uint, hardDelete bool, opts ...models.QueryOption) error
+	BatchDelete(ids []uint, hardDelete bool, opts ...models.QueryOption) error
 }
 
 // BaseCRUDService 基础实现
@@ -27,15 +28,25 @@ func NewBaseCRUDService[T any](db *gorm.DB) BaseCRUD[T] {
 	}
 }
 
-// GetByID 实现带查询选项的获取方法
-func (s *BaseCRUDService[T]) GetByID(id uint, opts ...models.QueryOption) (*T, error) {
-	var data T
+// session 应用查询选项，写入语句通过它继承上下文（租户）
+func (s *BaseCRUDService[T]) session(opts []models.QueryOption) *gorm.DB {
 	db := s.db
-
-	// 应用所有查询选项
 	for _, opt := range opts {
 		db = opt(db)
 	}
+	return db
+}
+
+// query 应用查询选项，并按数据范围过滤实现了 models.DataScoped 的资源
+func (s *BaseCRUDService[T]) query(opts []models.QueryOption) *gorm.DB {
+	var model T
+	return models.ApplyDataScope(s.session(opts), &model)
+}
+
+// GetByID 实现带查询选项的获取方法
+func (s *BaseCRUDService[T]) GetByID(id uint, opts ...models.QueryOption) (*T, error) {
+	var data T
+	db := s.query(opts)
 
 	if err := db.First(&data, id).Error; err != nil {
 		return nil, err
@@ -43,25 +54,11 @@ func (s *BaseCRUDService[T]) GetByID(id uint, opts ...models.QueryOption) (*T, e
 	return &data, nil
 }
 
-// List 实现带查询选项的列表方法
-func (s *BaseCRUDService[T]) List(query map[string]interface{}, page, pageSize string, opts ...models.QueryOption) ([]T, int64, error) {
+// List 实现带查询选项的列表方法，过滤和排序条件由资源的过滤规格解析得到
+func (s *BaseCRUDService[T]) List(filter models.Filter, page, pageSize string, opts ...models.QueryOption) ([]T, int64, error) {
 	var data []T
 	var total int64
-	db := s.db
-
-	// 应用所有查询选项
-	for _, opt := range opts {
-		db = opt(db)
-	}
-
-	// 处理查询条件
-	for field, value := range query {
-		if strValue, ok := value.(string); ok && strValue != "" {
-			db = db.Where(field+" LIKE ?", "%"+strValue+"%")
-		} else if value != nil {
-			db = db.Where(field+" = ?", value)
-		}
-	}
+	db := filter.Apply(s.query(opts))
 
 	// 获取总数
 	if err := db.Model(&data).Count(&total).Error; err != nil {
@@ -76,40 +73,83 @@ func (s *BaseCRUDService[T]) List(query map[string]interface{}, page, pageSize s
 	return data, total, nil
 }
 
-func (s *BaseCRUDService[T]) Create(entity *T) error {
-	return s.db.Create(entity).Error
+func (s *BaseCRUDService[T]) Create(entity *T, opts ...models.QueryOption) error {
+	return s.session(opts).Create(entity).Error
 }
 
-// Update 支持实体对象或map更新
-func (s *BaseCRUDService[T]) Update(id uint, data interface{}) error {
+// Update 支持实体对象或map更新，超出数据范围的记录视为不存在
+func (s *BaseCRUDService[T]) Update(id uint, data interface{}, opts ...models.QueryOption) error {
 	var model T
-	if err := s.db.First(&model, id).Error; err != nil {
+	if err := s.query(opts).First(&model, id).Error; err != nil {
 		return err
 	}
 
-	return s.db.Model(&model).Updates(data).Error
+	return s.session(opts).Model(&model).Updates(data).Error
 }
 
-// Delete 支持软删除和硬删除
-func (s *BaseCRUDService[T]) Delete(id uint, hardDelete bool) error {
+// Delete 支持软删除和硬删除，超出数据范围的记录视为不存在
+func (s *BaseCRUDService[T]) Delete(id uint, hardDelete bool, opts ...models.QueryOption) error {
 	var model T
-	if err := s.db.First(&model, id).Error; err != nil {
+	if err := s.query(opts).First(&model, id).Error; err != nil {
 		return err
 	}
 
+	db := s.session(opts)
 	if hardDelete {
 		// 硬删除
-		return s.db.Unscoped().Delete(&model).Error
+		return db.Unscoped().Delete(&model).Error
 	}
 	// 软删除
-	return s.db.Delete(&model).Error
+	return db.Delete(&model).Error
 }
 
-// BatchDelete 批量删除支持
-func (s *BaseCRUDService[T]) BatchDelete(ids []uint, hardDelete bool) error {
+// BatchDelete 批量删除支持，只删除数据范围内的记录
+func (s *BaseCRUDService[T]) BatchDelete(ids []uint, hardDelete bool, opts ...models.QueryOption) error {
 	var model T
+	db := s.query(opts)
 	if hardDelete {
-		return s.db.Unscoped().Delete(&model, ids).Error
+		return db.Unscoped().Delete(&model, ids).Error
 	}
-	return s.db.Delete(&model, ids).Error
+	return db.Delete(&model, ids).Error
+}
+
+// contextCRUD 为每次调用追加请求上下文，供业务服务的 WithContext 包装组合的基础CRUD
+type contextCRUD[T any] struct {
+	next BaseCRUD[T]
+	ctx  context.Context
+}
+
+func withContextCRUD[T any](next BaseCRUD[T], ctx context.Context) BaseCRUD[T] {
+	if wrapped, ok := next.(*contextCRUD[T]); ok {
+		next = wrapped.next
+	}
+	return &contextCRUD[T]{next: next, ctx: ctx}
+}
+
+func (s *contextCRUD[T]) options(opts []models.QueryOption) []models.QueryOption {
+	return append([]models.QueryOption{models.WithContext(s.ctx)}, opts...)
+}
+
+func (s *contextCRUD[T]) GetByID(id uint, opts ...models.QueryOption) (*T, error) {
+	return s.next.GetByID(id, s.options(opts)...)
+}
+
+func (s *contextCRUD[T]) List(filter models.Filter, page, pageSize string, opts ...models.QueryOption) ([]T, int64, error) {
+	return s.next.List(filter, page, pageSize, s.options(opts)...)
+}
+
+func (s *contextCRUD[T]) Create(entity *T, opts ...models.QueryOption) error {
+	return s.next.Create(entity, s.options(opts)...)
+}
+
+func (s *contextCRUD[T]) Update(id uint, data interface{}, opts ...models.QueryOption) error {
+	return s.next.Update(id, data, s.options(opts)...)
+}
+
+func (s *contextCRUD[T]) Delete(id uint, hardDelete bool, opts ...models.QueryOption) error {
+	return s.next.Delete(id, hardDelete, s.options(opts)...)
+}
+
+func (s *contextCRUD[T]) BatchDelete(ids []uint, hardDelete bool, opts ...models.QueryOption) error {
+	return s.next.BatchDelete(ids, hardDelete, s.options(opts)...)
 }
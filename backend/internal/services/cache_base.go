@@ -43,18 +43,22 @@ func NewCacheBaseService[T any](next BaseCRUD[T], prefix string, expiration ...t
 func (s *CacheBaseService[T]) generateKey(id uint) string {
 	return fmt.Sprintf("%s:%d", s.cachePrefix, id)
 }
-func (s *CacheBaseService[T]) Create(entity *T) error {
-	return s.next.Create(entity)
+func (s *CacheBaseService[T]) Create(entity *T, opts ...models.QueryOption) error {
+	return s.next.Create(entity, opts...)
 }
 
 // List 方法直接调用下一个服务，不使用缓存
-func (s *CacheBaseService[T]) List(query map[string]interface{}, page, pageSize string, opts ...models.QueryOption) ([]T, int64, error) {
+func (s *CacheBaseService[T]) List(filter models.Filter, page, pageSize string, opts ...models.QueryOption) ([]T, int64, error) {
 	// 直接调用下一个服务的 List 方法
-	return s.next.List(query, page, pageSize, opts...)
+	return s.next.List(filter, page, pageSize, opts...)
 }
 
 // GetByID 带防护机制的获取方法
+// 带查询选项（预加载、数据范围、租户上下文等）时结果与缓存内容不一致，直接查询下一个服务
 func (s *CacheBaseService[T]) GetByID(id uint, opts ...models.QueryOption) (*T, error) {
+	if len(opts) > 0 {
+		return s.next.GetByID(id, opts...)
+	}
 	cacheKey := s.generateKey(id)
 
 	// 1. 尝试从缓存获取
@@ -83,7 +87,7 @@ func (s *CacheBaseService[T]) GetByID(id uint, opts ...models.QueryOption) (*T,
 	}
 
 	// 3. 从数据库获取
-	entity_ptr, err := s.next.GetByID(id, opts...)
+	entity_ptr, err := s.next.GetByID(id)
 	if err != nil {
 		s.metrics.recordError()
 		return nil, err
@@ -105,8 +109,8 @@ func (s *CacheBaseService[T]) GetByID(id uint, opts ...models.QueryOption) (*T,
 }
 
 // Update 更新实体（更新缓存）
-func (s *CacheBaseService[T]) Update(id uint, data interface{}) error {
-	if err := s.next.Update(id, data); err != nil {
+func (s *CacheBaseService[T]) Update(id uint, data interface{}, opts ...models.QueryOption) error {
+	if err := s.next.Update(id, data, opts...); err != nil {
 		return err
 	}
 
@@ -120,8 +124,8 @@ func (s *CacheBaseService[T]) Update(id uint, data interface{}) error {
 }
 
 // Delete 删除实体（删除缓存）
-func (s *CacheBaseService[T]) Delete(id uint, hardDelete bool) error {
-	if err := s.next.Delete(id, hardDelete); err != nil {
+func (s *CacheBaseService[T]) Delete(id uint, hardDelete bool, opts ...models.QueryOption) error {
+	if err := s.next.Delete(id, hardDelete, opts...); err != nil {
 		return err
 	}
 
@@ -135,8 +139,8 @@ func (s *CacheBaseService[T]) Delete(id uint, hardDelete bool) error {
 }
 
 // BatchDelete 批量删除（批量删除缓存）
-func (s *CacheBaseService[T]) BatchDelete(ids []uint, hardDelete bool) error {
-	if err := s.next.BatchDelete(ids, hardDelete); err != nil {
+func (s *CacheBaseService[T]) BatchDelete(ids []uint, hardDelete bool, opts ...models.QueryOption) error {
+	if err := s.next.BatchDelete(ids, hardDelete, opts...); err != nil {
 		return err
 	}
 
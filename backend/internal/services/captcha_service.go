@@ -0,0 +1,135 @@
+package services
+
+import (
+	"normaladmin/backend/pkg/auth"
+	"normaladmin/backend/pkg/cache"
+	"normaladmin/backend/pkg/captcha"
+	"normaladmin/backend/pkg/sysconfig"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	captchaPrefix     = "captcha:"      // 验证码答案
+	captchaFailPrefix = "captcha:fail:" // 非登录场景的失败计数
+
+	captchaWidth  = 120
+	captchaHeight = 40
+)
+
+// 验证码启用方式
+const (
+	CaptchaModeOff           = "off"            // 不启用
+	CaptchaModeAlways        = "always"         // 始终需要
+	CaptchaModeAfterFailures = "after_failures" // 失败次数达到阈值后需要
+)
+
+// 需要验证码的场景
+const (
+	CaptchaSceneRegister = "register"
+)
+
+// CaptchaChallenge 返回给前端的验证码
+type CaptchaChallenge struct {
+	CaptchaID string `json:"captcha_id"`
+	Image     string `json:"image"`      // PNG 图片的 data URI
+	ExpiresIn int    `json:"expires_in"` // 有效期(秒)
+}
+
+// CaptchaService 图形验证码服务
+// 答案保存在 Redis，校验一次后立即失效；是否需要验证码由系统配置决定
+type CaptchaService interface {
+	// Generate 生成验证码图片
+	Generate() (*CaptchaChallenge, error)
+	// Verify 校验验证码，无论结果如何验证码都会失效，答案不区分大小写
+	Verify(captchaID, answer string) bool
+	// Required 按当前失败次数判断是否需要验证码
+	Required(failures int64) bool
+	// Failures 获取非登录场景的失败次数，登录失败次数由 LoginGuardService 统计
+	Failures(scene, subject string) int64
+	// RecordFailure 记录一次非登录场景的失败
+	RecordFailure(scene, subject string)
+}
+
+type captchaService struct{}
+
+func NewCaptchaService() CaptchaService {
+	return &captchaService{}
+}
+
+func (s *captchaService) Generate() (*CaptchaChallenge, error) {
+	challenge := captcha.New(sysconfig.Get("captcha_type", captcha.TypeText), sysconfig.GetInt("captcha_length", 4))
+	img, err := captcha.Render(challenge.Question, captchaWidth, captchaHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := captchaTTL()
+	id := auth.RandomURLToken(18)
+	if err := cache.Set(captchaPrefix+id, challenge.Answer, ttl); err != nil {
+		return nil, err
+	}
+	return &CaptchaChallenge{
+		CaptchaID: id,
+		Image:     captcha.DataURI(img),
+		ExpiresIn: int(ttl / time.Second),
+	}, nil
+}
+
+func (s *captchaService) Verify(captchaID, answer string) bool {
+	captchaID, answer = strings.TrimSpace(captchaID), strings.TrimSpace(answer)
+	if captchaID == "" || answer == "" {
+		return false
+	}
+	expected, err := cache.GetDel(captchaPrefix + captchaID)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(expected, answer)
+}
+
+func (s *captchaService) Required(failures int64) bool {
+	switch sysconfig.Get("captcha_mode", CaptchaModeAfterFailures) {
+	case CaptchaModeAlways:
+		return true
+	case CaptchaModeAfterFailures:
+		return failures >= int64(sysconfig.GetInt("captcha_after_failures", 3))
+	default:
+		return false
+	}
+}
+
+func (s *captchaService) Failures(scene, subject string) int64 {
+	value, err := cache.Get(captchaFailKey(scene, subject))
+	if err != nil {
+		return 0
+	}
+	failures, _ := strconv.ParseInt(value, 10, 64)
+	return failures
+}
+
+func (s *captchaService) RecordFailure(scene, subject string) {
+	// 与登录失败共用统计窗口
+	key := captchaFailKey(scene, subject)
+	window := time.Duration(sysconfig.GetInt("login_failure_window", 15)) * time.Minute
+	if _, err := cache.SetNX(key, 0, window); err != nil && err != redis.Nil {
+		return
+	}
+	_, _ = cache.Incr(key)
+}
+
+// captchaTTL 验证码有效期
+func captchaTTL() time.Duration {
+	seconds := sysconfig.GetInt("captcha_ttl_seconds", 120)
+	if seconds <= 0 {
+		seconds = 120
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func captchaFailKey(scene, subject string) string {
+	return captchaFailPrefix + scene + ":" + subject
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"normaladmin/backend/internal/models"
 	"normaladmin/backend/pkg/cache"
@@ -16,6 +17,8 @@ type ConfigService interface {
 	GetConfigValue(groupKey, itemKey string) (string, error)
 	UpdateConfigValue(groupID int64, itemKey string, value string) error
 	BatchUpdateConfigs(groupID int64, configs map[string]string) error
+	// WithContext 返回使用请求上下文的服务，配置按租户隔离
+	WithContext(ctx context.Context) ConfigService
 }
 
 type configService struct {
@@ -28,6 +31,10 @@ func NewConfigService(db *gorm.DB) ConfigService {
 	}
 }
 
+func (s *configService) WithContext(ctx context.Context) ConfigService {
+	return &configService{db: s.db.WithContext(ctx)}
+}
+
 // GetConfigGroups 获取所有配置组
 func (s *configService) GetConfigGroups() ([]models.ConfigGroup, error) {
 	var groups []models.ConfigGroup
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"normaladmin/backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrRoleNotFound 角色不存在
+var ErrRoleNotFound = errors.New("role not found")
+
+// DepartmentResolver 提供部门类数据范围所需的部门信息
+type DepartmentResolver interface {
+	// DepartmentOf 管理员所在部门，0 表示未分配部门
+	DepartmentOf(adminID uint) (uint, error)
+	// Descendants 部门本身及其全部下级部门
+	Descendants(departmentID uint) ([]uint, error)
+}
+
+// DataScopeService 数据权限服务
+// 按角色的 DataScope 计算管理员可访问的数据范围，并维护自定义范围的角色部门
+type DataScopeService interface {
+	// Resolve 计算管理员的数据范围，取全部已启用角色范围的并集；
+	// 拥有超级管理员或全部数据范围的角色时不做限制；始终包含本人的数据，未分配部门时部门类范围只包含本人的数据
+	Resolve(adminID uint) (*models.DataScope, error)
+	// ResolveRole 角色本身的数据范围，部门类范围取决于管理员所在部门，只返回范围类型
+	ResolveRole(roleID uint) (*models.DataScope, error)
+	// GetRoleDepartments 获取角色自定义数据范围的部门
+	GetRoleDepartments(roleID uint) ([]uint, error)
+	// UpdateRoleDepartments 更新角色自定义数据范围的部门，部门不存在时返回 ErrDepartmentNotFound
+	UpdateRoleDepartments(roleID uint, departmentIDs []uint) error
+	// WithContext 返回使用请求上下文的服务，只能设置上下文中租户的角色和部门
+	WithContext(ctx context.Context) DataScopeService
+}
+
+type dataScopeService struct {
+	db          *gorm.DB
+	departments DepartmentResolver
+}
+
+// NewDataScopeService departments 为 nil 时没有部门信息，部门类范围只包含本人的数据
+func NewDataScopeService(db *gorm.DB, departments DepartmentResolver) DataScopeService {
+	return &dataScopeService{db: db, departments: departments}
+}
+
+func (s *dataScopeService) WithContext(ctx context.Context) DataScopeService {
+	clone := *s
+	clone.db = s.db.WithContext(ctx)
+	return &clone
+}
+
+func (s *dataScopeService) Resolve(adminID uint) (*models.DataScope, error) {
+	roles, err := AdminRoles(s.db, adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := &models.DataScope{UserID: adminID}
+	seen := make(map[uint]bool)
+	for _, role := range roles {
+		if role.Code == SuperAdminRoleCode || role.DataScope == models.DataScopeAll {
+			scope.All = true
+			scope.Scopes = []int{models.DataScopeAll}
+			scope.DepartmentIDs = nil
+			return scope, nil
+		}
+
+		var departmentIDs []uint
+		switch role.DataScope {
+		case models.DataScopeCustom:
+			departmentIDs, err = s.GetRoleDepartments(role.ID)
+		case models.DataScopeDept, models.DataScopeDeptAndBelow:
+			departmentIDs, err = s.ownDepartments(adminID, role.DataScope == models.DataScopeDeptAndBelow)
+		default:
+			// 仅本人及未知的范围都只能访问本人的数据
+			role.DataScope = models.DataScopeSelf
+		}
+		if err != nil {
+			return nil, err
+		}
+		scope.Scopes = append(scope.Scopes, role.DataScope)
+		for _, departmentID := range departmentIDs {
+			if !seen[departmentID] {
+				seen[departmentID] = true
+				scope.DepartmentIDs = append(scope.DepartmentIDs, departmentID)
+			}
+		}
+	}
+	if len(scope.Scopes) == 0 {
+		// 没有已启用的角色，只能访问本人的数据
+		scope.Scopes = []int{models.DataScopeSelf}
+	}
+	return scope, nil
+}
+
+func (s *dataScopeService) ResolveRole(roleID uint) (*models.DataScope, error) {
+	var role models.Role
+	if err := s.db.Select("id", "code", "data_scope").First(&role, roleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+
+	scope := &models.DataScope{Scopes: []int{role.DataScope}}
+	if role.Code == SuperAdminRoleCode || role.DataScope == models.DataScopeAll {
+		scope.All = true
+		scope.Scopes = []int{models.DataScopeAll}
+		return scope, nil
+	}
+
+	switch role.DataScope {
+	case models.DataScopeCustom:
+		departmentIDs, err := s.GetRoleDepartments(roleID)
+		if err != nil {
+			return nil, err
+		}
+		scope.DepartmentIDs = departmentIDs
+	case models.DataScopeDept, models.DataScopeDeptAndBelow:
+	default:
+		scope.Scopes = []int{models.DataScopeSelf}
+	}
+	return scope, nil
+}
+
+func (s *dataScopeService) GetRoleDepartments(roleID uint) ([]uint, error) {
+	departmentIDs := make([]uint, 0)
+	if err := s.db.Model(&models.RoleDepartment{}).Where("role_id = ?", roleID).
+		Order("department_id").Pluck("department_id", &departmentIDs).Error; err != nil {
+		return nil, err
+	}
+	return departmentIDs, nil
+}
+
+func (s *dataScopeService) UpdateRoleDepartments(roleID uint, departmentIDs []uint) error {
+	var count int64
+	if err := s.db.Model(&models.Role{}).Where("id = ?", roleID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrRoleNotFound
+	}
+
+	unique := make([]uint, 0, len(departmentIDs))
+	seen := make(map[uint]bool)
+	for _, departmentID := range departmentIDs {
+		if departmentID != 0 && !seen[departmentID] {
+			seen[departmentID] = true
+			unique = append(unique, departmentID)
+		}
+	}
+	if len(unique) > 0 {
+		if err := s.db.Model(&models.Department{}).Where("id IN ?", unique).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(unique) {
+			return ErrDepartmentNotFound
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ?", roleID).Delete(&models.RoleDepartment{}).Error; err != nil {
+			return err
+		}
+		for _, departmentID := range unique {
+			if err := tx.Create(&models.RoleDepartment{RoleID: roleID, DepartmentID: departmentID}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ownDepartments 管理员所在部门，withDescendants 为 true 时包含全部下级部门
+func (s *dataScopeService) ownDepartments(adminID uint, withDescendants bool) ([]uint, error) {
+	if s.departments == nil {
+		return nil, nil
+	}
+	departmentID, err := s.departments.DepartmentOf(adminID)
+	if err != nil || departmentID == 0 {
+		return nil, err
+	}
+	if !withDescendants {
+		return []uint{departmentID}, nil
+	}
+	departmentIDs, err := s.departments.Descendants(departmentID)
+	if errors.Is(err, ErrDepartmentNotFound) {
+		// 所在部门已删除，只能访问本人的数据
+		return nil, nil
+	}
+	return departmentIDs, err
+}
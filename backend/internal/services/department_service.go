@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"normaladmin/backend/internal/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrDepartmentNotFound 部门不存在
+	ErrDepartmentNotFound = errors.New("department not found")
+	// ErrDepartmentParentNotFound 上级部门不存在
+	ErrDepartmentParentNotFound = errors.New("parent department not found")
+	// ErrDepartmentCycle 不能选择自身或下级部门作为上级部门
+	ErrDepartmentCycle = errors.New("cannot select self or child department as parent")
+	// ErrDepartmentHasChildren 存在下级部门，不能删除
+	ErrDepartmentHasChildren = errors.New("cannot delete department with child departments")
+	// ErrDepartmentHasAdmins 部门下还有管理员，不能删除
+	ErrDepartmentHasAdmins = errors.New("cannot delete department that has admins")
+	// ErrDepartmentLeaderNotFound 负责人不存在
+	ErrDepartmentLeaderNotFound = errors.New("department leader not found")
+)
+
+// DepartmentService 部门管理服务
+// 部门以 ParentID 组成树，同时维护物化路径 Path，下级部门查询只需一次前缀匹配
+type DepartmentService interface {
+	DepartmentResolver
+	GetDepartmentTree() ([]models.Department, error)
+	GetDepartments(query map[string]interface{}) ([]models.Department, error)
+	GetDepartment(id uint) (*models.Department, error)
+	CreateDepartment(department *models.Department) error
+	UpdateDepartment(id uint, department *models.Department) error
+	DeleteDepartment(id uint) error
+	UpdateStatus(id uint, status int) error
+	// MoveDepartment 将部门连同下级部门移动到新的上级部门下，并设置排序
+	MoveDepartment(id, parentID uint, sort int) error
+	// ReorderDepartments 按 ids 的顺序重排同一上级部门下的部门
+	ReorderDepartments(parentID uint, ids []uint) error
+	// WithContext 返回使用请求上下文的服务，只能访问上下文中租户的部门
+	WithContext(ctx context.Context) DepartmentService
+}
+
+type departmentService struct {
+	db *gorm.DB
+}
+
+func NewDepartmentService(db *gorm.DB) DepartmentService {
+	return &departmentService{db: db}
+}
+
+func (s *departmentService) WithContext(ctx context.Context) DepartmentService {
+	return &departmentService{db: s.db.WithContext(ctx)}
+}
+
+func (s *departmentService) GetDepartmentTree() ([]models.Department, error) {
+	var departments []models.Department
+	if err := s.db.Order("sort, id").Find(&departments).Error; err != nil {
+		return nil, err
+	}
+	return buildDepartmentTree(departments, 0), nil
+}
+
+func (s *departmentService) GetDepartments(query map[string]interface{}) ([]models.Department, error) {
+	var departments []models.Department
+	db := s.db.Order("sort, id")
+
+	for key, value := range query {
+		if str, ok := value.(string); ok && str != "" {
+			db = db.Where(key+" LIKE ?", "%"+str+"%")
+		} else if value != nil {
+			db = db.Where(key+" = ?", value)
+		}
+	}
+
+	if err := db.Find(&departments).Error; err != nil {
+		return nil, err
+	}
+	return departments, nil
+}
+
+func (s *departmentService) GetDepartment(id uint) (*models.Department, error) {
+	return s.find(s.db, id)
+}
+
+func (s *departmentService) CreateDepartment(department *models.Department) error {
+	if err := s.validateLeader(department.LeaderID); err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		parentPath := ""
+		if department.ParentID != 0 {
+			parent, err := s.find(tx, department.ParentID)
+			if err != nil {
+				return ErrDepartmentParentNotFound
+			}
+			parentPath = parent.Path
+		}
+
+		department.ID = 0
+		department.Path = ""
+		if err := tx.Create(department).Error; err != nil {
+			return err
+		}
+		department.Path = models.DepartmentPath(parentPath, department.ID)
+		return tx.Model(department).Update("path", department.Path).Error
+	})
+}
+
+func (s *departmentService) UpdateDepartment(id uint, department *models.Department) error {
+	if err := s.validateLeader(department.LeaderID); err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		existing, err := s.find(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.move(tx, existing, department.ParentID); err != nil {
+			return err
+		}
+		if department.Status == nil {
+			department.Status = existing.Status
+		}
+
+		if err := tx.Model(existing).
+			Select("name", "leader_id", "phone", "email", "sort", "status").
+			Updates(department).Error; err != nil {
+			return err
+		}
+		department.ID = id
+		department.Path = existing.Path
+		return nil
+	})
+}
+
+func (s *departmentService) DeleteDepartment(id uint) error {
+	if _, err := s.find(s.db, id); err != nil {
+		return err
+	}
+
+	// 检查是否有下级部门
+	var childCount int64
+	if err := s.db.Model(&models.Department{}).Where("parent_id = ?", id).Count(&childCount).Error; err != nil {
+		return err
+	}
+	if childCount > 0 {
+		return ErrDepartmentHasChildren
+	}
+
+	// 检查是否有管理员
+	var adminCount int64
+	if err := s.db.Model(&models.Admin{}).Where("department_id = ?", id).Count(&adminCount).Error; err != nil {
+		return err
+	}
+	if adminCount > 0 {
+		return ErrDepartmentHasAdmins
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("department_id = ?", id).Delete(&models.RoleDepartment{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Department{}, id).Error
+	})
+}
+
+func (s *departmentService) UpdateStatus(id uint, status int) error {
+	if _, err := s.find(s.db, id); err != nil {
+		return err
+	}
+	return s.db.Model(&models.Department{}).Where("id = ?", id).Update("status", status).Error
+}
+
+func (s *departmentService) MoveDepartment(id, parentID uint, sort int) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		department, err := s.find(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.move(tx, department, parentID); err != nil {
+			return err
+		}
+		return tx.Model(department).Update("sort", sort).Error
+	})
+}
+
+func (s *departmentService) ReorderDepartments(parentID uint, ids []uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for i, id := range ids {
+			result := tx.Model(&models.Department{}).Where("id = ? AND parent_id = ?", id, parentID).Update("sort", i+1)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				// 排序未变化时也不会有影响行，再确认部门是否存在
+				var count int64
+				if err := tx.Model(&models.Department{}).Where("id = ? AND parent_id = ?", id, parentID).Count(&count).Error; err != nil {
+					return err
+				}
+				if count == 0 {
+					return ErrDepartmentNotFound
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (s *departmentService) DepartmentOf(adminID uint) (uint, error) {
+	var departmentIDs []uint
+	if err := s.db.Model(&models.Admin{}).Where("id = ?", adminID).Pluck("department_id", &departmentIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(departmentIDs) == 0 {
+		return 0, nil
+	}
+	return departmentIDs[0], nil
+}
+
+func (s *departmentService) Descendants(departmentID uint) ([]uint, error) {
+	department, err := s.find(s.db, departmentID)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint
+	if err := s.db.Model(&models.Department{}).Where("path LIKE ?", department.Path+"%").Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// move 修改上级部门，同时改写自身及全部下级部门的物化路径
+func (s *departmentService) move(tx *gorm.DB, department *models.Department, parentID uint) error {
+	if parentID == department.ParentID {
+		return nil
+	}
+
+	parentPath := ""
+	if parentID != 0 {
+		parent, err := s.find(tx, parentID)
+		if err != nil {
+			return ErrDepartmentParentNotFound
+		}
+		// 上级部门的路径以自身路径开头，说明是自身或下级部门
+		if strings.HasPrefix(parent.Path, department.Path) {
+			return ErrDepartmentCycle
+		}
+		parentPath = parent.Path
+	}
+
+	oldPath := department.Path
+	newPath := models.DepartmentPath(parentPath, department.ID)
+	if err := tx.Model(&models.Department{}).
+		Where("path LIKE ?", oldPath+"%").
+		Update("path", gorm.Expr("CONCAT(?, SUBSTRING(path, ?))", newPath, len(oldPath)+1)).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(department).Update("parent_id", parentID).Error; err != nil {
+		return err
+	}
+	department.ParentID = parentID
+	department.Path = newPath
+	return nil
+}
+
+func (s *departmentService) find(db *gorm.DB, id uint) (*models.Department, error) {
+	var department models.Department
+	if err := db.First(&department, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDepartmentNotFound
+		}
+		return nil, err
+	}
+	return &department, nil
+}
+
+func (s *departmentService) validateLeader(leaderID uint) error {
+	if leaderID == 0 {
+		return nil
+	}
+	var count int64
+	if err := s.db.Model(&models.Admin{}).Where("id = ?", leaderID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrDepartmentLeaderNotFound
+	}
+	return nil
+}
+
+func buildDepartmentTree(departments []models.Department, parentID uint) []models.Department {
+	var tree []models.Department
+	for _, department := range departments {
+		if department.ParentID == parentID {
+			department.Children = buildDepartmentTree(departments, department.ID)
+			tree = append(tree, department)
+		}
+	}
+	return tree
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/utils"
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// ErrFieldNotEditable 没有编辑字段的权限
+var ErrFieldNotEditable = errors.New("field is not editable")
+
+// 字段权限的资源名称，与权限编码的前缀一致
+const (
+	FieldResourceMember = "member"
+	FieldResourceAdmin  = "admin"
+)
+
+// FieldGrants 管理员拥有的字段权限
+type FieldGrants struct {
+	all   bool
+	codes map[string]bool
+}
+
+// Can 是否拥有字段的操作权限，field 为字段的 JSON 名称
+func (g *FieldGrants) Can(resource, field, action string) bool {
+	return g.all || g.codes[models.FieldPermission(resource, field, action)]
+}
+
+// Mask 将没有查看权限的敏感字段替换为掩码，v 为模型指针、模型切片或其指针
+// 字符串按 mask 标签的方式遮盖，其他类型清空为零值
+func (g *FieldGrants) Mask(resource string, v interface{}) {
+	if g.all {
+		return
+	}
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			elem := value.Index(i)
+			if elem.Kind() != reflect.Ptr && elem.CanAddr() {
+				elem = elem.Addr()
+			}
+			g.Mask(resource, elem.Interface())
+		}
+	case reflect.Struct:
+		if !value.CanSet() {
+			return
+		}
+		for _, field := range models.SensitiveFields(value.Type()) {
+			if g.Can(resource, field.Name, models.FieldActionView) {
+				continue
+			}
+			fv := value.Field(field.Index)
+			if fv.Kind() == reflect.String {
+				fv.SetString(utils.Mask(field.Mask, fv.String()))
+			} else {
+				fv.Set(reflect.Zero(fv.Type()))
+			}
+		}
+	}
+}
+
+// CheckWrite 校验提交的模型是否修改了没有编辑权限的敏感字段，current 和 submitted 为同一模型的指针
+// 未提交、与当前值相同或为当前值掩码的字段视为未修改，恢复为当前值后由调用方照常保存；其他修改返回 ErrFieldNotEditable
+func (g *FieldGrants) CheckWrite(resource string, current, submitted interface{}) error {
+	if g.all {
+		return nil
+	}
+	cur := reflect.ValueOf(current).Elem()
+	sub := reflect.ValueOf(submitted).Elem()
+	for _, field := range models.SensitiveFields(sub.Type()) {
+		if g.Can(resource, field.Name, models.FieldActionEdit) {
+			continue
+		}
+		cv, sv := cur.Field(field.Index), sub.Field(field.Index)
+		if sv.IsZero() || sameFieldValue(cv, sv) {
+			continue
+		}
+		if sv.Kind() == reflect.String && utils.IsMasked(field.Mask, cv.String(), sv.String()) {
+			sv.SetString(cv.String())
+			continue
+		}
+		return fmt.Errorf("%w: %s", ErrFieldNotEditable, field.Name)
+	}
+	return nil
+}
+
+// sameFieldValue 按 JSON 序列化结果比较，时间等类型不受时区和单调时钟的影响
+func sameFieldValue(a, b reflect.Value) bool {
+	aj, err := json.Marshal(a.Interface())
+	if err != nil {
+		return false
+	}
+	bj, err := json.Marshal(b.Interface())
+	if err != nil {
+		return false
+	}
+	return string(aj) == string(bj)
+}
+
+// FieldPermissionService 字段权限服务
+// 字段权限以 field 类型的菜单声明，权限编码如 member.mobile:view，与按钮权限一样通过角色菜单分配
+type FieldPermissionService interface {
+	// Grants 管理员全部已启用角色的字段权限，超级管理员拥有全部字段权限
+	Grants(adminID uint) (*FieldGrants, error)
+}
+
+type fieldPermissionService struct {
+	db *gorm.DB
+}
+
+func NewFieldPermissionService(db *gorm.DB) FieldPermissionService {
+	return &fieldPermissionService{db: db}
+}
+
+func (s *fieldPermissionService) Grants(adminID uint) (*FieldGrants, error) {
+	if IsSuperAdmin(s.db, adminID) {
+		return &FieldGrants{all: true}, nil
+	}
+
+	menuIDs := s.db.Table("role_menus").
+		Select("role_menus.menu_id").
+		Joins("INNER JOIN (?) AS admin_roles ON admin_roles.role_id = role_menus.role_id", models.AdminRoleAssignments(s.db)).
+		Joins("INNER JOIN roles ON roles.id = role_menus.role_id AND roles.status = 1 AND roles.deleted_at IS NULL").
+		Where("admin_roles.admin_id = ?", adminID)
+
+	var codes []string
+	if err := s.db.Model(&models.Menu{}).
+		Distinct("menus.permission").
+		Where("menus.id IN (?) AND menus.status = 1 AND menus.type = ?", menuIDs, models.MenuTypeField).
+		Pluck("menus.permission", &codes).Error; err != nil {
+		return nil, err
+	}
+
+	grants := &FieldGrants{codes: make(map[string]bool, len(codes))}
+	for _, code := range codes {
+		grants.codes[code] = true
+	}
+	return grants, nil
+}
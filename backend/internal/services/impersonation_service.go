@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/auth"
+	"normaladmin/backend/pkg/cache"
+	"normaladmin/backend/pkg/logger"
+	"normaladmin/backend/pkg/sysconfig"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const impersonationPrefix = "impersonation:"
+
+// 模拟登录审计日志的动作
+const (
+	ImpersonationActionStart = "模拟登录"
+	ImpersonationActionEnd   = "结束模拟登录"
+)
+
+// ErrImpersonationNotFound 管理员没有正在模拟该会员的会话
+var ErrImpersonationNotFound = errors.New("impersonation session not found")
+
+// ImpersonationSession 模拟登录会话，FamilyID 为模拟令牌的令牌家族ID
+type ImpersonationSession struct {
+	FamilyID       string    `json:"family_id"`
+	AdminID        uint      `json:"admin_id"`
+	AdminUsername  string    `json:"admin_username"`
+	MemberID       uint      `json:"member_id"`
+	MemberUsername string    `json:"member_username"`
+	StartedAt      time.Time `json:"started_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// AuditContext 审计日志的请求信息
+type AuditContext struct {
+	IP        string
+	UserAgent string
+	Method    string
+	URL       string
+}
+
+// ImpersonationService 管理员模拟会员登录服务
+// 同一管理员对同一会员只保留一个模拟会话；开始和结束时写入系统日志并通知会员
+type ImpersonationService interface {
+	// TTL 模拟令牌有效期
+	TTL() time.Duration
+	// Start 开始模拟登录，已有的模拟会话会先结束
+	Start(session *ImpersonationSession, audit AuditContext) error
+	// End 结束模拟登录并吊销模拟令牌，reason 写入审计日志
+	End(adminID, memberID uint, reason string, audit AuditContext) error
+	// WithContext 返回使用请求上下文的服务，审计日志记录到上下文中的租户
+	WithContext(ctx context.Context) ImpersonationService
+}
+
+type impersonationService struct {
+	db            *gorm.DB
+	notifications NotificationService
+}
+
+func NewImpersonationService(db *gorm.DB, notifications NotificationService) ImpersonationService {
+	return &impersonationService{db: db, notifications: notifications}
+}
+
+func (s *impersonationService) WithContext(ctx context.Context) ImpersonationService {
+	clone := *s
+	clone.db = s.db.WithContext(ctx)
+	if s.notifications != nil {
+		clone.notifications = s.notifications.WithContext(ctx)
+	}
+	return &clone
+}
+
+func (s *impersonationService) TTL() time.Duration {
+	minutes := sysconfig.GetInt("impersonation_ttl_minutes", 30)
+	if minutes <= 0 {
+		minutes = 30
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+func (s *impersonationService) Start(session *ImpersonationSession, audit AuditContext) error {
+	if err := s.End(session.AdminID, session.MemberID, "restarted", audit); err != nil && !errors.Is(err, ErrImpersonationNotFound) {
+		return err
+	}
+
+	if err := cache.SetObject(impersonationKey(session.AdminID, session.MemberID), session, time.Until(session.ExpiresAt)); err != nil {
+		return err
+	}
+
+	s.audit(session, ImpersonationActionStart, audit, map[string]interface{}{
+		"member_id":  session.MemberID,
+		"expires_at": session.ExpiresAt,
+	})
+	s.notify(session.MemberID, "管理员正在以您的身份登录",
+		fmt.Sprintf("管理员 %s 于 %s 开始以您的身份登录，用于排查问题或协助操作。", session.AdminUsername, session.StartedAt.Format("2006-01-02 15:04:05")))
+	return nil
+}
+
+func (s *impersonationService) End(adminID, memberID uint, reason string, audit AuditContext) error {
+	key := impersonationKey(adminID, memberID)
+	var session ImpersonationSession
+	if err := cache.GetObject(key, &session); err != nil {
+		return ErrImpersonationNotFound
+	}
+
+	// 吊销模拟令牌，吊销记录保留到令牌过期
+	if ttl := time.Until(session.ExpiresAt); ttl > 0 {
+		if err := auth.RevokeFamily(session.FamilyID, ttl); err != nil {
+			return err
+		}
+	}
+	if err := cache.Delete(key); err != nil {
+		return err
+	}
+
+	s.audit(&session, ImpersonationActionEnd, audit, map[string]interface{}{
+		"member_id": session.MemberID,
+		"reason":    reason,
+	})
+	s.notify(session.MemberID, "管理员已结束以您的身份登录",
+		fmt.Sprintf("管理员 %s 已于 %s 结束以您的身份登录。", session.AdminUsername, time.Now().Format("2006-01-02 15:04:05")))
+	return nil
+}
+
+// audit 写入模拟登录审计日志，操作人为管理员
+func (s *impersonationService) audit(session *ImpersonationSession, action string, audit AuditContext, params map[string]interface{}) {
+	paramsJSON, _ := json.Marshal(params)
+	log := models.SystemLog{
+		UserID:    session.AdminID,
+		Username:  session.AdminUsername,
+		Module:    "members",
+		Action:    action,
+		Method:    audit.Method,
+		URL:       audit.URL,
+		IP:        audit.IP,
+		UserAgent: audit.UserAgent,
+		Params:    string(paramsJSON),
+		Status:    200,
+		CreatedAt: time.Now(),
+	}
+	if err := s.db.Create(&log).Error; err != nil {
+		logger.Error("保存模拟登录日志失败",
+			logger.Field("error", err),
+			logger.Field("log", log),
+		)
+	}
+}
+
+// notify 通知会员，通知失败不影响模拟登录
+func (s *impersonationService) notify(memberID uint, title, content string) {
+	if s.notifications == nil {
+		return
+	}
+	if err := s.notifications.NotifyUser(memberID, "member", NotificationTypeSecurity, title, content, 2); err != nil {
+		logger.Error("发送模拟登录通知失败",
+			logger.Field("member_id", memberID),
+			logger.Field("error", err),
+		)
+	}
+}
+
+func impersonationKey(adminID, memberID uint) string {
+	return fmt.Sprintf("%s%d:%d", impersonationPrefix, adminID, memberID)
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"normaladmin/backend/internal/models"
 	"normaladmin/backend/pkg/logger"
@@ -55,9 +56,9 @@ func (s *LogBaseService[T]) logOperation(operation string, args ...interface{})
 }
 
 // Create 创建实体（带日志）
-func (s *LogBaseService[T]) Create(entity *T) error {
+func (s *LogBaseService[T]) Create(entity *T, opts ...models.QueryOption) error {
 	startTime := time.Now()
-	err := s.next.Create(entity)
+	err := s.next.Create(entity, opts...)
 	duration := time.Since(startTime).Milliseconds()
 
 	// 记录操作日志
@@ -69,7 +70,7 @@ func (s *LogBaseService[T]) Create(entity *T) error {
 	}
 
 	// 构建并保存日志
-	s.logOperationWithResult("Create", status, result, duration, entity)
+	s.logOperationWithResult(s.context(opts), "Create", status, result, duration, entity)
 
 	return err
 }
@@ -88,14 +89,14 @@ func (s *LogBaseService[T]) GetByID(id uint, opts ...models.QueryOption) (*T, er
 		resultMsg = err.Error()
 	}
 
-	s.logOperationWithResult("GetByID", status, resultMsg, duration, id)
+	s.logOperationWithResult(s.context(opts), "GetByID", status, resultMsg, duration, id)
 	return result, err
 }
 
 // List 获取列表（带日志）
-func (s *LogBaseService[T]) List(query map[string]interface{}, page, pageSize string, opts ...models.QueryOption) ([]T, int64, error) {
+func (s *LogBaseService[T]) List(filter models.Filter, page, pageSize string, opts ...models.QueryOption) ([]T, int64, error) {
 	startTime := time.Now()
-	result, total, err := s.next.List(query, page, pageSize, opts...)
+	result, total, err := s.next.List(filter, page, pageSize, opts...)
 	duration := time.Since(startTime).Milliseconds()
 
 	// 记录操作日志
@@ -106,14 +107,14 @@ func (s *LogBaseService[T]) List(query map[string]interface{}, page, pageSize st
 		resultMsg = err.Error()
 	}
 
-	s.logOperationWithResult("List", status, resultMsg, duration, query, page, pageSize)
+	s.logOperationWithResult(s.context(opts), "List", status, resultMsg, duration, filter, page, pageSize)
 	return result, total, err
 }
 
 // Update 更新实体（带日志）
-func (s *LogBaseService[T]) Update(id uint, data interface{}) error {
+func (s *LogBaseService[T]) Update(id uint, data interface{}, opts ...models.QueryOption) error {
 	startTime := time.Now()
-	err := s.next.Update(id, data)
+	err := s.next.Update(id, data, opts...)
 	duration := time.Since(startTime).Milliseconds()
 
 	// 记录操作日志
@@ -124,14 +125,14 @@ func (s *LogBaseService[T]) Update(id uint, data interface{}) error {
 		result = err.Error()
 	}
 
-	s.logOperationWithResult("Update", status, result, duration, id, data)
+	s.logOperationWithResult(s.context(opts), "Update", status, result, duration, id, data)
 	return err
 }
 
 // Delete 删除实体（带日志）
-func (s *LogBaseService[T]) Delete(id uint, hardDelete bool) error {
+func (s *LogBaseService[T]) Delete(id uint, hardDelete bool, opts ...models.QueryOption) error {
 	startTime := time.Now()
-	err := s.next.Delete(id, hardDelete)
+	err := s.next.Delete(id, hardDelete, opts...)
 	duration := time.Since(startTime).Milliseconds()
 
 	// 记录操作日志
@@ -142,14 +143,14 @@ func (s *LogBaseService[T]) Delete(id uint, hardDelete bool) error {
 		result = err.Error()
 	}
 
-	s.logOperationWithResult("Delete", status, result, duration, id, hardDelete)
+	s.logOperationWithResult(s.context(opts), "Delete", status, result, duration, id, hardDelete)
 	return err
 }
 
 // BatchDelete 批量删除（带日志）
-func (s *LogBaseService[T]) BatchDelete(ids []uint, hardDelete bool) error {
+func (s *LogBaseService[T]) BatchDelete(ids []uint, hardDelete bool, opts ...models.QueryOption) error {
 	startTime := time.Now()
-	err := s.next.BatchDelete(ids, hardDelete)
+	err := s.next.BatchDelete(ids, hardDelete, opts...)
 	duration := time.Since(startTime).Milliseconds()
 
 	// 记录操作日志
@@ -160,12 +161,21 @@ func (s *LogBaseService[T]) BatchDelete(ids []uint, hardDelete bool) error {
 		result = err.Error()
 	}
 
-	s.logOperationWithResult("BatchDelete", status, result, duration, ids, hardDelete)
+	s.logOperationWithResult(s.context(opts), "BatchDelete", status, result, duration, ids, hardDelete)
 	return err
 }
 
+// context 查询选项携带的上下文
+func (s *LogBaseService[T]) context(opts []models.QueryOption) context.Context {
+	db := s.db
+	for _, opt := range opts {
+		db = opt(db)
+	}
+	return db.Statement.Context
+}
+
 // logOperationWithResult 记录带结果的操作日志
-func (s *LogBaseService[T]) logOperationWithResult(operation string, status int, result string, duration int64, args ...interface{}) {
+func (s *LogBaseService[T]) logOperationWithResult(ctx context.Context, operation string, status int, result string, duration int64, args ...interface{}) {
 	// 构建日志记录
 	log := models.SystemLog{
 		Module:    s.serviceName,
@@ -178,9 +188,10 @@ func (s *LogBaseService[T]) logOperationWithResult(operation string, status int,
 		CreatedAt: time.Now(),
 	}
 
-	// 异步保存日志到数据库
+	// 异步保存日志到数据库，沿用请求上下文记录所属租户，但不随请求结束而取消
+	db := s.db.WithContext(context.WithoutCancel(ctx))
 	go func(log models.SystemLog) {
-		if err := s.db.Create(&log).Error; err != nil {
+		if err := db.Create(&log).Error; err != nil {
 			// 如果数据库记录失败，则使用 zap 记录错误
 			logger.Error("保存操作日志失败",
 				logger.Field("error", err),
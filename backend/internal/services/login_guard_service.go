@@ -0,0 +1,253 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"normaladmin/backend/pkg/cache"
+	"normaladmin/backend/pkg/sysconfig"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	loginFailPrefix  = "login:fail:"  // 失败计数，按用户名和IP分别统计
+	loginDelayPrefix = "login:delay:" // 渐进延迟期间禁止再次尝试
+	loginLockPrefix  = "login:lock:"  // 临时锁定
+
+	// LockoutTypeUser 按用户名锁定
+	LockoutTypeUser = "user"
+	// LockoutTypeIP 按IP锁定
+	LockoutTypeIP = "ip"
+
+	// loginMaxDelay 渐进延迟的上限
+	loginMaxDelay = 60 * time.Second
+)
+
+// ErrLoginThrottled 登录尝试被延迟或锁定
+type ErrLoginThrottled struct {
+	RetryAfter time.Duration
+}
+
+func (e *ErrLoginThrottled) Error() string {
+	return fmt.Sprintf("too many failed login attempts, retry after %s", e.RetryAfter)
+}
+
+// Lockout 登录锁定记录
+type Lockout struct {
+	Type        string    `json:"type"`      // user 或 ip
+	UserType    string    `json:"user_type"` // 按用户名锁定时的用户类型：admin/member
+	Subject     string    `json:"subject"`   // 用户名或IP
+	Failures    int64     `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// LoginGuardService 登录防暴力破解服务
+// 失败次数达到阈值后先渐进延迟，继续失败则临时锁定；用户名和来源IP分别计数
+type LoginGuardService interface {
+	// Check 登录前检查，处于延迟或锁定期间返回 *ErrLoginThrottled
+	Check(userType, username, ip string) error
+	// RecordFailure 记录一次登录失败，用户名不存在时同样计数，避免泄露账号是否存在
+	RecordFailure(userType, username, ip string)
+	// Failures 统计窗口内的失败次数，取用户名和来源IP中较大的一个
+	Failures(userType, username, ip string) int64
+	// RecordSuccess 登录成功后清除该用户名的失败记录
+	RecordSuccess(userType, username string)
+	// ListLockouts 获取当前处于锁定状态的用户名和IP
+	ListLockouts() ([]Lockout, error)
+	// ClearLockout 解除锁定并清空失败计数；按IP解除时 userType 为空
+	ClearLockout(lockType, userType, subject string) error
+}
+
+type loginGuardService struct{}
+
+func NewLoginGuardService() LoginGuardService {
+	return &loginGuardService{}
+}
+
+// loginGuardPolicy 登录保护阈值，从系统配置读取
+type loginGuardPolicy struct {
+	delayAfter    int
+	maxFailures   int
+	ipMaxFailures int
+	lockDuration  time.Duration
+	window        time.Duration
+}
+
+func currentLoginGuardPolicy() loginGuardPolicy {
+	return loginGuardPolicy{
+		delayAfter:    sysconfig.GetInt("login_delay_after", 3),
+		maxFailures:   sysconfig.GetInt("login_max_failures", 5),
+		ipMaxFailures: sysconfig.GetInt("login_ip_max_failures", 20),
+		lockDuration:  time.Duration(sysconfig.GetInt("login_lock_minutes", 15)) * time.Minute,
+		window:        time.Duration(sysconfig.GetInt("login_failure_window", 15)) * time.Minute,
+	}
+}
+
+func (s *loginGuardService) Check(userType, username, ip string) error {
+	keys := []string{
+		loginLockPrefix + userSubject(userType, username),
+		loginDelayPrefix + userSubject(userType, username),
+	}
+	if ip != "" {
+		keys = append(keys, loginLockPrefix+ipSubject(ip))
+	}
+
+	var retryAfter time.Duration
+	for _, key := range keys {
+		ttl, err := cache.TTL(key)
+		if err != nil || ttl <= 0 {
+			continue
+		}
+		if ttl > retryAfter {
+			retryAfter = ttl
+		}
+	}
+	if retryAfter > 0 {
+		return &ErrLoginThrottled{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+func (s *loginGuardService) RecordFailure(userType, username, ip string) {
+	policy := currentLoginGuardPolicy()
+
+	subject := userSubject(userType, username)
+	failures := incrFailures(subject, policy.window)
+	switch {
+	case policy.maxFailures > 0 && failures >= int64(policy.maxFailures):
+		_ = cache.Set(loginLockPrefix+subject, strconv.FormatInt(failures, 10), policy.lockDuration)
+	case policy.delayAfter > 0 && failures >= int64(policy.delayAfter):
+		// 每多失败一次延迟翻倍：1s、2s、4s ...
+		delay := time.Second << uint(failures-int64(policy.delayAfter))
+		if delay > loginMaxDelay || delay <= 0 {
+			delay = loginMaxDelay
+		}
+		_ = cache.Set(loginDelayPrefix+subject, strconv.FormatInt(failures, 10), delay)
+	}
+
+	if ip == "" {
+		return
+	}
+	ipKey := ipSubject(ip)
+	if failures := incrFailures(ipKey, policy.window); policy.ipMaxFailures > 0 && failures >= int64(policy.ipMaxFailures) {
+		_ = cache.Set(loginLockPrefix+ipKey, strconv.FormatInt(failures, 10), policy.lockDuration)
+	}
+}
+
+func (s *loginGuardService) Failures(userType, username, ip string) int64 {
+	keys := []string{loginFailPrefix + userSubject(userType, username)}
+	if ip != "" {
+		keys = append(keys, loginFailPrefix+ipSubject(ip))
+	}
+
+	var failures int64
+	for _, key := range keys {
+		value, err := cache.Get(key)
+		if err != nil {
+			continue
+		}
+		if n, _ := strconv.ParseInt(value, 10, 64); n > failures {
+			failures = n
+		}
+	}
+	return failures
+}
+
+func (s *loginGuardService) RecordSuccess(userType, username string) {
+	subject := userSubject(userType, username)
+	_ = cache.Delete(loginFailPrefix + subject)
+	_ = cache.Delete(loginDelayPrefix + subject)
+}
+
+func (s *loginGuardService) ListLockouts() ([]Lockout, error) {
+	keys, err := cache.Keys(loginLockPrefix + "*")
+	if err != nil {
+		return nil, err
+	}
+
+	lockouts := make([]Lockout, 0, len(keys))
+	for _, key := range keys {
+		ttl, err := cache.TTL(key)
+		if err != nil || ttl <= 0 {
+			continue
+		}
+		lockout, ok := parseLockoutSubject(strings.TrimPrefix(key, loginLockPrefix))
+		if !ok {
+			continue
+		}
+		if value, err := cache.Get(key); err == nil {
+			lockout.Failures, _ = strconv.ParseInt(value, 10, 64)
+		}
+		lockout.LockedUntil = time.Now().Add(ttl)
+		lockouts = append(lockouts, lockout)
+	}
+
+	sort.Slice(lockouts, func(i, j int) bool {
+		return lockouts[i].LockedUntil.After(lockouts[j].LockedUntil)
+	})
+	return lockouts, nil
+}
+
+func (s *loginGuardService) ClearLockout(lockType, userType, subject string) error {
+	var key string
+	switch lockType {
+	case LockoutTypeUser:
+		key = userSubject(userType, subject)
+	case LockoutTypeIP:
+		key = ipSubject(subject)
+	default:
+		return errors.New("invalid lockout type")
+	}
+
+	for _, prefix := range []string{loginLockPrefix, loginDelayPrefix, loginFailPrefix} {
+		if err := cache.Delete(prefix + key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// incrFailures 在统计窗口内累加失败次数，窗口从第一次失败开始计算
+func incrFailures(subject string, window time.Duration) int64 {
+	key := loginFailPrefix + subject
+	if _, err := cache.SetNX(key, 0, window); err != nil && err != redis.Nil {
+		return 0
+	}
+	failures, err := cache.Incr(key)
+	if err != nil {
+		return 0
+	}
+	return failures
+}
+
+// userSubject 用户名不区分大小写，与数据库排序规则保持一致
+func userSubject(userType, username string) string {
+	return LockoutTypeUser + ":" + userType + ":" + strings.ToLower(strings.TrimSpace(username))
+}
+
+func ipSubject(ip string) string {
+	return LockoutTypeIP + ":" + ip
+}
+
+// parseLockoutSubject 解析 user:<userType>:<username> 或 ip:<ip>
+func parseLockoutSubject(subject string) (Lockout, bool) {
+	lockType, rest, ok := strings.Cut(subject, ":")
+	if !ok {
+		return Lockout{}, false
+	}
+	switch lockType {
+	case LockoutTypeUser:
+		userType, username, ok := strings.Cut(rest, ":")
+		if !ok {
+			return Lockout{}, false
+		}
+		return Lockout{Type: LockoutTypeUser, UserType: userType, Subject: username}, true
+	case LockoutTypeIP:
+		return Lockout{Type: LockoutTypeIP, Subject: rest}, true
+	}
+	return Lockout{}, false
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/cache"
+	"normaladmin/backend/pkg/logger"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 登录日志动作
+const (
+	LoginActionLogin   = "login"
+	LoginActionMFA     = "mfa"
+	LoginActionRefresh = "refresh"
+	LoginActionSSO     = "sso"
+)
+
+// 登录失败原因
+const (
+	LoginReasonInvalidCredentials = "invalid_credentials"
+	LoginReasonAccountDisabled    = "account_disabled"
+	LoginReasonThrottled          = "throttled"
+	LoginReasonInvalidMFACode     = "invalid_mfa_code"
+	LoginReasonInvalidToken       = "invalid_token"
+	LoginReasonTokenReused        = "token_reused"
+	LoginReasonTokenRevoked       = "token_revoked"
+	LoginReasonSSORejected        = "sso_rejected"
+	LoginReasonSSOFailed          = "sso_failed"
+	LoginReasonInvalidCaptcha     = "invalid_captcha"
+	LoginReasonEmailUnverified    = "email_unverified"
+)
+
+// LoginLogQuery 登录日志查询条件
+type LoginLogQuery struct {
+	UserType  string
+	UserID    uint
+	Username  string
+	Action    string
+	Success   *bool
+	Reason    string
+	IP        string
+	StartTime *time.Time
+	EndTime   *time.Time
+}
+
+// LoginLogService 登录日志服务
+type LoginLogService interface {
+	// Record 写入登录日志，登录成功时同时更新用户的最后登录时间
+	Record(log *models.LoginLog)
+	// List 分页查询登录日志，按时间倒序
+	List(query LoginLogQuery, page, pageSize string) ([]models.LoginLog, int64, error)
+	// WithContext 返回使用请求上下文的服务，只查询上下文中租户的登录日志
+	WithContext(ctx context.Context) LoginLogService
+}
+
+type loginLogService struct {
+	db *gorm.DB
+}
+
+func NewLoginLogService(db *gorm.DB) LoginLogService {
+	return &loginLogService{db: db}
+}
+
+func (s *loginLogService) WithContext(ctx context.Context) LoginLogService {
+	return &loginLogService{db: s.db.WithContext(ctx)}
+}
+
+func (s *loginLogService) Record(log *models.LoginLog) {
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = time.Now()
+	}
+	// 日志写入失败不影响登录流程
+	if err := s.db.Create(log).Error; err != nil {
+		logger.Error("写入登录日志失败", logger.Field("error", err), logger.Field("username", log.Username))
+	}
+
+	if log.Success && log.UserID > 0 && (log.Action == LoginActionLogin || log.Action == LoginActionMFA || log.Action == LoginActionSSO) {
+		if err := s.updateLastLogin(log); err != nil {
+			logger.Error("更新最后登录时间失败", logger.Field("error", err), logger.Field("username", log.Username))
+		}
+	}
+}
+
+// updateLastLogin 更新最后登录信息，并清除实体缓存
+func (s *loginLogService) updateLastLogin(log *models.LoginLog) error {
+	switch log.UserType {
+	case "admin":
+		if err := s.db.Model(&models.Admin{}).Where("id = ?", log.UserID).
+			UpdateColumn("last_login_time", log.CreatedAt).Error; err != nil {
+			return err
+		}
+		return cache.Delete(fmt.Sprintf("admin:%d", log.UserID))
+	case "member":
+		if err := s.db.Model(&models.Member{}).Where("id = ?", log.UserID).
+			UpdateColumns(map[string]interface{}{"last_login_time": log.CreatedAt, "last_login_ip": log.IP}).Error; err != nil {
+			return err
+		}
+		return cache.Delete(fmt.Sprintf("member:%d", log.UserID))
+	}
+	return nil
+}
+
+func (s *loginLogService) List(query LoginLogQuery, page, pageSize string) ([]models.LoginLog, int64, error) {
+	var logs []models.LoginLog
+	var total int64
+	db := s.db.Model(&models.LoginLog{})
+
+	if query.UserType != "" {
+		db = db.Where("user_type = ?", query.UserType)
+	}
+	if query.UserID > 0 {
+		db = db.Where("user_id = ?", query.UserID)
+	}
+	if query.Username != "" {
+		db = db.Where("username LIKE ?", "%"+query.Username+"%")
+	}
+	if query.Action != "" {
+		db = db.Where("action = ?", query.Action)
+	}
+	if query.Success != nil {
+		db = db.Where("success = ?", *query.Success)
+	}
+	if query.Reason != "" {
+		db = db.Where("reason = ?", query.Reason)
+	}
+	if query.IP != "" {
+		db = db.Where("ip = ?", query.IP)
+	}
+	if query.StartTime != nil {
+		db = db.Where("created_at >= ?", *query.StartTime)
+	}
+	if query.EndTime != nil {
+		db = db.Where("created_at <= ?", *query.EndTime)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := db.Order("created_at DESC").Scopes(models.Paginate(page, pageSize)).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/cache"
+	"normaladmin/backend/pkg/mail"
+	"normaladmin/backend/pkg/sysconfig"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	memberEmailCooldownPrefix = "member:email:cooldown:"
+	memberEmailSendTimeout    = 30 * time.Second
+)
+
+// 会员邮件用途
+const (
+	MemberEmailVerify        = "verify"
+	MemberEmailPasswordReset = "reset"
+)
+
+// ErrEmailChanged 令牌签发后会员邮箱已变更
+var ErrEmailChanged = errors.New("member email has changed")
+
+// MemberEmailService 会员邮箱验证和找回密码邮件服务
+// 链接中的令牌由调用方签发，这里负责发送邮件、限制发送频率和更新验证状态
+type MemberEmailService interface {
+	// VerificationRequired 是否必须验证邮箱后才能登录
+	VerificationRequired() bool
+	// VerifyTTL 邮箱验证令牌有效期
+	VerifyTTL() time.Duration
+	// ResetTTL 找回密码令牌有效期
+	ResetTTL() time.Duration
+	// Allow 同一邮箱同一用途在冷却时间内只发送一次
+	Allow(purpose, email string) bool
+	// SendVerification 发送邮箱验证邮件
+	SendVerification(ctx context.Context, member *models.Member, token string) error
+	// SendPasswordReset 发送找回密码邮件
+	SendPasswordReset(ctx context.Context, member *models.Member, token string) error
+	// MarkVerified 标记邮箱已验证，email 与会员当前邮箱不一致时返回 ErrEmailChanged
+	MarkVerified(memberID uint, email string) error
+}
+
+type memberEmailService struct {
+	db          *gorm.DB
+	sender      mail.Sender
+	linkBaseURL string
+}
+
+func NewMemberEmailService(db *gorm.DB, sender mail.Sender, linkBaseURL string) MemberEmailService {
+	return &memberEmailService{db: db, sender: sender, linkBaseURL: strings.TrimRight(linkBaseURL, "/")}
+}
+
+func (s *memberEmailService) VerificationRequired() bool {
+	return sysconfig.GetInt("member_email_verification_required", 0) == 1
+}
+
+func (s *memberEmailService) VerifyTTL() time.Duration {
+	hours := sysconfig.GetInt("member_email_verify_ttl_hours", 24)
+	if hours <= 0 {
+		hours = 24
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+func (s *memberEmailService) ResetTTL() time.Duration {
+	minutes := sysconfig.GetInt("member_password_reset_ttl_minutes", 30)
+	if minutes <= 0 {
+		minutes = 30
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+func (s *memberEmailService) Allow(purpose, email string) bool {
+	seconds := sysconfig.GetInt("member_email_resend_seconds", 60)
+	if seconds <= 0 {
+		return true
+	}
+	key := memberEmailCooldownPrefix + purpose + ":" + strings.ToLower(email)
+	ok, err := cache.SetNX(key, 1, time.Duration(seconds)*time.Second)
+	return err == nil && ok
+}
+
+func (s *memberEmailService) SendVerification(ctx context.Context, member *models.Member, token string) error {
+	link := s.link("/verify-email", token)
+	text := fmt.Sprintf("%s，您好：\n\n请在 %s 内打开以下链接完成邮箱验证：\n%s\n\n如果这不是您的操作，请忽略本邮件。\n",
+		member.Username, formatTTL(s.VerifyTTL()), link)
+	return s.send(ctx, member.Email, "请验证您的邮箱", text)
+}
+
+func (s *memberEmailService) SendPasswordReset(ctx context.Context, member *models.Member, token string) error {
+	link := s.link("/reset-password", token)
+	text := fmt.Sprintf("%s，您好：\n\n我们收到了重置您账号密码的请求，请在 %s 内打开以下链接设置新密码：\n%s\n\n如果这不是您的操作，请忽略本邮件，您的密码不会被修改。\n",
+		member.Username, formatTTL(s.ResetTTL()), link)
+	return s.send(ctx, member.Email, "重置密码", text)
+}
+
+func (s *memberEmailService) MarkVerified(memberID uint, email string) error {
+	var member models.Member
+	if err := s.db.Select("id", "email", "email_verified_at").First(&member, memberID).Error; err != nil {
+		return err
+	}
+	if !strings.EqualFold(member.Email, email) {
+		return ErrEmailChanged
+	}
+	if member.EmailVerifiedAt != nil {
+		return nil
+	}
+	if err := s.db.Model(&models.Member{}).Where("id = ?", memberID).UpdateColumn("email_verified_at", time.Now()).Error; err != nil {
+		return err
+	}
+	_ = cache.Delete(fmt.Sprintf("member:%d", memberID))
+	return nil
+}
+
+func (s *memberEmailService) send(ctx context.Context, to, subject, text string) error {
+	ctx, cancel := context.WithTimeout(ctx, memberEmailSendTimeout)
+	defer cancel()
+	return s.sender.Send(ctx, mail.Message{To: []string{to}, Subject: subject, Text: text})
+}
+
+// link 邮件中的链接；未配置前端地址时只给出令牌
+func (s *memberEmailService) link(path, token string) string {
+	if s.linkBaseURL == "" {
+		return token
+	}
+	return s.linkBaseURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// formatTTL 有效期的中文描述
+func formatTTL(ttl time.Duration) string {
+	if ttl >= time.Hour && ttl%time.Hour == 0 {
+		return fmt.Sprintf("%d 小时", int(ttl/time.Hour))
+	}
+	return fmt.Sprintf("%d 分钟", int(ttl/time.Minute))
+}
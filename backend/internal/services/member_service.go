@@ -1,8 +1,12 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/auth"
+	"normaladmin/backend/pkg/cache"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -11,21 +15,94 @@ type MemberService interface {
 	BaseCRUD[models.Member] // 组合基础CRUD接口
 
 	CheckMemberFieldUnique(field, value string, excludeID uint) (bool, error)
+	// RevokeTokens 吊销会员已签发的全部令牌
+	RevokeTokens(id uint) error
+	// WithContext 返回使用请求上下文的服务，查询和写入限定在上下文中的租户内
+	WithContext(ctx context.Context) MemberService
 }
 
 type memberService struct {
 	BaseCRUD[models.Member]
-	db *gorm.DB // 保存db实例用于特有方法
+	db             *gorm.DB      // 保存db实例用于特有方法
+	tokenTTL       time.Duration // 刷新令牌有效期，用于吊销会员令牌
+	passwordPolicy PasswordPolicyService
 }
 
-func NewMemberService(db *gorm.DB, base BaseCRUD[models.Member]) MemberService {
+func NewMemberService(db *gorm.DB, base BaseCRUD[models.Member], tokenTTL time.Duration, passwordPolicy PasswordPolicyService) MemberService {
 
 	return &memberService{
-		BaseCRUD: base, // 使用装饰后的服务
-		db:       db,   // 保存db实例
+		BaseCRUD:       base,     // 使用装饰后的服务
+		db:             db,       // 保存db实例
+		tokenTTL:       tokenTTL, // 令牌吊销标记的保留时间
+		passwordPolicy: passwordPolicy,
 	}
 }
 
+func (s *memberService) WithContext(ctx context.Context) MemberService {
+	clone := *s
+	clone.BaseCRUD = withContextCRUD(s.BaseCRUD, ctx)
+	clone.db = s.db.WithContext(ctx)
+	return &clone
+}
+
+// Create 校验密码策略后创建会员，并记录初始密码
+func (s *memberService) Create(member *models.Member, opts ...models.QueryOption) error {
+	if err := s.passwordPolicy.Validate("member", 0, member.Username, member.Password, ""); err != nil {
+		return err
+	}
+
+	// 密码由 BeforeCreate 钩子加密，创建后记录加密后的密码
+	if err := s.BaseCRUD.Create(member, opts...); err != nil {
+		return err
+	}
+	return s.passwordPolicy.Record(s.db, "member", member.ID, member.Password)
+}
+
+// Update 更新会员，包含新密码时先按密码策略校验，资料更新成功后再修改密码；邮箱变更后需要重新验证
+func (s *memberService) Update(id uint, data interface{}, opts ...models.QueryOption) error {
+	var password string
+	member, ok := data.(*models.Member)
+	if ok && member.Password != "" {
+		if _, err := s.BaseCRUD.GetByID(id, opts...); err != nil {
+			return err
+		}
+		if err := s.passwordPolicy.ValidateFor("member", id, member.Password); err != nil {
+			return err
+		}
+		password, member.Password = member.Password, ""
+	}
+
+	var emailChanged bool
+	if ok && member.Email != "" && member.EmailVerifiedAt == nil {
+		var current models.Member
+		if err := s.db.Select("email").First(&current, id).Error; err != nil {
+			return err
+		}
+		emailChanged = current.Email != member.Email
+	}
+
+	if err := s.BaseCRUD.Update(id, data, opts...); err != nil {
+		return err
+	}
+	if password != "" {
+		if err := s.passwordPolicy.SetPassword("member", id, password); err != nil {
+			return err
+		}
+	}
+	if emailChanged {
+		if err := s.db.Model(&models.Member{}).Where("id = ?", id).UpdateColumn("email_verified_at", nil).Error; err != nil {
+			return err
+		}
+		_ = cache.Delete(fmt.Sprintf("member:%d", id))
+	}
+	return nil
+}
+
+// RevokeTokens 吊销会员已签发的全部令牌
+func (s *memberService) RevokeTokens(id uint) error {
+	return auth.RevokeUserTokens("member", id, s.tokenTTL)
+}
+
 func (s *memberService) CheckMemberFieldUnique(field, value string, excludeID uint) (bool, error) {
 	var count int64
 	db := s.db.Model(&models.Member{})
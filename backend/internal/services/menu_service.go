@@ -1,8 +1,11 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/tenant"
+	"strings"
 
 	"gorm.io/gorm"
 )
@@ -14,25 +17,46 @@ type MenuService interface {
 	DeleteMenu(id uint) error
 	GetMenus(query map[string]interface{}) ([]models.Menu, error)
 	Update(id uint, data interface{}) error
+	// WithContext 返回使用请求上下文的服务，超级租户以外只能看到非平台菜单
+	WithContext(ctx context.Context) MenuService
 }
 
 type menuService struct {
-	db *gorm.DB
+	db       *gorm.DB
+	policies PolicySyncService
+	apis     ApiEndpointService
 }
 
-func NewMenuService(db *gorm.DB) MenuService {
-	return &menuService{db: db}
+func NewMenuService(db *gorm.DB, policies PolicySyncService, apis ApiEndpointService) MenuService {
+	return &menuService{db: db, policies: policies, apis: apis}
+}
+
+func (s *menuService) WithContext(ctx context.Context) MenuService {
+	clone := *s
+	clone.db = s.db.WithContext(ctx)
+	return &clone
 }
 
 func (s *menuService) GetMenuTree() ([]models.Menu, error) {
 	var menus []models.Menu
-	if err := s.db.Order("sort").Find(&menus).Error; err != nil {
+	if err := s.visible().Order("sort").Find(&menus).Error; err != nil {
 		return nil, err
 	}
 	return buildMenuTree(menus, 0), nil
 }
 
+// visible 菜单是全部租户共用的目录，平台菜单只对超级租户可见
+func (s *menuService) visible() *gorm.DB {
+	if tenant.IsSuper(s.db.Statement.Context) {
+		return s.db
+	}
+	return s.db.Where("platform = ?", false)
+}
+
 func (s *menuService) CreateMenu(menu *models.Menu) error {
+	if err := s.validateAPI(menu); err != nil {
+		return err
+	}
 	if menu.ParentID != 0 {
 		var parent models.Menu
 		if err := s.db.First(&parent, menu.ParentID).Error; err != nil {
@@ -48,6 +72,9 @@ func (s *menuService) UpdateMenu(id uint, menu *models.Menu) error {
 	if err := s.db.First(&existing, id).Error; err != nil {
 		return err
 	}
+	if err := s.validateAPI(menu); err != nil {
+		return err
+	}
 
 	if menu.ParentID != existing.ParentID {
 		if err := s.validateMenuHierarchy(id, menu.ParentID); err != nil {
@@ -55,7 +82,14 @@ func (s *menuService) UpdateMenu(id uint, menu *models.Menu) error {
 		}
 	}
 
-	return s.db.Save(menu).Error
+	// 接口、类型或状态可能变化，同步使用该菜单的角色的权限策略
+	menu.ID = id
+	return s.policies.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(menu).Error; err != nil {
+			return err
+		}
+		return s.policies.SyncMenus(tx, id)
+	})
 }
 
 func (s *menuService) DeleteMenu(id uint) error {
@@ -68,9 +102,9 @@ func (s *menuService) DeleteMenu(id uint) error {
 		return fmt.Errorf("cannot delete menu with child items")
 	}
 
-	// 检查是否被角色使用
+	// 检查是否被角色使用，菜单是全部租户共用的，需要统计所有租户的角色
 	var roleMenuCount int64
-	if err := s.db.Model(&models.RoleMenu{}).Where("menu_id = ?", id).Count(&roleMenuCount).Error; err != nil {
+	if err := s.db.WithContext(tenant.WithoutTenant(s.db.Statement.Context)).Model(&models.RoleMenu{}).Where("menu_id = ?", id).Count(&roleMenuCount).Error; err != nil {
 		return err
 	}
 	if roleMenuCount > 0 {
@@ -82,7 +116,7 @@ func (s *menuService) DeleteMenu(id uint) error {
 
 func (s *menuService) GetMenus(query map[string]interface{}) ([]models.Menu, error) {
 	var menus []models.Menu
-	db := s.db.Order("sort")
+	db := s.visible().Order("sort")
 
 	for key, value := range query {
 		if str, ok := value.(string); ok && str != "" {
@@ -101,10 +135,35 @@ func (s *menuService) Update(id uint, data interface{}) error {
 		return err
 	}
 
-	return s.db.Model(&models.Menu{}).Where("id = ?", id).Updates(data).Error
+	return s.policies.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Menu{}).Where("id = ?", id).Updates(data).Error; err != nil {
+			return err
+		}
+		return s.policies.SyncMenus(tx, id)
+	})
 }
 
 // 辅助方法
+// validateAPI 校验菜单的接口路径和请求方法在接口目录中，请求方法统一为大写
+func (s *menuService) validateAPI(menu *models.Menu) error {
+	menu.ApiMethod = strings.ToUpper(strings.TrimSpace(menu.ApiMethod))
+	menu.ApiPath = strings.TrimSpace(menu.ApiPath)
+	if menu.ApiMethod == "" && menu.ApiPath == "" {
+		return nil
+	}
+	if menu.ApiMethod == "" || menu.ApiPath == "" {
+		return ErrMenuAPIIncomplete
+	}
+	ok, err := s.apis.Match(menu.ApiMethod, menu.ApiPath)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s %s", ErrMenuAPINotFound, menu.ApiMethod, menu.ApiPath)
+	}
+	return nil
+}
+
 func (s *menuService) validateMenuHierarchy(menuID uint, parentID uint) error {
 	if parentID == 0 {
 		return nil
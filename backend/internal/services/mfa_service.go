@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/cache"
+	"normaladmin/backend/pkg/sysconfig"
+	"normaladmin/backend/pkg/utils"
+	"normaladmin/backend/pkg/utils/encrypt"
+	"normaladmin/backend/pkg/utils/totp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// recoveryCodeCount 每次生成的恢复码数量
+const recoveryCodeCount = 10
+
+var (
+	ErrMFANotEnrolled     = errors.New("two-factor authentication is not enabled")
+	ErrMFAAlreadyEnrolled = errors.New("two-factor authentication is already enabled")
+	ErrMFAInvalidCode     = errors.New("invalid verification code")
+)
+
+// MFAService 管理员两步验证服务
+type MFAService interface {
+	// IsRequired 管理员登录是否需要两步验证（已启用或所属角色被强制要求）
+	IsRequired(admin *models.Admin) (bool, error)
+	// BeginEnrollment 生成新的待确认密钥，返回密钥和 otpauth URI
+	BeginEnrollment(adminID uint) (string, string, error)
+	// ConfirmEnrollment 校验首个验证码并启用两步验证，返回恢复码明文
+	ConfirmEnrollment(adminID uint, code string) ([]string, error)
+	// Verify 校验 TOTP 验证码或恢复码
+	Verify(adminID uint, code string) error
+	// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部作废
+	RegenerateRecoveryCodes(adminID uint, code string) ([]string, error)
+	// Disable 校验验证码后关闭两步验证
+	Disable(adminID uint, code string) error
+	// Reset 管理员重置他人的两步验证（设备丢失时使用），管理员不存在时返回 gorm.ErrRecordNotFound
+	Reset(adminID uint) error
+	// WithContext 返回使用请求上下文的服务，只能访问上下文中租户的管理员
+	WithContext(ctx context.Context) MFAService
+}
+
+type mfaService struct {
+	db     *gorm.DB
+	issuer string
+}
+
+func NewMFAService(db *gorm.DB, issuer string) MFAService {
+	return &mfaService{db: db, issuer: issuer}
+}
+
+func (s *mfaService) WithContext(ctx context.Context) MFAService {
+	return &mfaService{db: s.db.WithContext(ctx), issuer: s.issuer}
+}
+
+func (s *mfaService) IsRequired(admin *models.Admin) (bool, error) {
+	if admin.MFAEnabled {
+		return true, nil
+	}
+
+	// 强制启用两步验证的角色编码，多个用逗号分隔
+	required := sysconfig.Get("mfa_required_roles", "")
+	if strings.TrimSpace(required) == "" {
+		return false, nil
+	}
+
+	// 任一已启用角色要求两步验证即需要启用
+	roles, err := AdminRoles(s.db, admin.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, code := range strings.Split(required, ",") {
+		for _, role := range roles {
+			if strings.EqualFold(strings.TrimSpace(code), role.Code) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (s *mfaService) BeginEnrollment(adminID uint) (string, string, error) {
+	admin, err := s.getAdmin(adminID)
+	if err != nil {
+		return "", "", err
+	}
+	if admin.MFAEnabled {
+		return "", "", ErrMFAAlreadyEnrolled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.db.Model(admin).Update("mfa_secret", encrypt.Encrypt(secret)).Error; err != nil {
+		return "", "", err
+	}
+	return secret, totp.ProvisioningURI(s.issuer, admin.Username, secret), nil
+}
+
+func (s *mfaService) ConfirmEnrollment(adminID uint, code string) ([]string, error) {
+	admin, err := s.getAdmin(adminID)
+	if err != nil {
+		return nil, err
+	}
+	if admin.MFAEnabled {
+		return nil, ErrMFAAlreadyEnrolled
+	}
+	if admin.MFASecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+	if err := s.verifyTOTP(admin, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(admin).Update("mfa_enabled", true).Error; err != nil {
+			return err
+		}
+		codes, err = s.replaceRecoveryCodes(tx, admin.ID)
+		return err
+	})
+	return codes, err
+}
+
+func (s *mfaService) Verify(adminID uint, code string) error {
+	admin, err := s.getAdmin(adminID)
+	if err != nil {
+		return err
+	}
+	if !admin.MFAEnabled {
+		return ErrMFANotEnrolled
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		return s.verifyTOTP(admin, code)
+	}
+	return s.useRecoveryCode(admin.ID, code)
+}
+
+func (s *mfaService) RegenerateRecoveryCodes(adminID uint, code string) ([]string, error) {
+	if err := s.Verify(adminID, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = s.replaceRecoveryCodes(tx, adminID)
+		return err
+	})
+	return codes, err
+}
+
+func (s *mfaService) Disable(adminID uint, code string) error {
+	if err := s.Verify(adminID, code); err != nil {
+		return err
+	}
+	return s.Reset(adminID)
+}
+
+func (s *mfaService) Reset(adminID uint) error {
+	admin, err := s.getAdmin(adminID)
+	if err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Admin{}).Where("id = ?", admin.ID).
+			Updates(map[string]interface{}{"mfa_enabled": false, "mfa_secret": ""}).Error; err != nil {
+			return err
+		}
+		return tx.Where("admin_id = ?", admin.ID).Delete(&models.AdminRecoveryCode{}).Error
+	})
+}
+
+// getAdmin 直接查询数据库，缓存中的管理员不包含密钥字段
+func (s *mfaService) getAdmin(adminID uint) (*models.Admin, error) {
+	var admin models.Admin
+	if err := s.db.First(&admin, adminID).Error; err != nil {
+		return nil, err
+	}
+	return &admin, nil
+}
+
+// verifyTOTP 校验 TOTP 验证码，同一时间步的验证码只能使用一次
+func (s *mfaService) verifyTOTP(admin *models.Admin, code string) error {
+	secret := encrypt.Decrypt(admin.MFASecret)
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return ErrMFAInvalidCode
+	}
+
+	usedKey := fmt.Sprintf("mfa:used:%d:%d", admin.ID, step)
+	first, err := cache.SetNX(usedKey, 1, time.Duration(2*totp.Skew+1)*totp.Period*time.Second)
+	if err != nil {
+		return err
+	}
+	if !first {
+		return ErrMFAInvalidCode
+	}
+	return nil
+}
+
+// useRecoveryCode 校验并消耗一个恢复码
+func (s *mfaService) useRecoveryCode(adminID uint, code string) error {
+	code = normalizeRecoveryCode(code)
+	if code == "" {
+		return ErrMFAInvalidCode
+	}
+
+	var records []models.AdminRecoveryCode
+	if err := s.db.Where("admin_id = ? AND used_at IS NULL", adminID).Find(&records).Error; err != nil {
+		return err
+	}
+	for _, record := range records {
+		if !utils.CheckPassword(code, record.CodeHash) {
+			continue
+		}
+		// 条件更新保证恢复码只能被使用一次
+		now := time.Now()
+		result := s.db.Model(&models.AdminRecoveryCode{}).
+			Where("id = ? AND used_at IS NULL", record.ID).
+			Update("used_at", &now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMFAInvalidCode
+		}
+		return nil
+	}
+	return ErrMFAInvalidCode
+}
+
+// replaceRecoveryCodes 作废旧恢复码并生成新的一组
+func (s *mfaService) replaceRecoveryCodes(tx *gorm.DB, adminID uint) ([]string, error) {
+	if err := tx.Where("admin_id = ?", adminID).Delete(&models.AdminRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.AdminRecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := hex.EncodeToString(buf)
+		hash, err := utils.HashPassword(raw)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		records = append(records, models.AdminRecoveryCode{AdminID: adminID, CodeHash: hash})
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode 忽略恢复码中的分隔符和大小写
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
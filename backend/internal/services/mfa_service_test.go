@@ -0,0 +1,44 @@
+package services
+
+import (
+	"errors"
+	"normaladmin/backend/internal/models"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestMFAServiceResetTenantIsolation(t *testing.T) {
+	db := newTenantTestDB(t)
+	service := NewMFAService(db, "normaladmin").WithContext(tenantContext(testTenantA))
+
+	// 其他租户的管理员视为不存在，两步验证和恢复码保持不变
+	if err := service.Reset(testAdminB); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Reset() admin of another tenant error = %v, want ErrRecordNotFound", err)
+	}
+	assertMFAState(t, db, testAdminB, true, 1)
+
+	if err := service.Reset(testAdminA); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	assertMFAState(t, db, testAdminA, false, 0)
+	assertMFAState(t, db, testAdminB, true, 1)
+}
+
+func assertMFAState(t *testing.T, db *gorm.DB, adminID uint, enabled bool, recoveryCodes int64) {
+	t.Helper()
+	var admin models.Admin
+	if err := db.First(&admin, adminID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if admin.MFAEnabled != enabled || (admin.MFASecret != "") != enabled {
+		t.Fatalf("admin %d mfa_enabled = %v, secret set = %v, want %v", adminID, admin.MFAEnabled, admin.MFASecret != "", enabled)
+	}
+	var count int64
+	if err := db.Model(&models.AdminRecoveryCode{}).Where("admin_id = ?", adminID).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != recoveryCodes {
+		t.Fatalf("admin %d recovery codes = %d, want %d", adminID, count, recoveryCodes)
+	}
+}
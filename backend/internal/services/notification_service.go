@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -30,6 +31,8 @@ type NotificationService interface {
 	GetNotifications(query *models.NotificationQuery) ([]models.Notification, int64, error)
 	PublishNotification(id uint, currentUserID uint, currentUserName string, currentUserType string) error
 	RecallNotification(id uint) error
+	// NotifyUser 向单个用户发送系统通知，typeCode 对应的通知类型不存在时自动创建
+	NotifyUser(userID uint, userType string, typeCode, title, content string, level int) error
 
 	// 用户通知相关
 	GetNotificationStats(notificationID uint) (map[string]int64, error)
@@ -39,6 +42,8 @@ type NotificationService interface {
 	DeleteUserNotification(notificationID uint, userID uint, userType string) error
 	GetUnreadNotificationCount(userID uint, userType string) (int64, error)
 	GetNotificationReceivers(notificationID uint, query *models.ReceiverQuery) ([]models.NotificationReceiver, int64, error)
+	// WithContext 返回使用请求上下文的服务，通知和接收人限定在上下文中的租户内
+	WithContext(ctx context.Context) NotificationService
 }
 
 type notificationService struct {
@@ -56,6 +61,12 @@ func NewNotificationService(db *gorm.DB, rabbitmq *rabbitmq.RabbitMQ, notificati
 	}
 }
 
+func (s *notificationService) WithContext(ctx context.Context) NotificationService {
+	clone := *s
+	clone.db = s.db.WithContext(ctx)
+	return &clone
+}
+
 // CreateNotificationType 创建通知类型
 func (s *notificationService) CreateNotificationType(notificationType *models.NotificationType) error {
 	return s.db.Create(notificationType).Error
@@ -318,6 +329,93 @@ func (s *notificationService) PublishNotification(id uint, currentUserID uint, c
 	return nil
 }
 
+// 系统通知类型
+const (
+	NotificationTypeSecurity  = "security"   // 安全提醒
+	NotificationTypeRoleGrant = "role_grant" // 临时角色授权
+)
+
+// systemNotificationTypes 系统通知类型名称
+var systemNotificationTypes = map[string]string{
+	NotificationTypeSecurity:  "安全提醒",
+	NotificationTypeRoleGrant: "临时角色授权",
+}
+
+// NotifyUser 向单个用户发送系统通知
+func (s *notificationService) NotifyUser(userID uint, userType string, typeCode, title, content string, level int) error {
+	notificationType := models.NotificationType{Code: typeCode, Name: systemNotificationTypes[typeCode]}
+	if notificationType.Name == "" {
+		notificationType.Name = typeCode
+	}
+	if err := s.db.Where("code = ?", typeCode).FirstOrCreate(&notificationType).Error; err != nil {
+		return fmt.Errorf("获取通知类型失败: %w", err)
+	}
+
+	// 通知属于接收人所在的租户，定时任务等没有请求上下文的调用也能写入正确的租户
+	var user struct {
+		Username string
+		TenantID uint
+	}
+	switch userType {
+	case "admin":
+		s.db.Model(&models.Admin{}).Select("username", "tenant_id").Where("id = ?", userID).Scan(&user)
+	case "member":
+		s.db.Model(&models.Member{}).Select("username", "tenant_id").Where("id = ?", userID).Scan(&user)
+	}
+	username := user.Username
+
+	now := time.Now()
+	notification := models.Notification{
+		TenantID:     user.TenantID,
+		Title:        title,
+		Content:      content,
+		TypeID:       notificationType.ID,
+		Level:        level,
+		Status:       1,
+		SenderName:   "系统",
+		PublishTime:  &now,
+		ReceiverType: "user",
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&notification).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.NotificationReceiver{
+			NotificationID: notification.ID,
+			UserID:         userID,
+			UserType:       userType,
+			UserName:       username,
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("创建通知失败: %w", err)
+	}
+
+	msg := map[string]interface{}{
+		"type":       "notification",
+		"action":     "new",
+		"user_id":    userID,
+		"user_type":  userType,
+		"id":         notification.ID,
+		"title":      notification.Title,
+		"content":    notification.Content,
+		"level":      notification.Level,
+		"createTime": notification.CreatedAt,
+	}
+	// 优先通过消息队列推送，未配置时直接推送给在线用户
+	if s.rabbitmq == nil {
+		if s.notificationHub != nil {
+			return s.notificationHub.SendToUser(userID, userType, msg)
+		}
+		return nil
+	}
+	msgBytes, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return s.rabbitmq.PublishMessage("notifications", msgBytes)
+}
+
 // UserInfo 用户信息结构体
 type UserInfo struct {
 	UserID   uint   `gorm:"column:user_id"`
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"normaladmin/backend/config"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/auth"
+	"normaladmin/backend/pkg/cache"
+	"normaladmin/backend/pkg/tenant"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	oidcStatePrefix = "oidc:state:"
+	oidcStateTTL    = 10 * time.Minute
+)
+
+var (
+	// ErrOIDCDisabled 未启用单点登录
+	ErrOIDCDisabled = errors.New("oidc login is not enabled")
+	// ErrOIDCInvalidState state 不存在、已过期或已使用
+	ErrOIDCInvalidState = errors.New("invalid or expired oidc state")
+	// ErrOIDCNoRole 身份提供方分组没有映射到任何启用的角色
+	ErrOIDCNoRole = errors.New("no role mapped for oidc user")
+	// ErrOIDCNotProvisioned 未开启自动创建且没有关联的管理员
+	ErrOIDCNotProvisioned = errors.New("oidc user is not provisioned")
+	// ErrOIDCUsernameTaken 自动创建时用户名已被本地管理员占用
+	ErrOIDCUsernameTaken = errors.New("oidc username is already taken")
+	// ErrOIDCAccountDisabled 关联的管理员已被禁用
+	ErrOIDCAccountDisabled = errors.New("admin account is disabled")
+)
+
+// oidcPendingLogin 授权请求发起时保存的一次性参数
+type oidcPendingLogin struct {
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+}
+
+// OIDCService 管理后台单点登录服务
+// 使用授权码 + PKCE 流程，校验 ID 令牌后按分组映射角色，并在配置的租户内关联或自动创建本地管理员
+type OIDCService interface {
+	// Enabled 是否启用单点登录
+	Enabled() bool
+	// AuthorizeURL 生成身份提供方授权地址，state 在回调时一次性校验
+	AuthorizeURL(ctx context.Context) (authURL, state string, err error)
+	// Authenticate 使用回调的授权码完成认证，返回对应的本地管理员
+	Authenticate(ctx context.Context, code, state string) (*models.Admin, error)
+}
+
+type oidcService struct {
+	db       *gorm.DB
+	cfg      config.OIDCConfig
+	provider *auth.OIDCProvider
+	policies PolicySyncService
+}
+
+// NewOIDCService 创建单点登录服务，httpClient 为空时使用默认客户端
+func NewOIDCService(db *gorm.DB, cfg config.OIDCConfig, httpClient *http.Client) OIDCService {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = "preferred_username"
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if cfg.TenantID == 0 {
+		cfg.TenantID = tenant.SuperTenantID
+	}
+	return &oidcService{
+		db:       db,
+		cfg:      cfg,
+		policies: NewPolicySyncService(db),
+		provider: auth.NewOIDCProvider(cfg.Issuer, cfg.ClientID, cfg.ClientSecret, httpClient),
+	}
+}
+
+func (s *oidcService) Enabled() bool {
+	return s.cfg.Enabled && s.cfg.Issuer != "" && s.cfg.ClientID != ""
+}
+
+func (s *oidcService) AuthorizeURL(ctx context.Context) (string, string, error) {
+	if !s.Enabled() {
+		return "", "", ErrOIDCDisabled
+	}
+
+	state := auth.RandomURLToken(24)
+	pending := oidcPendingLogin{
+		CodeVerifier: auth.NewPKCEVerifier(),
+		Nonce:        auth.RandomURLToken(24),
+	}
+	authURL, err := s.provider.AuthCodeURL(ctx, s.cfg.RedirectURL, state, pending.Nonce, pending.CodeVerifier, s.cfg.Scopes)
+	if err != nil {
+		return "", "", err
+	}
+	if err := cache.SetObject(oidcStatePrefix+state, pending, oidcStateTTL); err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+func (s *oidcService) Authenticate(ctx context.Context, code, state string) (*models.Admin, error) {
+	if !s.Enabled() {
+		return nil, ErrOIDCDisabled
+	}
+
+	// state 只能使用一次
+	var pending oidcPendingLogin
+	if err := cache.GetDelObject(oidcStatePrefix+state, &pending); err != nil {
+		return nil, ErrOIDCInvalidState
+	}
+
+	token, err := s.provider.Exchange(ctx, code, s.cfg.RedirectURL, pending.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.provider.VerifyIDToken(ctx, token.IDToken, pending.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	s.mergeUserInfo(ctx, claims, token.AccessToken)
+
+	// 角色映射、关联和自动创建只在配置的租户内进行
+	s = s.withTenant(ctx)
+	roleIDs, err := s.resolveRoles(claims)
+	if err != nil {
+		return nil, err
+	}
+	admin, err := s.findOrProvision(claims, roleIDs[0])
+	if err != nil {
+		return nil, err
+	}
+	if admin.Status == nil || *admin.Status != 1 {
+		return nil, ErrOIDCAccountDisabled
+	}
+
+	// 每次登录按身份提供方的分组同步角色
+	var current []uint
+	if err := s.db.Model(&models.AdminRole{}).Where("admin_id = ?", admin.ID).Order("id").Pluck("role_id", &current).Error; err != nil {
+		return nil, err
+	}
+	if !slices.Equal(current, roleIDs) {
+		if err := s.policies.Transaction(func(tx *gorm.DB) error {
+			return s.policies.AssignRoles(tx, admin.ID, roleIDs)
+		}); err != nil {
+			return nil, err
+		}
+		_ = cache.Delete(fmt.Sprintf("admin:%d", admin.ID))
+	}
+	admin.RoleID = roleIDs[0]
+	admin.RoleIDs = roleIDs
+	return admin, nil
+}
+
+// withTenant 返回只访问配置租户数据的服务
+func (s *oidcService) withTenant(ctx context.Context) *oidcService {
+	ctx = tenant.WithTenant(ctx, s.cfg.TenantID)
+	clone := *s
+	clone.db = s.db.WithContext(ctx)
+	clone.policies = s.policies.WithContext(ctx)
+	return &clone
+}
+
+// mergeUserInfo ID 令牌中没有分组声明时从 userinfo 补充，sub 不一致的响应会被忽略
+func (s *oidcService) mergeUserInfo(ctx context.Context, claims auth.OIDCClaims, accessToken string) {
+	if _, ok := claims[s.cfg.GroupsClaim]; ok || accessToken == "" {
+		return
+	}
+	info, err := s.provider.UserInfo(ctx, accessToken)
+	if err != nil || info.String("sub") != claims.String("sub") {
+		return
+	}
+	for name, value := range info {
+		if _, exists := claims[name]; !exists {
+			claims[name] = value
+		}
+	}
+}
+
+// resolveRoles 按配置顺序匹配分组，全部命中且启用的角色都生效，第一个为主角色；没有命中时使用默认角色
+func (s *oidcService) resolveRoles(claims auth.OIDCClaims) ([]uint, error) {
+	groups := make(map[string]struct{})
+	for _, group := range claims.Strings(s.cfg.GroupsClaim) {
+		groups[group] = struct{}{}
+	}
+
+	codes := make([]string, 0, len(s.cfg.RoleMappings)+1)
+	for _, mapping := range s.cfg.RoleMappings {
+		if _, ok := groups[mapping.Group]; ok {
+			codes = append(codes, mapping.Role)
+		}
+	}
+
+	roleIDs, err := s.enabledRoles(codes)
+	if err != nil || len(roleIDs) > 0 {
+		return roleIDs, err
+	}
+	if s.cfg.DefaultRole != "" {
+		if roleIDs, err = s.enabledRoles([]string{s.cfg.DefaultRole}); err != nil || len(roleIDs) > 0 {
+			return roleIDs, err
+		}
+	}
+	return nil, ErrOIDCNoRole
+}
+
+// enabledRoles 按编码的顺序获取已启用角色的ID，不存在或禁用的角色被忽略
+func (s *oidcService) enabledRoles(codes []string) ([]uint, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+	var roles []models.Role
+	if err := s.db.Select("id", "code").Where("tenant_id = ? AND code IN ? AND status = 1", s.cfg.TenantID, codes).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	roleIDs := make([]uint, 0, len(roles))
+	for _, code := range codes {
+		for _, role := range roles {
+			if role.Code == code && !slices.Contains(roleIDs, role.ID) {
+				roleIDs = append(roleIDs, role.ID)
+			}
+		}
+	}
+	return roleIDs, nil
+}
+
+// findOrProvision 按外部身份查找管理员；首次登录时按配置关联同名管理员或自动创建
+func (s *oidcService) findOrProvision(claims auth.OIDCClaims, roleID uint) (*models.Admin, error) {
+	subject := claims.String("sub")
+	now := time.Now()
+
+	var identity models.AdminIdentity
+	err := s.db.Where("issuer = ? AND subject = ?", claims.String("iss"), subject).First(&identity).Error
+	if err == nil {
+		var admin models.Admin
+		if err := s.db.First(&admin, identity.AdminID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrOIDCNotProvisioned
+			}
+			return nil, err
+		}
+		s.db.Model(&identity).Updates(map[string]interface{}{"last_login_at": now, "email": claims.String("email")})
+		return &admin, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	username := s.username(claims)
+	var admin models.Admin
+	err = s.db.Where("username = ?", username).First(&admin).Error
+	switch {
+	case err == nil && !s.cfg.LinkExisting:
+		return nil, ErrOIDCUsernameTaken
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	case err != nil && !s.cfg.AutoProvision:
+		return nil, ErrOIDCNotProvisioned
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if admin.ID == 0 {
+			// 单点登录账号不使用本地密码，写入随机密码（由 BeforeCreate 钩子加密）防止被用于本地登录
+			status := 1
+			admin = models.Admin{
+				Username:          username,
+				Password:          auth.RandomURLToken(32),
+				Email:             claims.String("email"),
+				RealName:          claims.String("name"),
+				RoleID:            roleID,
+				Status:            &status,
+				PasswordChangedAt: &now,
+			}
+			if err := tx.Create(&admin).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&models.AdminIdentity{
+			AdminID:     admin.ID,
+			Issuer:      claims.String("iss"),
+			Subject:     subject,
+			Email:       claims.String("email"),
+			LastLoginAt: &now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &admin, nil
+}
+
+// username 管理员用户名，依次使用配置的声明、邮箱和 sub
+func (s *oidcService) username(claims auth.OIDCClaims) string {
+	for _, name := range []string{s.cfg.UsernameClaim, "email", "sub"} {
+		if value := claims.String(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"normaladmin/backend/config"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/auth"
+	"normaladmin/backend/pkg/tenant"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testOIDCTenantID = 2
+
+// newOIDCTestIdP 模拟身份提供方，令牌端点校验 PKCE 后签发带 nonce 的 ID 令牌，声明由 claims 提供
+func newOIDCTestIdP(t *testing.T, claims jwt.MapClaims) *httptest.Server {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var server *httptest.Server
+	var mu sync.Mutex
+	pending := map[string]url.Values{}
+	writeJSON := func(w http.ResponseWriter, status int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, auth.OIDCDiscovery{
+			Issuer:                server.URL,
+			AuthorizationEndpoint: server.URL + "/authorize",
+			TokenEndpoint:         server.URL + "/token",
+			JWKSURI:               server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, auth.JWKS{Keys: []auth.JWK{{
+			Kty: "OKP", Kid: "key-1", Use: "sig", Alg: "EdDSA", Crv: "Ed25519",
+			X: jwt.EncodeSegment(pub),
+		}}})
+	})
+	// authorize 直接重定向回客户端，模拟用户已登录并同意授权
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		code := auth.RandomURLToken(16)
+		mu.Lock()
+		pending[code] = r.URL.Query()
+		mu.Unlock()
+		http.Redirect(w, r, r.URL.Query().Get("redirect_uri")+"?code="+code, http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		mu.Lock()
+		query, ok := pending[r.PostForm.Get("code")]
+		delete(pending, r.PostForm.Get("code"))
+		mu.Unlock()
+		if !ok || auth.PKCEChallenge(r.PostForm.Get("code_verifier")) != query.Get("code_challenge") {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		idClaims := jwt.MapClaims{
+			"iss":   server.URL,
+			"aud":   query.Get("client_id"),
+			"exp":   time.Now().Add(5 * time.Minute).Unix(),
+			"nonce": query.Get("nonce"),
+		}
+		for name, value := range claims {
+			idClaims[name] = value
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, idClaims)
+		token.Header["kid"] = "key-1"
+		signed, err := token.SignedString(priv)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, auth.OIDCTokenResponse{AccessToken: "access", TokenType: "Bearer", IDToken: signed})
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newOIDCTestDB 内存数据库，与生产环境一样按上下文中的租户隔离数据
+func newOIDCTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Role{}, &models.Admin{}, &models.AdminIdentity{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func createOIDCTestRole(t *testing.T, db *gorm.DB, tenantID uint, code string, status int) uint {
+	t.Helper()
+	role := models.Role{TenantID: tenantID, Name: code, Code: code, Status: &status}
+	if err := db.Create(&role).Error; err != nil {
+		t.Fatal(err)
+	}
+	return role.ID
+}
+
+// verifiedClaims 通过授权码 + PKCE 流程从模拟的身份提供方取得校验后的声明
+func verifiedClaims(t *testing.T, s *oidcService, server *httptest.Server) auth.OIDCClaims {
+	t.Helper()
+	ctx := context.Background()
+	verifier, nonce := auth.NewPKCEVerifier(), auth.RandomURLToken(24)
+	authURL, err := s.provider.AuthCodeURL(ctx, s.cfg.RedirectURL, "state", nonce, verifier, s.cfg.Scopes)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	client := server.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := s.provider.Exchange(ctx, location.Query().Get("code"), s.cfg.RedirectURL, verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	claims, err := s.provider.VerifyIDToken(ctx, token.IDToken, nonce)
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	return claims
+}
+
+func newOIDCTestService(db *gorm.DB, server *httptest.Server, cfg config.OIDCConfig) *oidcService {
+	cfg.Enabled = true
+	cfg.Issuer = server.URL
+	cfg.ClientID = "gam-admin"
+	cfg.RedirectURL = "https://admin.example.com/oidc/callback"
+	cfg.TenantID = testOIDCTenantID
+	return NewOIDCService(db, cfg, server.Client()).(*oidcService)
+}
+
+func TestOIDCServiceProvisionsAdminWithMappedRoles(t *testing.T) {
+	db := newOIDCTestDB(t)
+	server := newOIDCTestIdP(t, jwt.MapClaims{
+		"sub":                "idp-user-1",
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"groups":             []string{"developers", "operators", "auditors"},
+	})
+
+	// 同编码的角色在其他租户中也存在，只能映射到配置租户的角色
+	createOIDCTestRole(t, db, tenant.SuperTenantID, "OPS", 1)
+	opsID := createOIDCTestRole(t, db, testOIDCTenantID, "OPS", 1)
+	devID := createOIDCTestRole(t, db, testOIDCTenantID, "DEV", 1)
+	createOIDCTestRole(t, db, testOIDCTenantID, "AUDIT", 0)
+	// 其他租户的同名管理员不影响自动创建
+	status := 1
+	if err := db.Create(&models.Admin{TenantID: tenant.SuperTenantID, Username: "alice", Password: "secret", Status: &status}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	s := newOIDCTestService(db, server, config.OIDCConfig{
+		AutoProvision: true,
+		RoleMappings: []config.OIDCRoleMapping{
+			{Group: "operators", Role: "OPS"},
+			{Group: "developers", Role: "DEV"},
+			{Group: "auditors", Role: "AUDIT"},
+			{Group: "finance", Role: "FINANCE"},
+		},
+	})
+	claims := verifiedClaims(t, s, server)
+
+	scoped := s.withTenant(context.Background())
+	roleIDs, err := scoped.resolveRoles(claims)
+	if err != nil {
+		t.Fatalf("resolveRoles: %v", err)
+	}
+	// 按映射配置的顺序，禁用和不存在的角色被忽略
+	if len(roleIDs) != 2 || roleIDs[0] != opsID || roleIDs[1] != devID {
+		t.Fatalf("roleIDs = %v, want [%d %d]", roleIDs, opsID, devID)
+	}
+
+	admin, err := scoped.findOrProvision(claims, roleIDs[0])
+	if err != nil {
+		t.Fatalf("findOrProvision: %v", err)
+	}
+	if admin.TenantID != testOIDCTenantID || admin.Username != "alice" || admin.RoleID != opsID || admin.Email != "alice@example.com" {
+		t.Errorf("provisioned admin = %+v", admin)
+	}
+	if admin.CheckPassword("") {
+		t.Error("provisioned admin accepts an empty local password")
+	}
+
+	var identity models.AdminIdentity
+	if err := db.Where("subject = ?", "idp-user-1").First(&identity).Error; err != nil {
+		t.Fatalf("identity not linked: %v", err)
+	}
+	if identity.AdminID != admin.ID || identity.Issuer != server.URL {
+		t.Errorf("identity = %+v, want admin %d issuer %s", identity, admin.ID, server.URL)
+	}
+
+	// 再次登录按外部身份找到同一个管理员
+	again, err := scoped.findOrProvision(claims, roleIDs[0])
+	if err != nil {
+		t.Fatalf("findOrProvision again: %v", err)
+	}
+	if again.ID != admin.ID {
+		t.Errorf("second login admin = %d, want %d", again.ID, admin.ID)
+	}
+	var count int64
+	db.Model(&models.Admin{}).Where("username = ?", "alice").Count(&count)
+	if count != 2 {
+		t.Errorf("admins named alice = %d, want 2", count)
+	}
+}
+
+func TestOIDCServiceRoleMapping(t *testing.T) {
+	db := newOIDCTestDB(t)
+	createOIDCTestRole(t, db, tenant.SuperTenantID, "VIEWER", 1)
+	createOIDCTestRole(t, db, tenant.SuperTenantID, "OTHER", 1)
+	viewerID := createOIDCTestRole(t, db, testOIDCTenantID, "VIEWER", 1)
+
+	tests := []struct {
+		name        string
+		groups      interface{}
+		defaultRole string
+		want        []uint
+		wantErr     error
+	}{
+		{"default role when no group matches", []string{"sales"}, "VIEWER", []uint{viewerID}, nil},
+		{"single group as a string", "viewers", "", []uint{viewerID}, nil},
+		{"no role without a default", []string{"sales"}, "", nil, ErrOIDCNoRole},
+		{"default role from another tenant", nil, "OTHER", nil, ErrOIDCNoRole},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newOIDCTestIdP(t, jwt.MapClaims{"sub": "idp-user", "groups": tt.groups})
+			s := newOIDCTestService(db, server, config.OIDCConfig{
+				DefaultRole:  tt.defaultRole,
+				RoleMappings: []config.OIDCRoleMapping{{Group: "viewers", Role: "VIEWER"}},
+			})
+			roleIDs, err := s.withTenant(context.Background()).resolveRoles(verifiedClaims(t, s, server))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("resolveRoles error = %v, want %v", err, tt.wantErr)
+			}
+			if len(roleIDs) != len(tt.want) || (len(roleIDs) > 0 && roleIDs[0] != tt.want[0]) {
+				t.Errorf("roleIDs = %v, want %v", roleIDs, tt.want)
+			}
+		})
+	}
+}
+
+func TestOIDCServiceExistingUsername(t *testing.T) {
+	db := newOIDCTestDB(t)
+	roleID := createOIDCTestRole(t, db, testOIDCTenantID, "OPS", 1)
+	status := 1
+	existing := models.Admin{TenantID: testOIDCTenantID, Username: "bob", Password: "secret", RoleID: roleID, Status: &status}
+	if err := db.Create(&existing).Error; err != nil {
+		t.Fatal(err)
+	}
+	server := newOIDCTestIdP(t, jwt.MapClaims{"sub": "idp-bob", "preferred_username": "bob"})
+
+	s := newOIDCTestService(db, server, config.OIDCConfig{AutoProvision: true})
+	if _, err := s.withTenant(context.Background()).findOrProvision(verifiedClaims(t, s, server), roleID); !errors.Is(err, ErrOIDCUsernameTaken) {
+		t.Fatalf("findOrProvision error = %v, want %v", err, ErrOIDCUsernameTaken)
+	}
+
+	s = newOIDCTestService(db, server, config.OIDCConfig{LinkExisting: true})
+	admin, err := s.withTenant(context.Background()).findOrProvision(verifiedClaims(t, s, server), roleID)
+	if err != nil {
+		t.Fatalf("findOrProvision with link_existing: %v", err)
+	}
+	if admin.ID != existing.ID {
+		t.Errorf("linked admin = %d, want %d", admin.ID, existing.ID)
+	}
+}
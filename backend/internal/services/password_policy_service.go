@@ -0,0 +1,209 @@
+package services
+
+import (
+	"fmt"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/cache"
+	"normaladmin/backend/pkg/sysconfig"
+	"normaladmin/backend/pkg/utils"
+	"normaladmin/backend/pkg/utils/validator"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PasswordPolicyError 密码不满足策略，包含全部不满足的规则
+type PasswordPolicyError struct {
+	Violations []validator.PasswordViolation `json:"violations"`
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return strings.Join(messages, "；")
+}
+
+// PasswordPolicyService 密码策略服务
+// 规则来自系统配置：最小长度、必须包含的字符类别、禁用密码列表、禁止重复使用最近N次密码、密码最长有效期
+type PasswordPolicyService interface {
+	// Validate 校验新密码，userID 为0表示新建用户（不检查历史密码），currentHash 为当前密码哈希
+	Validate(userType string, userID uint, username, password, currentHash string) error
+	// Record 在同一事务中记录密码修改：写入历史、清理超出保留数量的历史并更新修改时间
+	Record(tx *gorm.DB, userType string, userID uint, passwordHash string) error
+	// ValidateFor 按已有用户当前的用户名和密码校验新密码，不修改密码
+	ValidateFor(userType string, userID uint, password string) error
+	// SetPassword 校验并修改已有用户的密码，同时记录密码历史
+	SetPassword(userType string, userID uint, password string) error
+	// IsExpired 密码是否已超过最长有效期，changedAt 为空时以 fallback 为准
+	IsExpired(changedAt *time.Time, fallback time.Time) bool
+}
+
+type passwordPolicyService struct {
+	db *gorm.DB
+}
+
+func NewPasswordPolicyService(db *gorm.DB) PasswordPolicyService {
+	return &passwordPolicyService{db: db}
+}
+
+func (s *passwordPolicyService) Validate(userType string, userID uint, username, password, currentHash string) error {
+	v := validator.NewPasswordValidator(
+		sysconfig.GetInt("password_min_length", 8),
+		sysconfig.Get("password_required_classes", "lower,digit"),
+		sysconfig.Get("password_banned_list", ""),
+	)
+	violations := v.Validate(username, password)
+
+	if userID > 0 && s.isReused(userType, userID, password, currentHash) {
+		violations = append(violations, validator.PasswordViolation{
+			Code:    validator.PasswordReused,
+			Message: fmt.Sprintf("不能使用最近%d次用过的密码", historyCount()),
+			Param:   fmt.Sprint(historyCount()),
+		})
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// isReused 新密码是否与当前密码或最近的历史密码相同
+func (s *passwordPolicyService) isReused(userType string, userID uint, password, currentHash string) bool {
+	if currentHash != "" && utils.CheckPassword(password, currentHash) {
+		return true
+	}
+
+	count := historyCount()
+	if count <= 0 {
+		return false
+	}
+	var hashes []string
+	s.db.Model(&models.PasswordHistory{}).
+		Where("user_type = ? AND user_id = ?", userType, userID).
+		Order("id DESC").
+		Limit(count).
+		Pluck("password_hash", &hashes)
+	for _, hash := range hashes {
+		if utils.CheckPassword(password, hash) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *passwordPolicyService) Record(tx *gorm.DB, userType string, userID uint, passwordHash string) error {
+	now := time.Now()
+	var model interface{}
+	switch userType {
+	case "admin":
+		model = &models.Admin{}
+	case "member":
+		model = &models.Member{}
+	default:
+		return fmt.Errorf("invalid user type: %s", userType)
+	}
+	if err := tx.Model(model).Where("id = ?", userID).UpdateColumn("password_changed_at", now).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Create(&models.PasswordHistory{
+		UserType:     userType,
+		UserID:       userID,
+		PasswordHash: passwordHash,
+		CreatedAt:    now,
+	}).Error; err != nil {
+		return err
+	}
+
+	// 只保留最近N条历史
+	count := historyCount()
+	if count < 1 {
+		count = 1
+	}
+	var keepIDs []uint
+	if err := tx.Model(&models.PasswordHistory{}).
+		Where("user_type = ? AND user_id = ?", userType, userID).
+		Order("id DESC").
+		Limit(count).
+		Pluck("id", &keepIDs).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_type = ? AND user_id = ? AND id NOT IN ?", userType, userID, keepIDs).
+		Delete(&models.PasswordHistory{}).Error
+}
+
+func (s *passwordPolicyService) ValidateFor(userType string, userID uint, password string) error {
+	_, err := s.validateFor(userType, userID, password)
+	return err
+}
+
+// validateFor 查询用户当前的用户名和密码哈希后校验新密码，返回写入密码时使用的模型
+func (s *passwordPolicyService) validateFor(userType string, userID uint, password string) (interface{}, error) {
+	var username, currentHash string
+	var model interface{}
+	switch userType {
+	case "admin":
+		var admin models.Admin
+		if err := s.db.First(&admin, userID).Error; err != nil {
+			return nil, err
+		}
+		username, currentHash, model = admin.Username, admin.Password, &models.Admin{}
+	case "member":
+		var member models.Member
+		if err := s.db.First(&member, userID).Error; err != nil {
+			return nil, err
+		}
+		username, currentHash, model = member.Username, member.Password, &models.Member{}
+	default:
+		return nil, fmt.Errorf("invalid user type: %s", userType)
+	}
+
+	if err := s.Validate(userType, userID, username, password, currentHash); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+func (s *passwordPolicyService) SetPassword(userType string, userID uint, password string) error {
+	model, err := s.validateFor(userType, userID, password)
+	if err != nil {
+		return err
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(model).Where("id = ?", userID).UpdateColumn("password", hash).Error; err != nil {
+			return err
+		}
+		return s.Record(tx, userType, userID, hash)
+	})
+	if err != nil {
+		return err
+	}
+	// 清除实体缓存，使修改时间等字段及时生效
+	return cache.Delete(fmt.Sprintf("%s:%d", userType, userID))
+}
+
+func (s *passwordPolicyService) IsExpired(changedAt *time.Time, fallback time.Time) bool {
+	maxAge := sysconfig.GetInt("password_max_age_days", 0)
+	if maxAge <= 0 {
+		return false
+	}
+	since := fallback
+	if changedAt != nil {
+		since = *changedAt
+	}
+	return time.Since(since) > time.Duration(maxAge)*24*time.Hour
+}
+
+// historyCount 禁止重复使用的最近密码数量
+func historyCount() int {
+	return sysconfig.GetInt("password_history_count", 5)
+}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/auth"
+	"strconv"
+	"strings"
+
+	"github.com/casbin/casbin/v2/util"
+	"gorm.io/gorm"
+)
+
+// ErrPermissionAdminNotFound 要排查的管理员不存在
+var ErrPermissionAdminNotFound = errors.New("admin not found")
+
+// 权限判定原因
+const (
+	PermissionReasonAllowlist     = "allowlist"      // 免检名单中的接口
+	PermissionReasonSuperAdmin    = "super_admin"    // 超级管理员不受接口权限限制
+	PermissionReasonPolicy        = "policy"         // 命中权限策略
+	PermissionReasonAdminDisabled = "admin_disabled" // 管理员已禁用
+	PermissionReasonRoleDisabled  = "role_disabled"  // 角色已禁用
+	PermissionReasonNoRole        = "no_role"        // 没有已启用的角色
+	PermissionReasonNoMenu        = "no_menu"        // 没有按钮菜单声明该接口
+	PermissionReasonMenuDisabled  = "menu_disabled"  // 声明该接口的按钮菜单都已禁用
+	PermissionReasonNoPolicy      = "no_policy"      // 角色没有对应的权限策略
+)
+
+// 路由访问结果
+const (
+	RouteAccessPublic    = "public"    // 不经过接口权限校验
+	RouteAccessAllowlist = "allowlist" // 免检名单中的接口
+	RouteAccessAllowed   = "allowed"   // 允许访问
+	RouteAccessDenied    = "denied"    // 拒绝访问
+)
+
+// PermissionRole 参与判定的角色
+type PermissionRole struct {
+	ID            uint     `json:"id"`
+	Name          string   `json:"name"`
+	Code          string   `json:"code"`
+	Enabled       bool     `json:"enabled"`
+	Allowed       bool     `json:"allowed"`                  // 该角色的策略是否允许访问
+	MatchedPolicy []string `json:"matched_policy,omitempty"` // 该角色命中的策略
+}
+
+// PermissionMenu 声明了该接口的按钮菜单
+type PermissionMenu struct {
+	ID         uint   `json:"id"`
+	Title      string `json:"title"`
+	Permission string `json:"permission"`
+	ApiMethod  string `json:"api_method"`
+	ApiPath    string `json:"api_path"`
+	Enabled    bool   `json:"enabled"`
+	RoleIDs    []uint `json:"role_ids"` // 参与判定的角色中已分配该菜单的角色
+}
+
+// PermissionExplanation 一次接口访问的权限判定过程
+type PermissionExplanation struct {
+	Subject         string            `json:"subject"`
+	Domain          string            `json:"domain"` // 主体所属租户在 Casbin 策略中的域
+	Method          string            `json:"method"`
+	Path            string            `json:"path"`
+	Allowed         bool              `json:"allowed"`
+	Reason          string            `json:"reason"`
+	MatchedPolicy   []string          `json:"matched_policy"`   // 命中的策略行，如 g, admin:2, 3, 1 和 p, 3, 1, /gam/admins, GET
+	MissingPolicies []string          `json:"missing_policies"` // 允许访问所缺少的策略行，为角色分配对应菜单后生成
+	Roles           []PermissionRole  `json:"roles"`
+	Menus           []PermissionMenu  `json:"menus"`
+	DataScope       *models.DataScope `json:"data_scope,omitempty"`
+}
+
+// RouteAccess 已注册路由的访问结果
+type RouteAccess struct {
+	Method        string   `json:"method"`
+	Path          string   `json:"path"`
+	Access        string   `json:"access"`
+	MatchedPolicy []string `json:"matched_policy,omitempty"`
+}
+
+// RoleRouteReport 角色可访问的接口报告
+type RoleRouteReport struct {
+	Role    PermissionRole `json:"role"`
+	Allowed int            `json:"allowed"`
+	Denied  int            `json:"denied"`
+	Routes  []RouteAccess  `json:"routes"`
+}
+
+// PermissionExplainService 接口权限排查服务
+// 按 CasbinMiddleware 的判定顺序依次评估免检名单、超级管理员和权限策略，
+// 并给出角色状态、声明该接口的按钮菜单和数据范围，说明允许或拒绝的原因
+type PermissionExplainService interface {
+	// ExplainAdmin 排查管理员访问接口的权限，user 为管理员ID或用户名
+	ExplainAdmin(user, method, path string) (*PermissionExplanation, error)
+	// ExplainRole 排查角色访问接口的权限，角色不存在时返回 ErrRoleNotFound
+	ExplainRole(roleID uint, method, path string) (*PermissionExplanation, error)
+	// RoleReport 评估角色对 routes 中每个路由的访问结果，已标记为 public 的路由保持不变
+	RoleReport(roleID uint, routes []RouteAccess) (*RoleRouteReport, error)
+	// WithContext 返回使用请求上下文的服务，只能排查上下文中租户的管理员和角色
+	WithContext(ctx context.Context) PermissionExplainService
+}
+
+type permissionExplainService struct {
+	db         *gorm.DB
+	dataScopes DataScopeService
+	allowlist  func(path string) (string, bool)
+}
+
+// NewPermissionExplainService allowlist 返回路径命中的免检名单规则，与 CasbinMiddleware 使用同一份名单
+func NewPermissionExplainService(db *gorm.DB, dataScopes DataScopeService, allowlist func(path string) (string, bool)) PermissionExplainService {
+	return &permissionExplainService{db: db, dataScopes: dataScopes, allowlist: allowlist}
+}
+
+func (s *permissionExplainService) WithContext(ctx context.Context) PermissionExplainService {
+	clone := *s
+	clone.db = s.db.WithContext(ctx)
+	clone.dataScopes = s.dataScopes.WithContext(ctx)
+	return &clone
+}
+
+func (s *permissionExplainService) ExplainAdmin(user, method, path string) (*PermissionExplanation, error) {
+	admin, err := s.findAdmin(user)
+	if err != nil {
+		return nil, err
+	}
+
+	var roles []models.Role
+	if err := s.db.Model(&models.Role{}).
+		Joins("INNER JOIN (?) AS admin_roles ON admin_roles.role_id = roles.id", models.AdminRoleAssignments(s.db)).
+		Where("admin_roles.admin_id = ?", admin.ID).
+		Order("admin_roles.temporary, admin_roles.sort").
+		Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	result := s.newExplanation(auth.AdminSubject(admin.ID), auth.TenantDomain(admin.TenantID), method, path, roles)
+	if result.DataScope, err = s.dataScopes.Resolve(admin.ID); err != nil {
+		return nil, err
+	}
+	if err := s.explain(result, roles, admin.Status == nil || *admin.Status != 1); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *permissionExplainService) ExplainRole(roleID uint, method, path string) (*PermissionExplanation, error) {
+	role, err := s.findRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := []models.Role{*role}
+	result := s.newExplanation(auth.RoleSubject(role.ID), auth.TenantDomain(role.TenantID), method, path, roles)
+	if result.DataScope, err = s.dataScopes.ResolveRole(role.ID); err != nil {
+		return nil, err
+	}
+	if err := s.explain(result, roles, false); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *permissionExplainService) RoleReport(roleID uint, routes []RouteAccess) (*RoleRouteReport, error) {
+	role, err := s.findRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+	enforcer := auth.GetEnforcer()
+	if enforcer == nil {
+		return nil, errors.New("casbin enforcer is not initialized")
+	}
+
+	report := &RoleRouteReport{Role: permissionRole(*role), Routes: make([]RouteAccess, 0, len(routes))}
+	superAdmin := isSuperAdminRole(*role) && report.Role.Enabled
+	domain := auth.TenantDomain(role.TenantID)
+	for _, route := range routes {
+		switch {
+		case route.Access == RouteAccessPublic:
+		case s.allowlisted(route.Path):
+			route.Access = RouteAccessAllowlist
+		case superAdmin:
+			route.Access = RouteAccessAllowed
+		default:
+			allowed, explain, err := enforcer.EnforceEx(auth.RoleSubject(role.ID), domain, route.Path, route.Method)
+			if err != nil {
+				return nil, err
+			}
+			route.Access = RouteAccessDenied
+			if allowed {
+				route.Access = RouteAccessAllowed
+				route.MatchedPolicy = []string{policyLine("p", explain...)}
+			}
+		}
+		switch route.Access {
+		case RouteAccessAllowed:
+			report.Allowed++
+		case RouteAccessDenied:
+			report.Denied++
+		}
+		report.Routes = append(report.Routes, route)
+	}
+	return report, nil
+}
+
+// newExplanation 初始化判定结果，domain 为主体所属租户的域
+func (s *permissionExplainService) newExplanation(subject, domain, method, path string, roles []models.Role) *PermissionExplanation {
+	result := &PermissionExplanation{
+		Subject:         subject,
+		Domain:          domain,
+		Method:          strings.ToUpper(method),
+		Path:            path,
+		MatchedPolicy:   []string{},
+		MissingPolicies: []string{},
+		Roles:           make([]PermissionRole, 0, len(roles)),
+		Menus:           []PermissionMenu{},
+	}
+	for _, role := range roles {
+		result.Roles = append(result.Roles, permissionRole(role))
+	}
+	return result
+}
+
+// explain 按 CasbinMiddleware 的顺序判定，并补充每个角色的判定和缺少的策略
+func (s *permissionExplainService) explain(result *PermissionExplanation, roles []models.Role, adminDisabled bool) error {
+	enforcer := auth.GetEnforcer()
+	if enforcer == nil {
+		return errors.New("casbin enforcer is not initialized")
+	}
+	if err := s.loadMenus(result, roles); err != nil {
+		return err
+	}
+
+	// 每个角色各自的判定，禁用的角色没有策略
+	for i := range result.Roles {
+		role := &result.Roles[i]
+		allowed, explain, err := enforcer.EnforceEx(auth.RoleSubject(role.ID), result.Domain, result.Path, result.Method)
+		if err != nil {
+			return err
+		}
+		role.Allowed = allowed
+		if allowed {
+			role.MatchedPolicy = []string{policyLine("p", explain...)}
+		}
+	}
+
+	switch {
+	case adminDisabled:
+		result.Reason = PermissionReasonAdminDisabled
+		return nil
+	case s.allowlisted(result.Path):
+		pattern, _ := s.allowlist(result.Path)
+		result.Allowed = true
+		result.Reason = PermissionReasonAllowlist
+		result.MatchedPolicy = append(result.MatchedPolicy, "allowlist, "+pattern)
+		return nil
+	}
+
+	enabled := 0
+	for i, role := range result.Roles {
+		if !role.Enabled {
+			continue
+		}
+		enabled++
+		if isSuperAdminRole(roles[i]) {
+			result.Allowed = true
+			result.Reason = PermissionReasonSuperAdmin
+			return nil
+		}
+	}
+
+	allowed, explain, err := enforcer.EnforceEx(result.Subject, result.Domain, result.Path, result.Method)
+	if err != nil {
+		return err
+	}
+	if allowed {
+		result.Allowed = true
+		result.Reason = PermissionReasonPolicy
+		if len(explain) > 0 && explain[0] != result.Subject {
+			result.MatchedPolicy = append(result.MatchedPolicy, policyLine("g", result.Subject, explain[0], result.Domain))
+		}
+		result.MatchedPolicy = append(result.MatchedPolicy, policyLine("p", explain...))
+		return nil
+	}
+
+	switch {
+	case enabled == 0 && len(result.Roles) == 1:
+		result.Reason = PermissionReasonRoleDisabled
+	case enabled == 0:
+		result.Reason = PermissionReasonNoRole
+	case len(result.Menus) == 0:
+		result.Reason = PermissionReasonNoMenu
+	default:
+		result.Reason = PermissionReasonMenuDisabled
+		for _, menu := range result.Menus {
+			if menu.Enabled {
+				result.Reason = PermissionReasonNoPolicy
+				break
+			}
+		}
+	}
+
+	// 为已启用的角色分配任一已启用菜单即可访问；角色本身允许访问时缺少的是分组策略，需重新同步
+	if result.Reason == PermissionReasonNoPolicy {
+		for _, role := range result.Roles {
+			if !role.Enabled {
+				continue
+			}
+			if role.Allowed {
+				result.MissingPolicies = append(result.MissingPolicies, policyLine("g", result.Subject, auth.RoleSubject(role.ID), result.Domain))
+				continue
+			}
+			for _, menu := range result.Menus {
+				if menu.Enabled {
+					result.MissingPolicies = append(result.MissingPolicies,
+						policyLine("p", auth.RoleSubject(role.ID), result.Domain, menu.ApiPath, strings.ToUpper(menu.ApiMethod)))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// loadMenus 查找声明了该接口的按钮菜单，按 keyMatch2 匹配路径
+func (s *permissionExplainService) loadMenus(result *PermissionExplanation, roles []models.Role) error {
+	var menus []models.Menu
+	if err := s.db.Where("type = 'button' AND api_path <> '' AND UPPER(api_method) = ?", result.Method).
+		Order("id").Find(&menus).Error; err != nil {
+		return err
+	}
+
+	roleIDs := make([]uint, len(roles))
+	for i, role := range roles {
+		roleIDs[i] = role.ID
+	}
+	for _, menu := range menus {
+		if !util.KeyMatch2(result.Path, menu.ApiPath) {
+			continue
+		}
+		assigned := make([]uint, 0)
+		if len(roleIDs) > 0 {
+			if err := s.db.Model(&models.RoleMenu{}).Where("menu_id = ? AND role_id IN ?", menu.ID, roleIDs).
+				Order("role_id").Pluck("role_id", &assigned).Error; err != nil {
+				return err
+			}
+		}
+		result.Menus = append(result.Menus, PermissionMenu{
+			ID:         menu.ID,
+			Title:      menu.Title,
+			Permission: menu.Permission,
+			ApiMethod:  menu.ApiMethod,
+			ApiPath:    menu.ApiPath,
+			Enabled:    menu.Status != nil && *menu.Status == 1,
+			RoleIDs:    assigned,
+		})
+	}
+	return nil
+}
+
+func (s *permissionExplainService) allowlisted(path string) bool {
+	if s.allowlist == nil {
+		return false
+	}
+	_, ok := s.allowlist(path)
+	return ok
+}
+
+// findAdmin 按管理员ID或用户名查找管理员
+func (s *permissionExplainService) findAdmin(user string) (*models.Admin, error) {
+	var admin models.Admin
+	db := s.db.Select("id", "tenant_id", "username", "status")
+	if id, err := strconv.ParseUint(user, 10, 64); err == nil {
+		db = db.Where("id = ?", id)
+	} else {
+		db = db.Where("username = ?", user)
+	}
+	if err := db.First(&admin).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPermissionAdminNotFound
+		}
+		return nil, err
+	}
+	return &admin, nil
+}
+
+func (s *permissionExplainService) findRole(roleID uint) (*models.Role, error) {
+	var role models.Role
+	if err := s.db.First(&role, roleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+func permissionRole(role models.Role) PermissionRole {
+	return PermissionRole{
+		ID:      role.ID,
+		Name:    role.Name,
+		Code:    role.Code,
+		Enabled: role.Status != nil && *role.Status == 1,
+	}
+}
+
+// policyLine 按策略文件的格式输出策略行，如 p, 3, 1, /gam/admins, GET
+func policyLine(ptype string, values ...string) string {
+	return strings.Join(append([]string{ptype}, values...), ", ")
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/auth"
+
+	"gorm.io/gorm"
+)
+
+// ErrAdminRoleNotFound 分配给管理员的角色不存在
+var ErrAdminRoleNotFound = errors.New("admin role not found")
+
+// PolicySyncResult 全量同步结果
+type PolicySyncResult struct {
+	Roles     int `json:"roles"`     // 同步的角色数
+	Policies  int `json:"policies"`  // 写入的策略数
+	Removed   int `json:"removed"`   // 清理的已删除角色数
+	Admins    int `json:"admins"`    // 同步的管理员数
+	Groupings int `json:"groupings"` // 写入的分组策略数
+}
+
+// PolicySyncService 按角色菜单重建角色的接口权限策略，按管理员角色重建分组策略
+// 角色的策略完全由其已启用按钮菜单的 ApiMethod/ApiPath 决定，禁用或删除的角色没有策略；
+// 管理员主体（admin:<id>）通过分组策略继承 admin_roles 中全部角色和生效中临时授权角色的策略；
+// 策略表与业务数据在同一事务中写入，只处理角色和管理员主体，不影响 API 密钥等其他主体的策略
+type PolicySyncService interface {
+	// Transaction 在事务中执行 fn，提交成功后刷新内存中的策略
+	Transaction(fn func(tx *gorm.DB) error) error
+	// SyncRoles 在事务中重建指定角色的策略
+	SyncRoles(tx *gorm.DB, roleIDs ...uint) error
+	// SyncMenus 在事务中重建使用了指定菜单的角色的策略
+	SyncMenus(tx *gorm.DB, menuIDs ...uint) error
+	// AssignRoles 在事务中替换管理员的角色，第一个角色作为主角色，并重建其分组策略
+	// 角色不存在时返回 ErrAdminRoleNotFound
+	AssignRoles(tx *gorm.DB, adminID uint, roleIDs []uint) error
+	// SyncAdmins 在事务中按 admin_roles 和生效中的临时授权重建指定管理员的分组策略，已删除的管理员没有分组策略
+	SyncAdmins(tx *gorm.DB, adminIDs ...uint) error
+	// SyncAll 重建全部角色的策略和全部管理员的分组策略，并清理已删除角色、管理员遗留的策略
+	SyncAll() (*PolicySyncResult, error)
+	// WithContext 返回在请求上下文中开启事务的服务，事务内写入的业务数据属于上下文中的租户；
+	// 菜单等全部租户共用的数据变更需要同步所有租户的角色，不能使用带租户的上下文
+	WithContext(ctx context.Context) PolicySyncService
+}
+
+type policySyncService struct {
+	db *gorm.DB
+}
+
+func NewPolicySyncService(db *gorm.DB) PolicySyncService {
+	return &policySyncService{db: db}
+}
+
+func (s *policySyncService) WithContext(ctx context.Context) PolicySyncService {
+	return &policySyncService{db: s.db.WithContext(ctx)}
+}
+
+func (s *policySyncService) Transaction(fn func(tx *gorm.DB) error) error {
+	if err := s.db.Transaction(fn); err != nil {
+		return err
+	}
+	return auth.ReloadPolicy()
+}
+
+func (s *policySyncService) SyncRoles(tx *gorm.DB, roleIDs ...uint) error {
+	for _, roleID := range roleIDs {
+		if _, err := s.syncRole(tx, roleID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *policySyncService) SyncMenus(tx *gorm.DB, menuIDs ...uint) error {
+	if len(menuIDs) == 0 {
+		return nil
+	}
+	var roleIDs []uint
+	if err := tx.Model(&models.RoleMenu{}).Where("menu_id IN ?", menuIDs).Distinct("role_id").Pluck("role_id", &roleIDs).Error; err != nil {
+		return err
+	}
+	return s.SyncRoles(tx, roleIDs...)
+}
+
+func (s *policySyncService) SyncAll() (*PolicySyncResult, error) {
+	result := &PolicySyncResult{}
+	err := s.Transaction(func(tx *gorm.DB) error {
+		var roleIDs []uint
+		if err := tx.Model(&models.Role{}).Pluck("id", &roleIDs).Error; err != nil {
+			return err
+		}
+		existing := make(map[string]bool, len(roleIDs))
+		for _, roleID := range roleIDs {
+			count, err := s.syncRole(tx, roleID)
+			if err != nil {
+				return err
+			}
+			existing[auth.RoleSubject(roleID)] = true
+			result.Roles++
+			result.Policies += count
+		}
+
+		// 清理已删除角色的策略
+		subjects, err := auth.PolicySubjects(tx)
+		if err != nil {
+			return err
+		}
+		for _, subject := range subjects {
+			if !auth.IsRoleSubject(subject) || existing[subject] {
+				continue
+			}
+			if _, err := auth.ReplaceSubjectPolicies(tx, subject, "", nil); err != nil {
+				return err
+			}
+			result.Removed++
+		}
+
+		// 重建管理员的分组策略，并清理已删除管理员的分组策略
+		var adminIDs []uint
+		if err := tx.Model(&models.Admin{}).Pluck("id", &adminIDs).Error; err != nil {
+			return err
+		}
+		admins := make(map[string]bool, len(adminIDs))
+		for _, adminID := range adminIDs {
+			count, err := s.syncAdmin(tx, adminID)
+			if err != nil {
+				return err
+			}
+			admins[auth.AdminSubject(adminID)] = true
+			result.Admins++
+			result.Groupings += count
+		}
+		users, err := auth.GroupingUsers(tx)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if userType, _, ok := auth.ParseUserSubject(user); !ok || userType != "admin" || admins[user] {
+				continue
+			}
+			if _, err := auth.ReplaceUserRoles(tx, user, "", nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *policySyncService) AssignRoles(tx *gorm.DB, adminID uint, roleIDs []uint) error {
+	unique := make([]uint, 0, len(roleIDs))
+	seen := make(map[uint]bool)
+	for _, roleID := range roleIDs {
+		if roleID != 0 && !seen[roleID] {
+			seen[roleID] = true
+			unique = append(unique, roleID)
+		}
+	}
+	if len(unique) > 0 {
+		var count int64
+		if err := tx.Model(&models.Role{}).Where("id IN ?", unique).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(unique) {
+			return ErrAdminRoleNotFound
+		}
+	}
+
+	if err := tx.Where("admin_id = ?", adminID).Delete(&models.AdminRole{}).Error; err != nil {
+		return err
+	}
+	for _, roleID := range unique {
+		if err := tx.Create(&models.AdminRole{AdminID: adminID, RoleID: roleID}).Error; err != nil {
+			return err
+		}
+	}
+
+	primary := uint(0)
+	if len(unique) > 0 {
+		primary = unique[0]
+	}
+	if err := tx.Model(&models.Admin{}).Where("id = ?", adminID).UpdateColumn("role_id", primary).Error; err != nil {
+		return err
+	}
+	return s.SyncAdmins(tx, adminID)
+}
+
+func (s *policySyncService) SyncAdmins(tx *gorm.DB, adminIDs ...uint) error {
+	for _, adminID := range adminIDs {
+		if _, err := s.syncAdmin(tx, adminID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncAdmin 按 admin_roles 和生效中的临时授权重建管理员的分组策略，返回写入的分组策略数
+// 禁用的角色没有权限策略，分组策略中保留也不会获得权限，因此不在这里过滤；
+// 分组策略写入管理员所属租户的域，其他租户的角色即使出现在关联中也不会生效
+func (s *policySyncService) syncAdmin(tx *gorm.DB, adminID uint) (int, error) {
+	var admin models.Admin
+	if err := tx.Select("id", "tenant_id").First(&admin, adminID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return auth.ReplaceUserRoles(tx, auth.AdminSubject(adminID), "", nil)
+		}
+		return 0, err
+	}
+
+	var roleIDs []uint
+	if err := tx.Table("(?) AS admin_roles", models.AdminRoleAssignments(tx)).
+		Joins("INNER JOIN roles ON roles.id = admin_roles.role_id AND roles.tenant_id = ?", admin.TenantID).
+		Where("admin_roles.admin_id = ?", adminID).
+		Order("admin_roles.temporary, admin_roles.sort").
+		Pluck("admin_roles.role_id", &roleIDs).Error; err != nil {
+		return 0, err
+	}
+	roles := make([]string, len(roleIDs))
+	for i, roleID := range roleIDs {
+		roles[i] = auth.RoleSubject(roleID)
+	}
+	return auth.ReplaceUserRoles(tx, auth.AdminSubject(adminID), auth.TenantDomain(admin.TenantID), roles)
+}
+
+// syncRole 按角色菜单重建角色的策略，返回写入的策略数
+func (s *policySyncService) syncRole(tx *gorm.DB, roleID uint) (int, error) {
+	var policies []auth.Policy
+	var role models.Role
+	err := tx.Select("id", "tenant_id", "status").First(&role, roleID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	if err == nil && role.Status != nil && *role.Status == 1 {
+		if err := tx.Model(&models.Menu{}).
+			Select("menus.api_path AS path, UPPER(menus.api_method) AS method").
+			Joins("INNER JOIN role_menus ON menus.id = role_menus.menu_id").
+			Where("role_menus.role_id = ? AND menus.type = 'button' AND menus.status = 1 AND menus.api_path <> '' AND menus.api_method <> ''", roleID).
+			Scan(&policies).Error; err != nil {
+			return 0, err
+		}
+	}
+	return auth.ReplaceSubjectPolicies(tx, auth.RoleSubject(roleID), auth.TenantDomain(role.TenantID), policies)
+}
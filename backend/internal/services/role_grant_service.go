@@ -0,0 +1,296 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/logger"
+	"normaladmin/backend/pkg/sysconfig"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrRoleGrantNotFound 临时授权不存在
+	ErrRoleGrantNotFound = errors.New("role grant not found")
+	// ErrRoleGrantInvalidPeriod 授权时段无效：到期时间需晚于生效时间和当前时间，且不超过最长时长
+	ErrRoleGrantInvalidPeriod = errors.New("invalid role grant period")
+	// ErrRoleGrantClosed 授权已到期或已撤销
+	ErrRoleGrantClosed = errors.New("role grant already expired or revoked")
+	// ErrRoleGrantSelf 不能给自己临时授权
+	ErrRoleGrantSelf = errors.New("cannot grant a role to yourself")
+	// ErrRoleGrantAdminNotFound 被授权的管理员不存在
+	ErrRoleGrantAdminNotFound = errors.New("admin not found")
+)
+
+// RoleGrantRequest 临时授权请求
+type RoleGrantRequest struct {
+	AdminID  uint       `json:"admin_id" binding:"required"`
+	RoleID   uint       `json:"role_id" binding:"required"`
+	StartsAt *time.Time `json:"starts_at"` // 为空时立即生效
+	EndsAt   time.Time  `json:"ends_at" binding:"required"`
+	Reason   string     `json:"reason" binding:"required,max=255"`
+}
+
+// RoleGrantQuery 临时授权查询条件
+type RoleGrantQuery struct {
+	AdminID uint
+	RoleID  uint
+	Status  *int
+}
+
+// RoleGrantSweepResult 定时任务的处理结果
+type RoleGrantSweepResult struct {
+	Activated int // 到达生效时间的授权数
+	Expired   int // 到期收回的授权数
+	Reminded  int // 发送到期提醒的授权数
+}
+
+// RoleGrantService 临时角色授权服务
+// 授权在生效时段内计入管理员的角色（分组策略、菜单和数据范围），到期由定时任务收回；
+// 授权记录不删除，作为审计列表
+type RoleGrantService interface {
+	// Grant 临时授予管理员角色，生效时间已到时立即同步分组策略
+	Grant(req RoleGrantRequest, grantorID uint, grantorName string) (*models.RoleGrant, error)
+	// Revoke 提前撤销授权并通知管理员，已到期或已撤销时返回 ErrRoleGrantClosed
+	Revoke(id uint, operator string) error
+	// List 分页查询全部授权记录，按创建时间倒序
+	List(query RoleGrantQuery, page, pageSize string) ([]models.RoleGrant, int64, error)
+	// Sweep 由定时任务调用：同步到达生效时间的授权，收回到期的授权并通知，提醒即将到期的管理员
+	Sweep() (*RoleGrantSweepResult, error)
+	// WithContext 返回使用请求上下文的服务，只能授予和查看上下文中租户的管理员和角色
+	WithContext(ctx context.Context) RoleGrantService
+}
+
+type roleGrantService struct {
+	db            *gorm.DB
+	policies      PolicySyncService
+	notifications NotificationService
+}
+
+// NewRoleGrantService notifications 为 nil 时不发送通知
+func NewRoleGrantService(db *gorm.DB, policies PolicySyncService, notifications NotificationService) RoleGrantService {
+	return &roleGrantService{db: db, policies: policies, notifications: notifications}
+}
+
+func (s *roleGrantService) WithContext(ctx context.Context) RoleGrantService {
+	clone := *s
+	clone.db = s.db.WithContext(ctx)
+	clone.policies = s.policies.WithContext(ctx)
+	return &clone
+}
+
+func (s *roleGrantService) Grant(req RoleGrantRequest, grantorID uint, grantorName string) (*models.RoleGrant, error) {
+	if req.AdminID == grantorID {
+		return nil, ErrRoleGrantSelf
+	}
+	now := time.Now()
+	startsAt := now
+	if req.StartsAt != nil && req.StartsAt.After(now) {
+		startsAt = *req.StartsAt
+	}
+	maxHours := sysconfig.GetInt("role_grant_max_hours", 72)
+	if !req.EndsAt.After(startsAt) || (maxHours > 0 && req.EndsAt.Sub(startsAt) > time.Duration(maxHours)*time.Hour) {
+		return nil, ErrRoleGrantInvalidPeriod
+	}
+
+	var admin models.Admin
+	if err := s.db.Select("id").First(&admin, req.AdminID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleGrantAdminNotFound
+		}
+		return nil, err
+	}
+	var role models.Role
+	if err := s.db.Select("id").First(&role, req.RoleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAdminRoleNotFound
+		}
+		return nil, err
+	}
+
+	grant := &models.RoleGrant{
+		AdminID:     req.AdminID,
+		RoleID:      req.RoleID,
+		StartsAt:    startsAt,
+		EndsAt:      req.EndsAt,
+		Reason:      req.Reason,
+		GrantorID:   grantorID,
+		GrantorName: grantorName,
+		Status:      models.RoleGrantPending,
+	}
+	if !startsAt.After(now) {
+		grant.Status = models.RoleGrantActive
+	}
+	err := s.policies.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(grant).Error; err != nil {
+			return err
+		}
+		if grant.Status != models.RoleGrantActive {
+			return nil
+		}
+		return s.policies.SyncAdmins(tx, grant.AdminID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+func (s *roleGrantService) Revoke(id uint, operator string) error {
+	var grant models.RoleGrant
+	if err := s.db.First(&grant, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRoleGrantNotFound
+		}
+		return err
+	}
+	if grant.Status == models.RoleGrantExpired || grant.Status == models.RoleGrantRevoked {
+		return ErrRoleGrantClosed
+	}
+
+	now := time.Now()
+	err := s.policies.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&grant).Updates(map[string]interface{}{
+			"status":     models.RoleGrantRevoked,
+			"revoked_at": now,
+			"revoked_by": operator,
+		}).Error; err != nil {
+			return err
+		}
+		return s.policies.SyncAdmins(tx, grant.AdminID)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.notify(grant, "临时角色已撤销", fmt.Sprintf("您临时拥有的角色「%s」已被 %s 提前撤销。", s.roleName(grant.RoleID), operator))
+	return nil
+}
+
+func (s *roleGrantService) List(query RoleGrantQuery, page, pageSize string) ([]models.RoleGrant, int64, error) {
+	var grants []models.RoleGrant
+	var total int64
+	db := s.db.Model(&models.RoleGrant{})
+
+	if query.AdminID != 0 {
+		db = db.Where("admin_id = ?", query.AdminID)
+	}
+	if query.RoleID != 0 {
+		db = db.Where("role_id = ?", query.RoleID)
+	}
+	if query.Status != nil {
+		db = db.Where("status = ?", *query.Status)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.
+		Preload("Admin", func(db *gorm.DB) *gorm.DB { return db.Unscoped().Select("id", "username", "real_name") }).
+		Preload("Role", func(db *gorm.DB) *gorm.DB { return db.Unscoped().Select("id", "name", "code") }).
+		Order("id DESC").Scopes(models.Paginate(page, pageSize)).Find(&grants).Error; err != nil {
+		return nil, 0, err
+	}
+	return grants, total, nil
+}
+
+func (s *roleGrantService) Sweep() (*RoleGrantSweepResult, error) {
+	result := &RoleGrantSweepResult{}
+	now := time.Now()
+
+	var activated, expired []models.RoleGrant
+	if err := s.db.Where("status = ? AND starts_at <= ? AND ends_at > ?", models.RoleGrantPending, now, now).
+		Find(&activated).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("status IN ? AND ends_at <= ?", []int{models.RoleGrantPending, models.RoleGrantActive}, now).
+		Find(&expired).Error; err != nil {
+		return nil, err
+	}
+
+	// 没有状态变化时不重新加载策略
+	if len(activated) > 0 || len(expired) > 0 {
+		err := s.policies.Transaction(func(tx *gorm.DB) error {
+			adminIDs := make(map[uint]bool)
+			for _, grant := range activated {
+				// 按原状态更新，避免覆盖同时发生的撤销
+				if err := tx.Model(&grant).Where("status = ?", grant.Status).Update("status", models.RoleGrantActive).Error; err != nil {
+					return err
+				}
+				adminIDs[grant.AdminID] = true
+			}
+			for _, grant := range expired {
+				if err := tx.Model(&grant).Where("status = ?", grant.Status).Updates(map[string]interface{}{
+					"status":     models.RoleGrantExpired,
+					"revoked_at": now,
+				}).Error; err != nil {
+					return err
+				}
+				adminIDs[grant.AdminID] = true
+			}
+			for adminID := range adminIDs {
+				if err := s.policies.SyncAdmins(tx, adminID); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	result.Activated = len(activated)
+	result.Expired = len(expired)
+
+	for _, grant := range expired {
+		s.notify(grant, "临时角色已到期", fmt.Sprintf("您临时拥有的角色「%s」已于 %s 到期收回。",
+			s.roleName(grant.RoleID), grant.EndsAt.Format("2006-01-02 15:04")))
+	}
+
+	// 即将到期的授权只提醒一次
+	remindMinutes := sysconfig.GetInt("role_grant_remind_minutes", 30)
+	if remindMinutes <= 0 {
+		return result, nil
+	}
+	var expiring []models.RoleGrant
+	if err := s.db.Where("status = ? AND expiry_notified_at IS NULL AND ends_at > ? AND ends_at <= ?",
+		models.RoleGrantActive, now, now.Add(time.Duration(remindMinutes)*time.Minute)).
+		Find(&expiring).Error; err != nil {
+		return result, err
+	}
+	for _, grant := range expiring {
+		if err := s.db.Model(&grant).Update("expiry_notified_at", now).Error; err != nil {
+			return result, err
+		}
+		s.notify(grant, "临时角色即将到期", fmt.Sprintf("您临时拥有的角色「%s」将于 %s 到期，届时相关权限会自动收回。",
+			s.roleName(grant.RoleID), grant.EndsAt.Format("2006-01-02 15:04")))
+		result.Reminded++
+	}
+	return result, nil
+}
+
+// notify 通知被授权的管理员，发送失败只记录日志
+func (s *roleGrantService) notify(grant models.RoleGrant, title, content string) {
+	if s.notifications == nil {
+		return
+	}
+	if err := s.notifications.NotifyUser(grant.AdminID, "admin", NotificationTypeRoleGrant, title, content, 2); err != nil {
+		logger.Error("发送临时授权通知失败",
+			logger.Field("grant_id", grant.ID),
+			logger.Field("admin_id", grant.AdminID),
+			logger.Field("error", err),
+		)
+	}
+}
+
+// roleName 角色名称，角色已删除时使用角色ID
+func (s *roleGrantService) roleName(roleID uint) string {
+	var role models.Role
+	if err := s.db.Unscoped().Select("id", "name").First(&role, roleID).Error; err != nil {
+		return fmt.Sprintf("#%d", roleID)
+	}
+	return role.Name
+}
@@ -1,32 +1,98 @@
 package services
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"normaladmin/backend/internal/models"
-	"normaladmin/backend/pkg/auth"
+	"normaladmin/backend/pkg/tenant"
 
 	"gorm.io/gorm"
 )
 
+// SuperAdminRoleCode 超级管理员角色编码
+const SuperAdminRoleCode = "SUPER_ADMIN"
+
+// IsSuperAdmin 判断管理员是否拥有已启用的超级管理员角色，包括生效中的临时授权
+// 超级管理员不受接口权限限制，接口权限中间件和各服务共用此判断；只有超级租户的超级管理员角色有效，其他租户创建同编码的角色不会获得平台权限
+func IsSuperAdmin(db *gorm.DB, adminID uint) bool {
+	var count int64
+	enabledAdminRoles(db, adminID).Where("roles.code = ? AND roles.tenant_id = ?", SuperAdminRoleCode, tenant.SuperTenantID).Count(&count)
+	return count > 0
+}
+
+// isSuperAdminRole 角色是否为超级租户的超级管理员角色
+func isSuperAdminRole(role models.Role) bool {
+	return role.Code == SuperAdminRoleCode && role.TenantID == tenant.SuperTenantID
+}
+
+// AdminRoles 获取管理员已启用的角色，按分配顺序排列，生效中的临时授权排在最后
+func AdminRoles(db *gorm.DB, adminID uint) ([]models.Role, error) {
+	var roles []models.Role
+	if err := enabledAdminRoles(db, adminID).Order("admin_roles.temporary, admin_roles.sort").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// enabledAdminRoles 管理员已启用角色的查询，禁用的角色不提供任何菜单和权限
+func enabledAdminRoles(db *gorm.DB, adminID uint) *gorm.DB {
+	return db.Model(&models.Role{}).
+		Joins("INNER JOIN (?) AS admin_roles ON admin_roles.role_id = roles.id", models.AdminRoleAssignments(db)).
+		Where("admin_roles.admin_id = ? AND roles.status = 1", adminID)
+}
+
 type RoleService interface {
 	BaseCRUD[models.Role] // 组合基础CRUD接口
 	CheckRoleFieldUnique(field, value string, excludeID uint) (bool, error)
 	UpdateRoleMenus(roleID uint, menuIDs []uint) error
 	GetRoleMenus(roleID uint) ([]map[string]interface{}, []uint, error)
+	SyncPolicies() (*PolicySyncResult, error)
+	// WithContext 返回使用请求上下文的服务，查询和写入限定在上下文中的租户内
+	WithContext(ctx context.Context) RoleService
 }
 
 type roleService struct {
 	BaseCRUD[models.Role]
-	db *gorm.DB
+	db       *gorm.DB
+	policies PolicySyncService
 }
 
-func NewRoleService(db *gorm.DB, base BaseCRUD[models.Role]) RoleService {
+func NewRoleService(db *gorm.DB, base BaseCRUD[models.Role], policies PolicySyncService) RoleService {
 	return &roleService{
 		BaseCRUD: base,
 		db:       db,
+		policies: policies,
 	}
 }
 
+func (s *roleService) WithContext(ctx context.Context) RoleService {
+	clone := *s
+	clone.BaseCRUD = withContextCRUD(s.BaseCRUD, ctx)
+	clone.db = s.db.WithContext(ctx)
+	clone.policies = s.policies.WithContext(ctx)
+	return &clone
+}
+
+// Update 更新角色，角色状态变化会影响其权限策略，更新后重新同步
+func (s *roleService) Update(id uint, data interface{}, opts ...models.QueryOption) error {
+	if err := s.BaseCRUD.Update(id, data, opts...); err != nil {
+		return err
+	}
+	return s.policies.Transaction(func(tx *gorm.DB) error {
+		return s.policies.SyncRoles(tx, id)
+	})
+}
+
+// Delete 删除角色，并清理其权限策略
+func (s *roleService) Delete(id uint, hardDelete bool, opts ...models.QueryOption) error {
+	if err := s.BaseCRUD.Delete(id, hardDelete, opts...); err != nil {
+		return err
+	}
+	return s.policies.Transaction(func(tx *gorm.DB) error {
+		return s.policies.SyncRoles(tx, id)
+	})
+}
+
 func (s *roleService) CheckRoleFieldUnique(field, value string, excludeID uint) (bool, error) {
 	var count int64
 	db := s.db.Model(&models.Role{}).Where(field+" = ?", value)
@@ -39,9 +105,21 @@ func (s *roleService) CheckRoleFieldUnique(field, value string, excludeID uint)
 	return count == 0, nil
 }
 
-// UpdateRoleMenus 更新角色菜单权限(包含事务处理)
+// UpdateRoleMenus 更新角色菜单权限，并在同一事务中重建角色的权限策略
+// 平台菜单只能分配给超级租户的角色，其他租户的角色忽略平台菜单
 func (s *roleService) UpdateRoleMenus(roleID uint, menuIDs []uint) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
+	role, err := s.findRole(roleID)
+	if err != nil {
+		return err
+	}
+	if role.TenantID != tenant.SuperTenantID && len(menuIDs) > 0 {
+		if err := s.db.Model(&models.Menu{}).Where("id IN ? AND platform = ?", menuIDs, false).
+			Pluck("id", &menuIDs).Error; err != nil {
+			return err
+		}
+	}
+
+	return s.policies.Transaction(func(tx *gorm.DB) error {
 		// 删除原有的角色-菜单关联
 		if err := tx.Where("role_id = ?", roleID).Delete(&models.RoleMenu{}).Error; err != nil {
 			return err
@@ -50,35 +128,38 @@ func (s *roleService) UpdateRoleMenus(roleID uint, menuIDs []uint) error {
 		// 创建新的角色-菜单关联
 		for _, menuID := range menuIDs {
 			roleMenu := models.RoleMenu{
-				RoleID: roleID,
-				MenuID: menuID,
+				TenantID: role.TenantID,
+				RoleID:   roleID,
+				MenuID:   menuID,
 			}
 			if err := tx.Create(&roleMenu).Error; err != nil {
 				return err
 			}
-
-			// 如果是按钮类型的菜单，同时更新 Casbin 策略
-			var menu models.Menu
-			if err := tx.First(&menu, menuID).Error; err != nil {
-				return err
-			}
-
-			if menu.Type == "button" && menu.ApiMethod != "" && menu.ApiPath != "" {
-				if _, err := auth.AddPolicy(fmt.Sprint(roleID), menu.ApiPath, menu.ApiMethod); err != nil {
-					return err
-				}
-			}
 		}
-		return nil
+		return s.policies.SyncRoles(tx, roleID)
 	})
 }
 
+// SyncPolicies 按角色菜单重建全部角色的权限策略
+func (s *roleService) SyncPolicies() (*PolicySyncResult, error) {
+	return s.policies.SyncAll()
+}
+
 // GetRoleMenus 获取角色的菜单权限
 func (s *roleService) GetRoleMenus(roleID uint) ([]map[string]interface{}, []uint, error) {
+	role, err := s.findRole(roleID)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	checkedMenus := make([]uint, 0)
-	// 获取所有菜单
+	// 获取所有菜单，其他租户的角色不能分配平台菜单
 	var menus []models.Menu
-	if err := s.db.Order("sort").Find(&menus).Error; err != nil {
+	db := s.db.Order("sort")
+	if role.TenantID != tenant.SuperTenantID {
+		db = db.Where("platform = ?", false)
+	}
+	if err := db.Find(&menus).Error; err != nil {
 		return nil, nil, err
 	}
 
@@ -96,6 +177,18 @@ func (s *roleService) GetRoleMenus(roleID uint) ([]map[string]interface{}, []uin
 	return menuTree, checkedMenus, nil
 }
 
+// findRole 查找当前租户内的角色
+func (s *roleService) findRole(roleID uint) (*models.Role, error) {
+	var role models.Role
+	if err := s.db.Select("id", "tenant_id").First(&role, roleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
 // buildMenuTreeWithChecked 构建带选中状态的菜单树
 func buildMenuTreeWithChecked(menus []models.Menu, roleMenus []models.RoleMenu) []map[string]interface{} {
 	// 创建已选中菜单ID的map，方便查找
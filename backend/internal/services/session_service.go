@@ -0,0 +1,221 @@
+package services
+
+import (
+	"errors"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/auth"
+	"normaladmin/backend/pkg/websocket"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrSessionNotFound 会话不存在或已失效
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionService 登录会话管理服务
+// 在线会话保存在 Redis，数据库保存镜像记录；注销会话会吊销其令牌家族并断开对应的 WebSocket 连接
+type SessionService interface {
+	// Create 登录成功后创建会话，session.SessionID 为本次登录的令牌家族ID
+	Create(session *models.UserSession) error
+	// List 获取用户当前有效的会话
+	List(userType string, userID uint) ([]models.UserSession, error)
+	// Get 按会话ID获取会话记录
+	Get(sessionID string) (*models.UserSession, error)
+	// Revoke 注销单个会话
+	Revoke(sessionID string) error
+	// RevokeAll 注销用户的全部会话，exceptSessionID 不为空时保留该会话，返回注销数量
+	RevokeAll(userType string, userID uint, exceptSessionID string) (int, error)
+}
+
+type sessionService struct {
+	db  *gorm.DB
+	hub *websocket.NotificationHub
+	ttl time.Duration // 会话有效期，与刷新令牌有效期一致
+}
+
+func NewSessionService(db *gorm.DB, hub *websocket.NotificationHub, ttl time.Duration) SessionService {
+	return &sessionService{db: db, hub: hub, ttl: ttl}
+}
+
+func (s *sessionService) Create(session *models.UserSession) error {
+	now := time.Now()
+	session.CreatedAt = now
+	session.LastSeenAt = now
+	session.ExpiresAt = now.Add(s.ttl)
+	if session.Device == "" {
+		session.Device = deviceFromUserAgent(session.UserAgent)
+	}
+
+	if err := auth.SaveSession(&auth.Session{
+		ID:         session.SessionID,
+		UserType:   session.UserType,
+		UserID:     session.UserID,
+		Username:   session.Username,
+		Device:     session.Device,
+		IP:         session.IP,
+		UserAgent:  session.UserAgent,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+		ExpiresAt:  session.ExpiresAt,
+	}, s.ttl); err != nil {
+		return err
+	}
+	return s.db.Create(session).Error
+}
+
+func (s *sessionService) List(userType string, userID uint) ([]models.UserSession, error) {
+	online, err := auth.ListSessions(userType, userID)
+	if err != nil {
+		// Redis 不可用时回退到数据库镜像
+		var sessions []models.UserSession
+		err := s.db.Where("user_type = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", userType, userID, time.Now()).
+			Order("last_seen_at DESC").
+			Find(&sessions).Error
+		return sessions, err
+	}
+	if len(online) == 0 {
+		return []models.UserSession{}, nil
+	}
+
+	ids := make([]string, 0, len(online))
+	for _, session := range online {
+		ids = append(ids, session.ID)
+	}
+	var records []models.UserSession
+	if err := s.db.Where("session_id IN ?", ids).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	recordIDs := make(map[string]uint, len(records))
+	for _, record := range records {
+		recordIDs[record.SessionID] = record.ID
+	}
+
+	sessions := make([]models.UserSession, 0, len(online))
+	for _, session := range online {
+		sessions = append(sessions, models.UserSession{
+			ID:         recordIDs[session.ID],
+			SessionID:  session.ID,
+			UserType:   session.UserType,
+			UserID:     session.UserID,
+			Username:   session.Username,
+			Device:     session.Device,
+			IP:         session.IP,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+		})
+	}
+	return sessions, nil
+}
+
+func (s *sessionService) Get(sessionID string) (*models.UserSession, error) {
+	var session models.UserSession
+	if err := s.db.Where("session_id = ?", sessionID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *sessionService) Revoke(sessionID string) error {
+	session, err := s.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	return s.revoke(session.UserType, session.UserID, sessionID)
+}
+
+func (s *sessionService) RevokeAll(userType string, userID uint, exceptSessionID string) (int, error) {
+	// 合并 Redis 在线会话与数据库中未注销的会话，避免任一侧数据缺失
+	sessionIDs := make(map[string]struct{})
+	if online, err := auth.ListSessions(userType, userID); err == nil {
+		for _, session := range online {
+			sessionIDs[session.ID] = struct{}{}
+		}
+	}
+	var records []string
+	if err := s.db.Model(&models.UserSession{}).
+		Where("user_type = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", userType, userID, time.Now()).
+		Pluck("session_id", &records).Error; err != nil {
+		return 0, err
+	}
+	for _, id := range records {
+		sessionIDs[id] = struct{}{}
+	}
+
+	revoked := 0
+	for id := range sessionIDs {
+		if id == exceptSessionID {
+			continue
+		}
+		if err := s.revoke(userType, userID, id); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// revoke 吊销令牌家族、删除在线会话、更新镜像记录并断开 WebSocket 连接
+func (s *sessionService) revoke(userType string, userID uint, sessionID string) error {
+	if err := auth.RevokeFamily(sessionID, s.ttl); err != nil {
+		return err
+	}
+	if err := auth.DeleteSession(userType, userID, sessionID); err != nil {
+		return err
+	}
+	if err := s.db.Model(&models.UserSession{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	if s.hub != nil {
+		s.hub.Disconnect(userID, userType, sessionID)
+	}
+	return nil
+}
+
+// deviceFromUserAgent 从 User-Agent 粗略识别浏览器和操作系统
+func deviceFromUserAgent(ua string) string {
+	if ua == "" {
+		return "Unknown"
+	}
+
+	var browser string
+	switch {
+	case strings.Contains(ua, "MicroMessenger"):
+		browser = "WeChat"
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	default:
+		browser = "Unknown"
+	}
+
+	var os string
+	switch {
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		os = "iOS"
+	case strings.Contains(ua, "Android"):
+		os = "Android"
+	case strings.Contains(ua, "Windows"):
+		os = "Windows"
+	case strings.Contains(ua, "Mac OS"):
+		os = "macOS"
+	case strings.Contains(ua, "Linux"):
+		os = "Linux"
+	default:
+		os = "Unknown"
+	}
+	return browser + " / " + os
+}
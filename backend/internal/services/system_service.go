@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"normaladmin/backend/internal/models"
 	"normaladmin/backend/pkg/utils"
@@ -13,12 +14,14 @@ import (
 type SystemService interface {
 	// 日志管理
 	CreateLog(log *models.SystemLog) error
-	GetLogList(query map[string]interface{}, page, pageSize string) ([]models.SystemLog, int64, error)
+	GetLogList(filter models.Filter, page, pageSize string) ([]models.SystemLog, int64, error)
 	DeleteLogs(before time.Time) error
 
 	// 系统监控
 	CollectSystemInfo() (*models.SystemMonitor, error)
 	GetMonitorData(duration string) ([]models.SystemMonitor, error)
+	// WithContext 返回使用请求上下文的服务，操作日志按租户隔离
+	WithContext(ctx context.Context) SystemService
 }
 
 type systemService struct {
@@ -29,6 +32,10 @@ func NewSystemService(db *gorm.DB) SystemService {
 	return &systemService{db: db}
 }
 
+func (s *systemService) WithContext(ctx context.Context) SystemService {
+	return &systemService{db: s.db.WithContext(ctx)}
+}
+
 // 系统监控实现
 func (s *systemService) CollectSystemInfo() (*models.SystemMonitor, error) {
 	monitor := &models.SystemMonitor{
@@ -90,19 +97,10 @@ func (s *systemService) CreateLog(log *models.SystemLog) error {
 	return s.db.Create(log).Error
 }
 
-func (s *systemService) GetLogList(query map[string]interface{}, page, pageSize string) ([]models.SystemLog, int64, error) {
+func (s *systemService) GetLogList(filter models.Filter, page, pageSize string) ([]models.SystemLog, int64, error) {
 	var logs []models.SystemLog
 	var total int64
-	db := s.db.Model(&models.SystemLog{})
-
-	// 处理查询条件
-	for field, value := range query {
-		if strValue, ok := value.(string); ok && strValue != "" {
-			db = db.Where(field+" = ?", strValue)
-		} else if value != nil {
-			db = db.Where(field+" = ?", value)
-		}
-	}
+	db := filter.Apply(s.db.Model(&models.SystemLog{}))
 
 	// 获取总数
 	if err := db.Count(&total).Error; err != nil {
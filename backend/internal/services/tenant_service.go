@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"normaladmin/backend/internal/models"
+	"normaladmin/backend/pkg/tenant"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrTenantNotFound 租户不存在
+	ErrTenantNotFound = errors.New("tenant not found")
+	// ErrTenantDisabled 租户已禁用或已到期
+	ErrTenantDisabled = errors.New("tenant is disabled or expired")
+	// ErrTenantCodeExists 租户编码已被使用（包括已删除的租户）
+	ErrTenantCodeExists = errors.New("tenant code already exists")
+	// ErrTenantInvalidCode 租户编码只能包含小写字母、数字和连字符，长度 2-50
+	ErrTenantInvalidCode = errors.New("invalid tenant code")
+	// ErrTenantSuper 超级租户不能修改状态
+	ErrTenantSuper = errors.New("cannot change the status of the super tenant")
+)
+
+var tenantCodePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,49}$`)
+
+// 开通租户时创建的管理员角色
+const (
+	tenantAdminRoleName = "租户管理员"
+	tenantAdminRoleCode = "TENANT_ADMIN"
+)
+
+// TenantRequest 开通或修改租户的请求，Code 开通后不能修改
+type TenantRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Code      string     `json:"code" binding:"max=50"`
+	Contact   string     `json:"contact" binding:"max=50"`
+	Phone     string     `json:"phone" binding:"max=20"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Remark    string     `json:"remark" binding:"max=500"`
+}
+
+// TenantProvisionRequest 开通租户的请求，同时创建租户的第一个管理员
+type TenantProvisionRequest struct {
+	TenantRequest
+	AdminUsername string `json:"admin_username" binding:"required,max=50"`
+	AdminPassword string `json:"admin_password" binding:"required"`
+}
+
+// TenantProvisionResult 开通结果
+type TenantProvisionResult struct {
+	Tenant  *models.Tenant `json:"tenant"`
+	AdminID uint           `json:"admin_id"`
+	RoleID  uint           `json:"role_id"`
+}
+
+// TenantService 租户管理服务，只供超级租户使用
+type TenantService interface {
+	// Resolve 按登录时填写的编码查找租户，编码为空时返回超级租户；禁用或到期时返回 ErrTenantDisabled
+	Resolve(code string) (*models.Tenant, error)
+	// List 分页查询租户，query 按名称或编码模糊匹配
+	List(query string, page, pageSize string) ([]models.Tenant, int64, error)
+	Get(id uint) (*models.Tenant, error)
+	// Provision 开通租户：创建租户、租户管理员角色（拥有全部非平台菜单）和第一个管理员
+	Provision(req TenantProvisionRequest) (*TenantProvisionResult, error)
+	// Update 修改租户信息，编码不能修改
+	Update(id uint, req TenantRequest) (*models.Tenant, error)
+	// UpdateStatus 启用或禁用租户，禁用后租户的管理员和会员不能登录，已签发的令牌随即失效
+	UpdateStatus(id uint, status int) error
+}
+
+type tenantService struct {
+	db             *gorm.DB
+	policies       PolicySyncService
+	passwordPolicy PasswordPolicyService
+}
+
+func NewTenantService(db *gorm.DB, policies PolicySyncService, passwordPolicy PasswordPolicyService) TenantService {
+	return &tenantService{db: db, policies: policies, passwordPolicy: passwordPolicy}
+}
+
+func (s *tenantService) Resolve(code string) (*models.Tenant, error) {
+	var t models.Tenant
+	db := s.db
+	if code = strings.TrimSpace(code); code == "" {
+		db = db.Where("id = ?", tenant.SuperTenantID)
+	} else {
+		db = db.Where("code = ?", strings.ToLower(code))
+	}
+	if err := db.First(&t).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTenantNotFound
+		}
+		return nil, err
+	}
+	if t.ID != tenant.SuperTenantID && !t.Active(time.Now()) {
+		return nil, ErrTenantDisabled
+	}
+	return &t, nil
+}
+
+func (s *tenantService) List(query string, page, pageSize string) ([]models.Tenant, int64, error) {
+	var tenants []models.Tenant
+	var total int64
+	db := s.db.Model(&models.Tenant{})
+	if query = strings.TrimSpace(query); query != "" {
+		db = db.Where("name LIKE ? OR code LIKE ?", "%"+query+"%", "%"+query+"%")
+	}
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := db.Order("id").Scopes(models.Paginate(page, pageSize)).Find(&tenants).Error; err != nil {
+		return nil, 0, err
+	}
+	return tenants, total, nil
+}
+
+func (s *tenantService) Get(id uint) (*models.Tenant, error) {
+	var t models.Tenant
+	if err := s.db.First(&t, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTenantNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (s *tenantService) Provision(req TenantProvisionRequest) (*TenantProvisionResult, error) {
+	code := strings.ToLower(strings.TrimSpace(req.Code))
+	if !tenantCodePattern.MatchString(code) {
+		return nil, ErrTenantInvalidCode
+	}
+	// 已删除租户的编码也不再使用，避免旧租户的登录入口指向新租户
+	var count int64
+	if err := s.db.Unscoped().Model(&models.Tenant{}).Where("code = ?", code).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrTenantCodeExists
+	}
+	if err := s.passwordPolicy.Validate("admin", 0, req.AdminUsername, req.AdminPassword, ""); err != nil {
+		return nil, err
+	}
+
+	status := 1
+	t := &models.Tenant{
+		Name:      req.Name,
+		Code:      code,
+		Contact:   req.Contact,
+		Phone:     req.Phone,
+		Status:    &status,
+		ExpiresAt: req.ExpiresAt,
+		Remark:    req.Remark,
+	}
+	result := &TenantProvisionResult{Tenant: t}
+	err := s.policies.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(t).Error; err != nil {
+			return err
+		}
+		// 以下数据都写入新租户
+		scoped := tx.WithContext(tenant.WithTenant(context.Background(), t.ID))
+
+		roleStatus, adminStatus := 1, 1
+		role := &models.Role{
+			Name:      tenantAdminRoleName,
+			Code:      tenantAdminRoleCode,
+			Status:    &roleStatus,
+			DataScope: models.DataScopeAll,
+		}
+		if err := scoped.Create(role).Error; err != nil {
+			return err
+		}
+		var menuIDs []uint
+		if err := scoped.Model(&models.Menu{}).Where("platform = ?", false).Pluck("id", &menuIDs).Error; err != nil {
+			return err
+		}
+		for _, menuID := range menuIDs {
+			if err := scoped.Create(&models.RoleMenu{RoleID: role.ID, MenuID: menuID}).Error; err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		admin := &models.Admin{
+			Username:          req.AdminUsername,
+			Password:          req.AdminPassword, // 由 BeforeCreate 钩子加密
+			RoleID:            role.ID,
+			Status:            &adminStatus,
+			PasswordChangedAt: &now,
+		}
+		if err := scoped.Create(admin).Error; err != nil {
+			return err
+		}
+		if err := s.passwordPolicy.Record(scoped, "admin", admin.ID, admin.Password); err != nil {
+			return err
+		}
+		if err := s.policies.SyncRoles(scoped, role.ID); err != nil {
+			return err
+		}
+		if err := s.policies.AssignRoles(scoped, admin.ID, []uint{role.ID}); err != nil {
+			return err
+		}
+		result.AdminID, result.RoleID = admin.ID, role.ID
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *tenantService) Update(id uint, req TenantRequest) (*models.Tenant, error) {
+	t, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(t).Select("name", "contact", "phone", "expires_at", "remark").Updates(&models.Tenant{
+		Name:      req.Name,
+		Contact:   req.Contact,
+		Phone:     req.Phone,
+		ExpiresAt: req.ExpiresAt,
+		Remark:    req.Remark,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+func (s *tenantService) UpdateStatus(id uint, status int) error {
+	if id == tenant.SuperTenantID {
+		return ErrTenantSuper
+	}
+	result := s.db.Model(&models.Tenant{}).Where("id = ?", id).Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := s.Get(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
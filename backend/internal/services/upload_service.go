@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"mime/multipart"
 	"normaladmin/backend/internal/models"
@@ -21,6 +22,8 @@ type UploadService interface {
 	GetUploadConfig() (*models.UploadConfig, error)
 	DeleteFile(filePath string) error
 	BatchUploadFiles(files []*multipart.FileHeader, fileType string) ([]map[string]interface{}, error)
+	// WithContext 返回使用请求上下文的服务，上传记录属于上下文中的租户
+	WithContext(ctx context.Context) UploadService
 }
 
 type uploadService struct {
@@ -50,6 +53,10 @@ func NewUploadService(db *gorm.DB) UploadService {
 	}
 }
 
+func (s *uploadService) WithContext(ctx context.Context) UploadService {
+	return &uploadService{db: s.db.WithContext(ctx), storage: s.storage}
+}
+
 // UploadFile 上传文件
 func (s *uploadService) UploadFile(file *multipart.FileHeader, fileType string) (*models.UploadFile, error) {
 	// 创建文件验证器
@@ -113,8 +120,12 @@ func (s *uploadService) GetUploadConfig() (*models.UploadConfig, error) {
 	}, nil
 }
 
-// DeleteFile 删除文件
+// DeleteFile 删除文件，只能删除当前租户上传的文件
 func (s *uploadService) DeleteFile(filePath string) error {
+	var file models.UploadFile
+	if err := s.db.Select("id").Where("file_path = ?", filePath).First(&file).Error; err != nil {
+		return err
+	}
 	if err := s.storage.Delete(filePath); err != nil {
 		return err
 	}
@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+const (
+	// APIKeyPrefix API 密钥前缀，便于识别和密钥扫描
+	APIKeyPrefix = "gam_"
+	// apiKeyDisplayLength 保存并展示的密钥开头长度，用于辨认密钥
+	apiKeyDisplayLength = 12
+)
+
+// GenerateAPIKey 生成新的 API 密钥，返回明文、展示前缀和哈希；明文只在创建时返回一次
+func GenerateAPIKey() (key, prefix, hash string) {
+	key = APIKeyPrefix + RandomURLToken(32)
+	return key, key[:apiKeyDisplayLength], HashAPIKey(key)
+}
+
+// HashAPIKey 计算 API 密钥的哈希，密钥本身为高熵随机值，使用 SHA-256 即可
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(key)))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeySubject API 密钥在 Casbin 策略中的主体
+func APIKeySubject(id uint) string {
+	return "apikey:" + strconv.FormatUint(uint64(id), 10)
+}
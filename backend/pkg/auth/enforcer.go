@@ -10,9 +10,10 @@ import (
 	"gorm.io/gorm"
 )
 
-var enforcer *casbin.Enforcer
+// enforcer 使用带读写锁的 SyncedEnforcer，策略监听器在收到其他实例的变更时会并发重新加载策略
+var enforcer *casbin.SyncedEnforcer
 
-func InitCasbin(db *gorm.DB, rootDir string) (*casbin.Enforcer, error) {
+func InitCasbin(db *gorm.DB, rootDir string) (*casbin.SyncedEnforcer, error) {
 	// 使用 MySQL 适配器
 	a, err := gormadapter.NewAdapterByDB(db)
 	if err != nil {
@@ -21,7 +22,7 @@ func InitCasbin(db *gorm.DB, rootDir string) (*casbin.Enforcer, error) {
 
 	configPath := filepath.Join(rootDir, "config/rbac_model.conf")
 
-	e, err := casbin.NewEnforcer(configPath, a)
+	e, err := casbin.NewSyncedEnforcer(configPath, a)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create enforcer: %v", err)
 	}
@@ -35,27 +36,22 @@ func InitCasbin(db *gorm.DB, rootDir string) (*casbin.Enforcer, error) {
 	return e, nil
 }
 
-func GetEnforcer() *casbin.Enforcer {
+func GetEnforcer() *casbin.SyncedEnforcer {
 	return enforcer
 }
 
-// 添加权限策略
-func AddPolicy(role, path, method string) (bool, error) {
-	return enforcer.AddPolicy(role, path, method)
+// 添加权限策略，domain 为主体所属租户的域
+func AddPolicy(role, domain, path, method string) (bool, error) {
+	return enforcer.AddPolicy(role, domain, path, method)
 }
 
 // 删除权限策略
-func RemovePolicy(role, path, method string) (bool, error) {
+func RemovePolicy(role, domain, path, method string) (bool, error) {
 
-	return enforcer.RemovePolicy(role, path, method)
+	return enforcer.RemovePolicy(role, domain, path, method)
 }
 
 // RemoveFilteredPolicy 删除符合条件的权限策略
 func RemoveFilteredPolicy(fieldIndex int, fieldValues ...string) (bool, error) {
 	return enforcer.RemoveFilteredPolicy(fieldIndex, fieldValues...)
 }
-
-// 添加角色继承关系
-func AddRoleForUser(user, role string) (bool, error) {
-	return enforcer.AddGroupingPolicy(user, role)
-}
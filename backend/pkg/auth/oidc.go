@@ -0,0 +1,390 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	// ErrOIDCInvalidIDToken ID 令牌签名或声明校验失败
+	ErrOIDCInvalidIDToken = errors.New("invalid id token")
+	// ErrOIDCNonceMismatch ID 令牌的 nonce 与登录请求不一致
+	ErrOIDCNonceMismatch = errors.New("id token nonce mismatch")
+)
+
+// oidcJWKSRefreshInterval 遇到未知 kid 时重新拉取 JWKS 的最短间隔，防止被恶意令牌放大请求
+const oidcJWKSRefreshInterval = time.Minute
+
+// OIDCDiscovery OpenID Provider 元数据（/.well-known/openid-configuration）
+type OIDCDiscovery struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	UserinfoEndpoint              string   `json:"userinfo_endpoint"`
+	JWKSURI                       string   `json:"jwks_uri"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+}
+
+// OIDCTokenResponse 授权码换取的令牌
+type OIDCTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// OIDCClaims ID 令牌中的声明，保留全部原始字段用于角色映射
+type OIDCClaims map[string]interface{}
+
+// String 读取字符串声明
+func (c OIDCClaims) String(name string) string {
+	value, _ := c[name].(string)
+	return value
+}
+
+// Strings 读取字符串数组声明，单个字符串视为只有一个元素
+func (c OIDCClaims) Strings(name string) []string {
+	switch value := c[name].(type) {
+	case string:
+		if value == "" {
+			return nil
+		}
+		return []string{value}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// OIDCProvider OpenID Connect 身份提供方客户端，支持授权码 + PKCE 流程
+// 元数据在首次使用时发现并缓存，签名公钥按 kid 缓存，遇到未知 kid 时重新拉取
+type OIDCProvider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+
+	mu          sync.Mutex
+	discovery   *OIDCDiscovery
+	keys        map[string]interface{}
+	keysFetched time.Time
+}
+
+// NewOIDCProvider 创建身份提供方客户端，httpClient 为空时使用默认客户端
+func NewOIDCProvider(issuer, clientID, clientSecret string, httpClient *http.Client) *OIDCProvider {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &OIDCProvider{
+		issuer:       strings.TrimSuffix(issuer, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		httpClient:   httpClient,
+	}
+}
+
+// Discover 获取身份提供方元数据，成功后缓存
+func (p *OIDCProvider) Discover(ctx context.Context) (*OIDCDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery OIDCDiscovery
+	if err := p.getJSON(ctx, p.issuer+"/.well-known/openid-configuration", "", &discovery); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	// 元数据中的 issuer 必须与配置完全一致，防止混淆攻击
+	if strings.TrimSuffix(discovery.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer mismatch %q", discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: missing required endpoints")
+	}
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// AuthCodeURL 构造授权请求地址，使用 S256 PKCE
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, redirectURL, state, nonce, codeVerifier string, scopes []string) (string, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.clientID)
+	query.Set("redirect_uri", redirectURL)
+	query.Set("scope", strings.Join(scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", PKCEChallenge(codeVerifier))
+	query.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return discovery.AuthorizationEndpoint + sep + query.Encode(), nil
+}
+
+// Exchange 使用授权码和 PKCE 校验码换取令牌
+func (p *OIDCProvider) Exchange(ctx context.Context, code, redirectURL, codeVerifier string) (*OIDCTokenResponse, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURL)
+	form.Set("client_id", p.clientID)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.clientID), url.QueryEscape(p.clientSecret))
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc token exchange: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc token exchange: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var token OIDCTokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("oidc token exchange: %w", err)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("oidc token exchange: missing id_token")
+	}
+	return &token, nil
+}
+
+// VerifyIDToken 校验 ID 令牌的签名、签发者、受众、有效期和 nonce
+func (p *OIDCProvider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (OIDCClaims, error) {
+	if _, err := p.Discover(ctx); err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384", "EdDSA"}))
+	token, err := parser.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.lookupKey(ctx, kid)
+	})
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCInvalidIDToken, err)
+	}
+
+	if !claims.VerifyIssuer(p.issuer, true) {
+		return nil, fmt.Errorf("%w: issuer mismatch", ErrOIDCInvalidIDToken)
+	}
+	if !claims.VerifyAudience(p.clientID, true) {
+		return nil, fmt.Errorf("%w: audience mismatch", ErrOIDCInvalidIDToken)
+	}
+	// 多个受众时 azp 必须为本客户端
+	if azp, ok := claims["azp"].(string); ok && azp != p.clientID {
+		return nil, fmt.Errorf("%w: authorized party mismatch", ErrOIDCInvalidIDToken)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%w: missing exp", ErrOIDCInvalidIDToken)
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrOIDCInvalidIDToken)
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return nil, ErrOIDCNonceMismatch
+	}
+	return OIDCClaims(claims), nil
+}
+
+// UserInfo 使用访问令牌获取用户信息
+func (p *OIDCProvider) UserInfo(ctx context.Context, accessToken string) (OIDCClaims, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if discovery.UserinfoEndpoint == "" {
+		return nil, errors.New("oidc userinfo endpoint not supported")
+	}
+	claims := OIDCClaims{}
+	if err := p.getJSON(ctx, discovery.UserinfoEndpoint, accessToken, &claims); err != nil {
+		return nil, fmt.Errorf("oidc userinfo: %w", err)
+	}
+	return claims, nil
+}
+
+// lookupKey 按 kid 查找签名公钥，未命中时重新拉取 JWKS
+func (p *OIDCProvider) lookupKey(ctx context.Context, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.findKey(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetched) < oidcJWKSRefreshInterval && p.keys != nil {
+		return nil, ErrUnknownKeyID
+	}
+
+	var jwks JWKS
+	if err := p.getJSON(ctx, p.discovery.JWKSURI, "", &jwks); err != nil {
+		return nil, fmt.Errorf("oidc jwks: %w", err)
+	}
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.PublicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	p.keys = keys
+	p.keysFetched = time.Now()
+
+	if key, ok := p.findKey(kid); ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKeyID
+}
+
+// findKey 令牌未携带 kid 且只有一把密钥时直接使用该密钥
+func (p *OIDCProvider) findKey(kid string) (interface{}, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, endpoint, bearer string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+// PublicKey 将 JWK 转换为验证公钥，支持 RSA、EC（P-256/P-384）和 Ed25519
+func (k JWK) PublicKey() (interface{}, error) {
+	enc := base64.RawURLEncoding
+	switch k.Kty {
+	case "RSA":
+		n, err := enc.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := enc.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve: %s", k.Crv)
+		}
+		x, err := enc.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := enc.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported OKP curve: %s", k.Crv)
+		}
+		x, err := enc.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+}
+
+// NewPKCEVerifier 生成 PKCE 校验码（RFC 7636，43 个字符）
+func NewPKCEVerifier() string {
+	return RandomURLToken(32)
+}
+
+// PKCEChallenge 计算 S256 方式的 code_challenge
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// RandomURLToken 生成 URL 安全的随机字符串，用于 state、nonce 等一次性参数
+func RandomURLToken(size int) string {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
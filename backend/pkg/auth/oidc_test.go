@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	testClientID    = "gam-admin"
+	testRedirectURL = "https://admin.example.com/oidc/callback"
+)
+
+// mockIdP 模拟身份提供方：发现端点、JWKS 和令牌端点，令牌端点校验 PKCE 后返回带 nonce 的 ID 令牌
+type mockIdP struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu       sync.Mutex
+	keys     *KeySet
+	codes    map[string]mockGrant
+	jwksHits int
+}
+
+// mockGrant 授权码对应的授权请求参数和要签发的声明
+type mockGrant struct {
+	challenge   string
+	nonce       string
+	redirectURI string
+	claims      jwt.MapClaims
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	idp := &mockIdP{t: t, keys: &KeySet{algorithm: AlgorithmEdDSA, keys: map[string]*SigningKey{}}, codes: map[string]mockGrant{}}
+	idp.rotate("key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, OIDCDiscovery{
+			Issuer:                        idp.server.URL,
+			AuthorizationEndpoint:         idp.server.URL + "/authorize",
+			TokenEndpoint:                 idp.server.URL + "/token",
+			JWKSURI:                       idp.server.URL + "/jwks",
+			CodeChallengeMethodsSupported: []string{"S256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		idp.jwksHits++
+		idp.mu.Unlock()
+		writeJSON(w, http.StatusOK, idp.keys.JWKS())
+	})
+	mux.HandleFunc("/token", idp.handleToken)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// rotate 生成新的签名密钥，旧密钥仍保留在 JWKS 中
+func (idp *mockIdP) rotate(kid string) {
+	idp.t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	key := &SigningKey{ID: kid, Algorithm: AlgorithmEdDSA, Method: jwt.SigningMethodEdDSA, Private: priv, Public: pub, CreatedAt: time.Now()}
+	idp.keys.mu.Lock()
+	idp.keys.keys[kid] = key
+	idp.keys.mu.Unlock()
+}
+
+// authorize 模拟用户在身份提供方登录并同意授权，返回回调的授权码
+func (idp *mockIdP) authorize(authURL string, claims jwt.MapClaims) string {
+	idp.t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	query := u.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		idp.t.Fatalf("authorize request without S256 PKCE: %s", authURL)
+	}
+	if query.Get("client_id") != testClientID || query.Get("response_type") != "code" {
+		idp.t.Fatalf("unexpected authorize request: %s", authURL)
+	}
+	code := RandomURLToken(16)
+	idp.mu.Lock()
+	idp.codes[code] = mockGrant{
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+		redirectURI: query.Get("redirect_uri"),
+		claims:      claims,
+	}
+	idp.mu.Unlock()
+	return code
+}
+
+func (idp *mockIdP) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	idp.mu.Lock()
+	grant, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("redirect_uri") != grant.redirectURI ||
+		PKCEChallenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := idp.claims(jwt.MapClaims{"nonce": grant.nonce})
+	for name, value := range grant.claims {
+		claims[name] = value
+	}
+	writeJSON(w, http.StatusOK, OIDCTokenResponse{
+		AccessToken: RandomURLToken(16),
+		TokenType:   "Bearer",
+		IDToken:     idp.sign(claims),
+		ExpiresIn:   300,
+	})
+}
+
+// claims 有效的 ID 令牌声明，extra 覆盖默认值
+func (idp *mockIdP) claims(extra jwt.MapClaims) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": idp.server.URL,
+		"aud": testClientID,
+		"sub": "user-1",
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	}
+	for name, value := range extra {
+		claims[name] = value
+	}
+	return claims
+}
+
+// sign 使用当前密钥签名
+func (idp *mockIdP) sign(claims jwt.MapClaims) string {
+	idp.t.Helper()
+	key := idp.keys.Current()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.Private)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	return signed
+}
+
+// hits JWKS 被拉取的次数
+func (idp *mockIdP) hits() int {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	return idp.jwksHits
+}
+
+func (idp *mockIdP) provider() *OIDCProvider {
+	return NewOIDCProvider(idp.server.URL, testClientID, "", idp.server.Client())
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestOIDCProviderAuthorizationCodeFlow(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := idp.provider()
+	ctx := context.Background()
+
+	verifier, nonce := NewPKCEVerifier(), RandomURLToken(24)
+	authURL, err := provider.AuthCodeURL(ctx, testRedirectURL, "state-1", nonce, verifier, []string{"openid", "profile"})
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code := idp.authorize(authURL, jwt.MapClaims{"preferred_username": "alice", "groups": []string{"ops", "dev"}})
+
+	token, err := provider.Exchange(ctx, code, testRedirectURL, verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, nonce)
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if claims.String("sub") != "user-1" || claims.String("preferred_username") != "alice" {
+		t.Errorf("unexpected claims: %v", claims)
+	}
+	if groups := claims.Strings("groups"); len(groups) != 2 || groups[0] != "ops" || groups[1] != "dev" {
+		t.Errorf("groups = %v, want [ops dev]", groups)
+	}
+}
+
+func TestOIDCProviderExchangeRejectsWrongVerifier(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := idp.provider()
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, testRedirectURL, "state-1", "nonce-1", NewPKCEVerifier(), []string{"openid"})
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code := idp.authorize(authURL, nil)
+
+	if _, err := provider.Exchange(ctx, code, testRedirectURL, NewPKCEVerifier()); err == nil {
+		t.Fatal("Exchange with a different code_verifier succeeded")
+	}
+}
+
+func TestOIDCProviderVerifyIDTokenRejectsInvalidClaims(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := idp.provider()
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		mutate  func(claims jwt.MapClaims)
+		wantErr error
+	}{
+		{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "other-client" }, ErrOIDCInvalidIDToken},
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, ErrOIDCInvalidIDToken},
+		{"wrong authorized party", func(c jwt.MapClaims) {
+			c["aud"], c["azp"] = []string{testClientID, "other-client"}, "other-client"
+		}, ErrOIDCInvalidIDToken},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }, ErrOIDCInvalidIDToken},
+		{"missing sub", func(c jwt.MapClaims) { delete(c, "sub") }, ErrOIDCInvalidIDToken},
+		{"wrong nonce", func(c jwt.MapClaims) { c["nonce"] = "replayed-nonce" }, ErrOIDCNonceMismatch},
+		{"missing nonce", func(c jwt.MapClaims) { delete(c, "nonce") }, ErrOIDCNonceMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := idp.claims(jwt.MapClaims{"nonce": "nonce-1"})
+			tt.mutate(claims)
+			_, err := provider.VerifyIDToken(ctx, idp.sign(claims), "nonce-1")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyIDToken error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOIDCProviderRefetchesJWKSOnUnknownKid(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := idp.provider()
+	ctx := context.Background()
+
+	if _, err := provider.VerifyIDToken(ctx, idp.sign(idp.claims(jwt.MapClaims{"nonce": "n"})), "n"); err != nil {
+		t.Fatalf("VerifyIDToken with the first key: %v", err)
+	}
+	if idp.hits() != 1 {
+		t.Fatalf("jwks fetched %d times, want 1", idp.hits())
+	}
+
+	// 身份提供方轮换密钥后，在限流间隔内不重新拉取
+	idp.rotate("key-2")
+	rotated := idp.sign(idp.claims(jwt.MapClaims{"nonce": "n"}))
+	if _, err := provider.VerifyIDToken(ctx, rotated, "n"); !errors.Is(err, ErrOIDCInvalidIDToken) {
+		t.Fatalf("VerifyIDToken within the refresh interval error = %v, want %v", err, ErrOIDCInvalidIDToken)
+	}
+	if idp.hits() != 1 {
+		t.Fatalf("jwks fetched %d times within the refresh interval, want 1", idp.hits())
+	}
+
+	// 超过限流间隔后，未知 kid 触发重新拉取
+	provider.mu.Lock()
+	provider.keysFetched = time.Now().Add(-oidcJWKSRefreshInterval)
+	provider.mu.Unlock()
+	if _, err := provider.VerifyIDToken(ctx, rotated, "n"); err != nil {
+		t.Fatalf("VerifyIDToken after the refresh interval: %v", err)
+	}
+	if idp.hits() != 2 {
+		t.Fatalf("jwks fetched %d times, want 2", idp.hits())
+	}
+
+	// 已缓存的密钥不再拉取
+	if _, err := provider.VerifyIDToken(ctx, idp.sign(idp.claims(jwt.MapClaims{"nonce": "n"})), "n"); err != nil {
+		t.Fatalf("VerifyIDToken with a cached key: %v", err)
+	}
+	if idp.hits() != 2 {
+		t.Fatalf("jwks fetched %d times for a cached key, want 2", idp.hits())
+	}
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"normaladmin/backend/pkg/tenant"
+	"strconv"
+	"strings"
+
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	"gorm.io/gorm"
+)
+
+// Policy 一条接口权限策略
+type Policy struct {
+	Path   string `json:"path"`
+	Method string `json:"method"`
+}
+
+// TenantDomain 租户在 Casbin 策略中的域，策略和分组策略都只在所属租户的域内生效
+// 未指定租户（0）时为超级租户的域
+func TenantDomain(tenantID uint) string {
+	if tenantID == 0 {
+		tenantID = tenant.SuperTenantID
+	}
+	return strconv.FormatUint(uint64(tenantID), 10)
+}
+
+// RoleSubject 角色在 Casbin 策略中的主体
+func RoleSubject(roleID uint) string {
+	return strconv.FormatUint(uint64(roleID), 10)
+}
+
+// IsRoleSubject 判断主体是否为角色，API 密钥等其他主体不是纯数字
+func IsRoleSubject(subject string) bool {
+	_, err := strconv.ParseUint(subject, 10, 64)
+	return err == nil
+}
+
+// UserSubject 用户在 Casbin 策略中的主体，如 admin:1，通过分组策略继承所属角色的权限
+func UserSubject(userType string, userID uint) string {
+	return userType + ":" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// AdminSubject 管理员在 Casbin 策略中的主体
+func AdminSubject(adminID uint) string {
+	return UserSubject("admin", adminID)
+}
+
+// ParseUserSubject 解析用户主体，返回用户类型和用户ID
+func ParseUserSubject(subject string) (string, uint, bool) {
+	userType, id, found := strings.Cut(subject, ":")
+	if !found || userType == "" {
+		return "", 0, false
+	}
+	userID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || userID == 0 {
+		return "", 0, false
+	}
+	return userType, uint(userID), true
+}
+
+// ReplaceSubjectPolicies 在事务中直接改写策略表，用 policies 替换主体原有的全部权限策略，新策略写入 domain
+// 与业务数据在同一事务中提交，提交后需调用 ReloadPolicy 刷新内存中的策略；返回去重后写入的策略数
+func ReplaceSubjectPolicies(tx *gorm.DB, subject, domain string, policies []Policy) (int, error) {
+	if err := tx.Where("ptype = ? AND v0 = ?", "p", subject).Delete(&gormadapter.CasbinRule{}).Error; err != nil {
+		return 0, err
+	}
+
+	rules := make([]gormadapter.CasbinRule, 0, len(policies))
+	seen := make(map[Policy]bool)
+	for _, policy := range policies {
+		if policy.Path == "" || policy.Method == "" || seen[policy] {
+			continue
+		}
+		seen[policy] = true
+		rules = append(rules, gormadapter.CasbinRule{Ptype: "p", V0: subject, V1: domain, V2: policy.Path, V3: policy.Method})
+	}
+	if len(rules) == 0 {
+		return 0, nil
+	}
+	if err := tx.Create(&rules).Error; err != nil {
+		return 0, err
+	}
+	return len(rules), nil
+}
+
+// ReplaceUserRoles 在事务中直接改写策略表，用 roles 替换用户原有的全部分组策略（g, user, role, domain）
+// 与 ReplaceSubjectPolicies 相同，提交后需调用 ReloadPolicy；返回去重后写入的分组策略数
+func ReplaceUserRoles(tx *gorm.DB, user, domain string, roles []string) (int, error) {
+	if err := tx.Where("ptype = ? AND v0 = ?", "g", user).Delete(&gormadapter.CasbinRule{}).Error; err != nil {
+		return 0, err
+	}
+
+	rules := make([]gormadapter.CasbinRule, 0, len(roles))
+	seen := make(map[string]bool)
+	for _, role := range roles {
+		if role == "" || seen[role] {
+			continue
+		}
+		seen[role] = true
+		rules = append(rules, gormadapter.CasbinRule{Ptype: "g", V0: user, V1: role, V2: domain})
+	}
+	if len(rules) == 0 {
+		return 0, nil
+	}
+	if err := tx.Create(&rules).Error; err != nil {
+		return 0, err
+	}
+	return len(rules), nil
+}
+
+// GroupingUsers 策略表中所有分组策略的用户
+func GroupingUsers(tx *gorm.DB) ([]string, error) {
+	var users []string
+	err := tx.Model(&gormadapter.CasbinRule{}).Where("ptype = ?", "g").Distinct("v0").Pluck("v0", &users).Error
+	return users, err
+}
+
+// PolicySubjects 策略表中所有权限策略的主体
+func PolicySubjects(tx *gorm.DB) ([]string, error) {
+	var subjects []string
+	err := tx.Model(&gormadapter.CasbinRule{}).Where("ptype = ?", "p").Distinct("v0").Pluck("v0", &subjects).Error
+	return subjects, err
+}
+
+// ReloadPolicy 从数据库重新加载全部策略，并通知其他实例重新加载
+func ReloadPolicy() error {
+	if err := enforcer.LoadPolicy(); err != nil {
+		return err
+	}
+	if policyWatcher != nil {
+		return policyWatcher.Update()
+	}
+	return nil
+}
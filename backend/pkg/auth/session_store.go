@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"fmt"
+	"normaladmin/backend/pkg/cache"
+	"sort"
+	"time"
+)
+
+const (
+	sessionPrefix      = "auth:session:"       // 在线会话 <userType>:<userID>:<sessionID>
+	sessionTouchPrefix = "auth:session:touch:" // 最后活跃时间写入节流
+	sessionTouchPeriod = time.Minute
+)
+
+// Session 在线会话，会话ID即刷新令牌家族ID
+type Session struct {
+	ID         string    `json:"id"`
+	UserType   string    `json:"user_type"`
+	UserID     uint      `json:"user_id"`
+	Username   string    `json:"username"`
+	Device     string    `json:"device"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// SaveSession 保存在线会话，ttl 与刷新令牌有效期一致
+func SaveSession(session *Session, ttl time.Duration) error {
+	return cache.SetObject(sessionKey(session.UserType, session.UserID, session.ID), session, ttl)
+}
+
+// GetSession 获取在线会话
+func GetSession(userType string, userID uint, sessionID string) (*Session, error) {
+	var session Session
+	if err := cache.GetObject(sessionKey(userType, userID, sessionID), &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListSessions 获取用户的全部在线会话，按最后活跃时间倒序
+func ListSessions(userType string, userID uint) ([]Session, error) {
+	keys, err := cache.Keys(sessionKey(userType, userID, "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, 0, len(keys))
+	for _, key := range keys {
+		var session Session
+		if err := cache.GetObject(key, &session); err != nil {
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+// TouchSession 更新会话最后活跃时间并续期，每个会话每分钟最多写入一次
+// 返回是否实际更新，调用方据此同步数据库镜像
+func TouchSession(userType string, userID uint, sessionID string, ttl time.Duration) (*Session, bool) {
+	if sessionID == "" {
+		return nil, false
+	}
+	first, err := cache.SetNX(sessionTouchPrefix+sessionID, 1, sessionTouchPeriod)
+	if err != nil || !first {
+		return nil, false
+	}
+
+	session, err := GetSession(userType, userID, sessionID)
+	if err != nil {
+		return nil, false
+	}
+	now := time.Now()
+	session.LastSeenAt = now
+	session.ExpiresAt = now.Add(ttl)
+	if err := SaveSession(session, ttl); err != nil {
+		return nil, false
+	}
+	return session, true
+}
+
+// DeleteSession 删除在线会话
+func DeleteSession(userType string, userID uint, sessionID string) error {
+	return cache.Delete(sessionKey(userType, userID, sessionID))
+}
+
+func sessionKey(userType string, userID uint, sessionID string) string {
+	return fmt.Sprintf("%s%s:%d:%s", sessionPrefix, userType, userID, sessionID)
+}
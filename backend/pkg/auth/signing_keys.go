@@ -0,0 +1,384 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// 签名算法
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+const (
+	privateKeyExt = ".key" // 私钥文件，可签名也可验证
+	publicKeyExt  = ".pub" // 公钥文件，仅用于验证（如其他服务签发的令牌）
+	rsaKeyBits    = 2048
+
+	// keyReloadInterval 验证时遇到未知 kid 重新加载密钥目录的最短间隔，防止伪造的 kid 放大磁盘读取
+	keyReloadInterval = 10 * time.Second
+)
+
+// KeyPublishLead 下一把签名密钥提前发布的时间，不短于 JWKS 的缓存时间（5 分钟）与各实例重新加载密钥目录的间隔（1 分钟）之和
+const KeyPublishLead = 10 * time.Minute
+
+var (
+	// ErrUnknownKeyID 令牌的 kid 不在当前密钥集中
+	ErrUnknownKeyID = errors.New("unknown signing key id")
+	// ErrNoSigningKey 密钥目录中没有可用于签名的私钥
+	ErrNoSigningKey = errors.New("no signing key available")
+)
+
+// SigningKey 令牌签名密钥
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	Method    jwt.SigningMethod
+	Private   crypto.Signer // 为空表示仅用于验证
+	Public    crypto.PublicKey
+	CreatedAt time.Time // 启用时间（密钥文件的修改时间），预先发布的密钥晚于当前时间
+}
+
+// KeySet 从密钥目录加载的签名密钥集合
+// 目录中每个 <kid>.key 为私钥、<kid>.pub 为公钥（PEM 格式），已到启用时间的最新私钥用于签名，其余全部用于验证
+type KeySet struct {
+	mu         sync.RWMutex
+	dir        string
+	algorithm  string
+	keys       map[string]*SigningKey
+	reloadedAt time.Time
+
+	reloadMu sync.Mutex // 未知 kid 触发的重新加载串行执行
+}
+
+var keySet *KeySet
+
+// InitSigningKeys 初始化非对称签名密钥，HS256 模式下不加载密钥
+// 密钥目录不存在或没有私钥时自动生成第一把密钥
+func InitSigningKeys(algorithm, dir, rootDir string) error {
+	if algorithm == "" || algorithm == AlgorithmHS256 {
+		keySet = nil
+		return nil
+	}
+	if algorithm != AlgorithmRS256 && algorithm != AlgorithmEdDSA {
+		return fmt.Errorf("unsupported jwt algorithm: %s", algorithm)
+	}
+	if dir == "" {
+		return errors.New("jwt key_dir is required for asymmetric signing")
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(rootDir, dir)
+	}
+
+	ks := &KeySet{dir: dir, algorithm: algorithm}
+	if err := ks.Reload(); err != nil {
+		return err
+	}
+	if ks.Current() == nil {
+		if _, err := ks.Rotate(); err != nil {
+			return err
+		}
+	}
+	keySet = ks
+	return nil
+}
+
+// Keys 获取当前密钥集，HS256 模式下返回 nil
+func Keys() *KeySet {
+	return keySet
+}
+
+// Algorithm 密钥集使用的签名算法
+func (ks *KeySet) Algorithm() string {
+	return ks.algorithm
+}
+
+// Current 当前用于签名的密钥，预先发布的下一把密钥到启用时间后自动接替
+func (ks *KeySet) Current() *SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	now := time.Now()
+	var current *SigningKey
+	for _, key := range ks.keys {
+		if key.Private != nil && key.Algorithm == ks.algorithm && !key.CreatedAt.After(now) &&
+			(current == nil || key.CreatedAt.After(current.CreatedAt)) {
+			current = key
+		}
+	}
+	return current
+}
+
+// Next 已发布但尚未启用的签名密钥，没有时返回 nil
+func (ks *KeySet) Next() *SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	now := time.Now()
+	var next *SigningKey
+	for _, key := range ks.keys {
+		if key.Private != nil && key.Algorithm == ks.algorithm && key.CreatedAt.After(now) &&
+			(next == nil || key.CreatedAt.Before(next.CreatedAt)) {
+			next = key
+		}
+	}
+	return next
+}
+
+// Lookup 按 kid 查找验证密钥；未命中时重新加载密钥目录（限流），以验证其他实例刚轮换的密钥签发的令牌
+func (ks *KeySet) Lookup(kid string) (*SigningKey, bool) {
+	if key, ok := ks.lookup(kid); ok {
+		return key, true
+	}
+
+	ks.reloadMu.Lock()
+	defer ks.reloadMu.Unlock()
+	// 等待期间可能已被其他请求重新加载
+	if key, ok := ks.lookup(kid); ok {
+		return key, true
+	}
+	ks.mu.RLock()
+	reloadedAt := ks.reloadedAt
+	ks.mu.RUnlock()
+	if time.Since(reloadedAt) < keyReloadInterval {
+		return nil, false
+	}
+	if err := ks.Reload(); err != nil {
+		return nil, false
+	}
+	return ks.lookup(kid)
+}
+
+func (ks *KeySet) lookup(kid string) (*SigningKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// Reload 重新读取密钥目录，多实例共享目录时可感知其他实例的轮换
+func (ks *KeySet) Reload() error {
+	if err := os.MkdirAll(ks.dir, 0700); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(ks.dir)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*SigningKey)
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != privateKeyExt && ext != publicKeyExt) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		kid := strings.TrimSuffix(entry.Name(), ext)
+		// 同一个 kid 同时存在私钥和公钥时以私钥为准
+		if existing, ok := keys[kid]; ok && existing.Private != nil {
+			continue
+		}
+
+		key, err := loadKeyFile(filepath.Join(ks.dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("load jwt key %s: %w", entry.Name(), err)
+		}
+		key.ID = kid
+		key.CreatedAt = info.ModTime()
+		keys[kid] = key
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.reloadedAt = time.Now()
+	ks.mu.Unlock()
+	return nil
+}
+
+// Rotate 生成新的签名密钥并立即启用，旧密钥继续保留用于验证已签发的令牌
+func (ks *KeySet) Rotate() (*SigningKey, error) {
+	return ks.RotateAt(time.Now())
+}
+
+// RotateAt 生成在 activateAt 启用的签名密钥；启用前只在 JWKS 中发布用于验证，
+// 使其他实例和缓存了 JWKS 的服务在新密钥签发令牌之前已获得其公钥
+func (ks *KeySet) RotateAt(activateAt time.Time) (*SigningKey, error) {
+	var signer crypto.Signer
+	switch ks.algorithm {
+	case AlgorithmRS256:
+		key, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, err
+		}
+		signer = key
+	case AlgorithmEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		signer = key
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm: %s", ks.algorithm)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, err
+	}
+	kid := activateAt.UTC().Format("20060102T150405Z") + "-" + strings.ToLower(ks.algorithm)
+	path := filepath.Join(ks.dir, kid+privateKeyExt)
+	if err := os.MkdirAll(ks.dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return nil, err
+	}
+	// 启用时间记录为文件的修改时间，共享目录的其他实例同样按此时间切换
+	if err := os.Chtimes(path, activateAt, activateAt); err != nil {
+		return nil, err
+	}
+
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	if key, ok := ks.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, ErrNoSigningKey
+}
+
+// Prune 删除启用时间早于 retention 的私钥，当前签名密钥、尚未启用的密钥和公钥文件不会被删除
+// retention 应不短于刷新令牌的有效期，保证已签发的令牌在过期前仍可验证
+func (ks *KeySet) Prune(retention time.Duration) error {
+	current := ks.Current()
+	ks.mu.RLock()
+	var expired []string
+	for kid, key := range ks.keys {
+		if key.Private == nil || key == current || time.Since(key.CreatedAt) < retention {
+			continue
+		}
+		expired = append(expired, kid)
+	}
+	ks.mu.RUnlock()
+
+	if len(expired) == 0 {
+		return nil
+	}
+	for _, kid := range expired {
+		if err := os.Remove(filepath.Join(ks.dir, kid+privateKeyExt)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return ks.Reload()
+}
+
+// JWK JSON Web Key（RFC 7517）
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA 模数
+	E   string `json:"e,omitempty"`   // RSA 指数
+	Crv string `json:"crv,omitempty"` // OKP/EC 曲线
+	X   string `json:"x,omitempty"`   // OKP 公钥或 EC 坐标
+	Y   string `json:"y,omitempty"`   // EC 坐标
+}
+
+// JWKS JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 导出全部验证公钥，HS256 模式下为空集合
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	if ks == nil {
+		return jwks
+	}
+
+	ks.mu.RLock()
+	keys := make([]*SigningKey, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		keys = append(keys, key)
+	}
+	ks.mu.RUnlock()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+
+	enc := base64.RawURLEncoding
+	for _, key := range keys {
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Algorithm}
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = enc.EncodeToString(pub.N.Bytes())
+			jwk.E = enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = enc.EncodeToString(pub)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+// loadKeyFile 读取 PEM 格式的私钥（PKCS#8 / PKCS#1）或公钥（PKIX）
+func loadKeyFile(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type: %s", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &SigningKey{Algorithm: AlgorithmRS256, Method: jwt.SigningMethodRS256, Private: k, Public: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &SigningKey{Algorithm: AlgorithmRS256, Method: jwt.SigningMethodRS256, Public: k}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{Algorithm: AlgorithmEdDSA, Method: jwt.SigningMethodEdDSA, Private: k, Public: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &SigningKey{Algorithm: AlgorithmEdDSA, Method: jwt.SigningMethodEdDSA, Public: k}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", parsed)
+}
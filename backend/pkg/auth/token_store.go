@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"normaladmin/backend/pkg/cache"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	refreshTokenPrefix = "auth:refresh:"        // 已签发的刷新令牌 jti -> family
+	refreshUsedPrefix  = "auth:refresh:used:"   // 已被轮换过的刷新令牌
+	familyRevokedKey   = "auth:family:revoked:" // 已吊销的令牌家族
+	denylistPrefix     = "auth:denylist:"       // 已注销的单个令牌
+	userRevokedPrefix  = "auth:user:revoked:"   // 用户令牌统一吊销时间点（毫秒）
+)
+
+var (
+	// ErrRefreshTokenInvalid 刷新令牌未登记或已过期
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid")
+	// ErrRefreshTokenReused 已轮换的刷新令牌被再次使用
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	// ErrTokenFamilyRevoked 令牌家族已被吊销
+	ErrTokenFamilyRevoked = errors.New("token family has been revoked")
+)
+
+// NewTokenID 生成令牌ID（jti）或令牌家族ID
+func NewTokenID() string {
+	return uuid.NewString()
+}
+
+// SaveRefreshToken 登记新签发的刷新令牌
+func SaveRefreshToken(tokenID, familyID string, ttl time.Duration) error {
+	return cache.Set(refreshTokenPrefix+tokenID, familyID, ttl)
+}
+
+// RotateRefreshToken 消费一个刷新令牌，每个刷新令牌只能使用一次
+// 已轮换的令牌被再次提交时，整个令牌家族会被吊销
+func RotateRefreshToken(tokenID, familyID string, ttl time.Duration) error {
+	if IsFamilyRevoked(familyID) {
+		return ErrTokenFamilyRevoked
+	}
+
+	storedFamily, err := cache.Get(refreshTokenPrefix + tokenID)
+	if err == redis.Nil {
+		return ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return err
+	}
+	if storedFamily != familyID {
+		return ErrRefreshTokenInvalid
+	}
+
+	// 利用 SetNX 保证同一个刷新令牌只能被轮换一次
+	first, err := cache.SetNX(refreshUsedPrefix+tokenID, 1, ttl)
+	if err != nil {
+		return err
+	}
+	if !first {
+		if err := RevokeFamily(familyID, ttl); err != nil {
+			return err
+		}
+		return ErrRefreshTokenReused
+	}
+	return nil
+}
+
+// RevokeFamily 吊销整个令牌家族，ttl 应不短于刷新令牌的有效期
+func RevokeFamily(familyID string, ttl time.Duration) error {
+	return cache.Set(familyRevokedKey+familyID, "1", ttl)
+}
+
+// IsFamilyRevoked 检查令牌家族是否已被吊销
+func IsFamilyRevoked(familyID string) bool {
+	return cache.Exists(familyRevokedKey + familyID)
+}
+
+// DenyToken 将令牌加入黑名单，ttl 为令牌剩余有效期
+func DenyToken(tokenID string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	return cache.Set(denylistPrefix+tokenID, "1", ttl)
+}
+
+// RevokeUserTokens 吊销用户在此刻之前签发的全部令牌，吊销时间精确到毫秒
+func RevokeUserTokens(userType string, userID uint, ttl time.Duration) error {
+	return cache.Set(userRevokedKey(userType, userID), strconv.FormatInt(time.Now().UnixMilli(), 10), ttl)
+}
+
+// IsTokenRevoked 检查令牌是否已被注销：单个令牌黑名单、令牌家族吊销或用户全部令牌吊销
+func IsTokenRevoked(tokenID, familyID, userType string, userID uint, issuedAt time.Time) bool {
+	if tokenID != "" && cache.Exists(denylistPrefix+tokenID) {
+		return true
+	}
+	if familyID != "" && IsFamilyRevoked(familyID) {
+		return true
+	}
+
+	revokedAt, err := cache.Get(userRevokedKey(userType, userID))
+	if err != nil {
+		return false
+	}
+	ts, err := strconv.ParseInt(revokedAt, 10, 64)
+	if err != nil {
+		return false
+	}
+	// 吊销之后（包括同一秒内）重新登录签发的令牌不受影响
+	return issuedAt.UnixMilli() < ts
+}
+
+func userRevokedKey(userType string, userID uint) string {
+	return fmt.Sprintf("%s%s:%d", userRevokedPrefix, userType, userID)
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"errors"
+	"normaladmin/backend/config"
+	"normaladmin/backend/pkg/cache"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+const testTokenTTL = time.Hour
+
+// newTestTokenStore 使用内存 Redis 初始化缓存，令牌存储的数据都保存在其中
+func newTestTokenStore(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	port, err := strconv.Atoi(mr.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.InitRedis(config.RedisConfig{Host: mr.Host(), Port: port, DefaultTTL: 3600}); err != nil {
+		t.Fatal(err)
+	}
+	return mr
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(t *testing.T, tokenID, familyID string)
+		familyID   string // 提交的令牌家族，为空时使用登记的家族
+		wantErr    error
+		wantFamily bool // 轮换后令牌家族是否被吊销
+	}{
+		{
+			name:  "first use",
+			setup: func(*testing.T, string, string) {},
+		},
+		{
+			name:    "unknown token",
+			setup:   func(t *testing.T, tokenID, _ string) { deleteRefreshToken(t, tokenID) },
+			wantErr: ErrRefreshTokenInvalid,
+		},
+		{
+			name:     "family mismatch",
+			setup:    func(*testing.T, string, string) {},
+			familyID: "other-family",
+			wantErr:  ErrRefreshTokenInvalid,
+		},
+		{
+			name: "reuse revokes family",
+			setup: func(t *testing.T, tokenID, familyID string) {
+				if err := RotateRefreshToken(tokenID, familyID, testTokenTTL); err != nil {
+					t.Fatalf("first rotation: %v", err)
+				}
+			},
+			wantErr:    ErrRefreshTokenReused,
+			wantFamily: true,
+		},
+		{
+			name: "revoked family",
+			setup: func(t *testing.T, _, familyID string) {
+				if err := RevokeFamily(familyID, testTokenTTL); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr:    ErrTokenFamilyRevoked,
+			wantFamily: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestTokenStore(t)
+			tokenID, familyID := NewTokenID(), NewTokenID()
+			if err := SaveRefreshToken(tokenID, familyID, testTokenTTL); err != nil {
+				t.Fatal(err)
+			}
+			tt.setup(t, tokenID, familyID)
+
+			submitted := familyID
+			if tt.familyID != "" {
+				submitted = tt.familyID
+			}
+			if err := RotateRefreshToken(tokenID, submitted, testTokenTTL); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RotateRefreshToken() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := IsFamilyRevoked(familyID); got != tt.wantFamily {
+				t.Fatalf("IsFamilyRevoked() = %v, want %v", got, tt.wantFamily)
+			}
+		})
+	}
+}
+
+func deleteRefreshToken(t *testing.T, tokenID string) {
+	t.Helper()
+	if err := cache.Delete(refreshTokenPrefix + tokenID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDenyToken(t *testing.T) {
+	mr := newTestTokenStore(t)
+	issuedAt := time.Now()
+
+	if err := DenyToken("expired", 0); err != nil {
+		t.Fatal(err)
+	}
+	if IsTokenRevoked("expired", "", "admin", 1, issuedAt) {
+		t.Fatal("token with no remaining lifetime should not be denylisted")
+	}
+
+	if err := DenyToken("logout", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if !IsTokenRevoked("logout", "", "admin", 1, issuedAt) {
+		t.Fatal("denylisted token is not revoked")
+	}
+	if IsTokenRevoked("other", "", "admin", 1, issuedAt) {
+		t.Fatal("other token is revoked")
+	}
+
+	// 黑名单随令牌剩余有效期过期
+	mr.FastForward(time.Minute)
+	if IsTokenRevoked("logout", "", "admin", 1, issuedAt) {
+		t.Fatal("denylist entry outlived the token")
+	}
+}
+
+func TestIsTokenRevokedByFamily(t *testing.T) {
+	newTestTokenStore(t)
+	if err := RevokeFamily("family", testTokenTTL); err != nil {
+		t.Fatal(err)
+	}
+	if !IsTokenRevoked("token", "family", "admin", 1, time.Now()) {
+		t.Fatal("token of a revoked family is not revoked")
+	}
+	if IsTokenRevoked("token", "other-family", "admin", 1, time.Now()) {
+		t.Fatal("token of another family is revoked")
+	}
+}
+
+func TestIsTokenRevokedByUser(t *testing.T) {
+	revokedAt := time.Date(2024, 5, 1, 12, 0, 0, 500*int(time.Millisecond), time.UTC)
+	tests := []struct {
+		name     string
+		userType string
+		userID   uint
+		issuedAt time.Time
+		want     bool
+	}{
+		{name: "issued a second earlier", userType: "admin", userID: 1, issuedAt: revokedAt.Add(-time.Second), want: true},
+		{name: "issued a millisecond earlier", userType: "admin", userID: 1, issuedAt: revokedAt.Add(-time.Millisecond), want: true},
+		{name: "issued in the same millisecond", userType: "admin", userID: 1, issuedAt: revokedAt.Add(500 * time.Microsecond), want: false},
+		{name: "issued later in the same second", userType: "admin", userID: 1, issuedAt: revokedAt.Add(100 * time.Millisecond), want: false},
+		{name: "other user", userType: "admin", userID: 2, issuedAt: revokedAt.Add(-time.Second), want: false},
+		{name: "other user type", userType: "member", userID: 1, issuedAt: revokedAt.Add(-time.Second), want: false},
+	}
+
+	newTestTokenStore(t)
+	if err := cache.Set(userRevokedKey("admin", 1), strconv.FormatInt(revokedAt.UnixMilli(), 10), testTokenTTL); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTokenRevoked("", "", tt.userType, tt.userID, tt.issuedAt); got != tt.want {
+				t.Fatalf("IsTokenRevoked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevokeUserTokens(t *testing.T) {
+	newTestTokenStore(t)
+	before := time.Now().Add(-time.Millisecond)
+	if err := RevokeUserTokens("member", 7, testTokenTTL); err != nil {
+		t.Fatal(err)
+	}
+	if !IsTokenRevoked("", "", "member", 7, before) {
+		t.Fatal("token issued before revocation is not revoked")
+	}
+	// 吊销后重新登录签发的令牌不受影响
+	if IsTokenRevoked("", "", "member", 7, time.Now().Add(time.Millisecond)) {
+		t.Fatal("token issued after revocation is revoked")
+	}
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"normaladmin/backend/pkg/cache"
+	"normaladmin/backend/pkg/logger"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/google/uuid"
+)
+
+// policyUpdate 通过 Redis 广播的策略变更通知
+type policyUpdate struct {
+	Instance string `json:"instance"` // 发布变更的实例，用于忽略自己发出的消息
+}
+
+// RedisWatcher 基于 Redis 发布订阅的策略监听器
+// 策略都在业务事务中直接写入策略表（见 ReplaceSubjectPolicies），提交后由 ReloadPolicy 重新加载并广播通知，
+// 其他实例收到通知后从数据库全量重新加载；不广播增量变更，权限编辑频率低，全量加载的开销可以接受
+type RedisWatcher struct {
+	instance    string
+	channel     string
+	unsubscribe func() error
+	stop        chan struct{}
+	closeOnce   sync.Once
+
+	mu       sync.RWMutex
+	callback func(string)
+}
+
+var _ persist.Watcher = (*RedisWatcher)(nil)
+
+var policyWatcher *RedisWatcher
+
+// StartPolicyWatcher 订阅策略变更频道并注册到 enforcer，reloadInterval 大于 0 时定期全量重新加载策略，作为丢失消息时的兜底
+func StartPolicyWatcher(channel string, reloadInterval time.Duration) (*RedisWatcher, error) {
+	if enforcer == nil {
+		return nil, errors.New("casbin enforcer is not initialized")
+	}
+
+	w := &RedisWatcher{instance: uuid.NewString(), channel: channel, stop: make(chan struct{})}
+	w.callback = w.reload
+	unsubscribe, err := cache.Subscribe(channel, w.receive)
+	if err != nil {
+		return nil, err
+	}
+	w.unsubscribe = unsubscribe
+	if err := enforcer.SetWatcher(w); err != nil {
+		unsubscribe()
+		return nil, err
+	}
+
+	if reloadInterval > 0 {
+		go w.reloadLoop(reloadInterval)
+	}
+	policyWatcher = w
+	return w, nil
+}
+
+// SetUpdateCallback 设置收到其他实例变更时的处理函数，默认全量重新加载策略
+func (w *RedisWatcher) SetUpdateCallback(callback func(string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callback = callback
+	return nil
+}
+
+// Update 通知其他实例重新加载全部策略
+// 策略已写入数据库，广播失败只记录日志，由定期重新加载兜底，不让调用方的操作失败
+func (w *RedisWatcher) Update() error {
+	data, err := json.Marshal(policyUpdate{Instance: w.instance})
+	if err != nil {
+		return err
+	}
+	if err := cache.Publish(w.channel, string(data)); err != nil {
+		logger.Error("广播权限策略变更失败", logger.Field("error", err))
+	}
+	return nil
+}
+
+// Close 取消订阅并停止定期重新加载
+func (w *RedisWatcher) Close() {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+		if w.unsubscribe != nil {
+			w.unsubscribe()
+		}
+	})
+}
+
+// receive 处理订阅到的消息，忽略本实例发出的消息
+func (w *RedisWatcher) receive(message string) {
+	var update policyUpdate
+	if err := json.Unmarshal([]byte(message), &update); err == nil && update.Instance == w.instance {
+		return
+	}
+	w.mu.RLock()
+	callback := w.callback
+	w.mu.RUnlock()
+	if callback != nil {
+		callback(message)
+	}
+}
+
+// reload 默认的变更处理：从数据库全量重新加载策略
+func (w *RedisWatcher) reload(string) {
+	if err := enforcer.LoadPolicy(); err != nil {
+		logger.Error("重新加载权限策略失败", logger.Field("error", err))
+	}
+}
+
+func (w *RedisWatcher) reloadLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := enforcer.LoadPolicy(); err != nil {
+				logger.Error("定期重新加载权限策略失败", logger.Field("error", err))
+			}
+		case <-w.stop:
+			return
+		}
+	}
+}
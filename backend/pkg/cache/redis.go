@@ -41,6 +41,20 @@ func GetObject(key string, val interface{}) error {
 	return json.Unmarshal([]byte(data), val)
 }
 
+// GetDel 获取并删除缓存，用于一次性凭据
+func GetDel(key string) (string, error) {
+	return client.GetDel(context.Background(), key).Result()
+}
+
+// GetDelObject 获取并删除JSON对象，用于一次性凭据
+func GetDelObject(key string, val interface{}) error {
+	data, err := GetDel(key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(data), val)
+}
+
 // Set 设置缓存
 func Set(key string, value string, expiration ...time.Duration) error {
 	exp := time.Duration(cfg.DefaultTTL) * time.Second
@@ -147,3 +161,41 @@ func DeletePattern(pattern string) error {
 	}
 	return iter.Err()
 }
+
+// Keys 扫描匹配模式的所有键
+func Keys(pattern string) ([]string, error) {
+	ctx := context.Background()
+	var keys []string
+	iter := client.Scan(ctx, 0, pattern, 0).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// TTL 获取键的剩余过期时间
+func TTL(key string) (time.Duration, error) {
+	return client.TTL(context.Background(), key).Result()
+}
+
+// Publish 向频道发布消息
+func Publish(channel string, message string) error {
+	return client.Publish(context.Background(), channel, message).Err()
+}
+
+// Subscribe 订阅频道，消息按到达顺序依次交给 handler 处理，连接断开后自动重新订阅；返回的函数用于取消订阅
+func Subscribe(channel string, handler func(message string)) (func() error, error) {
+	ctx := context.Background()
+	pubsub := client.Subscribe(ctx, channel)
+	// 等待订阅确认，确保返回之后发布的消息不会丢失
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	go func() {
+		for msg := range pubsub.Channel() {
+			handler(msg.Payload)
+		}
+	}()
+	return pubsub.Close, nil
+}
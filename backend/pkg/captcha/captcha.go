@@ -0,0 +1,215 @@
+// Package captcha 纯 Go 实现的图形验证码，支持字符和算术两种题型
+package captcha
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"math/big"
+	mrand "math/rand/v2"
+	"strconv"
+)
+
+// 验证码题型
+const (
+	TypeText = "text" // 字符
+	TypeMath = "math" // 算术
+)
+
+// textAlphabet 字符验证码的字符集，去掉了 0/O、1/I/L 等容易混淆的字符
+const textAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// Challenge 验证码题目，Question 绘制到图片上，Answer 用于校验
+type Challenge struct {
+	Question string
+	Answer   string
+}
+
+// NewText 生成指定长度的字符验证码
+func NewText(length int) Challenge {
+	if length <= 0 {
+		length = 4
+	}
+	buf := make([]byte, length)
+	for i := range buf {
+		buf[i] = textAlphabet[randInt(len(textAlphabet))]
+	}
+	return Challenge{Question: string(buf), Answer: string(buf)}
+}
+
+// NewMath 生成算术验证码，结果为非负整数
+func NewMath() Challenge {
+	a, b := randInt(20)+1, randInt(10)+1
+	switch randInt(3) {
+	case 0:
+		return Challenge{Question: fmt.Sprintf("%d+%d=?", a, b), Answer: strconv.Itoa(a + b)}
+	case 1:
+		if a < b {
+			a, b = b, a
+		}
+		return Challenge{Question: fmt.Sprintf("%d-%d=?", a, b), Answer: strconv.Itoa(a - b)}
+	default:
+		a = randInt(9) + 1
+		return Challenge{Question: fmt.Sprintf("%dx%d=?", a, b), Answer: strconv.Itoa(a * b)}
+	}
+}
+
+// New 按题型生成验证码，未知题型按字符验证码处理
+func New(kind string, length int) Challenge {
+	if kind == TypeMath {
+		return NewMath()
+	}
+	return NewText(length)
+}
+
+// Render 将题目绘制为带干扰的 PNG 图片
+// 每个字符随机偏移、倾斜和着色，整体做正弦扭曲，再叠加干扰线和噪点
+func Render(text string, width, height int) ([]byte, error) {
+	runes := []rune(text)
+	if len(runes) == 0 {
+		return nil, fmt.Errorf("captcha text is empty")
+	}
+
+	bg := color.NRGBA{R: uint8(230 + mrand.IntN(26)), G: uint8(230 + mrand.IntN(26)), B: uint8(230 + mrand.IntN(26)), A: 255}
+	canvas := image.NewNRGBA(image.Rect(0, 0, width, height))
+	fill(canvas, bg)
+
+	// 按图片大小计算字符放大倍数
+	scale := min((width-8)/(len(runes)*(glyphWidth+1)), (height-6)/glyphHeight)
+	if scale < 1 {
+		scale = 1
+	}
+	advance := (width - 8) / len(runes)
+	top := (height - glyphHeight*scale) / 2
+
+	for i, r := range runes {
+		glyph, ok := glyphs[r]
+		if !ok {
+			continue
+		}
+		ink := randomInk()
+		x0 := 4 + i*advance + (advance-glyphWidth*scale)/2 + mrand.IntN(5) - 2
+		y0 := top + mrand.IntN(7) - 3
+		shear := (mrand.Float64() - 0.5) * 0.6
+		for row := 0; row < glyphHeight; row++ {
+			for col := 0; col < glyphWidth; col++ {
+				if glyph[row]&(1<<(glyphWidth-1-col)) == 0 {
+					continue
+				}
+				// 倾斜：越靠下的行水平偏移越大
+				dx := int(shear * float64((row-glyphHeight/2)*scale))
+				fillRect(canvas, x0+col*scale+dx, y0+row*scale, scale, scale, ink)
+			}
+		}
+	}
+
+	img := warp(canvas, bg)
+
+	for i := 0; i < 4; i++ {
+		drawLine(img, mrand.IntN(width), mrand.IntN(height), mrand.IntN(width), mrand.IntN(height), randomInk())
+	}
+	for i := 0; i < width*height/25; i++ {
+		img.Set(mrand.IntN(width), mrand.IntN(height), randomInk())
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DataURI 将 PNG 图片编码为 data URI，前端可直接用于 img 标签
+func DataURI(pngData []byte) string {
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngData)
+}
+
+// warp 沿水平方向做正弦扭曲
+func warp(src *image.NRGBA, bg color.NRGBA) *image.NRGBA {
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(bounds)
+	fill(dst, bg)
+
+	amplitude := 2 + mrand.Float64()*2
+	period := 30 + mrand.Float64()*30
+	phase := mrand.Float64() * 2 * math.Pi
+	for x := bounds.Min.X; x < bounds.Max.X; x++ {
+		dy := int(amplitude * math.Sin(2*math.Pi*float64(x)/period+phase))
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			sy := y + dy
+			if sy < bounds.Min.Y || sy >= bounds.Max.Y {
+				continue
+			}
+			dst.SetNRGBA(x, y, src.NRGBAAt(x, sy))
+		}
+	}
+	return dst
+}
+
+func fill(img *image.NRGBA, c color.NRGBA) {
+	bounds := img.Bounds()
+	fillRect(img, bounds.Min.X, bounds.Min.Y, bounds.Dx(), bounds.Dy(), c)
+}
+
+func fillRect(img *image.NRGBA, x, y, w, h int, c color.NRGBA) {
+	rect := image.Rect(x, y, x+w, y+h).Intersect(img.Bounds())
+	for py := rect.Min.Y; py < rect.Max.Y; py++ {
+		for px := rect.Min.X; px < rect.Max.X; px++ {
+			img.SetNRGBA(px, py, c)
+		}
+	}
+}
+
+// drawLine Bresenham 画线
+func drawLine(img *image.NRGBA, x0, y0, x1, y1 int, c color.NRGBA) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+	for {
+		img.SetNRGBA(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+// randomInk 随机深色，保证与浅色背景有足够对比度
+func randomInk() color.NRGBA {
+	return color.NRGBA{R: uint8(mrand.IntN(140)), G: uint8(mrand.IntN(140)), B: uint8(mrand.IntN(140)), A: 255}
+}
+
+// randInt 使用安全随机数生成 [0, n) 的整数，用于生成答案
+func randInt(n int) int {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		panic(err)
+	}
+	return int(v.Int64())
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,47 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"normaladmin/backend/pkg/logger"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileSender 将邮件写入目录下的 .eml 文件，用于开发和测试
+type FileSender struct {
+	Dir  string
+	From mail.Address
+}
+
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	data, err := Build(s.From, msg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405"), randomID())
+	return os.WriteFile(filepath.Join(s.Dir, name), data, 0o600)
+}
+
+// LogSender 只把邮件内容写入日志，不实际发送，用于开发环境
+type LogSender struct {
+	From mail.Address
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	if _, err := Build(s.From, msg); err != nil {
+		return err
+	}
+	logger.Info("邮件未实际发送(log)",
+		logger.Field("to", strings.Join(msg.To, ", ")),
+		logger.Field("subject", msg.Subject),
+		logger.Field("text", msg.Text),
+	)
+	return nil
+}
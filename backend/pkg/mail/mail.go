@@ -0,0 +1,140 @@
+// Package mail 邮件发送，支持 SMTP，以及开发测试用的文件和日志发送方式
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"normaladmin/backend/config"
+	"strings"
+	"time"
+)
+
+// 发送方式
+const (
+	DriverSMTP = "smtp"
+	DriverFile = "file"
+	DriverLog  = "log"
+)
+
+// Message 邮件内容，HTML 为空时只发送纯文本
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender 邮件发送接口
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender 按配置创建邮件发送器，未配置发送方式时使用日志发送
+func NewSender(cfg config.MailConfig) (Sender, error) {
+	from := mail.Address{Name: cfg.FromName, Address: cfg.From}
+	switch cfg.Driver {
+	case DriverSMTP:
+		if cfg.Host == "" || cfg.From == "" {
+			return nil, fmt.Errorf("mail: smtp driver requires host and from")
+		}
+		return &SMTPSender{cfg: cfg, from: from}, nil
+	case DriverFile:
+		dir := cfg.Dir
+		if dir == "" {
+			dir = "./logs/mail"
+		}
+		return &FileSender{Dir: dir, From: from}, nil
+	case DriverLog, "":
+		return &LogSender{From: from}, nil
+	default:
+		return nil, fmt.Errorf("mail: unsupported driver %q", cfg.Driver)
+	}
+}
+
+// Build 生成 RFC 5322 格式的邮件，同时有纯文本和 HTML 时使用 multipart/alternative
+func Build(from mail.Address, msg Message) ([]byte, error) {
+	if len(msg.To) == 0 {
+		return nil, fmt.Errorf("mail: no recipients")
+	}
+	to := make([]string, 0, len(msg.To))
+	for _, addr := range msg.To {
+		parsed, err := mail.ParseAddress(addr)
+		if err != nil {
+			return nil, fmt.Errorf("mail: invalid recipient %q: %w", addr, err)
+		}
+		to = append(to, parsed.String())
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "From", from.String())
+	writeHeader(&buf, "To", strings.Join(to, ", "))
+	writeHeader(&buf, "Subject", mime.BEncoding.Encode("UTF-8", msg.Subject))
+	writeHeader(&buf, "Date", time.Now().Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", fmt.Sprintf("<%s@%s>", randomID(), domainOf(from.Address)))
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	if msg.HTML == "" {
+		writeHeader(&buf, "Content-Type", "text/plain; charset=UTF-8")
+		writeHeader(&buf, "Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, msg.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	boundary := "alt-" + randomID()
+	writeHeader(&buf, "Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", boundary))
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	} {
+		if part.body == "" {
+			continue
+		}
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		writeHeader(&buf, "Content-Type", part.contentType+"; charset=UTF-8")
+		writeHeader(&buf, "Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, part.body); err != nil {
+			return nil, err
+		}
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+	return buf.Bytes(), nil
+}
+
+func writeHeader(buf *bytes.Buffer, name, value string) {
+	// 防止邮件头注入
+	value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+	fmt.Fprintf(buf, "%s: %s\r\n", name, value)
+}
+
+func writeQuotedPrintable(buf *bytes.Buffer, body string) error {
+	w := quotedprintable.NewWriter(buf)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func randomID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func domainOf(address string) string {
+	if i := strings.LastIndex(address, "@"); i >= 0 && i < len(address)-1 {
+		return address[i+1:]
+	}
+	return "localhost"
+}
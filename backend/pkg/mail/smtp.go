@@ -0,0 +1,105 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"normaladmin/backend/config"
+	"strconv"
+	"time"
+)
+
+// 连接加密方式
+const (
+	EncryptionSTARTTLS = "starttls" // 明文连接后升级，通常为 587 端口
+	EncryptionSSL      = "ssl"      // 直接使用 TLS 连接，通常为 465 端口
+	EncryptionNone     = "none"     // 不加密，仅用于内网中继
+)
+
+// smtpTimeout 建立连接的超时时间
+const smtpTimeout = 10 * time.Second
+
+// SMTPSender 通过 SMTP 服务器发送邮件
+type SMTPSender struct {
+	cfg  config.MailConfig
+	from mail.Address
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	data, err := Build(s.from, msg)
+	if err != nil {
+		return err
+	}
+
+	port := s.cfg.Port
+	if port == 0 {
+		port = 587
+		if s.cfg.Encryption == EncryptionSSL {
+			port = 465
+		}
+	}
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: s.cfg.Host, MinVersion: tls.VersionTLS12}
+
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	var conn net.Conn
+	if s.cfg.Encryption == EncryptionSSL {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("mail: connect %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if s.cfg.Encryption != EncryptionSSL && s.cfg.Encryption != EncryptionNone {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("mail: server %s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return fmt.Errorf("mail: auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(s.from.Address); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return err
+		}
+		if err := client.Rcpt(addr.Address); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -3,6 +3,7 @@ package sysconfig
 import (
 	"encoding/json"
 	"fmt"
+	"normaladmin/backend/pkg/tenant"
 	"normaladmin/backend/pkg/utils/encrypt"
 	"strconv"
 	"sync"
@@ -31,10 +32,15 @@ type SysConfig struct {
 	cacheTTL time.Duration
 }
 
+// items 系统配置是平台级的，只读写超级租户的配置项
+func (s *SysConfig) items() *gorm.DB {
+	return s.db.Table("config_items").Where("tenant_id = ?", tenant.SuperTenantID)
+}
+
 // loadConfig 从数据库加载配置
 func (s *SysConfig) loadConfig() error {
 	var items []ConfigItem
-	if err := s.db.Table("config_items").
+	if err := s.items().
 		Select("item_key", "item_value", "value_type", "encrypted").
 		Find(&items).Error; err != nil {
 		return err
@@ -161,7 +167,7 @@ func (s *SysConfig) Set(key string, value interface{}) error {
 
 	// 获取配置项信息
 	var item ConfigItem
-	if err := s.db.Table("config_items").
+	if err := s.items().
 		Select("encrypted").
 		Where("item_key = ?", key).
 		First(&item).Error; err != nil {
@@ -177,7 +183,7 @@ func (s *SysConfig) Set(key string, value interface{}) error {
 	}
 
 	// 更新数据库
-	if err := s.db.Table("config_items").
+	if err := s.items().
 		Where("item_key = ?", key).
 		Update("item_value", valueStr).Error; err != nil {
 		return err
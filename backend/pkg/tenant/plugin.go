@@ -0,0 +1,130 @@
+package tenant
+
+import (
+	"errors"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrTenantMismatch 写入的记录属于其他租户
+var ErrTenantMismatch = errors.New("record belongs to another tenant")
+
+// Plugin 按上下文中的租户隔离数据的 GORM 插件
+// 模型有 TenantID 字段（列名 tenant_id）且会话的上下文携带租户时：查询、更新、删除只作用于该租户的数据，
+// 创建时填充租户；上下文没有租户（系统内部调用、定时任务、迁移）时不做处理。
+// 只作用于主表，Joins 关联的表和 Table/Raw 写出的 SQL 需要通过主表的条件限定范围
+type Plugin struct{}
+
+func (Plugin) Name() string {
+	return "tenant"
+}
+
+func (Plugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	if err := callback.Create().Before("gorm:create").Register("tenant:create", assignTenant); err != nil {
+		return err
+	}
+	if err := callback.Query().Before("gorm:query").Register("tenant:query", filterTenant); err != nil {
+		return err
+	}
+	if err := callback.Row().Before("gorm:row").Register("tenant:row", filterTenant); err != nil {
+		return err
+	}
+	if err := callback.Update().Before("gorm:update").Register("tenant:update", filterTenantWrite); err != nil {
+		return err
+	}
+	return callback.Delete().Before("gorm:delete").Register("tenant:delete", filterTenantWrite)
+}
+
+// tenantField 语句模型的租户字段和上下文中的租户，模型不按租户隔离或上下文没有租户时返回 false
+func tenantField(db *gorm.DB) (*schema.Field, uint, bool) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return nil, 0, false
+	}
+	field := db.Statement.Schema.LookUpField("TenantID")
+	if field == nil || field.DBName != Column {
+		return nil, 0, false
+	}
+	tenantID, ok := FromContext(db.Statement.Context)
+	return field, tenantID, ok
+}
+
+// assignTenant 为未指定租户的新记录填充当前租户，指定了其他租户时拒绝写入
+func assignTenant(db *gorm.DB) {
+	field, tenantID, ok := tenantField(db)
+	if !ok {
+		return
+	}
+	assign := func(value reflect.Value) {
+		value = reflect.Indirect(value)
+		if value.Kind() != reflect.Struct {
+			return
+		}
+		current, zero := field.ValueOf(db.Statement.Context, value)
+		if zero {
+			if err := field.Set(db.Statement.Context, value, tenantID); err != nil {
+				db.AddError(err)
+			}
+			return
+		}
+		if id, ok := current.(uint); ok && id != tenantID {
+			db.AddError(ErrTenantMismatch)
+		}
+	}
+
+	switch value := db.Statement.ReflectValue; value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			assign(value.Index(i))
+		}
+	case reflect.Struct:
+		assign(value)
+	}
+}
+
+// filterTenant 为查询添加当前租户的条件
+func filterTenant(db *gorm.DB) {
+	field, tenantID, ok := tenantField(db)
+	if !ok {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenantID},
+	}})
+}
+
+// filterTenantWrite 为更新、删除添加当前租户的条件
+// 既没有条件也没有主键的语句交给 GORM 按 ErrMissingWhereClause 拒绝，不因租户条件变成整个租户的批量操作
+func filterTenantWrite(db *gorm.DB) {
+	if _, ok := db.Statement.Clauses["WHERE"]; !ok && !db.AllowGlobalUpdate && !hasPrimaryKey(db.Statement) {
+		return
+	}
+	filterTenant(db)
+}
+
+// hasPrimaryKey 语句的模型或目标是否带有主键值，GORM 会按主键生成条件
+func hasPrimaryKey(stmt *gorm.Statement) bool {
+	if stmt.Schema == nil || stmt.Schema.PrioritizedPrimaryField == nil {
+		return false
+	}
+	for _, target := range []interface{}{stmt.Model, stmt.Dest} {
+		value := reflect.Indirect(reflect.ValueOf(target))
+		switch value.Kind() {
+		case reflect.Struct:
+			if value.Type() != stmt.Schema.ModelType {
+				continue
+			}
+			if _, zero := stmt.Schema.PrioritizedPrimaryField.ValueOf(stmt.Context, value); !zero {
+				return true
+			}
+		case reflect.Slice, reflect.Array:
+			if value.Len() > 0 && reflect.Indirect(value.Index(0)).Type() == stmt.Schema.ModelType {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// tenantRecord 按租户隔离的模型
+type tenantRecord struct {
+	ID       uint
+	TenantID uint
+	Name     string
+}
+
+// sharedRecord 没有租户字段，全部租户共用的模型
+type sharedRecord struct {
+	ID   uint
+	Name string
+}
+
+const (
+	testTenantA uint = 2
+	testTenantB uint = 3
+)
+
+func newPluginTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.Use(Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&tenantRecord{}, &sharedRecord{}); err != nil {
+		t.Fatal(err)
+	}
+	// 没有租户的上下文不做处理，按指定的租户写入初始数据
+	records := []tenantRecord{
+		{ID: 1, TenantID: testTenantA, Name: "a1"},
+		{ID: 2, TenantID: testTenantA, Name: "a2"},
+		{ID: 3, TenantID: testTenantB, Name: "b1"},
+	}
+	if err := db.Create(&records).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&[]sharedRecord{{ID: 1, Name: "s1"}, {ID: 2, Name: "s2"}}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func tenantDB(db *gorm.DB, tenantID uint) *gorm.DB {
+	return db.WithContext(WithTenant(context.Background(), tenantID))
+}
+
+func recordIDs(t *testing.T, db *gorm.DB) []uint {
+	t.Helper()
+	var ids []uint
+	if err := db.Model(&tenantRecord{}).Order("id").Pluck("id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestPluginQuery(t *testing.T) {
+	db := newPluginTestDB(t)
+	tests := []struct {
+		name string
+		db   *gorm.DB
+		want []uint
+	}{
+		{name: "no tenant", db: db, want: []uint{1, 2, 3}},
+		{name: "tenant a", db: tenantDB(db, testTenantA), want: []uint{1, 2}},
+		{name: "tenant b", db: tenantDB(db, testTenantB), want: []uint{3}},
+		{name: "without tenant", db: db.WithContext(WithoutTenant(context.Background())), want: []uint{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recordIDs(t, tt.db); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 按主键查询其他租户的记录视为不存在
+	var record tenantRecord
+	if err := tenantDB(db, testTenantA).First(&record, 3).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("First() error = %v, want ErrRecordNotFound", err)
+	}
+	var count int64
+	if err := tenantDB(db, testTenantB).Model(&tenantRecord{}).Count(&count).Error; err != nil || count != 1 {
+		t.Fatalf("Count() = %d, %v, want 1", count, err)
+	}
+
+	// 没有租户字段的模型不受影响
+	var shared []sharedRecord
+	if err := tenantDB(db, testTenantA).Find(&shared).Error; err != nil || len(shared) != 2 {
+		t.Fatalf("shared records = %d, %v, want 2", len(shared), err)
+	}
+}
+
+func TestPluginUpdate(t *testing.T) {
+	db := newPluginTestDB(t)
+	a := tenantDB(db, testTenantA)
+
+	// 其他租户的记录不会被更新
+	result := a.Model(&tenantRecord{ID: 3}).Update("name", "changed")
+	if result.Error != nil || result.RowsAffected != 0 {
+		t.Fatalf("Update() affected %d, %v, want 0", result.RowsAffected, result.Error)
+	}
+	result = a.Model(&tenantRecord{}).Where("name <> ?", "").Update("name", "renamed")
+	if result.Error != nil || result.RowsAffected != 2 {
+		t.Fatalf("Update() affected %d, %v, want 2", result.RowsAffected, result.Error)
+	}
+
+	var record tenantRecord
+	if err := db.First(&record, 3).Error; err != nil {
+		t.Fatal(err)
+	}
+	if record.Name != "b1" {
+		t.Fatalf("record of tenant b renamed to %q", record.Name)
+	}
+
+	// 既没有条件也没有主键的更新仍按 ErrMissingWhereClause 拒绝
+	if err := a.Model(&tenantRecord{}).Update("name", "all").Error; !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Fatalf("Update() error = %v, want ErrMissingWhereClause", err)
+	}
+}
+
+func TestPluginDelete(t *testing.T) {
+	db := newPluginTestDB(t)
+	b := tenantDB(db, testTenantB)
+
+	result := b.Delete(&tenantRecord{}, []uint{1, 2, 3})
+	if result.Error != nil || result.RowsAffected != 1 {
+		t.Fatalf("Delete() affected %d, %v, want 1", result.RowsAffected, result.Error)
+	}
+	if got := recordIDs(t, db); !reflect.DeepEqual(got, []uint{1, 2}) {
+		t.Fatalf("ids = %v, want [1 2]", got)
+	}
+	if err := b.Delete(&tenantRecord{}).Error; !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Fatalf("Delete() error = %v, want ErrMissingWhereClause", err)
+	}
+}
+
+func TestPluginCreate(t *testing.T) {
+	db := newPluginTestDB(t)
+	a := tenantDB(db, testTenantA)
+
+	record := tenantRecord{Name: "a3"}
+	if err := a.Create(&record).Error; err != nil {
+		t.Fatal(err)
+	}
+	if record.TenantID != testTenantA {
+		t.Fatalf("TenantID = %d, want %d", record.TenantID, testTenantA)
+	}
+
+	batch := []tenantRecord{{Name: "a4"}, {Name: "a5", TenantID: testTenantA}}
+	if err := a.Create(&batch).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range batch {
+		if r.TenantID != testTenantA {
+			t.Fatalf("TenantID of %s = %d, want %d", r.Name, r.TenantID, testTenantA)
+		}
+	}
+
+	// 指定其他租户的记录拒绝写入
+	if err := a.Create(&tenantRecord{Name: "b2", TenantID: testTenantB}).Error; !errors.Is(err, ErrTenantMismatch) {
+		t.Fatalf("Create() error = %v, want ErrTenantMismatch", err)
+	}
+	mixed := []tenantRecord{{Name: "a6"}, {Name: "b3", TenantID: testTenantB}}
+	if err := a.Create(&mixed).Error; !errors.Is(err, ErrTenantMismatch) {
+		t.Fatalf("Create() error = %v, want ErrTenantMismatch", err)
+	}
+	if got := recordIDs(t, tenantDB(db, testTenantB)); !reflect.DeepEqual(got, []uint{3}) {
+		t.Fatalf("tenant b ids = %v, want [3]", got)
+	}
+}
@@ -0,0 +1,36 @@
+package tenant
+
+import "context"
+
+// SuperTenantID 超级租户（平台）ID，开启多租户前的数据都属于超级租户
+const SuperTenantID uint = 1
+
+// Column 租户隔离的字段，模型通过 TenantID 字段声明按租户隔离
+const Column = "tenant_id"
+
+type contextKey struct{}
+
+// WithTenant 返回携带租户的上下文，使用该上下文的查询只能访问该租户的数据
+func WithTenant(ctx context.Context, tenantID uint) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenantID)
+}
+
+// FromContext 获取上下文中的租户，没有租户（系统内部调用）时返回 false
+func FromContext(ctx context.Context) (uint, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	tenantID, ok := ctx.Value(contextKey{}).(uint)
+	return tenantID, ok && tenantID != 0
+}
+
+// IsSuper 上下文中的租户是否为超级租户，没有租户时视为超级租户
+func IsSuper(ctx context.Context) bool {
+	tenantID, ok := FromContext(ctx)
+	return !ok || tenantID == SuperTenantID
+}
+
+// WithoutTenant 返回不限定租户的上下文，用于全部租户共用的数据（如菜单）需要统计各租户引用的场景
+func WithoutTenant(ctx context.Context) context.Context {
+	return WithTenant(ctx, 0)
+}
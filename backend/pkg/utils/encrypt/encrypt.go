@@ -9,10 +9,7 @@ import (
 	"sync"
 )
 
-var (
-	encryptKey []byte
-	key        []byte // AES密钥
-)
+var encryptKey []byte // AES密钥
 var once sync.Once
 
 func InitEncryptKey(key string) {
@@ -30,7 +27,7 @@ func Encrypt(plaintext string) string {
 	}
 
 	// 创建cipher
-	block, err := aes.NewCipher(key)
+	block, err := aes.NewCipher(encryptKey)
 	if err != nil {
 		return plaintext // 加密失败时返回原文
 	}
@@ -67,7 +64,7 @@ func Decrypt(ciphertext string) string {
 	}
 
 	// 创建cipher
-	block, err := aes.NewCipher(key)
+	block, err := aes.NewCipher(encryptKey)
 	if err != nil {
 		return ciphertext
 	}
@@ -0,0 +1,56 @@
+package utils
+
+import "strings"
+
+// 敏感字段的掩码方式
+const (
+	MaskMobile = "mobile" // 保留前三位和后四位，如 138****8000
+	MaskEmail  = "email"  // 保留用户名首字符和域名，如 t***@example.com
+	MaskIP     = "ip"     // 保留前两段，如 192.168.*.*
+	MaskHidden = "hidden" // 完全隐藏
+)
+
+// Mask 按掩码方式遮盖字符串，空值保持不变
+func Mask(kind, value string) string {
+	if value == "" {
+		return value
+	}
+	switch kind {
+	case MaskMobile:
+		return MaskMiddle(value, 3, 4)
+	case MaskEmail:
+		at := strings.LastIndex(value, "@")
+		if at <= 0 {
+			return MaskMiddle(value, 1, 0)
+		}
+		return MaskMiddle(value[:at], 1, 0) + value[at:]
+	case MaskIP:
+		if parts := strings.Split(value, "."); len(parts) == 4 {
+			return parts[0] + "." + parts[1] + ".*.*"
+		}
+		// IPv6 只保留前两组
+		if parts := strings.Split(value, ":"); len(parts) > 2 {
+			return parts[0] + ":" + parts[1] + ":*"
+		}
+		return "***"
+	default:
+		return "***"
+	}
+}
+
+// MaskMiddle 保留开头 head 个和末尾 tail 个字符，中间替换为星号；长度不足时只保留开头一个字符
+func MaskMiddle(value string, head, tail int) string {
+	runes := []rune(value)
+	if len(runes) <= head+tail {
+		if len(runes) <= 1 {
+			return "*"
+		}
+		return string(runes[:1]) + strings.Repeat("*", len(runes)-1)
+	}
+	return string(runes[:head]) + strings.Repeat("*", len(runes)-head-tail) + string(runes[len(runes)-tail:])
+}
+
+// IsMasked 判断 masked 是否为 value 按 kind 遮盖后的结果
+func IsMasked(kind, value, masked string) bool {
+	return value != "" && Mask(kind, value) == masked
+}
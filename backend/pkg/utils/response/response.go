@@ -38,3 +38,9 @@ func Success(c *gin.Context, data interface{}) {
 func Error(c *gin.Context, status int, errMsg string) {
 	response(c, status, nil, errMsg)
 }
+
+// ErrorWithData 携带结构化详情的错误响应，如字段校验失败的明细
+func ErrorWithData(c *gin.Context, status int, errMsg string, data interface{}) {
+	logger.Error(errMsg)
+	c.JSON(status, ResponseData{Error: errMsg, Data: data})
+}
@@ -0,0 +1,147 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 密码字符类别
+const (
+	PasswordClassUpper  = "upper"
+	PasswordClassLower  = "lower"
+	PasswordClassDigit  = "digit"
+	PasswordClassSymbol = "symbol"
+)
+
+// 密码校验失败代码
+const (
+	PasswordTooShort        = "too_short"
+	PasswordTooLong         = "too_long"
+	PasswordMissingClass    = "missing_class"
+	PasswordBanned          = "banned"
+	PasswordContainsAccount = "contains_username"
+	PasswordReused          = "reused"
+)
+
+// passwordMaxLength bcrypt 只使用前72字节
+const passwordMaxLength = 72
+
+// PasswordViolation 密码不满足的单条规则
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"param,omitempty"`
+}
+
+// PasswordValidator 密码强度验证器
+type PasswordValidator struct {
+	MinLength       int      // 最小长度
+	RequiredClasses []string // 必须包含的字符类别：upper/lower/digit/symbol
+	Banned          []string // 禁用密码（不区分大小写）
+}
+
+// NewPasswordValidator 创建密码验证器，requiredClasses 和 banned 为逗号分隔的列表
+func NewPasswordValidator(minLength int, requiredClasses, banned string) *PasswordValidator {
+	return &PasswordValidator{
+		MinLength:       minLength,
+		RequiredClasses: splitList(requiredClasses),
+		Banned:          splitList(banned),
+	}
+}
+
+// Validate 校验密码，返回全部不满足的规则
+func (v *PasswordValidator) Validate(username, password string) []PasswordViolation {
+	var violations []PasswordViolation
+
+	if length := utf8.RuneCountInString(password); length < v.MinLength {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordTooShort,
+			Message: fmt.Sprintf("密码长度不能少于%d位", v.MinLength),
+			Param:   fmt.Sprint(v.MinLength),
+		})
+	}
+	if len(password) > passwordMaxLength {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordTooLong,
+			Message: fmt.Sprintf("密码长度不能超过%d字节", passwordMaxLength),
+			Param:   fmt.Sprint(passwordMaxLength),
+		})
+	}
+
+	for _, class := range v.RequiredClasses {
+		// 未知类别忽略，避免配置错误导致无法设置密码
+		if _, ok := classNames[class]; !ok {
+			continue
+		}
+		if !hasClass(password, class) {
+			violations = append(violations, PasswordViolation{
+				Code:    PasswordMissingClass,
+				Message: "密码必须包含" + classNames[class],
+				Param:   class,
+			})
+		}
+	}
+
+	lower := strings.ToLower(password)
+	for _, banned := range v.Banned {
+		if lower == strings.ToLower(banned) {
+			violations = append(violations, PasswordViolation{
+				Code:    PasswordBanned,
+				Message: "密码过于常见，请更换",
+			})
+			break
+		}
+	}
+
+	if username = strings.ToLower(strings.TrimSpace(username)); username != "" && strings.Contains(lower, username) {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordContainsAccount,
+			Message: "密码不能包含用户名",
+		})
+	}
+
+	return violations
+}
+
+var classNames = map[string]string{
+	PasswordClassUpper:  "大写字母",
+	PasswordClassLower:  "小写字母",
+	PasswordClassDigit:  "数字",
+	PasswordClassSymbol: "特殊字符",
+}
+
+func hasClass(password, class string) bool {
+	for _, r := range password {
+		switch class {
+		case PasswordClassUpper:
+			if unicode.IsUpper(r) {
+				return true
+			}
+		case PasswordClassLower:
+			if unicode.IsLower(r) {
+				return true
+			}
+		case PasswordClassDigit:
+			if unicode.IsDigit(r) {
+				return true
+			}
+		case PasswordClassSymbol:
+			if unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
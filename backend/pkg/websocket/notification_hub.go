@@ -99,6 +99,27 @@ func (h *NotificationHub) SendToUser(userID uint, userType string, message inter
 	return nil
 }
 
+// Disconnect 关闭用户的 WebSocket 连接，sessionID 为空时关闭该用户的全部连接
+func (h *NotificationHub) Disconnect(userID uint, userType string, sessionID string) int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	closed := 0
+	for id, client := range h.clients {
+		if client.UserID != userID || client.UserType != userType {
+			continue
+		}
+		if sessionID != "" && client.SessionID != sessionID {
+			continue
+		}
+		// 关闭发送通道后 WritePump 会发送关闭帧并断开连接
+		delete(h.clients, id)
+		close(client.Send)
+		closed++
+	}
+	return closed
+}
+
 // Broadcast 广播消息给所有用户
 func (h *NotificationHub) Broadcast(message interface{}) error {
 	data, err := json.Marshal(message)
@@ -36,13 +36,14 @@ type WebSocket struct {
 
 // Client WebSocket客户端连接
 type Client struct {
-	ID       string           // 客户端唯一标识
-	Conn     *gorillaws.Conn  // WebSocket连接
-	UserID   uint             // 用户ID
-	UserType string           // 用户类型
-	Username string           // 用户名
-	Hub      *NotificationHub // 通知中心
-	Send     chan []byte      // 发送消息的通道
+	ID        string           // 客户端唯一标识
+	Conn      *gorillaws.Conn  // WebSocket连接
+	UserID    uint             // 用户ID
+	UserType  string           // 用户类型
+	Username  string           // 用户名
+	SessionID string           // 登录会话ID
+	Hub       *NotificationHub // 通知中心
+	Send      chan []byte      // 发送消息的通道
 }
 
 // NewWebSocket 创建新的WebSocket连接
@@ -138,15 +139,16 @@ func (c *Client) WritePump() {
 	}
 }
 
-// NewClient 创建新的客户端连接
-func NewClient(conn *gorillaws.Conn, userID uint, userType string, username string, hub *NotificationHub) *Client {
+// NewClient 创建新的客户端连接，同一用户的不同登录会话可同时在线
+func NewClient(conn *gorillaws.Conn, userID uint, userType string, username string, sessionID string, hub *NotificationHub) *Client {
 	return &Client{
-		ID:       fmt.Sprintf("%s_%d", userType, userID),
-		Conn:     conn,
-		UserID:   userID,
-		UserType: userType,
-		Username: username,
-		Hub:      hub,
-		Send:     make(chan []byte, 256), // 缓冲区大小设为256
+		ID:        fmt.Sprintf("%s_%d_%s", userType, userID, sessionID),
+		Conn:      conn,
+		UserID:    userID,
+		UserType:  userType,
+		Username:  username,
+		SessionID: sessionID,
+		Hub:       hub,
+		Send:      make(chan []byte, 256), // 缓冲区大小设为256
 	}
 }